@@ -10,6 +10,9 @@ import (
 type ConnectionManager interface {
 	Lifecycle
 	Count() int
+	// OutboundCount returns the number of active connections handled by the
+	// outbound, including those dialed through a load-balance group.
+	OutboundCount(tag string) int
 	CloseAll()
 	TrackConn(conn net.Conn) net.Conn
 	TrackPacketConn(conn net.PacketConn) net.PacketConn
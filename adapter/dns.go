@@ -3,6 +3,7 @@ package adapter
 import (
 	"context"
 	"net/netip"
+	"strings"
 	"time"
 
 	C "github.com/sagernet/sing-box/constant"
@@ -10,6 +11,7 @@ import (
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/common/observable"
 	"github.com/sagernet/sing/service"
 
 	"github.com/miekg/dns"
@@ -24,6 +26,72 @@ type DNSRouter interface {
 	ResetNetwork()
 }
 
+// DNSQueryLogRouter is implemented by DNS routers keeping a log of queries.
+type DNSQueryLogRouter interface {
+	// QueryLog returns nil if the query log is disabled.
+	QueryLog() DNSQueryLog
+}
+
+// DNSQueryLog is a ring buffer of recent queries, observable for new ones.
+type DNSQueryLog interface {
+	observable.Observable[DNSQueryLogEntry]
+	// Entries returns the entries matching the filter, newest first.
+	Entries(filter DNSQueryLogFilter) []DNSQueryLogEntry
+	Clear()
+}
+
+type DNSQueryLogEntry struct {
+	ID        uint64
+	Time      time.Time
+	Duration  time.Duration
+	Client    netip.Addr
+	Inbound   string
+	Domain    string
+	QueryType string
+	// Rule is the rule with the final action, or empty for the default server.
+	Rule      string
+	Transport string
+	// Rcode is empty if the exchange failed with Error.
+	Rcode   string
+	Answers []string
+	Error   string
+}
+
+type DNSQueryLogFilter struct {
+	Client    netip.Prefix
+	Domain    string
+	Rcode     string
+	Transport string
+	// Before skips entries with greater or equal IDs, for pagination.
+	Before uint64
+	Limit  int
+}
+
+func (f DNSQueryLogFilter) Match(entry *DNSQueryLogEntry) bool {
+	if f.Before != 0 && entry.ID >= f.Before {
+		return false
+	}
+	if f.Client.IsValid() && !f.Client.Contains(entry.Client) {
+		return false
+	}
+	if f.Domain != "" && !strings.Contains(strings.ToLower(entry.Domain), strings.ToLower(f.Domain)) {
+		return false
+	}
+	if f.Rcode != "" {
+		if strings.EqualFold(f.Rcode, "error") {
+			if entry.Error == "" {
+				return false
+			}
+		} else if !strings.EqualFold(f.Rcode, entry.Rcode) {
+			return false
+		}
+	}
+	if f.Transport != "" && f.Transport != entry.Transport {
+		return false
+	}
+	return true
+}
+
 type DNSClient interface {
 	Start()
 	Exchange(ctx context.Context, transport DNSTransport, message *dns.Msg, options DNSQueryOptions, responseChecker func(response *dns.Msg) bool) (*dns.Msg, error)
@@ -37,6 +105,7 @@ type DNSQueryOptions struct {
 	LookupStrategy         C.DomainStrategy
 	DisableCache           bool
 	DisableOptimisticCache bool
+	DisableDNSSEC          bool
 	RewriteTTL             *uint32
 	Timeout                time.Duration
 	ClientSubnet           netip.Prefix
@@ -9,11 +9,15 @@ import (
 	"github.com/sagernet/sing-box/common/taskmonitor"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 )
 
-var _ adapter.EndpointManager = (*Manager)(nil)
+var (
+	_ adapter.EndpointManager        = (*Manager)(nil)
+	_ adapter.OutboundOptionsManager = (*Manager)(nil)
+)
 
 type Manager struct {
 	logger        log.ContextLogger
@@ -23,6 +27,7 @@ type Manager struct {
 	stage         adapter.StartStage
 	endpoints     []adapter.Endpoint
 	endpointByTag map[string]adapter.Endpoint
+	optionsByTag  map[string]option.Outbound
 }
 
 func NewManager(logger log.ContextLogger, registry adapter.EndpointRegistry) *Manager {
@@ -30,6 +35,7 @@ func NewManager(logger log.ContextLogger, registry adapter.EndpointRegistry) *Ma
 		logger:        logger,
 		registry:      registry,
 		endpointByTag: make(map[string]adapter.Endpoint),
+		optionsByTag:  make(map[string]option.Outbound),
 	}
 }
 
@@ -94,6 +100,13 @@ func (m *Manager) Get(tag string) (adapter.Endpoint, bool) {
 	return endpoint, found
 }
 
+func (m *Manager) OutboundOptions(tag string) (option.Outbound, bool) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	options, loaded := m.optionsByTag[tag]
+	return options, loaded
+}
+
 func (m *Manager) Remove(tag string) error {
 	m.access.Lock()
 	endpoint, found := m.endpointByTag[tag]
@@ -102,6 +115,7 @@ func (m *Manager) Remove(tag string) error {
 		return os.ErrInvalid
 	}
 	delete(m.endpointByTag, tag)
+	delete(m.optionsByTag, tag)
 	index := common.Index(m.endpoints, func(it adapter.Endpoint) bool {
 		return it == endpoint
 	})
@@ -152,5 +166,10 @@ func (m *Manager) Create(ctx context.Context, router adapter.Router, logger log.
 	}
 	m.endpoints = append(m.endpoints, endpoint)
 	m.endpointByTag[tag] = endpoint
+	m.optionsByTag[tag] = option.Outbound{
+		Type:    outboundType,
+		Tag:     tag,
+		Options: options,
+	}
 	return nil
 }
@@ -5,8 +5,10 @@ import (
 	"context"
 	"encoding/binary"
 	"io"
+	"net/netip"
 	"time"
 
+	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/observable"
 	"github.com/sagernet/sing/common/varbin"
 )
@@ -28,6 +30,9 @@ type URLTestHistory struct {
 type URLTestHistoryStorage interface {
 	SetHook(hook *observable.Subscriber[struct{}])
 	LoadURLTestHistory(tag string) *URLTestHistory
+	// LoadURLTestFailures returns the number of failed tests since the last
+	// successful one.
+	LoadURLTestFailures(tag string) uint32
 	DeleteURLTestHistory(tag string)
 	StoreURLTestHistory(tag string, history *URLTestHistory)
 	Close() error
@@ -61,17 +66,51 @@ type CacheFile interface {
 	StoreGroupExpand(group string, expand bool) error
 	LoadRuleSet(tag string) *SavedBinary
 	SaveRuleSet(tag string, set *SavedBinary) error
+	URLTestHistoryCache
+	UserQuotaCache
+
+	StoreRuleStats() bool
+	RuleStatsStore
+
+	StoreDNSQueryLog() bool
+	DNSQueryLogStore
+}
+
+type URLTestHistoryCache interface {
+	LoadURLTestHistories() map[string]*SavedURLTestHistory
+	SaveURLTestHistory(tag string, history *SavedURLTestHistory) error
+	DeleteURLTestHistory(tag string) error
+}
+
+type UserQuotaCache interface {
+	LoadUserQuotas(tag string) map[string]*SavedUserQuota
+	SaveUserQuota(tag string, user string, quota *SavedUserQuota) error
+	DeleteUserQuota(tag string, user string) error
+}
+
+type RuleStatsStore interface {
+	LoadRuleStats() map[string]*SavedRuleStats
+	// SaveRuleStats replaces all saved rule statistics.
+	SaveRuleStats(stats map[string]*SavedRuleStats) error
+}
+
+type DNSQueryLogStore interface {
+	// LoadDNSQueryLog returns saved entries in ascending order of ID.
+	LoadDNSQueryLog() []DNSQueryLogEntry
+	// SaveDNSQueryLog replaces all saved entries.
+	SaveDNSQueryLog(entries []DNSQueryLogEntry) error
 }
 
 type SavedBinary struct {
-	Content     []byte
-	LastUpdated time.Time
-	LastEtag    string
+	Content      []byte
+	LastUpdated  time.Time
+	LastEtag     string
+	LastModified string
 }
 
 func (s *SavedBinary) MarshalBinary() ([]byte, error) {
 	var buffer bytes.Buffer
-	err := binary.Write(&buffer, binary.BigEndian, uint8(1))
+	err := binary.Write(&buffer, binary.BigEndian, uint8(2))
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +134,14 @@ func (s *SavedBinary) MarshalBinary() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	_, err = varbin.WriteUvarint(&buffer, uint64(len(s.LastModified)))
+	if err != nil {
+		return nil, err
+	}
+	_, err = buffer.WriteString(s.LastModified)
+	if err != nil {
+		return nil, err
+	}
 	return buffer.Bytes(), nil
 }
 
@@ -130,6 +177,315 @@ func (s *SavedBinary) UnmarshalBinary(data []byte) error {
 		return err
 	}
 	s.LastEtag = string(etagBytes)
+	if version < 2 {
+		return nil
+	}
+	lastModifiedLength, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return err
+	}
+	lastModifiedBytes := make([]byte, lastModifiedLength)
+	_, err = io.ReadFull(reader, lastModifiedBytes)
+	if err != nil {
+		return err
+	}
+	s.LastModified = string(lastModifiedBytes)
+	return nil
+}
+
+type SavedURLTestHistory struct {
+	Time     time.Time
+	Delay    uint16
+	Failures uint32
+}
+
+func (s *SavedURLTestHistory) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	err := binary.Write(&buffer, binary.BigEndian, uint8(1))
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, s.Time.Unix())
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, s.Delay)
+	if err != nil {
+		return nil, err
+	}
+	_, err = varbin.WriteUvarint(&buffer, uint64(s.Failures))
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (s *SavedURLTestHistory) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+	var version uint8
+	err := binary.Read(reader, binary.BigEndian, &version)
+	if err != nil {
+		return err
+	}
+	var testTime int64
+	err = binary.Read(reader, binary.BigEndian, &testTime)
+	if err != nil {
+		return err
+	}
+	s.Time = time.Unix(testTime, 0)
+	err = binary.Read(reader, binary.BigEndian, &s.Delay)
+	if err != nil {
+		return err
+	}
+	failures, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return err
+	}
+	s.Failures = uint32(failures)
+	return nil
+}
+
+// SavedUserQuota is the usage of a user, with limit and expiry overridden
+// at runtime if set.
+type SavedUserQuota struct {
+	Upload   uint64
+	Download uint64
+	Limit    *uint64
+	Expire   *time.Time
+	// AddedByAPI is true for users not in the configuration.
+	AddedByAPI bool
+}
+
+func (s *SavedUserQuota) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	err := binary.Write(&buffer, binary.BigEndian, uint8(2))
+	if err != nil {
+		return nil, err
+	}
+	_, err = varbin.WriteUvarint(&buffer, s.Upload)
+	if err != nil {
+		return nil, err
+	}
+	_, err = varbin.WriteUvarint(&buffer, s.Download)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, s.Limit != nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.Limit != nil {
+		_, err = varbin.WriteUvarint(&buffer, *s.Limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = binary.Write(&buffer, binary.BigEndian, s.Expire != nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.Expire != nil {
+		err = binary.Write(&buffer, binary.BigEndian, s.Expire.Unix())
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = binary.Write(&buffer, binary.BigEndian, s.AddedByAPI)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (s *SavedUserQuota) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+	var version uint8
+	err := binary.Read(reader, binary.BigEndian, &version)
+	if err != nil {
+		return err
+	}
+	s.Upload, err = binary.ReadUvarint(reader)
+	if err != nil {
+		return err
+	}
+	s.Download, err = binary.ReadUvarint(reader)
+	if err != nil {
+		return err
+	}
+	var hasLimit bool
+	err = binary.Read(reader, binary.BigEndian, &hasLimit)
+	if err != nil {
+		return err
+	}
+	if hasLimit {
+		limit, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return err
+		}
+		s.Limit = &limit
+	}
+	var hasExpire bool
+	err = binary.Read(reader, binary.BigEndian, &hasExpire)
+	if err != nil {
+		return err
+	}
+	if hasExpire {
+		var expire int64
+		err = binary.Read(reader, binary.BigEndian, &expire)
+		if err != nil {
+			return err
+		}
+		expireTime := time.Unix(expire, 0)
+		s.Expire = &expireTime
+	}
+	if version < 2 {
+		// version 1 restored users with overrides as added by the API
+		s.AddedByAPI = s.Limit != nil || s.Expire != nil
+		return nil
+	}
+	return binary.Read(reader, binary.BigEndian, &s.AddedByAPI)
+}
+
+type SavedRuleStats struct {
+	Matches  uint64
+	Upload   uint64
+	Download uint64
+	LastHit  time.Time
+}
+
+func (s *SavedRuleStats) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	err := binary.Write(&buffer, binary.BigEndian, uint8(1))
+	if err != nil {
+		return nil, err
+	}
+	_, err = varbin.WriteUvarint(&buffer, s.Matches)
+	if err != nil {
+		return nil, err
+	}
+	_, err = varbin.WriteUvarint(&buffer, s.Upload)
+	if err != nil {
+		return nil, err
+	}
+	_, err = varbin.WriteUvarint(&buffer, s.Download)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, s.LastHit.Unix())
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (s *SavedRuleStats) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+	var version uint8
+	err := binary.Read(reader, binary.BigEndian, &version)
+	if err != nil {
+		return err
+	}
+	s.Matches, err = binary.ReadUvarint(reader)
+	if err != nil {
+		return err
+	}
+	s.Upload, err = binary.ReadUvarint(reader)
+	if err != nil {
+		return err
+	}
+	s.Download, err = binary.ReadUvarint(reader)
+	if err != nil {
+		return err
+	}
+	var lastHit int64
+	err = binary.Read(reader, binary.BigEndian, &lastHit)
+	if err != nil {
+		return err
+	}
+	if lastHit > 0 {
+		s.LastHit = time.Unix(lastHit, 0)
+	}
+	return nil
+}
+
+type savedDNSQueryLogEntry struct {
+	ID        uint64
+	Time      int64
+	Duration  int64
+	Client    string
+	Inbound   string
+	Domain    string
+	QueryType string
+	Rule      string
+	Transport string
+	Rcode     string
+	Answers   []string
+	Error     string
+}
+
+func (e *DNSQueryLogEntry) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	err := binary.Write(&buffer, binary.BigEndian, uint8(1))
+	if err != nil {
+		return nil, err
+	}
+	var client string
+	if e.Client.IsValid() {
+		client = e.Client.String()
+	}
+	err = varbin.Write(&buffer, binary.BigEndian, savedDNSQueryLogEntry{
+		ID:        e.ID,
+		Time:      e.Time.UnixNano(),
+		Duration:  int64(e.Duration),
+		Client:    client,
+		Inbound:   e.Inbound,
+		Domain:    e.Domain,
+		QueryType: e.QueryType,
+		Rule:      e.Rule,
+		Transport: e.Transport,
+		Rcode:     e.Rcode,
+		Answers:   e.Answers,
+		Error:     e.Error,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (e *DNSQueryLogEntry) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+	var version uint8
+	err := binary.Read(reader, binary.BigEndian, &version)
+	if err != nil {
+		return err
+	}
+	if version != 1 {
+		return E.New("unknown DNS query log entry version: ", version)
+	}
+	saved, err := varbin.ReadValue[savedDNSQueryLogEntry](reader, binary.BigEndian)
+	if err != nil {
+		return err
+	}
+	*e = DNSQueryLogEntry{
+		ID:        saved.ID,
+		Time:      time.Unix(0, saved.Time),
+		Duration:  time.Duration(saved.Duration),
+		Inbound:   saved.Inbound,
+		Domain:    saved.Domain,
+		QueryType: saved.QueryType,
+		Rule:      saved.Rule,
+		Transport: saved.Transport,
+		Rcode:     saved.Rcode,
+		Answers:   saved.Answers,
+		Error:     saved.Error,
+	}
+	if saved.Client != "" {
+		e.Client, err = netip.ParseAddr(saved.Client)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -158,6 +514,19 @@ type URLTestGroup interface {
 	PerformUpdateCheck(tag string, force bool)
 }
 
+// CircuitBreakerGroup is implemented by groups that passively eject failing
+// outbounds.
+type CircuitBreakerGroup interface {
+	OutboundGroup
+	CircuitBreakerStatus() map[string]CircuitBreakerStatus
+}
+
+type CircuitBreakerStatus struct {
+	State    string
+	Failures uint32
+	RetryAt  time.Time
+}
+
 type SelectorGroup interface {
 	OutboundGroup
 	Selected() Outbound
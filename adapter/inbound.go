@@ -6,6 +6,7 @@ import (
 	"net/netip"
 	"time"
 
+	"github.com/sagernet/sing-box/common/ratelimit"
 	"github.com/sagernet/sing-box/common/tlsspoof"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -56,13 +57,18 @@ type InboundContext struct {
 
 	// sniffer
 
-	Protocol     string
-	Domain       string
-	Client       string
-	SniffHost    string
-	SniffContext any
-	SnifferNames []string
-	SniffError   error
+	Protocol      string
+	Domain        string
+	Client        string
+	SniffHost     string
+	HTTPMethod    string
+	HTTPPath      string
+	HTTPUserAgent string
+	JA3           string
+	JA4           string
+	SniffContext  any
+	SnifferNames  []string
+	SniffError    error
 
 	// cache
 
@@ -79,6 +85,8 @@ type InboundContext struct {
 	TLSRecordFragment         bool
 	TLSSpoof                  string
 	TLSSpoofMethod            tlsspoof.Method
+	UploadLimits              []*ratelimit.Bucket
+	DownloadLimits            []*ratelimit.Bucket
 
 	NetworkStrategy     *C.NetworkStrategy
 	NetworkType         []C.InterfaceType
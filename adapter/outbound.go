@@ -40,6 +40,11 @@ type OutboundRegistry interface {
 	CreateOutbound(ctx context.Context, router Router, logger log.ContextLogger, tag string, outboundType string, options any) (Outbound, error)
 }
 
+// OutboundOptionsManager looks up the options an outbound was created with.
+type OutboundOptionsManager interface {
+	OutboundOptions(tag string) (option.Outbound, bool)
+}
+
 type OutboundManager interface {
 	Lifecycle
 	Outbounds() []Outbound
@@ -11,12 +11,16 @@ import (
 	"github.com/sagernet/sing-box/common/taskmonitor"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
 )
 
-var _ adapter.OutboundManager = (*Manager)(nil)
+var (
+	_ adapter.OutboundManager        = (*Manager)(nil)
+	_ adapter.OutboundOptionsManager = (*Manager)(nil)
+)
 
 type Manager struct {
 	logger                  log.ContextLogger
@@ -28,6 +32,7 @@ type Manager struct {
 	stage                   adapter.StartStage
 	outbounds               []adapter.Outbound
 	outboundByTag           map[string]adapter.Outbound
+	optionsByTag            map[string]option.Outbound
 	dependByTag             map[string][]string
 	defaultOutbound         adapter.Outbound
 	defaultOutboundFallback func() (adapter.Outbound, error)
@@ -41,6 +46,7 @@ func NewManager(logger logger.ContextLogger, registry adapter.OutboundRegistry,
 		endpoint:      endpoint,
 		defaultTag:    defaultTag,
 		outboundByTag: make(map[string]adapter.Outbound),
+		optionsByTag:  make(map[string]option.Outbound),
 		dependByTag:   make(map[string][]string),
 	}
 }
@@ -210,6 +216,19 @@ func (m *Manager) Outbound(tag string) (adapter.Outbound, bool) {
 	return m.endpoint.Get(tag)
 }
 
+func (m *Manager) OutboundOptions(tag string) (option.Outbound, bool) {
+	m.access.RLock()
+	options, loaded := m.optionsByTag[tag]
+	m.access.RUnlock()
+	if loaded {
+		return options, true
+	}
+	if optionsManager, isOptionsManager := m.endpoint.(adapter.OutboundOptionsManager); isOptionsManager {
+		return optionsManager.OutboundOptions(tag)
+	}
+	return option.Outbound{}, false
+}
+
 func (m *Manager) Default() adapter.Outbound {
 	m.access.RLock()
 	defer m.access.RUnlock()
@@ -238,6 +257,7 @@ func (m *Manager) Remove(tag string) error {
 		return os.ErrInvalid
 	}
 	delete(m.outboundByTag, tag)
+	delete(m.optionsByTag, tag)
 	index := common.Index(m.outbounds, func(it adapter.Outbound) bool {
 		return it == outbound
 	})
@@ -316,6 +336,11 @@ func (m *Manager) Create(ctx context.Context, router adapter.Router, logger log.
 	}
 	m.outbounds = append(m.outbounds, outbound)
 	m.outboundByTag[tag] = outbound
+	m.optionsByTag[tag] = option.Outbound{
+		Type:    inboundType,
+		Tag:     tag,
+		Options: options,
+	}
 	dependencies := outbound.Dependencies()
 	for _, dependency := range dependencies {
 		m.dependByTag[dependency] = append(m.dependByTag[dependency], tag)
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/x/list"
 )
 
 type OutboundProvider interface {
@@ -12,6 +13,7 @@ type OutboundProvider interface {
 	Path() string
 	Type() string
 	HealthcheckUrl() string
+	Format() string
 	Outbounds() []Outbound
 	Outbound(tag string) (Outbound, bool)
 	UpdateTime() time.Time
@@ -34,4 +36,24 @@ type OutboundProviderManager interface {
 	Remove(tag string) error
 	Create(ctx context.Context, router Router, tag string, options option.OutboundProvider) error
 	Default() Outbound
+	// RegisterCallback registers a callback invoked after each successful
+	// update of an outbound provider.
+	RegisterCallback(callback OutboundProviderUpdateCallback) *list.Element[OutboundProviderUpdateCallback]
+	UnregisterCallback(element *list.Element[OutboundProviderUpdateCallback])
+}
+
+type OutboundProviderUpdateCallback func(provider OutboundProvider)
+
+// OutboundProviderQuota is implemented by providers enforcing the usage and
+// expiry reported by the subscription.
+type OutboundProviderQuota interface {
+	QuotaStatus() OutboundProviderQuotaStatus
+	// Exhausted reports whether groups should stop selecting outbounds of
+	// the provider.
+	Exhausted() bool
+}
+
+type OutboundProviderQuotaStatus struct {
+	Warnings []string `json:"warnings,omitempty"`
+	Exceeded bool     `json:"exceeded"`
 }
@@ -25,6 +25,7 @@ import (
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/rw"
+	"github.com/sagernet/sing/service"
 	"github.com/sagernet/sing/service/pause"
 
 	R "github.com/dlclark/regexp2"
@@ -46,6 +47,7 @@ type myProviderAdapter struct {
 	router          adapter.Router
 	logger          log.ContextLogger
 	subInfo         SubInfo
+	quota           *providerQuota
 
 	// Common config
 	tag                 string
@@ -53,9 +55,11 @@ type myProviderAdapter struct {
 	enableHealthcheck   bool
 	healthcheckUrl      string
 	healthcheckInterval time.Duration
+	healthcheckProbe    *urltest.Probe
 	outboundOverride    *option.OutboundOverrideOptions
 	healchcheckHistory  adapter.URLTestHistoryStorage
 	providerType        string
+	format              common.TypedValue[string]
 	lastUpdated         time.Time
 	outbounds           []adapter.Outbound
 	outboundByTag       map[string]adapter.Outbound
@@ -91,6 +95,10 @@ func (p *myProviderAdapter) Type() string {
 	return p.providerType
 }
 
+func (p *myProviderAdapter) Format() string {
+	return p.format.Load()
+}
+
 func (p *myProviderAdapter) UpdateTime() time.Time {
 	return p.lastUpdated
 }
@@ -102,6 +110,17 @@ func (p *myProviderAdapter) Outbound(tag string) (adapter.Outbound, bool) {
 	return outbound, loaded
 }
 
+func (p *myProviderAdapter) OutboundOptions(tag string) (option.Outbound, bool) {
+	p.access.RLock()
+	defer p.access.RUnlock()
+	for _, outbound := range p.lastOuts {
+		if outbound.Tag == tag {
+			return outbound, true
+		}
+	}
+	return option.Outbound{}, false
+}
+
 func (p *myProviderAdapter) Outbounds() []adapter.Outbound {
 	p.access.RLock()
 	defer p.access.RUnlock()
@@ -123,7 +142,8 @@ func (p *myProviderAdapter) firstStart(ports []string) error {
 	fileInfo, _ := os.Stat(p.path)
 	fileModeTime := fileInfo.ModTime()
 	info, content := p.getContentFromFile(p.router)
-	p.subInfo = info
+	p.setSubInfo(info)
+	p.updateQuota(false)
 	p.lastUpdated = fileModeTime
 	outbounds, err := p.parseOutbounds(p.ctx, p.router, decodeBase64Safe(content))
 	if err != nil {
@@ -153,17 +173,30 @@ func getFirstLine(content string) (string, string) {
 }
 
 func (p *myProviderAdapter) SubInfo() map[string]int64 {
-	if p.subInfo.upload != 0 || p.subInfo.download != 0 || p.subInfo.total != 0 || p.subInfo.expire != 0 {
+	subInfo := p.loadSubInfo()
+	if subInfo.upload != 0 || subInfo.download != 0 || subInfo.total != 0 || subInfo.expire != 0 {
 		info := make(map[string]int64)
-		info["Upload"] = p.subInfo.upload
-		info["Download"] = p.subInfo.download
-		info["Total"] = p.subInfo.total
-		info["Expire"] = p.subInfo.expire
+		info["Upload"] = subInfo.upload
+		info["Download"] = subInfo.download
+		info["Total"] = subInfo.total
+		info["Expire"] = subInfo.expire
 		return info
 	}
 	return nil
 }
 
+func (p *myProviderAdapter) loadSubInfo() SubInfo {
+	p.access.RLock()
+	defer p.access.RUnlock()
+	return p.subInfo
+}
+
+func (p *myProviderAdapter) setSubInfo(info SubInfo) {
+	p.access.Lock()
+	p.subInfo = info
+	p.access.Unlock()
+}
+
 func parseSubInfo(infoString string) (SubInfo, bool) {
 	var info SubInfo
 	result := subInfoParser.FindStringSubmatch(infoString)
@@ -189,6 +222,13 @@ func (p *myProviderAdapter) createOutbounds(ctx context.Context, router adapter.
 		switch otype {
 		case C.TypeDirect, C.TypeBlock, C.TypeDNS, C.TypeSelector, C.TypeURLTest, C.TypePass:
 			continue
+		case C.TypeWireGuard:
+			out, err := p.createWireGuardEndpoint(ctx, router, outbound)
+			if err != nil && p.logger != nil {
+				p.logger.WarnContext(ctx, "create provider[", p.tag, "] outbound[", tag, "]/", otype, " failed: ", err)
+				continue
+			}
+			outs = append(outs, out)
 		default:
 			out, err := p.router.OutboundManager().CreateOutbound(
 				ctx,
@@ -211,6 +251,27 @@ func (p *myProviderAdapter) createOutbounds(ctx context.Context, router adapter.
 	return outs, nil
 }
 
+// createWireGuardEndpoint creates a WireGuard endpoint for a parsed WireGuard
+// outbound, since the legacy WireGuard outbound has been removed.
+func (p *myProviderAdapter) createWireGuardEndpoint(ctx context.Context, router adapter.Router, outbound option.Outbound) (adapter.Outbound, error) {
+	legacyOptions, isLegacy := outbound.Options.(*option.LegacyWireGuardOutboundOptions)
+	if !isLegacy {
+		return nil, E.New("WireGuard outbound is removed, use WireGuard endpoint instead")
+	}
+	endpointRegistry := service.FromContext[adapter.EndpointRegistry](ctx)
+	if endpointRegistry == nil {
+		return nil, E.New("missing endpoint registry in context")
+	}
+	return endpointRegistry.Create(
+		ctx,
+		router,
+		p.manager.logFactory.NewLogger(F.ToString("provider[", p.tag, "] endpoint/", outbound.Type, "[", outbound.Tag, "]")),
+		outbound.Tag,
+		C.TypeWireGuard,
+		newWireGuardEndpointOptions(legacyOptions),
+	)
+}
+
 func getTrimedFile(path string) []byte {
 	content, _ := os.ReadFile(path)
 	return []byte(trimBlank(string(content)))
@@ -284,7 +345,9 @@ func (p *myProviderAdapter) parseOutbounds(ctx context.Context, router adapter.R
 	if !p.checkChange(finalOuts) {
 		return nil, nil
 	}
+	p.access.Lock()
 	p.lastOuts = finalOuts
+	p.access.Unlock()
 	return p.createOutbounds(ctx, router, finalOuts)
 }
 
@@ -370,7 +433,16 @@ func (p *myProviderAdapter) startOutbounds(router adapter.Router, outbounds []ad
 		}
 		outboundTag[tag] = true
 		monitor := taskmonitor.New(p.logger, C.StartTimeout)
-		if starter, isStarter := out.(interface {
+		if lifecycle, isLifecycle := out.(adapter.Lifecycle); isLifecycle {
+			for _, stage := range adapter.ListStartStages {
+				monitor.Start("initialize outbound provider[", pTag, "]", " outbound/", out.Type(), "[", tag, "]")
+				err := lifecycle.Start(stage)
+				monitor.Finish()
+				if err != nil {
+					return nil, nil, E.Cause(err, stage, " outbound provider[", pTag, "]", " outbound/", out.Type(), "[", tag, "]")
+				}
+			}
+		} else if starter, isStarter := out.(interface {
 			Start() error
 		}); isStarter {
 			monitor.Start("initialize outbound provider[", pTag, "]", " outbound/", out.Type(), "[", tag, "]")
@@ -473,7 +545,7 @@ func (p *myProviderAdapter) healthcheck(ctx context.Context, link string) map[st
 		b.Go(tag, func() (any, error) {
 			ctx, cancel := context.WithTimeout(log.ContextWithNewID(context.Background()), C.TCPTimeout)
 			defer cancel()
-			t, err := urltest.URLTest(ctx, link, detour)
+			t, err := p.healthcheckProbe.Test(ctx, link, detour)
 			if err != nil {
 				p.logger.DebugContext(ctx, "outbound ", tag, " unavailable: ", err)
 				p.healchcheckHistory.DeleteURLTestHistory(tag)
@@ -506,6 +578,9 @@ func (p *myProviderAdapter) Close() error {
 	if p.healthCheckTicker != nil {
 		p.healthCheckTicker.Stop()
 	}
+	if p.quota != nil {
+		p.quota.close()
+	}
 	p.cancel()
 	return nil
 }
@@ -400,72 +400,77 @@ func newClashParser(content string) ([]option.Outbound, error) {
 		return outbounds, err
 	}
 	for _, proxy := range clashConfig.Proxies {
-		protocol, exists := proxy["type"]
-		if !exists {
-			continue
-		}
-		var (
-			outbound option.Outbound
-			stlsPart option.Outbound
-			err      error
-		)
-		stlsPart = option.Outbound{}
-		switch protocol {
-		case "ss":
-			if plugin, exists := proxy["plugin"]; exists {
-				switch plugin {
-				case "shadow-tls":
-					outbound, stlsPart, err = newSTLSClashParser(proxy)
-				case "obfs", "v2ray-plugin":
-					outbound, err = newSSClashParser(proxy)
-				default:
-					continue
-				}
-			} else {
+		outbounds = append(outbounds, newClashProxyParser(proxy)...)
+	}
+	return outbounds, nil
+}
+
+func newClashProxyParser(proxy map[string]any) []option.Outbound {
+	protocol, exists := proxy["type"]
+	if !exists {
+		return nil
+	}
+	var (
+		outbound option.Outbound
+		stlsPart option.Outbound
+		err      error
+	)
+	stlsPart = option.Outbound{}
+	switch protocol {
+	case "ss":
+		if plugin, exists := proxy["plugin"]; exists {
+			switch plugin {
+			case "shadow-tls":
+				outbound, stlsPart, err = newSTLSClashParser(proxy)
+			case "obfs", "v2ray-plugin":
 				outbound, err = newSSClashParser(proxy)
+			default:
+				return nil
 			}
-		case "ssr":
-			outbound, err = newSSRClashParser(proxy)
-		case "http":
-			outbound, err = newHTTPClashParser(proxy)
-		case "tuic":
-			if _, exists := proxy["token"]; exists {
-				continue
-			}
-			outbound, err = newTUICClashParser(proxy)
-		case "vmess":
-			outbound, err = newVMessClashParser(proxy)
-		case "vless":
-			if flow, exists := proxy["flow"].(string); exists && flow != "xtls-rprx-vision" && flow != "" {
-				continue
-			}
-			outbound, err = newVLESSClashParser(proxy)
-		case "socks5":
-			outbound, err = newSOCKS5ClashParser(proxy)
-		case "trojan":
-			if _, exists := proxy["flow"].(string); exists {
-				continue
-			}
-			outbound, err = newTrojanClashParser(proxy)
-		case "hysteria":
-			outbound, err = newHysteriaClashParser(proxy)
-		case "hysteria2":
-			outbound, err = newHysteria2ClashParser(proxy)
-		case "wireguard":
-			outbound, err = newWireGuardClashParser(proxy)
-		case "anytls":
-			outbound, err = newAnyTLSClashParser(proxy)
-		default:
-			continue
+		} else {
+			outbound, err = newSSClashParser(proxy)
 		}
-		if err == nil {
-			outbounds = append(outbounds, outbound)
-			if stlsPart.Type != "" {
-				outbounds = append(outbounds, stlsPart)
-			}
+	case "ssr":
+		outbound, err = newSSRClashParser(proxy)
+	case "http":
+		outbound, err = newHTTPClashParser(proxy)
+	case "tuic":
+		if _, exists := proxy["token"]; exists {
+			return nil
+		}
+		outbound, err = newTUICClashParser(proxy)
+	case "vmess":
+		outbound, err = newVMessClashParser(proxy)
+	case "vless":
+		if flow, exists := proxy["flow"].(string); exists && flow != "xtls-rprx-vision" && flow != "" {
+			return nil
 		}
+		outbound, err = newVLESSClashParser(proxy)
+	case "socks5":
+		outbound, err = newSOCKS5ClashParser(proxy)
+	case "trojan":
+		if _, exists := proxy["flow"].(string); exists {
+			return nil
+		}
+		outbound, err = newTrojanClashParser(proxy)
+	case "hysteria":
+		outbound, err = newHysteriaClashParser(proxy)
+	case "hysteria2":
+		outbound, err = newHysteria2ClashParser(proxy)
+	case "wireguard":
+		outbound, err = newWireGuardClashParser(proxy)
+	case "anytls":
+		outbound, err = newAnyTLSClashParser(proxy)
+	default:
+		return nil
 	}
-	return outbounds, nil
+	if err != nil {
+		return nil
+	}
+	if stlsPart.Type != "" {
+		return []option.Outbound{outbound, stlsPart}
+	}
+	return []option.Outbound{outbound}
 }
 
 func newSSClashParser(proxy map[string]any) (option.Outbound, error) {
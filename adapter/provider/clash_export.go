@@ -0,0 +1,482 @@
+package provider
+
+import (
+	"strings"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// ExportClashProxy converts an outbound into a Clash `proxies` entry
+// understood by newClashParser.
+func ExportClashProxy(outbound option.Outbound) (map[string]any, error) {
+	switch options := outbound.Options.(type) {
+	case *option.ShadowsocksOutboundOptions:
+		return exportSSClashProxy(outbound.Tag, options)
+	case *option.ShadowsocksROutboundOptions:
+		return exportSSRClashProxy(outbound.Tag, options), nil
+	case *option.VMessOutboundOptions:
+		return exportVMessClashProxy(outbound.Tag, options)
+	case *option.VLESSOutboundOptions:
+		return exportVLESSClashProxy(outbound.Tag, options)
+	case *option.TrojanOutboundOptions:
+		return exportTrojanClashProxy(outbound.Tag, options)
+	case *option.TUICOutboundOptions:
+		return exportTUICClashProxy(outbound.Tag, options), nil
+	case *option.HysteriaOutboundOptions:
+		return exportHysteriaClashProxy(outbound.Tag, options), nil
+	case *option.Hysteria2OutboundOptions:
+		return exportHysteria2ClashProxy(outbound.Tag, options), nil
+	case *option.AnyTLSOutboundOptions:
+		return exportAnyTLSClashProxy(outbound.Tag, options), nil
+	case *option.LegacyWireGuardOutboundOptions:
+		return exportWireGuardClashProxy(outbound.Tag, options), nil
+	case *option.WireGuardEndpointOptions:
+		legacyOptions, err := newLegacyWireGuardOptions(options)
+		if err != nil {
+			return nil, err
+		}
+		return exportWireGuardClashProxy(outbound.Tag, legacyOptions), nil
+	case *option.SOCKSOutboundOptions:
+		return exportSOCKSClashProxy(outbound.Tag, options), nil
+	case *option.HTTPOutboundOptions:
+		return exportHTTPClashProxy(outbound.Tag, options), nil
+	case *option.SSHOutboundOptions:
+		return exportSSHClashProxy(outbound.Tag, options), nil
+	default:
+		return nil, E.New("unsupported outbound type: ", outbound.Type)
+	}
+}
+
+// ExportClashConfig converts outbounds into a Clash configuration containing
+// only the `proxies` section.
+func ExportClashConfig(outbounds []option.Outbound) ([]byte, error) {
+	return ExportOutbounds(outbounds, ExportFormatClash, nil)
+}
+
+func newClashProxy(tag string, proxyType string, options option.ServerOptions, dialer option.DialerOptions) map[string]any {
+	proxy := map[string]any{
+		"name":   tag,
+		"type":   proxyType,
+		"server": options.Server,
+		"port":   int(options.ServerPort),
+	}
+	if dialer.TCPFastOpen {
+		proxy["tfo"] = true
+	}
+	if dialer.Detour != "" {
+		proxy["dialer-proxy"] = dialer.Detour
+	}
+	if dialer.BindInterface != "" {
+		proxy["interface-name"] = dialer.BindInterface
+	}
+	return proxy
+}
+
+func exportClashTLS(proxy map[string]any, serverNameKey string, options *option.OutboundTLSOptions) {
+	if options == nil || !options.Enabled {
+		return
+	}
+	proxy["tls"] = true
+	if options.ServerName != "" {
+		proxy[serverNameKey] = options.ServerName
+	}
+	if options.Insecure {
+		proxy["skip-cert-verify"] = true
+	}
+	if options.DisableSNI {
+		proxy["disable-sni"] = true
+	}
+	if len(options.ALPN) > 0 {
+		proxy["alpn"] = []string(options.ALPN)
+	}
+	if options.UTLS != nil && options.UTLS.Enabled {
+		proxy["client-fingerprint"] = options.UTLS.Fingerprint
+	}
+	if options.Reality != nil && options.Reality.Enabled {
+		proxy["reality-opts"] = map[string]any{
+			"public-key": options.Reality.PublicKey,
+			"short-id":   options.Reality.ShortID,
+		}
+	}
+}
+
+func exportClashHeaders(headers map[string][]string) map[string]any {
+	result := make(map[string]any, len(headers))
+	for key, values := range headers {
+		if len(values) == 1 {
+			result[key] = values[0]
+		} else {
+			result[key] = values
+		}
+	}
+	return result
+}
+
+func exportClashTransport(proxy map[string]any, options *option.V2RayTransportOptions, tls bool) error {
+	if options == nil {
+		return nil
+	}
+	switch options.Type {
+	case "":
+	case C.V2RayTransportTypeWebsocket:
+		wsOpts := map[string]any{
+			"path": options.WebsocketOptions.Path,
+		}
+		if len(options.WebsocketOptions.Headers) > 0 {
+			headers := make(map[string][]string, len(options.WebsocketOptions.Headers))
+			for key, values := range options.WebsocketOptions.Headers {
+				headers[key] = values
+			}
+			wsOpts["headers"] = exportClashHeaders(headers)
+		}
+		if options.WebsocketOptions.MaxEarlyData > 0 {
+			wsOpts["max-early-data"] = int(options.WebsocketOptions.MaxEarlyData)
+			wsOpts["early-data-header-name"] = options.WebsocketOptions.EarlyDataHeaderName
+		}
+		proxy["network"] = "ws"
+		proxy["ws-opts"] = wsOpts
+	case C.V2RayTransportTypeHTTPUpgrade:
+		wsOpts := map[string]any{
+			"path":               options.HTTPUpgradeOptions.Path,
+			"v2ray-http-upgrade": true,
+		}
+		headers := make(map[string][]string, len(options.HTTPUpgradeOptions.Headers)+1)
+		for key, values := range options.HTTPUpgradeOptions.Headers {
+			headers[key] = values
+		}
+		if options.HTTPUpgradeOptions.Host != "" {
+			headers["Host"] = []string{options.HTTPUpgradeOptions.Host}
+		}
+		if len(headers) > 0 {
+			wsOpts["headers"] = exportClashHeaders(headers)
+		}
+		proxy["network"] = "ws"
+		proxy["ws-opts"] = wsOpts
+	case C.V2RayTransportTypeHTTP:
+		if tls {
+			h2Opts := map[string]any{
+				"path": options.HTTPOptions.Path,
+			}
+			if len(options.HTTPOptions.Host) > 0 {
+				h2Opts["host"] = []string(options.HTTPOptions.Host)
+			}
+			proxy["network"] = "h2"
+			proxy["h2-opts"] = h2Opts
+			break
+		}
+		httpOpts := map[string]any{}
+		if options.HTTPOptions.Method != "" {
+			httpOpts["method"] = options.HTTPOptions.Method
+		}
+		if options.HTTPOptions.Path != "" {
+			httpOpts["path"] = []string{options.HTTPOptions.Path}
+		}
+		if len(options.HTTPOptions.Host) > 0 {
+			httpOpts["host"] = []string(options.HTTPOptions.Host)
+		}
+		if len(options.HTTPOptions.Headers) > 0 {
+			headers := make(map[string][]string, len(options.HTTPOptions.Headers))
+			for key, values := range options.HTTPOptions.Headers {
+				headers[key] = values
+			}
+			httpOpts["headers"] = exportClashHeaders(headers)
+		}
+		proxy["network"] = "http"
+		proxy["http-opts"] = httpOpts
+	case C.V2RayTransportTypeGRPC:
+		proxy["network"] = "grpc"
+		proxy["grpc-opts"] = map[string]any{
+			"grpc-service-name": options.GRPCOptions.ServiceName,
+		}
+	default:
+		return E.New("unsupported transport type: ", options.Type)
+	}
+	return nil
+}
+
+func exportClashMultiplex(proxy map[string]any, options *option.OutboundMultiplexOptions) {
+	if options == nil || !options.Enabled {
+		return
+	}
+	smux := map[string]any{
+		"enabled": true,
+	}
+	if options.Protocol != "" {
+		smux["protocol"] = options.Protocol
+	}
+	if options.MaxConnections > 0 {
+		smux["max-connections"] = options.MaxConnections
+	}
+	if options.MinStreams > 0 {
+		smux["min-streams"] = options.MinStreams
+	}
+	if options.MaxStreams > 0 {
+		smux["max-streams"] = options.MaxStreams
+	}
+	proxy["smux"] = smux
+}
+
+func exportSSClashProxy(tag string, options *option.ShadowsocksOutboundOptions) (map[string]any, error) {
+	proxy := newClashProxy(tag, "ss", options.ServerOptions, options.DialerOptions)
+	proxy["cipher"] = options.Method
+	proxy["password"] = options.Password
+	if options.UDPOverTCP != nil && options.UDPOverTCP.Enabled {
+		proxy["udp-over-tcp"] = true
+	}
+	exportClashMultiplex(proxy, options.Multiplex)
+	if options.Plugin == "" {
+		return proxy, nil
+	}
+	pluginOpts := map[string]any{}
+	for _, item := range strings.Split(options.PluginOptions, ";") {
+		if item == "" {
+			continue
+		}
+		key, value, found := strings.Cut(item, "=")
+		if !found {
+			pluginOpts[key] = true
+			continue
+		}
+		pluginOpts[key] = value
+	}
+	switch options.Plugin {
+	case "obfs-local":
+		proxy["plugin"] = "obfs"
+		clashOpts := map[string]any{}
+		if mode, exists := pluginOpts["obfs"]; exists {
+			clashOpts["mode"] = mode
+		}
+		if host, exists := pluginOpts["obfs-host"]; exists {
+			clashOpts["host"] = host
+		}
+		proxy["plugin-opts"] = clashOpts
+	case "v2ray-plugin":
+		proxy["plugin"] = "v2ray-plugin"
+		clashOpts := map[string]any{
+			"mode": "websocket",
+		}
+		for key, value := range pluginOpts {
+			switch key {
+			case "host", "path", "tls":
+				clashOpts[key] = value
+			}
+		}
+		proxy["plugin-opts"] = clashOpts
+	default:
+		return nil, E.New("unsupported shadowsocks plugin: ", options.Plugin)
+	}
+	return proxy, nil
+}
+
+func exportSSRClashProxy(tag string, options *option.ShadowsocksROutboundOptions) map[string]any {
+	proxy := newClashProxy(tag, "ssr", options.ServerOptions, options.DialerOptions)
+	proxy["cipher"] = options.Method
+	proxy["password"] = options.Password
+	proxy["obfs"] = options.Obfs
+	proxy["protocol"] = options.Protocol
+	if options.ObfsParam != "" {
+		proxy["obfs-param"] = options.ObfsParam
+	}
+	if options.ProtocolParam != "" {
+		proxy["protocol-param"] = options.ProtocolParam
+	}
+	return proxy
+}
+
+func exportVMessClashProxy(tag string, options *option.VMessOutboundOptions) (map[string]any, error) {
+	proxy := newClashProxy(tag, "vmess", options.ServerOptions, options.DialerOptions)
+	proxy["uuid"] = options.UUID
+	proxy["alterId"] = options.AlterId
+	proxy["cipher"] = options.Security
+	if options.Security == "" {
+		proxy["cipher"] = "auto"
+	}
+	exportClashTLS(proxy, "servername", options.TLS)
+	exportClashMultiplex(proxy, options.Multiplex)
+	return proxy, exportClashTransport(proxy, options.Transport, options.TLS != nil && options.TLS.Enabled)
+}
+
+func exportVLESSClashProxy(tag string, options *option.VLESSOutboundOptions) (map[string]any, error) {
+	proxy := newClashProxy(tag, "vless", options.ServerOptions, options.DialerOptions)
+	proxy["uuid"] = options.UUID
+	if options.Flow != "" {
+		proxy["flow"] = options.Flow
+	}
+	exportClashTLS(proxy, "servername", options.TLS)
+	exportClashMultiplex(proxy, options.Multiplex)
+	return proxy, exportClashTransport(proxy, options.Transport, options.TLS != nil && options.TLS.Enabled)
+}
+
+func exportTrojanClashProxy(tag string, options *option.TrojanOutboundOptions) (map[string]any, error) {
+	proxy := newClashProxy(tag, "trojan", options.ServerOptions, options.DialerOptions)
+	proxy["password"] = options.Password
+	exportClashTLS(proxy, "sni", options.TLS)
+	exportClashMultiplex(proxy, options.Multiplex)
+	return proxy, exportClashTransport(proxy, options.Transport, options.TLS != nil && options.TLS.Enabled)
+}
+
+func exportTUICClashProxy(tag string, options *option.TUICOutboundOptions) map[string]any {
+	proxy := newClashProxy(tag, "tuic", options.ServerOptions, options.DialerOptions)
+	proxy["uuid"] = options.UUID
+	proxy["password"] = options.Password
+	if options.CongestionControl != "" {
+		proxy["congestion-controller"] = options.CongestionControl
+	}
+	if options.UDPRelayMode != "" {
+		proxy["udp-relay-mode"] = options.UDPRelayMode
+	}
+	if options.UDPOverStream {
+		proxy["udp-over-stream"] = true
+	}
+	if options.ZeroRTTHandshake {
+		proxy["reduce-rtt"] = true
+	}
+	if options.Heartbeat > 0 {
+		proxy["heartbeat-interval"] = int(time.Duration(options.Heartbeat).Milliseconds())
+	}
+	exportClashTLS(proxy, "sni", options.TLS)
+	return proxy
+}
+
+func exportHysteriaClashProxy(tag string, options *option.HysteriaOutboundOptions) map[string]any {
+	proxy := newClashProxy(tag, "hysteria", options.ServerOptions, options.DialerOptions)
+	if len(options.ServerPorts) > 0 {
+		proxy["ports"] = formatHysteriaPorts(options.ServerPorts)
+	}
+	if options.UpMbps > 0 {
+		proxy["up"] = options.UpMbps
+	}
+	if options.DownMbps > 0 {
+		proxy["down"] = options.DownMbps
+	}
+	if options.AuthString != "" {
+		proxy["auth-str"] = options.AuthString
+	}
+	if options.Obfs != "" {
+		proxy["obfs"] = options.Obfs
+	}
+	exportClashTLS(proxy, "sni", options.TLS)
+	return proxy
+}
+
+func exportHysteria2ClashProxy(tag string, options *option.Hysteria2OutboundOptions) map[string]any {
+	proxy := newClashProxy(tag, "hysteria2", options.ServerOptions, options.DialerOptions)
+	if len(options.ServerPorts) > 0 {
+		proxy["ports"] = formatHysteriaPorts(options.ServerPorts)
+	}
+	proxy["password"] = options.Password
+	if options.UpMbps > 0 {
+		proxy["up"] = options.UpMbps
+	}
+	if options.DownMbps > 0 {
+		proxy["down"] = options.DownMbps
+	}
+	if options.Obfs != nil && options.Obfs.Type != "" {
+		proxy["obfs"] = options.Obfs.Type
+		proxy["obfs-password"] = options.Obfs.Password
+	}
+	exportClashTLS(proxy, "sni", options.TLS)
+	return proxy
+}
+
+func exportAnyTLSClashProxy(tag string, options *option.AnyTLSOutboundOptions) map[string]any {
+	proxy := newClashProxy(tag, "anytls", options.ServerOptions, options.DialerOptions)
+	proxy["password"] = options.Password
+	if options.IdleSessionCheckInterval > 0 {
+		proxy["idle-session-check-interval"] = int(time.Duration(options.IdleSessionCheckInterval).Seconds())
+	}
+	if options.IdleSessionTimeout > 0 {
+		proxy["idle-session-timeout"] = int(time.Duration(options.IdleSessionTimeout).Seconds())
+	}
+	if options.MinIdleSession > 0 {
+		proxy["min-idle-session"] = options.MinIdleSession
+	}
+	exportClashTLS(proxy, "sni", options.TLS)
+	return proxy
+}
+
+func exportWireGuardClashProxy(tag string, options *option.LegacyWireGuardOutboundOptions) map[string]any {
+	proxy := newClashProxy(tag, "wireguard", options.ServerOptions, options.DialerOptions)
+	proxy["private-key"] = options.PrivateKey
+	proxy["public-key"] = options.PeerPublicKey
+	if options.PreSharedKey != "" {
+		proxy["pre-shared-key"] = options.PreSharedKey
+	}
+	for _, address := range options.LocalAddress {
+		if address.Addr().Is4() {
+			proxy["ip"] = address.String()
+		} else {
+			proxy["ipv6"] = address.String()
+		}
+	}
+	if len(options.Reserved) > 0 {
+		reserved := make([]int, 0, len(options.Reserved))
+		for _, value := range options.Reserved {
+			reserved = append(reserved, int(value))
+		}
+		proxy["reserved"] = reserved
+	}
+	if options.MTU > 0 {
+		proxy["mtu"] = int(options.MTU)
+	}
+	proxy["udp"] = true
+	return proxy
+}
+
+func exportSOCKSClashProxy(tag string, options *option.SOCKSOutboundOptions) map[string]any {
+	proxy := newClashProxy(tag, "socks5", options.ServerOptions, options.DialerOptions)
+	if options.Username != "" {
+		proxy["username"] = options.Username
+		proxy["password"] = options.Password
+	}
+	if options.UDPOverTCP != nil && options.UDPOverTCP.Enabled {
+		proxy["udp-over-tcp"] = true
+	}
+	return proxy
+}
+
+func exportHTTPClashProxy(tag string, options *option.HTTPOutboundOptions) map[string]any {
+	proxy := newClashProxy(tag, "http", options.ServerOptions, options.DialerOptions)
+	if options.Username != "" {
+		proxy["username"] = options.Username
+		proxy["password"] = options.Password
+	}
+	if len(options.Headers) > 0 {
+		headers := make(map[string][]string, len(options.Headers))
+		for key, values := range options.Headers {
+			headers[key] = values
+		}
+		proxy["headers"] = exportClashHeaders(headers)
+	}
+	exportClashTLS(proxy, "sni", options.TLS)
+	return proxy
+}
+
+func exportSSHClashProxy(tag string, options *option.SSHOutboundOptions) map[string]any {
+	proxy := newClashProxy(tag, "ssh", options.ServerOptions, options.DialerOptions)
+	proxy["username"] = options.User
+	if options.Password != "" {
+		proxy["password"] = options.Password
+	}
+	if len(options.PrivateKey) > 0 {
+		proxy["private-key"] = strings.Join(options.PrivateKey, "\n")
+	} else if options.PrivateKeyPath != "" {
+		proxy["private-key"] = options.PrivateKeyPath
+	}
+	if options.PrivateKeyPassphrase != "" {
+		proxy["private-key-passphrase"] = options.PrivateKeyPassphrase
+	}
+	if len(options.HostKey) > 0 {
+		proxy["host-key"] = []string(options.HostKey)
+	}
+	if len(options.HostKeyAlgorithms) > 0 {
+		proxy["host-key-algorithms"] = []string(options.HostKeyAlgorithms)
+	}
+	if options.ClientVersion != "" {
+		proxy["client-version"] = options.ClientVersion
+	}
+	return proxy
+}
@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"net/netip"
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportClashConfig(t *testing.T) {
+	t.Parallel()
+	outbounds, err := newClashParser(`proxies:
+  - name: SS
+    type: ss
+    server: 1.1.1.1
+    port: 8388
+    cipher: aes-128-gcm
+    password: pw
+    plugin: obfs
+    plugin-opts:
+      mode: http
+  - name: VMess
+    type: vmess
+    server: example.com
+    port: 443
+    uuid: b831bb3e-9856-4c4d-a04d-19fa75961d56
+    alterId: 0
+    cipher: auto
+    tls: true
+    servername: cdn.example.com
+    network: ws
+    ws-opts:
+      path: /ws
+      headers:
+        Host: cdn.example.com
+  - name: VLESS
+    type: vless
+    server: example.com
+    port: 443
+    uuid: b831bb3e-9856-4c4d-a04d-19fa75961d56
+    tls: true
+    network: grpc
+    grpc-opts:
+      grpc-service-name: grpc
+  - name: Trojan
+    type: trojan
+    server: example.com
+    port: 443
+    password: pw
+    skip-cert-verify: true
+  - name: Hy2
+    type: hysteria2
+    server: example.com
+    port: 443
+    password: pw
+    tls: true
+    obfs: salamander
+    obfs-password: secret
+  - name: TUIC
+    type: tuic
+    server: example.com
+    port: 443
+    uuid: b831bb3e-9856-4c4d-a04d-19fa75961d56
+    password: pw
+    tls: true
+    alpn: [h3]
+  - name: SOCKS
+    type: socks5
+    server: 1.1.1.1
+    port: 1080
+    username: user
+    password: pass
+`)
+	require.NoError(t, err)
+	require.Len(t, outbounds, 7)
+	content, err := ExportClashConfig(outbounds)
+	require.NoError(t, err)
+	reparsed, err := newClashParser(string(content))
+	require.NoError(t, err)
+	require.Equal(t, outbounds, reparsed)
+}
+
+func TestExportClashProxyUnsupported(t *testing.T) {
+	t.Parallel()
+	_, err := ExportClashProxy(option.Outbound{
+		Type:    C.TypeDirect,
+		Tag:     "direct",
+		Options: &option.DirectOutboundOptions{},
+	})
+	require.Error(t, err)
+	_, err = ExportClashConfig([]option.Outbound{{
+		Type:    C.TypeDirect,
+		Tag:     "direct",
+		Options: &option.DirectOutboundOptions{},
+	}})
+	require.Error(t, err)
+}
+
+func TestExportOutbounds(t *testing.T) {
+	t.Parallel()
+	outbounds := []option.Outbound{{
+		Type: C.TypeWireGuard,
+		Tag:  "WG",
+		Options: &option.WireGuardEndpointOptions{
+			Address:    []netip.Prefix{netip.MustParsePrefix("10.0.0.2/32")},
+			PrivateKey: "private",
+			Peers: []option.WireGuardPeer{{
+				Address:   "example.com",
+				Port:      51820,
+				PublicKey: "public",
+			}},
+		},
+	}, {
+		Type:    C.TypeDirect,
+		Tag:     "direct",
+		Options: &option.DirectOutboundOptions{},
+	}}
+	var skipped []string
+	content, err := ExportOutbounds(outbounds, ExportFormatURI, func(outbound option.Outbound, err error) error {
+		skipped = append(skipped, outbound.Tag)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "wireguard://private@example.com:51820/?address=10.0.0.2%2F32&publickey=public#WG", string(content))
+	require.Equal(t, []string{"direct"}, skipped)
+
+	_, err = ExportOutbounds(outbounds, ExportFormatClash, nil)
+	require.Error(t, err)
+	content, err = ExportOutbounds(outbounds[:1], ExportFormatClash, nil)
+	require.NoError(t, err)
+	reparsed, err := newClashParser(string(content))
+	require.NoError(t, err)
+	require.Len(t, reparsed, 1)
+	require.Equal(t, C.TypeWireGuard, reparsed[0].Type)
+
+	_, err = ExportOutbounds(outbounds, "unknown", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ExportFormatURI   = "uri"
+	ExportFormatClash = "clash"
+)
+
+// ExportOutbounds renders outbounds as share links, one per line, or as a
+// Clash configuration. Outbounds that cannot be exported are reported to skip,
+// and fail the export if skip is nil or returns an error.
+func ExportOutbounds(outbounds []option.Outbound, format string, skip func(outbound option.Outbound, err error) error) ([]byte, error) {
+	skipOutbound := func(outbound option.Outbound, err error) error {
+		if skip == nil {
+			return E.Cause(err, "export outbound[", outbound.Tag, "]")
+		}
+		return skip(outbound, err)
+	}
+	switch format {
+	case ExportFormatURI:
+		var links []string
+		for _, outbound := range outbounds {
+			link, err := ExportNativeURI(outbound)
+			if err != nil {
+				err = skipOutbound(outbound, err)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			links = append(links, link)
+		}
+		return []byte(strings.Join(links, "\n")), nil
+	case ExportFormatClash:
+		config := ClashConfig{
+			Proxies: make([]map[string]any, 0, len(outbounds)),
+		}
+		for _, outbound := range outbounds {
+			proxy, err := ExportClashProxy(outbound)
+			if err != nil {
+				err = skipOutbound(outbound, err)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			config.Proxies = append(config.Proxies, proxy)
+		}
+		return yaml.Marshal(config)
+	default:
+		return nil, E.New("unknown export format: ", format)
+	}
+}
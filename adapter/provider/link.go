@@ -0,0 +1,586 @@
+package provider
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// ExportNativeURI converts an outbound back into a share link understood by
+// newNativeURIParser.
+func ExportNativeURI(outbound option.Outbound) (string, error) {
+	switch options := outbound.Options.(type) {
+	case *option.ShadowsocksOutboundOptions:
+		return exportSSNativeURI(outbound.Tag, options), nil
+	case *option.ShadowsocksROutboundOptions:
+		return exportSSRNativeURI(outbound.Tag, options), nil
+	case *option.VMessOutboundOptions:
+		return exportVMessNativeURI(outbound.Tag, options)
+	case *option.VLESSOutboundOptions:
+		return exportVLESSNativeURI(outbound.Tag, options)
+	case *option.TrojanOutboundOptions:
+		return exportTrojanNativeURI(outbound.Tag, options)
+	case *option.TUICOutboundOptions:
+		return exportTUICNativeURI(outbound.Tag, options), nil
+	case *option.HysteriaOutboundOptions:
+		return exportHysteriaNativeURI(outbound.Tag, options), nil
+	case *option.AnyTLSOutboundOptions:
+		return exportAnyTLSNativeURI(outbound.Tag, options), nil
+	case *option.LegacyWireGuardOutboundOptions:
+		return exportWireGuardNativeURI(outbound.Tag, options), nil
+	case *option.WireGuardEndpointOptions:
+		legacyOptions, err := newLegacyWireGuardOptions(options)
+		if err != nil {
+			return "", err
+		}
+		return exportWireGuardNativeURI(outbound.Tag, legacyOptions), nil
+	case *option.SOCKSOutboundOptions:
+		return exportSOCKSNativeURI(outbound.Tag, options), nil
+	case *option.HTTPOutboundOptions:
+		return exportHTTPNativeURI(outbound.Tag, options), nil
+	case *option.SSHOutboundOptions:
+		return exportSSHNativeURI(outbound.Tag, options), nil
+	case *option.NaiveOutboundOptions:
+		return exportNaiveNativeURI(outbound.Tag, options), nil
+	case *option.Hysteria2OutboundOptions:
+		return exportHysteria2NativeURI(outbound.Tag, options), nil
+	default:
+		return "", E.New("unsupported outbound type: ", outbound.Type)
+	}
+}
+
+func encodeURIComponent(content string) string {
+	return strings.ReplaceAll(url.QueryEscape(content), "+", "%20")
+}
+
+// encodeURIQueryValue escapes a query value while keeping the characters
+// commonly left readable in share links, such as paths and ALPN lists.
+func encodeURIQueryValue(content string) string {
+	var builder strings.Builder
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+			builder.WriteByte(c)
+		case c == '-' || c == '_' || c == '.' || c == '~' || c == ',' || c == '/' || c == ':':
+			builder.WriteByte(c)
+		default:
+			builder.WriteString("%")
+			builder.WriteString(strings.ToUpper(strconv.FormatUint(uint64(c)>>4, 16)))
+			builder.WriteString(strings.ToUpper(strconv.FormatUint(uint64(c)&0xF, 16)))
+		}
+	}
+	return builder.String()
+}
+
+type nativeURI struct {
+	scheme   string
+	userInfo string
+	host     string
+	query    map[string]string
+	tag      string
+	// compact writes the query right after the port and keeps separators
+	// readable, for the regexp based parsers which read `/?` as part of the
+	// first key and do not unescape every value.
+	compact bool
+}
+
+func (u nativeURI) String() string {
+	var builder strings.Builder
+	builder.WriteString(u.scheme)
+	builder.WriteString("://")
+	if u.userInfo != "" {
+		builder.WriteString(u.userInfo)
+		builder.WriteString("@")
+	}
+	builder.WriteString(u.host)
+	keys := make([]string, 0, len(u.query))
+	for key, value := range u.query {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		if u.compact {
+			builder.WriteString("?")
+		} else {
+			builder.WriteString("/?")
+		}
+		for i, key := range keys {
+			if i > 0 {
+				builder.WriteString("&")
+			}
+			builder.WriteString(key)
+			builder.WriteString("=")
+			if u.compact {
+				builder.WriteString(encodeURIQueryValue(u.query[key]))
+			} else {
+				builder.WriteString(encodeURIComponent(u.query[key]))
+			}
+		}
+	}
+	if u.tag != "" {
+		builder.WriteString("#")
+		builder.WriteString(encodeURIComponent(u.tag))
+	}
+	return builder.String()
+}
+
+func nativeUserInfo(username string, password string) string {
+	if username == "" && password == "" {
+		return ""
+	}
+	if password == "" {
+		return encodeURIComponent(username)
+	}
+	return encodeURIComponent(username) + ":" + encodeURIComponent(password)
+}
+
+func nativeHost(options option.ServerOptions) string {
+	return net.JoinHostPort(options.Server, strconv.Itoa(int(options.ServerPort)))
+}
+
+func nativeBool(value bool) string {
+	if value {
+		return "1"
+	}
+	return ""
+}
+
+func nativeInt(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+func exportWireGuardNativeURI(tag string, options *option.LegacyWireGuardOutboundOptions) string {
+	addresses := make([]string, 0, len(options.LocalAddress))
+	for _, address := range options.LocalAddress {
+		addresses = append(addresses, address.String())
+	}
+	reserved := make([]string, 0, len(options.Reserved))
+	for _, value := range options.Reserved {
+		reserved = append(reserved, strconv.Itoa(int(value)))
+	}
+	mtu := ""
+	if options.MTU > 0 {
+		mtu = strconv.FormatUint(uint64(options.MTU), 10)
+	}
+	return nativeURI{
+		scheme:   "wireguard",
+		userInfo: encodeURIComponent(options.PrivateKey),
+		host:     nativeHost(options.ServerOptions),
+		query: map[string]string{
+			"publickey":    options.PeerPublicKey,
+			"presharedkey": options.PreSharedKey,
+			"address":      strings.Join(addresses, ","),
+			"reserved":     strings.Join(reserved, ","),
+			"mtu":          mtu,
+		},
+		tag: tag,
+	}.String()
+}
+
+func exportSOCKSNativeURI(tag string, options *option.SOCKSOutboundOptions) string {
+	return nativeURI{
+		scheme:   "socks5",
+		userInfo: nativeUserInfo(options.Username, options.Password),
+		host:     nativeHost(options.ServerOptions),
+		query: map[string]string{
+			"tfo": nativeBool(options.TCPFastOpen),
+		},
+		tag: tag,
+	}.String()
+}
+
+func exportHTTPNativeURI(tag string, options *option.HTTPOutboundOptions) string {
+	uri := nativeURI{
+		scheme:   "http",
+		userInfo: nativeUserInfo(options.Username, options.Password),
+		host:     nativeHost(options.ServerOptions),
+		query: map[string]string{
+			"tfo": nativeBool(options.TCPFastOpen),
+		},
+		tag: tag,
+	}
+	if options.TLS != nil && options.TLS.Enabled {
+		uri.scheme = "https"
+		if options.TLS.ServerName != options.Server {
+			uri.query["sni"] = options.TLS.ServerName
+		}
+		uri.query["insecure"] = nativeBool(options.TLS.Insecure)
+	}
+	return uri.String()
+}
+
+func exportSSHNativeURI(tag string, options *option.SSHOutboundOptions) string {
+	return nativeURI{
+		scheme:   "ssh",
+		userInfo: nativeUserInfo(options.User, options.Password),
+		host:     nativeHost(options.ServerOptions),
+		query: map[string]string{
+			"private-key":            strings.Join(options.PrivateKey, "\n"),
+			"private-key-passphrase": options.PrivateKeyPassphrase,
+			"host-key":               strings.Join(options.HostKey, ","),
+			"host-key-algorithms":    strings.Join(options.HostKeyAlgorithms, ","),
+			"client-version":         options.ClientVersion,
+			"tfo":                    nativeBool(options.TCPFastOpen),
+		},
+		tag: tag,
+	}.String()
+}
+
+func exportNaiveNativeURI(tag string, options *option.NaiveOutboundOptions) string {
+	uri := nativeURI{
+		scheme:   "naive+https",
+		userInfo: nativeUserInfo(options.Username, options.Password),
+		host:     nativeHost(options.ServerOptions),
+		query: map[string]string{
+			"insecure-concurrency": nativeInt(options.InsecureConcurrency),
+		},
+		tag: tag,
+	}
+	if options.QUIC {
+		uri.scheme = "naive+quic"
+	}
+	if options.TLS != nil && options.TLS.ServerName != options.Server {
+		uri.query["sni"] = options.TLS.ServerName
+	}
+	if len(options.ExtraHeaders) > 0 {
+		headers := make([]string, 0, len(options.ExtraHeaders))
+		for key, values := range options.ExtraHeaders {
+			for _, value := range values {
+				headers = append(headers, key+": "+value)
+			}
+		}
+		sort.Strings(headers)
+		uri.query["extra-headers"] = strings.Join(headers, "\r\n")
+	}
+	return uri.String()
+}
+
+func exportHysteria2NativeURI(tag string, options *option.Hysteria2OutboundOptions) string {
+	uri := nativeURI{
+		scheme:   "hysteria2",
+		userInfo: encodeURIComponent(options.Password),
+		host:     nativeHost(options.ServerOptions),
+		query: map[string]string{
+			"up":   nativeInt(options.UpMbps),
+			"down": nativeInt(options.DownMbps),
+		},
+		tag: tag,
+	}
+	if len(options.ServerPorts) > 0 {
+		uri.host = net.JoinHostPort(options.Server, formatHysteriaPorts(options.ServerPorts))
+	}
+	if options.Obfs != nil {
+		uri.query["obfs"] = options.Obfs.Type
+		uri.query["obfs-password"] = options.Obfs.Password
+	}
+	if options.TLS != nil {
+		if options.TLS.ServerName != options.Server {
+			uri.query["sni"] = options.TLS.ServerName
+		}
+		uri.query["insecure"] = nativeBool(options.TLS.Insecure)
+	}
+	return uri.String()
+}
+
+// formatHysteriaPorts converts sing-box server_ports ranges back into port
+// hopping notation like `443,20000-30000`.
+func formatHysteriaPorts(serverPorts []string) string {
+	ports := make([]string, 0, len(serverPorts))
+	for _, portRange := range serverPorts {
+		start, end, _ := strings.Cut(portRange, ":")
+		if start == end {
+			ports = append(ports, start)
+		} else {
+			ports = append(ports, start+"-"+end)
+		}
+	}
+	return strings.Join(ports, ",")
+}
+
+func nativeTLS(query map[string]string, server string, options *option.OutboundTLSOptions) {
+	if options == nil || !options.Enabled {
+		return
+	}
+	if options.ServerName != server {
+		query["sni"] = options.ServerName
+	}
+	query["alpn"] = strings.Join(options.ALPN, ",")
+	if options.UTLS != nil && options.UTLS.Enabled {
+		query["fp"] = options.UTLS.Fingerprint
+	}
+}
+
+func nativeTransport(query map[string]string, options *option.V2RayTransportOptions, serviceNameKey string) error {
+	if options == nil {
+		return nil
+	}
+	switch options.Type {
+	case "":
+	case C.V2RayTransportTypeWebsocket:
+		query["type"] = "ws"
+		query["path"] = websocketPath(options.WebsocketOptions)
+		query["host"] = strings.Join(options.WebsocketOptions.Headers["Host"], ",")
+	case C.V2RayTransportTypeHTTP:
+		query["type"] = "http"
+		query["path"] = options.HTTPOptions.Path
+		query["host"] = strings.Join(options.HTTPOptions.Host, ",")
+	case C.V2RayTransportTypeGRPC:
+		query["type"] = "grpc"
+		query[serviceNameKey] = options.GRPCOptions.ServiceName
+	default:
+		return E.New("unsupported transport type: ", options.Type)
+	}
+	return nil
+}
+
+func websocketPath(options option.V2RayWebsocketOptions) string {
+	if options.MaxEarlyData > 0 && options.EarlyDataHeaderName == "Sec-WebSocket-Protocol" {
+		return options.Path + "?ed=" + strconv.FormatUint(uint64(options.MaxEarlyData), 10)
+	}
+	return options.Path
+}
+
+func exportSSNativeURI(tag string, options *option.ShadowsocksOutboundOptions) string {
+	uri := nativeURI{
+		scheme:   "ss",
+		compact:  true,
+		userInfo: base64.RawURLEncoding.EncodeToString([]byte(options.Method + ":" + options.Password)),
+		host:     nativeHost(options.ServerOptions),
+		query:    map[string]string{},
+		tag:      tag,
+	}
+	if options.Plugin != "" {
+		plugin := options.Plugin
+		if options.PluginOptions != "" {
+			plugin += ";" + options.PluginOptions
+		}
+		uri.query["plugin"] = plugin
+	}
+	return uri.String()
+}
+
+func exportSSRNativeURI(tag string, options *option.ShadowsocksROutboundOptions) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	query := map[string]string{
+		"obfsparam":  encode([]byte(options.ObfsParam)),
+		"protoparam": encode([]byte(options.ProtocolParam)),
+		"remarks":    encode([]byte(tag)),
+	}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, key := range keys {
+		params = append(params, key+"="+query[key])
+	}
+	content := strings.Join([]string{
+		options.Server,
+		strconv.Itoa(int(options.ServerPort)),
+		options.Protocol,
+		options.Method,
+		options.Obfs,
+		encode([]byte(options.Password)),
+	}, ":") + "?" + strings.Join(params, "&")
+	return "ssr://" + encode([]byte(content))
+}
+
+func exportVMessNativeURI(tag string, options *option.VMessOutboundOptions) (string, error) {
+	security := options.Security
+	if security == "" {
+		security = "auto"
+	}
+	proxy := map[string]string{
+		"v":    "2",
+		"ps":   tag,
+		"add":  options.Server,
+		"port": strconv.Itoa(int(options.ServerPort)),
+		"id":   options.UUID,
+		"aid":  strconv.Itoa(options.AlterId),
+		"scy":  security,
+		"net":  "tcp",
+	}
+	if options.PacketEncoding != "" {
+		proxy["packet_encoding"] = options.PacketEncoding
+	}
+	if options.TLS != nil && options.TLS.Enabled {
+		proxy["tls"] = "tls"
+		if options.TLS.ServerName != options.Server {
+			proxy["sni"] = options.TLS.ServerName
+		}
+		if len(options.TLS.ALPN) > 0 {
+			proxy["alpn"] = strings.Join(options.TLS.ALPN, ",")
+		}
+		if options.TLS.UTLS != nil && options.TLS.UTLS.Enabled {
+			proxy["fp"] = options.TLS.UTLS.Fingerprint
+		}
+		if options.TLS.Insecure {
+			proxy["insecure"] = "1"
+		}
+	}
+	if options.Transport != nil {
+		switch options.Transport.Type {
+		case "":
+		case C.V2RayTransportTypeWebsocket:
+			proxy["net"] = "ws"
+			proxy["path"] = websocketPath(options.Transport.WebsocketOptions)
+			proxy["host"] = strings.Join(options.Transport.WebsocketOptions.Headers["Host"], ",")
+		case C.V2RayTransportTypeHTTP:
+			if options.TLS != nil && options.TLS.Enabled {
+				proxy["net"] = "h2"
+			} else {
+				proxy["type"] = "http"
+			}
+			proxy["path"] = options.Transport.HTTPOptions.Path
+			proxy["host"] = strings.Join(options.Transport.HTTPOptions.Host, ",")
+		case C.V2RayTransportTypeGRPC:
+			proxy["net"] = "grpc"
+			proxy["path"] = options.Transport.GRPCOptions.ServiceName
+		default:
+			return "", E.New("unsupported transport type: ", options.Transport.Type)
+		}
+	}
+	for key, value := range proxy {
+		if value == "" {
+			delete(proxy, key)
+		}
+	}
+	content, err := json.Marshal(proxy)
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(content), nil
+}
+
+func exportVLESSNativeURI(tag string, options *option.VLESSOutboundOptions) (string, error) {
+	uri := nativeURI{
+		scheme:   "vless",
+		compact:  true,
+		userInfo: encodeURIComponent(options.UUID),
+		host:     nativeHost(options.ServerOptions),
+		query: map[string]string{
+			"encryption": "none",
+			"flow":       options.Flow,
+			"tfo":        nativeBool(options.TCPFastOpen),
+		},
+		tag: tag,
+	}
+	if options.TLS != nil && options.TLS.Enabled {
+		uri.query["security"] = "tls"
+		if options.TLS.Reality != nil && options.TLS.Reality.Enabled {
+			uri.query["security"] = "reality"
+			uri.query["pbk"] = options.TLS.Reality.PublicKey
+			uri.query["sid"] = options.TLS.Reality.ShortID
+		}
+		uri.query["insecure"] = nativeBool(options.TLS.Insecure)
+		nativeTLS(uri.query, options.Server, options.TLS)
+	}
+	err := nativeTransport(uri.query, options.Transport, "serviceName")
+	if err != nil {
+		return "", err
+	}
+	return uri.String(), nil
+}
+
+func exportTrojanNativeURI(tag string, options *option.TrojanOutboundOptions) (string, error) {
+	uri := nativeURI{
+		scheme:   "trojan",
+		compact:  true,
+		userInfo: encodeURIComponent(options.Password),
+		host:     nativeHost(options.ServerOptions),
+		query: map[string]string{
+			"tfo": nativeBool(options.TCPFastOpen),
+		},
+		tag: tag,
+	}
+	if options.TLS != nil {
+		uri.query["allowInsecure"] = nativeBool(options.TLS.Insecure)
+		nativeTLS(uri.query, options.Server, options.TLS)
+	}
+	err := nativeTransport(uri.query, options.Transport, "serviceName")
+	if err != nil {
+		return "", err
+	}
+	return uri.String(), nil
+}
+
+func exportTUICNativeURI(tag string, options *option.TUICOutboundOptions) string {
+	uri := nativeURI{
+		scheme:   "tuic",
+		compact:  true,
+		userInfo: encodeURIComponent(options.UUID) + ":" + encodeURIComponent(options.Password),
+		host:     nativeHost(options.ServerOptions),
+		query: map[string]string{
+			"congestion_control": options.CongestionControl,
+			"udp_relay_mode":     options.UDPRelayMode,
+			"udp_over_stream":    nativeBool(options.UDPOverStream),
+			"zero_rtt_handshake": nativeBool(options.ZeroRTTHandshake),
+			"tfo":                nativeBool(options.TCPFastOpen),
+		},
+		tag: tag,
+	}
+	if options.TLS != nil {
+		uri.query["allow_insecure"] = nativeBool(options.TLS.Insecure)
+		uri.query["disable_sni"] = nativeBool(options.TLS.DisableSNI)
+		nativeTLS(uri.query, options.Server, options.TLS)
+		delete(uri.query, "fp")
+	}
+	return uri.String()
+}
+
+func exportHysteriaNativeURI(tag string, options *option.HysteriaOutboundOptions) string {
+	uri := nativeURI{
+		scheme:  "hysteria",
+		compact: true,
+		host:    nativeHost(options.ServerOptions),
+		query: map[string]string{
+			"auth":      options.AuthString,
+			"up_mbps":   nativeInt(options.UpMbps),
+			"down_mbps": nativeInt(options.DownMbps),
+			"obfsParam": options.Obfs,
+			"tfo":       nativeBool(options.TCPFastOpen),
+		},
+		tag: tag,
+	}
+	if options.TLS != nil {
+		if options.TLS.ServerName != options.Server {
+			uri.query["peer"] = options.TLS.ServerName
+		}
+		uri.query["alpn"] = strings.Join(options.TLS.ALPN, ",")
+		uri.query["insecure"] = nativeBool(options.TLS.Insecure)
+	}
+	return uri.String()
+}
+
+func exportAnyTLSNativeURI(tag string, options *option.AnyTLSOutboundOptions) string {
+	uri := nativeURI{
+		scheme:   "anytls",
+		compact:  true,
+		userInfo: encodeURIComponent(options.Password),
+		host:     nativeHost(options.ServerOptions),
+		query: map[string]string{
+			"tfo": nativeBool(options.TCPFastOpen),
+		},
+		tag: tag,
+	}
+	if options.TLS != nil {
+		uri.query["insecure"] = nativeBool(options.TLS.Insecure)
+		nativeTLS(uri.query, options.Server, options.TLS)
+	}
+	return uri.String()
+}
@@ -26,6 +26,7 @@ import (
 var (
 	_ adapter.OutboundProvider        = (*LocalProvider)(nil)
 	_ adapter.InterfaceUpdateListener = (*LocalProvider)(nil)
+	_ adapter.OutboundOptionsManager  = (*LocalProvider)(nil)
 )
 
 type LocalProvider struct {
@@ -44,6 +45,10 @@ func NewLocalProvider(ctx context.Context, manager *Manager, router adapter.Rout
 	if interval == 0 {
 		interval = C.DefaultURLTestInterval
 	}
+	healthcheckProbe, err := urltest.NewProbe(localOptions.HealthcheckProbe)
+	if err != nil {
+		return nil, E.Cause(err, "parse healthcheck_probe")
+	}
 	ctx, cancel := context.WithCancel(ctx)
 	provider := &LocalProvider{
 		myProviderAdapter: myProviderAdapter{
@@ -57,6 +62,7 @@ func NewLocalProvider(ctx context.Context, manager *Manager, router adapter.Rout
 			enableHealthcheck:   localOptions.EnableHealthcheck,
 			healthcheckUrl:      localOptions.HealthcheckUrl,
 			healthcheckInterval: interval,
+			healthcheckProbe:    healthcheckProbe,
 			outboundOverride:    options.OutboundOverride,
 			types:               options.Types,
 			ports:               make(map[int]bool),
@@ -159,9 +165,10 @@ func (p *LocalProvider) UpdateProvider(ctx context.Context, router adapter.Route
 		return err
 	}
 
-	p.subInfo = info
+	p.setSubInfo(info)
 	p.lastUpdated = fileModeTime
 	p.logger.InfoContext(ctx, "update outbound provider ", p.tag, " success")
+	p.manager.providerUpdated(p)
 
 	if updated && p.enableHealthcheck {
 		p.CheckOutbounds(true)
@@ -15,6 +15,7 @@ import (
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/rw"
+	"github.com/sagernet/sing/common/x/list"
 	"github.com/sagernet/sing/service/filemanager"
 )
 
@@ -29,6 +30,8 @@ type Manager struct {
 	outbound              adapter.OutboundManager
 	outboundProviders     []adapter.OutboundProvider
 	outboundProviderByTag map[string]adapter.OutboundProvider
+	callbackAccess        sync.Mutex
+	callbacks             list.List[adapter.OutboundProviderUpdateCallback]
 }
 
 func NewManager(logFactory log.Factory, outbound adapter.OutboundManager) *Manager {
@@ -280,3 +283,24 @@ func (m *Manager) OutboundProvider(tag string) (adapter.OutboundProvider, bool)
 	provider, found := m.outboundProviderByTag[tag]
 	return provider, found
 }
+
+func (m *Manager) RegisterCallback(callback adapter.OutboundProviderUpdateCallback) *list.Element[adapter.OutboundProviderUpdateCallback] {
+	m.callbackAccess.Lock()
+	defer m.callbackAccess.Unlock()
+	return m.callbacks.PushBack(callback)
+}
+
+func (m *Manager) UnregisterCallback(element *list.Element[adapter.OutboundProviderUpdateCallback]) {
+	m.callbackAccess.Lock()
+	defer m.callbackAccess.Unlock()
+	m.callbacks.Remove(element)
+}
+
+func (m *Manager) providerUpdated(provider adapter.OutboundProvider) {
+	m.callbackAccess.Lock()
+	callbacks := m.callbacks.Array()
+	m.callbackAccess.Unlock()
+	for _, callback := range callbacks {
+		callback(provider)
+	}
+}
@@ -3,6 +3,7 @@ package provider
 import (
 	"encoding/json"
 	"fmt"
+	"net/netip"
 	"net/url"
 	"regexp"
 	"strconv"
@@ -23,8 +24,9 @@ var (
 	vlessParser     = regexp.MustCompile(`^(.*?)@(.*?):(\d+)(?:(?:\/|\?|\/\?)(.*?))?(?:#(.*?))$`)
 	trojanParser    = regexp.MustCompile(`^(.*?)@(.*?):(\d+)(?:(?:\/|\?|\/\?)(.*?))?(?:#(.*?))$`)
 	hysteriaParser  = regexp.MustCompile(`^(.*?):(\d+)(?:(?:\/|\?|\/\?)(.*?))?(?:#(.*?))?$`)
-	hysteria2Parser = regexp.MustCompile(`^(.+?)@(.+?)(?::(\d+))?(?:(?:\/|\?|\/\?)(.*?))?(?:#(.*?))?$`)
+	hysteria2Parser = regexp.MustCompile(`^(.+?)@(.+?)(?::([\d,\-]+))?(?:(?:\/|\?|\/\?)(.*?))?(?:#(.*?))?$`)
 	anytlsParser    = regexp.MustCompile(`^(.*?)@(.*?):(\d+)(?:(?:\/|\?|\/\?)(.*?))?(?:#(.*?))$`)
+	authorityParser = regexp.MustCompile(`^(?:([^?#]*)@)?(\[[^\]]+\]|[^:/?#\[\]]+)(?::(\d+))?\/?(?:\?([^#]*))?(?:#(.*))?$`)
 )
 
 func newNativeURIParser(content string) ([]option.Outbound, error) {
@@ -60,6 +62,16 @@ func newNativeURIParser(content string) ([]option.Outbound, error) {
 			outbound, err = newHysteria2NativeParser(parsedProxy)
 		case "anytls":
 			outbound, err = newAnyTLSNativeParser(parsedProxy)
+		case "wireguard", "wg":
+			outbound, err = newWireGuardNativeParser(parsedProxy)
+		case "socks", "socks5", "socks5h":
+			outbound, err = newSOCKSNativeParser(parsedProxy)
+		case "http", "https":
+			outbound, err = newHTTPNativeParser(parsedProxy, protocol == "https")
+		case "ssh":
+			outbound, err = newSSHNativeParser(parsedProxy)
+		case "naive+https", "naive+quic":
+			outbound, err = newNaiveNativeParser(parsedProxy, protocol == "naive+quic")
 		default:
 			continue
 		}
@@ -90,6 +102,55 @@ func decodeURIComponent(content string) string {
 	return result
 }
 
+// decodeURIComponentStrict unescapes a URI component without turning '+' into
+// a space, which keeps base64 keys and passwords intact.
+func decodeURIComponentStrict(content string) string {
+	result, err := url.PathUnescape(content)
+	if err != nil {
+		return content
+	}
+	return result
+}
+
+func parseNativeQuery(content string) map[string]string {
+	query := make(map[string]string)
+	for _, addon := range strings.Split(strings.TrimPrefix(content, "?"), "&") {
+		if addon == "" {
+			continue
+		}
+		key, value, found := strings.Cut(addon, "=")
+		if !found {
+			value = "1"
+		}
+		query[trimBlank(decodeURIComponentStrict(key))] = decodeURIComponentStrict(value)
+	}
+	return query
+}
+
+func parseNativeUserInfo(content string) (string, string) {
+	if content != "" && !strings.Contains(content, ":") {
+		content = decodeBase64Safe(decodeURIComponentStrict(content))
+	}
+	username, password, _ := strings.Cut(content, ":")
+	return decodeURIComponentStrict(username), decodeURIComponentStrict(password)
+}
+
+func parseNativeAuthority(content string, scheme string, defaultPort uint16) ([]string, uint16, error) {
+	result := authorityParser.FindStringSubmatch(content)
+	if len(result) == 0 {
+		return nil, 0, E.New("invalid ", scheme, " uri")
+	}
+	port := defaultPort
+	if result[3] != "" {
+		port = stringToUint16(result[3])
+	}
+	if port == 0 {
+		return nil, 0, E.New("invalid ", scheme, " uri: missing port")
+	}
+	result[2] = strings.Trim(result[2], "[]")
+	return result, port, nil
+}
+
 func splitKeyValueWithEqual(content string) (string, string) {
 	if !strings.Contains(content, "=") {
 		return trimBlank(content), "1"
@@ -691,7 +752,6 @@ func newHysteria2NativeParser(content string) (option.Outbound, error) {
 	if len(result) == 0 {
 		return outbound, E.New("invalid hysteria2 uri")
 	}
-	outbound.Tag = result[5]
 	options := option.Hysteria2OutboundOptions{}
 	TLSOptions := option.OutboundTLSOptions{
 		Enabled: true,
@@ -700,17 +760,21 @@ func newHysteria2NativeParser(content string) (option.Outbound, error) {
 		Reality: &option.OutboundRealityOptions{},
 	}
 	options.ServerPort = uint16(443)
-	options.Server = result[2]
-	TLSOptions.ServerName = result[2]
-	options.Password = result[1]
+	options.Server = strings.Trim(result[2], "[]")
+	TLSOptions.ServerName = options.Server
+	options.Password = decodeURIComponentStrict(result[1])
 	if strings.Contains(result[1], ":") {
-		options.Password = strings.Split(result[1], ":")[1]
+		options.Password = decodeURIComponentStrict(strings.Split(result[1], ":")[1])
 	}
 	if result[3] != "" {
-		options.ServerPort = stringToUint16(result[3])
+		if strings.ContainsAny(result[3], ",-") {
+			options.ServerPort = 0
+			options.ServerPorts = parseHysteria2Ports(result[3])
+		} else {
+			options.ServerPort = stringToUint16(result[3])
+		}
 	}
-	for _, addon := range strings.Split(result[4], "\n") {
-		key, value := splitKeyValueWithEqual(addon)
+	for key, value := range parseNativeQuery(result[4]) {
 		switch key {
 		case "up":
 			options.UpMbps, _ = strconv.Atoi(value)
@@ -718,21 +782,53 @@ func newHysteria2NativeParser(content string) (option.Outbound, error) {
 			options.DownMbps, _ = strconv.Atoi(value)
 		case "obfs":
 			if value == "salamander" {
+				if options.Obfs == nil {
+					options.Obfs = &option.Hysteria2Obfs{}
+				}
 				options.Obfs.Type = "salamander"
 			}
 		case "obfs-password":
+			if options.Obfs == nil {
+				options.Obfs = &option.Hysteria2Obfs{}
+			}
 			options.Obfs.Password = value
+		case "sni", "peer":
+			TLSOptions.ServerName = value
+		case "mport":
+			options.ServerPorts = parseHysteria2Ports(value)
 		case "insecure", "skip-cert-verify":
 			if value == "1" || value == "true" {
 				TLSOptions.Insecure = true
 			}
 		}
 	}
+	if options.Obfs != nil && options.Obfs.Type == "" {
+		options.Obfs = nil
+	}
+	outbound.Tag = decodeURIComponentStrict(result[5])
 	options.TLS = &TLSOptions
 	outbound.Options = &options
 	return outbound, nil
 }
 
+// parseHysteria2Ports converts port hopping notation like `443,20000-30000`
+// into sing-box server_ports ranges.
+func parseHysteria2Ports(content string) badoption.Listable[string] {
+	var ports badoption.Listable[string]
+	for _, port := range strings.Split(content, ",") {
+		port = trimBlank(port)
+		if port == "" {
+			continue
+		}
+		start, end, isRange := strings.Cut(port, "-")
+		if !isRange {
+			end = start
+		}
+		ports = append(ports, start+":"+end)
+	}
+	return ports
+}
+
 func newAnyTLSNativeParser(content string) (option.Outbound, error) {
 	outbound := option.Outbound{
 		Type: C.TypeAnyTLS,
@@ -781,3 +877,209 @@ func newAnyTLSNativeParser(content string) (option.Outbound, error) {
 	outbound.Options = &options
 	return outbound, nil
 }
+
+func newWireGuardNativeParser(content string) (option.Outbound, error) {
+	outbound := option.Outbound{
+		Type: C.TypeWireGuard,
+	}
+	result, port, err := parseNativeAuthority(content, "wireguard", 51820)
+	if err != nil {
+		return outbound, err
+	}
+	outbound.Tag = decodeURIComponentStrict(result[5])
+	options := option.LegacyWireGuardOutboundOptions{}
+	options.Server = result[2]
+	options.ServerPort = port
+	options.PrivateKey = decodeURIComponentStrict(result[1])
+	for key, value := range parseNativeQuery(result[4]) {
+		switch key {
+		case "privatekey", "private-key", "private_key":
+			options.PrivateKey = value
+		case "publickey", "public-key", "public_key", "peer_public_key":
+			options.PeerPublicKey = value
+		case "presharedkey", "pre-shared-key", "pre_shared_key", "psk":
+			options.PreSharedKey = value
+		case "address", "ip", "local_address":
+			for _, address := range strings.Split(value, ",") {
+				address = trimBlank(address)
+				if address == "" {
+					continue
+				}
+				if !strings.Contains(address, "/") {
+					addr, err := netip.ParseAddr(address)
+					if err != nil {
+						return outbound, E.Cause(err, "invalid wireguard address")
+					}
+					options.LocalAddress = append(options.LocalAddress, netip.PrefixFrom(addr, addr.BitLen()))
+					continue
+				}
+				prefix, err := netip.ParsePrefix(address)
+				if err != nil {
+					return outbound, E.Cause(err, "invalid wireguard address")
+				}
+				options.LocalAddress = append(options.LocalAddress, prefix)
+			}
+		case "reserved":
+			if strings.Contains(value, ",") {
+				for _, reserved := range strings.Split(value, ",") {
+					options.Reserved = append(options.Reserved, uint8(stringToUint16(trimBlank(reserved))))
+				}
+			} else if decoded := decodeBase64Safe(value); decoded != value {
+				options.Reserved = []uint8(decoded)
+			}
+		case "mtu":
+			options.MTU = stringToUint32(value)
+		}
+	}
+	if options.PrivateKey == "" || options.PeerPublicKey == "" {
+		return outbound, E.New("invalid wireguard uri: missing key")
+	}
+	outbound.Options = &options
+	return outbound, nil
+}
+
+func newSOCKSNativeParser(content string) (option.Outbound, error) {
+	outbound := option.Outbound{
+		Type: C.TypeSOCKS,
+	}
+	result, port, err := parseNativeAuthority(content, "socks", 1080)
+	if err != nil {
+		return outbound, err
+	}
+	outbound.Tag = decodeURIComponentStrict(result[5])
+	options := option.SOCKSOutboundOptions{}
+	options.Server = result[2]
+	options.ServerPort = port
+	options.Username, options.Password = parseNativeUserInfo(result[1])
+	for key, value := range parseNativeQuery(result[4]) {
+		switch key {
+		case "tfo", "tcp-fast-open", "tcp_fast_open":
+			if value == "1" || value == "true" {
+				options.TCPFastOpen = true
+			}
+		}
+	}
+	outbound.Options = &options
+	return outbound, nil
+}
+
+func newHTTPNativeParser(content string, tls bool) (option.Outbound, error) {
+	outbound := option.Outbound{
+		Type: C.TypeHTTP,
+	}
+	defaultPort := uint16(80)
+	if tls {
+		defaultPort = 443
+	}
+	result, port, err := parseNativeAuthority(content, "http", defaultPort)
+	if err != nil {
+		return outbound, err
+	}
+	outbound.Tag = decodeURIComponentStrict(result[5])
+	options := option.HTTPOutboundOptions{}
+	options.Server = result[2]
+	options.ServerPort = port
+	options.Username, options.Password = parseNativeUserInfo(result[1])
+	if tls {
+		options.TLS = &option.OutboundTLSOptions{
+			Enabled:    true,
+			ServerName: result[2],
+		}
+	}
+	for key, value := range parseNativeQuery(result[4]) {
+		switch key {
+		case "sni", "peer":
+			if options.TLS != nil {
+				options.TLS.ServerName = value
+			}
+		case "insecure", "allowInsecure", "skip-cert-verify":
+			if options.TLS != nil && (value == "1" || value == "true") {
+				options.TLS.Insecure = true
+			}
+		case "tfo", "tcp-fast-open", "tcp_fast_open":
+			if value == "1" || value == "true" {
+				options.TCPFastOpen = true
+			}
+		}
+	}
+	outbound.Options = &options
+	return outbound, nil
+}
+
+func newSSHNativeParser(content string) (option.Outbound, error) {
+	outbound := option.Outbound{
+		Type: C.TypeSSH,
+	}
+	result, port, err := parseNativeAuthority(content, "ssh", 22)
+	if err != nil {
+		return outbound, err
+	}
+	outbound.Tag = decodeURIComponentStrict(result[5])
+	options := option.SSHOutboundOptions{}
+	options.Server = result[2]
+	options.ServerPort = port
+	options.User, options.Password = parseNativeUserInfo(result[1])
+	for key, value := range parseNativeQuery(result[4]) {
+		switch key {
+		case "private-key", "private_key":
+			options.PrivateKey = strings.Split(value, "\n")
+		case "private-key-passphrase", "private_key_passphrase":
+			options.PrivateKeyPassphrase = value
+		case "host-key", "host_key":
+			options.HostKey = strings.Split(value, ",")
+		case "host-key-algorithms", "host_key_algorithms":
+			options.HostKeyAlgorithms = strings.Split(value, ",")
+		case "client-version", "client_version":
+			options.ClientVersion = value
+		case "tfo", "tcp-fast-open", "tcp_fast_open":
+			if value == "1" || value == "true" {
+				options.TCPFastOpen = true
+			}
+		}
+	}
+	if options.User == "" {
+		return outbound, E.New("invalid ssh uri: missing user")
+	}
+	outbound.Options = &options
+	return outbound, nil
+}
+
+func newNaiveNativeParser(content string, quic bool) (option.Outbound, error) {
+	outbound := option.Outbound{
+		Type: C.TypeNaive,
+	}
+	result, port, err := parseNativeAuthority(content, "naive", 443)
+	if err != nil {
+		return outbound, err
+	}
+	outbound.Tag = decodeURIComponentStrict(result[5])
+	options := option.NaiveOutboundOptions{}
+	options.Server = result[2]
+	options.ServerPort = port
+	options.Username, options.Password = parseNativeUserInfo(result[1])
+	options.QUIC = quic
+	options.TLS = &option.OutboundTLSOptions{
+		Enabled:    true,
+		ServerName: result[2],
+	}
+	for key, value := range parseNativeQuery(result[4]) {
+		switch key {
+		case "sni", "peer":
+			options.TLS.ServerName = value
+		case "insecure-concurrency", "insecure_concurrency":
+			options.InsecureConcurrency, _ = strconv.Atoi(value)
+		case "extra-headers", "extra_headers":
+			options.ExtraHeaders = badoption.HTTPHeader{}
+			for _, header := range strings.Split(value, "\r\n") {
+				key, value, found := strings.Cut(header, ":")
+				if !found {
+					continue
+				}
+				key = trimBlank(key)
+				options.ExtraHeaders[key] = append(options.ExtraHeaders[key], trimBlank(value))
+			}
+		}
+	}
+	outbound.Options = &options
+	return outbound, nil
+}
@@ -1,6 +1,8 @@
 package provider
 
 import (
+	"context"
+	"encoding/json"
 	"reflect"
 	"strings"
 
@@ -10,28 +12,82 @@ import (
 )
 
 func (p *myProviderAdapter) newParser(content string) ([]option.Outbound, error) {
+	outbounds, format, err := parseContent(p.ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	p.format.Store(format)
+	return p.overrideOutbounds(outbounds), nil
+}
+
+// ParseContent parses subscription content in any supported format, optionally
+// base64 encoded, and returns the outbounds with the detected format.
+func ParseContent(ctx context.Context, content string) ([]option.Outbound, string, error) {
+	return parseContent(ctx, decodeBase64Safe(trimBlank(content)))
+}
+
+func parseContent(ctx context.Context, content string) ([]option.Outbound, string, error) {
+	format := detectFormat(content)
 	var outbounds []option.Outbound
 	var err error
-	switch true {
-	case strings.Contains(content, "\"outbounds\""):
-		var options option.OutboundProviderOptions
-		err = options.UnmarshalJSONContext(p.ctx, []byte(content))
-		if err != nil {
-			return nil, E.Cause(err, "decode config at ")
-		}
-		outbounds = options.Outbounds
-	case strings.Contains(content, "proxies"):
+	switch format {
+	case C.ProviderFormatSingBox:
+		outbounds, err = newSingBoxParser(ctx, content)
+	case C.ProviderFormatSIP008:
+		outbounds, err = newSIP008Parser(content)
+	case C.ProviderFormatSurge:
+		outbounds, err = newSurgeParser(content)
+	case C.ProviderFormatClash:
 		outbounds, err = newClashParser(content)
-		if err != nil {
-			return nil, err
-		}
 	default:
 		outbounds, err = newNativeURIParser(content)
-		if err != nil {
-			return nil, err
+	}
+	if err != nil {
+		return nil, format, E.Cause(err, "parse ", format, " content")
+	}
+	return outbounds, format, nil
+}
+
+// detectFormat sniffs the subscription format of decoded provider content.
+func detectFormat(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "{") {
+		var object map[string]json.RawMessage
+		if json.Unmarshal([]byte(trimmed), &object) == nil {
+			if _, isSIP008 := object["servers"]; isSIP008 {
+				return C.ProviderFormatSIP008
+			}
+			return C.ProviderFormatSingBox
+		}
+	} else if strings.HasPrefix(trimmed, "[") {
+		var array []map[string]json.RawMessage
+		if json.Unmarshal([]byte(trimmed), &array) == nil {
+			return C.ProviderFormatSingBox
 		}
 	}
-	return p.overrideOutbounds(outbounds), nil
+	if isSurgeContent(trimmed) {
+		return C.ProviderFormatSurge
+	}
+	if strings.Contains(content, "\"outbounds\"") {
+		return C.ProviderFormatSingBox
+	}
+	if strings.Contains(content, "proxies") {
+		return C.ProviderFormatClash
+	}
+	return C.ProviderFormatURI
+}
+
+func newSingBoxParser(ctx context.Context, content string) ([]option.Outbound, error) {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "[") {
+		content = `{"outbounds":` + content + `}`
+	}
+	var options option.OutboundProviderOptions
+	err := options.UnmarshalJSONContext(ctx, []byte(content))
+	if err != nil {
+		return nil, E.Cause(err, "decode config")
+	}
+	return options.Outbounds, nil
 }
 
 func (p *myProviderAdapter) overrideOutbounds(outbounds []option.Outbound) []option.Outbound {
@@ -83,9 +139,11 @@ func (p *myProviderAdapter) overrideOutbounds(outbounds []option.Outbound) []opt
 			options.DialerOptions = p.overrideDialerOption(options.DialerOptions)
 			outbound.Options = options
 		case C.TypeWireGuard:
-			options := outbound.Options.(*option.LegacyWireGuardOutboundOptions)
-			options.DialerOptions = p.overrideDialerOption(options.DialerOptions)
-			outbound.Options = options
+			// sing-box providers decode wireguard outbounds into stub options
+			if options, isLegacy := outbound.Options.(*option.LegacyWireGuardOutboundOptions); isLegacy {
+				options.DialerOptions = p.overrideDialerOption(options.DialerOptions)
+				outbound.Options = options
+			}
 		case C.TypeAnyTLS:
 			options := outbound.Options.(*option.AnyTLSOutboundOptions)
 			options.DialerOptions = p.overrideDialerOption(options.DialerOptions)
@@ -98,6 +156,14 @@ func (p *myProviderAdapter) overrideOutbounds(outbounds []option.Outbound) []opt
 			options := outbound.Options.(*option.ShadowsocksROutboundOptions)
 			options.DialerOptions = p.overrideDialerOption(options.DialerOptions)
 			outbound.Options = options
+		case C.TypeSSH:
+			options := outbound.Options.(*option.SSHOutboundOptions)
+			options.DialerOptions = p.overrideDialerOption(options.DialerOptions)
+			outbound.Options = options
+		case C.TypeNaive:
+			options := outbound.Options.(*option.NaiveOutboundOptions)
+			options.DialerOptions = p.overrideDialerOption(options.DialerOptions)
+			outbound.Options = options
 		}
 		parsedOutbounds = append(parsedOutbounds, outbound)
 	}
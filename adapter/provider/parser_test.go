@@ -0,0 +1,193 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter/outbound"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/protocol/socks"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFormat(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		content string
+		format  string
+	}{
+		{"sing-box", `{"outbounds":[{"type":"direct"}]}`, C.ProviderFormatSingBox},
+		{"sing-box array", `[{"type":"direct","tag":"a"}]`, C.ProviderFormatSingBox},
+		{"sip008", `{"version":1,"servers":[]}`, C.ProviderFormatSIP008},
+		{"clash", "proxies:\n  - name: a\n    type: ss\n", C.ProviderFormatClash},
+		{"surge", "[General]\nloglevel = notify\n[Proxy]\nA = ss, 1.1.1.1, 443, encrypt-method=aes-128-gcm, password=pw\n", C.ProviderFormatSurge},
+		{"quantumult x", "shadowsocks=1.1.1.1:443, method=aes-128-gcm, password=pw, tag=A\n", C.ProviderFormatSurge},
+		{"uri", "ss://YWVzLTEyOC1nY206cHc@1.1.1.1:443#A\n", C.ProviderFormatURI},
+	} {
+		require.Equal(t, testCase.format, detectFormat(testCase.content), testCase.name)
+	}
+}
+
+func TestSingBoxParser(t *testing.T) {
+	t.Parallel()
+	registry := outbound.NewRegistry()
+	socks.RegisterOutbound(registry)
+	ctx := service.ContextWith[option.OutboundOptionsRegistry](context.Background(), registry)
+	outbounds, err := newSingBoxParser(ctx, `[{"type":"socks","tag":"a","server":"1.1.1.1","server_port":1080,"username":"user","password":"pw"}]`)
+	require.NoError(t, err)
+	require.Len(t, outbounds, 1)
+	require.Equal(t, C.TypeSOCKS, outbounds[0].Type)
+	require.Equal(t, "a", outbounds[0].Tag)
+	require.Equal(t, "pw", outbounds[0].Options.(*option.SOCKSOutboundOptions).Password)
+}
+
+func TestSIP008Parser(t *testing.T) {
+	t.Parallel()
+	outbounds, err := newSIP008Parser(`{
+  "version": 1,
+  "servers": [
+    {
+      "id": "27b8a625-4f4b-4428-9f0f-8a2317db7c79",
+      "remarks": "Name of the server",
+      "server": "example.com",
+      "server_port": 8388,
+      "password": "example",
+      "method": "chacha20-ietf-poly1305",
+      "plugin": "obfs-local",
+      "plugin_opts": "obfs=http;obfs-host=www.example.com"
+    },
+    {
+      "id": "7842c068-c667-41f2-8f7d-04feece3cb67",
+      "server": "example.com",
+      "server_port": 8389,
+      "password": "example",
+      "method": "2022-blake3-aes-128-gcm"
+    },
+    {
+      "id": "unsupported-plugin",
+      "server": "example.com",
+      "server_port": 8390,
+      "password": "example",
+      "method": "aes-128-gcm",
+      "plugin": "kcptun"
+    }
+  ],
+  "bytes_used": 274877906944,
+  "bytes_remaining": 824633720832
+}`)
+	require.NoError(t, err)
+	require.Len(t, outbounds, 2)
+	require.Equal(t, "Name of the server", outbounds[0].Tag)
+	options := outbounds[0].Options.(*option.ShadowsocksOutboundOptions)
+	require.Equal(t, "example.com", options.Server)
+	require.Equal(t, uint16(8388), options.ServerPort)
+	require.Equal(t, "chacha20-ietf-poly1305", options.Method)
+	require.Equal(t, "obfs-local", options.Plugin)
+	require.Equal(t, "obfs=http;obfs-host=www.example.com", options.PluginOptions)
+	require.Equal(t, "7842c068-c667-41f2-8f7d-04feece3cb67", outbounds[1].Tag)
+}
+
+func TestSurgeParser(t *testing.T) {
+	t.Parallel()
+	outbounds, err := newSurgeParser(`[General]
+loglevel = notify
+
+[Proxy]
+# comment
+Direct = direct
+SS = ss, 1.1.1.1, 8388, encrypt-method=aes-128-gcm, password=pw, obfs=http, obfs-host=www.example.com, tfo=true
+Custom = custom, 1.1.1.2, 8388, chacha20-ietf-poly1305, pw2
+VMess = vmess, example.com, 443, username=uuid, ws=true, ws-path=/ws, ws-headers=Host:cdn.example.com, tls=true, sni=example.com
+Trojan = trojan, example.com, 443, password=pw, skip-cert-verify=true
+HTTPS = https, example.com, 443, user, pass
+SOCKS = socks5, 1.1.1.3, 1080
+Hy2 = hysteria2, example.com, 443, password=pw, download-bandwidth=100
+TUIC = tuic-v5, example.com, 443, uuid=uuid, password=pw, alpn=h3
+
+[Proxy Group]
+Proxy = select, SS, VMess
+`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"SS", "Custom", "VMess", "Trojan", "HTTPS", "SOCKS", "Hy2", "TUIC"}, outboundTags(outbounds))
+
+	ss := outbounds[0].Options.(*option.ShadowsocksOutboundOptions)
+	require.Equal(t, "aes-128-gcm", ss.Method)
+	require.Equal(t, "pw", ss.Password)
+	require.Equal(t, "obfs-local", ss.Plugin)
+	require.Contains(t, ss.PluginOptions, "obfs=http")
+	require.Contains(t, ss.PluginOptions, "obfs-host=www.example.com")
+	require.True(t, ss.TCPFastOpen)
+
+	custom := outbounds[1].Options.(*option.ShadowsocksOutboundOptions)
+	require.Equal(t, "chacha20-ietf-poly1305", custom.Method)
+	require.Equal(t, "pw2", custom.Password)
+
+	vmess := outbounds[2].Options.(*option.VMessOutboundOptions)
+	require.Equal(t, "uuid", vmess.UUID)
+	require.True(t, vmess.TLS.Enabled)
+	require.Equal(t, "example.com", vmess.TLS.ServerName)
+	require.Equal(t, C.V2RayTransportTypeWebsocket, vmess.Transport.Type)
+	require.Equal(t, "/ws", vmess.Transport.WebsocketOptions.Path)
+	require.Equal(t, "cdn.example.com", vmess.Transport.WebsocketOptions.Headers["Host"][0])
+
+	trojan := outbounds[3].Options.(*option.TrojanOutboundOptions)
+	require.Equal(t, "pw", trojan.Password)
+	require.True(t, trojan.TLS.Insecure)
+
+	https := outbounds[4].Options.(*option.HTTPOutboundOptions)
+	require.Equal(t, "user", https.Username)
+	require.Equal(t, "pass", https.Password)
+	require.True(t, https.TLS.Enabled)
+
+	require.Equal(t, C.TypeSOCKS, outbounds[5].Type)
+
+	hy2 := outbounds[6].Options.(*option.Hysteria2OutboundOptions)
+	require.Equal(t, "pw", hy2.Password)
+	require.Equal(t, 100, hy2.DownMbps)
+
+	tuic := outbounds[7].Options.(*option.TUICOutboundOptions)
+	require.Equal(t, "uuid", tuic.UUID)
+	require.Equal(t, []string{"h3"}, []string(tuic.TLS.ALPN))
+}
+
+func TestQuantumultXParser(t *testing.T) {
+	t.Parallel()
+	outbounds, err := newSurgeParser(`shadowsocks=1.1.1.1:8388, method=aes-128-gcm, password=pw, obfs=http, obfs-host=www.example.com, tag=SS
+vmess=example.com:443, method=aes-128-gcm, password=uuid, obfs=wss, obfs-host=cdn.example.com, obfs-uri=/ws, tag=VMess
+trojan=example.com:443, password=pw, over-tls=true, tls-host=example.com, tls-verification=false, tag=Trojan
+http=1.1.1.2:8080, username=user, password=pass, tag=HTTP
+`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"SS", "VMess", "Trojan", "HTTP"}, outboundTags(outbounds))
+
+	ss := outbounds[0].Options.(*option.ShadowsocksOutboundOptions)
+	require.Equal(t, "1.1.1.1", ss.Server)
+	require.Equal(t, uint16(8388), ss.ServerPort)
+	require.Equal(t, "obfs-local", ss.Plugin)
+
+	vmess := outbounds[1].Options.(*option.VMessOutboundOptions)
+	require.Equal(t, "uuid", vmess.UUID)
+	require.Equal(t, "aes-128-gcm", vmess.Security)
+	require.True(t, vmess.TLS.Enabled)
+	require.Equal(t, "cdn.example.com", vmess.TLS.ServerName)
+	require.Equal(t, "/ws", vmess.Transport.WebsocketOptions.Path)
+
+	trojan := outbounds[2].Options.(*option.TrojanOutboundOptions)
+	require.Equal(t, "example.com", trojan.TLS.ServerName)
+	require.True(t, trojan.TLS.Insecure)
+
+	http := outbounds[3].Options.(*option.HTTPOutboundOptions)
+	require.Equal(t, "user", http.Username)
+}
+
+func outboundTags(outbounds []option.Outbound) []string {
+	tags := make([]string, 0, len(outbounds))
+	for _, outbound := range outbounds {
+		tags = append(tags, outbound.Tag)
+	}
+	return tags
+}
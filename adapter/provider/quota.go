@@ -0,0 +1,151 @@
+package provider
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/byteformats"
+	F "github.com/sagernet/sing/common/format"
+)
+
+type providerQuota struct {
+	usageThreshold  uint8
+	expireThreshold time.Duration
+	disableExceeded bool
+
+	access    sync.Mutex
+	status    adapter.OutboundProviderQuotaStatus
+	exhausted atomic.Bool
+	timer     *time.Timer
+}
+
+func newProviderQuota(options *option.ProviderQuotaOptions) *providerQuota {
+	if options == nil {
+		return nil
+	}
+	quota := &providerQuota{
+		usageThreshold:  options.UsageThreshold,
+		expireThreshold: time.Duration(options.ExpireThreshold),
+		disableExceeded: options.DisableExceeded,
+	}
+	if quota.usageThreshold == 0 || quota.usageThreshold > 100 {
+		quota.usageThreshold = 90
+	}
+	if quota.expireThreshold <= 0 {
+		quota.expireThreshold = 72 * time.Hour
+	}
+	return quota
+}
+
+// evaluate computes the quota status of info at now, and returns the time
+// the status should be evaluated again when expiry is pending.
+func (q *providerQuota) evaluate(info SubInfo, now time.Time) (adapter.OutboundProviderQuotaStatus, time.Time) {
+	var (
+		status    adapter.OutboundProviderQuotaStatus
+		nextCheck time.Time
+	)
+	if info.total > 0 {
+		used := info.upload + info.download
+		usage := F.ToString(byteformats.FormatBytes(uint64(used)), " of ", byteformats.FormatBytes(uint64(info.total)))
+		if used >= info.total {
+			status.Exceeded = true
+			status.Warnings = append(status.Warnings, "quota exceeded: "+usage+" used")
+		} else if used*100 >= info.total*int64(q.usageThreshold) {
+			status.Warnings = append(status.Warnings, F.ToString("quota usage over ", q.usageThreshold, "%: ", usage, " used"))
+		}
+	}
+	if info.expire > 0 {
+		expireAt := time.Unix(info.expire, 0)
+		if !now.Before(expireAt) {
+			status.Exceeded = true
+			status.Warnings = append(status.Warnings, "subscription expired at "+expireAt.Format(time.DateTime))
+		} else {
+			warnAt := expireAt.Add(-q.expireThreshold)
+			if !now.Before(warnAt) {
+				status.Warnings = append(status.Warnings, "subscription expires at "+expireAt.Format(time.DateTime))
+				nextCheck = expireAt
+			} else {
+				nextCheck = warnAt
+			}
+		}
+	}
+	return status, nextCheck
+}
+
+// update refreshes the status from info, logs new warnings, and reports
+// whether Exhausted changed.
+func (q *providerQuota) update(logger log.ContextLogger, tag string, info SubInfo, onNextCheck func()) bool {
+	status, nextCheck := q.evaluate(info, time.Now())
+	q.access.Lock()
+	for _, warning := range status.Warnings {
+		if !slices.Contains(q.status.Warnings, warning) {
+			logger.Warn("provider ", tag, ": ", warning)
+		}
+	}
+	q.status = status
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	if onNextCheck != nil && !nextCheck.IsZero() {
+		q.timer = time.AfterFunc(time.Until(nextCheck), onNextCheck)
+	}
+	q.access.Unlock()
+	exhausted := q.disableExceeded && status.Exceeded
+	if q.exhausted.Swap(exhausted) == exhausted {
+		return false
+	}
+	if exhausted {
+		logger.Warn("provider ", tag, ": outbounds disabled for groups")
+	} else {
+		logger.Info("provider ", tag, ": outbounds enabled for groups")
+	}
+	return true
+}
+
+func (q *providerQuota) close() {
+	q.access.Lock()
+	defer q.access.Unlock()
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+}
+
+func (p *myProviderAdapter) QuotaStatus() adapter.OutboundProviderQuotaStatus {
+	if p.quota == nil {
+		return adapter.OutboundProviderQuotaStatus{}
+	}
+	p.quota.access.Lock()
+	defer p.quota.access.Unlock()
+	return p.quota.status
+}
+
+func (p *myProviderAdapter) Exhausted() bool {
+	return p.quota != nil && p.quota.exhausted.Load()
+}
+
+// updateQuota applies the quota policy to the current subscription info.
+// Groups are only notified once started.
+func (p *myProviderAdapter) updateQuota(notify bool) {
+	if p.quota == nil {
+		return
+	}
+	var onNextCheck func()
+	if notify {
+		onNextCheck = func() {
+			p.updateQuota(true)
+		}
+	}
+	if p.quota.update(p.logger, p.tag, p.loadSubInfo(), onNextCheck) && notify {
+		err := p.updateGroups(p.router)
+		if err != nil {
+			p.logger.Error(err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderQuotaEvaluate(t *testing.T) {
+	t.Parallel()
+	quota := newProviderQuota(&option.ProviderQuotaOptions{
+		UsageThreshold:  80,
+		ExpireThreshold: badoption.Duration(24 * time.Hour),
+	})
+	now := time.Unix(1700000000, 0)
+
+	status, nextCheck := quota.evaluate(SubInfo{upload: 10, download: 10, total: 100, expire: now.Add(48 * time.Hour).Unix()}, now)
+	require.Empty(t, status.Warnings)
+	require.False(t, status.Exceeded)
+	require.Equal(t, now.Add(24*time.Hour), nextCheck)
+
+	status, nextCheck = quota.evaluate(SubInfo{upload: 40, download: 45, total: 100, expire: now.Add(time.Hour).Unix()}, now)
+	require.Len(t, status.Warnings, 2)
+	require.False(t, status.Exceeded)
+	require.Equal(t, now.Add(time.Hour), nextCheck)
+
+	status, nextCheck = quota.evaluate(SubInfo{download: 100, total: 100}, now)
+	require.Len(t, status.Warnings, 1)
+	require.True(t, status.Exceeded)
+	require.True(t, nextCheck.IsZero())
+
+	status, _ = quota.evaluate(SubInfo{expire: now.Unix()}, now)
+	require.True(t, status.Exceeded)
+}
+
+func TestProviderQuotaUpdate(t *testing.T) {
+	t.Parallel()
+	logger := log.NewNOPFactory().NewLogger("provider")
+	quota := newProviderQuota(&option.ProviderQuotaOptions{
+		DisableExceeded: true,
+	})
+	require.False(t, quota.update(logger, "test", SubInfo{download: 10, total: 100}, nil))
+	require.False(t, quota.exhausted.Load())
+	require.True(t, quota.update(logger, "test", SubInfo{download: 100, total: 100}, nil))
+	require.True(t, quota.exhausted.Load())
+	require.False(t, quota.update(logger, "test", SubInfo{download: 200, total: 100}, nil))
+	require.True(t, quota.update(logger, "test", SubInfo{}, nil))
+	require.False(t, quota.exhausted.Load())
+	require.Nil(t, newProviderQuota(nil))
+}
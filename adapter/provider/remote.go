@@ -30,6 +30,8 @@ import (
 var (
 	_ adapter.OutboundProvider        = (*RemoteProvider)(nil)
 	_ adapter.InterfaceUpdateListener = (*RemoteProvider)(nil)
+	_ adapter.OutboundOptionsManager  = (*RemoteProvider)(nil)
+	_ adapter.OutboundProviderQuota   = (*RemoteProvider)(nil)
 )
 
 type RemoteProvider struct {
@@ -78,6 +80,10 @@ func NewRemoteProvider(ctx context.Context, manager *Manager, router adapter.Rou
 	if downloadInterval < C.DefaultDonloadInterval {
 		downloadInterval = C.DefaultDonloadInterval
 	}
+	healthcheckProbe, err := urltest.NewProbe(remoteOptions.HealthcheckProbe)
+	if err != nil {
+		return nil, E.Cause(err, "parse healthcheck_probe")
+	}
 	ctx, cancel := context.WithCancel(ctx)
 	provider := &RemoteProvider{
 		myProviderAdapter: myProviderAdapter{
@@ -91,6 +97,7 @@ func NewRemoteProvider(ctx context.Context, manager *Manager, router adapter.Rou
 			enableHealthcheck:   remoteOptions.EnableHealthcheck,
 			healthcheckUrl:      healthcheckUrl,
 			healthcheckInterval: healthcheckInterval,
+			healthcheckProbe:    healthcheckProbe,
 			types:               options.Types,
 			ports:               make(map[int]bool),
 			providerType:        C.ProviderTypeRemote,
@@ -98,6 +105,7 @@ func NewRemoteProvider(ctx context.Context, manager *Manager, router adapter.Rou
 			close:               make(chan struct{}),
 			pauseManager:        service.FromContext[pause.Manager](ctx),
 			subInfo:             SubInfo{},
+			quota:               newProviderQuota(remoteOptions.Quota),
 			outbounds:           []adapter.Outbound{},
 			outboundByTag:       make(map[string]adapter.Outbound),
 		},
@@ -143,6 +151,7 @@ func (p *RemoteProvider) PostStart() error {
 		dialer = outbound
 	}
 	p.dialer = dialer
+	p.updateQuota(true)
 	go p.loopUpdateCheck()
 	go p.loopHealthCheck()
 	return nil
@@ -191,7 +200,8 @@ func (p *RemoteProvider) updateCacheFileModTime(subInfo string) {
 	if !ok {
 		return
 	}
-	p.subInfo = info
+	p.setSubInfo(info)
+	p.updateQuota(true)
 
 	contentRaw := getTrimedFile(p.path)
 	content := decodeBase64Safe(string(contentRaw))
@@ -278,8 +288,10 @@ func (p *RemoteProvider) fetchOnce(ctx context.Context, router adapter.Router) e
 		return err
 	}
 
-	p.subInfo = info
+	p.setSubInfo(info)
+	p.updateQuota(true)
 	p.logger.InfoContext(ctx, "update outbound provider ", p.tag, " success")
+	p.manager.providerUpdated(p)
 
 	if hasSubInfo {
 		subInfo = fmt.Sprint("# upload=", info.upload, "; download=", info.download, "; total=", info.total, "; expire=", info.expire, ";")
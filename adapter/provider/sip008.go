@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"encoding/json"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// SIP008Config is the Shadowsocks online configuration format.
+// https://shadowsocks.org/doc/sip008.html
+type SIP008Config struct {
+	Version int            `json:"version"`
+	Servers []SIP008Server `json:"servers"`
+}
+
+type SIP008Server struct {
+	ID         string `json:"id"`
+	Remarks    string `json:"remarks"`
+	Server     string `json:"server"`
+	ServerPort uint16 `json:"server_port"`
+	Password   string `json:"password"`
+	Method     string `json:"method"`
+	Plugin     string `json:"plugin"`
+	PluginOpts string `json:"plugin_opts"`
+}
+
+func newSIP008Parser(content string) ([]option.Outbound, error) {
+	var config SIP008Config
+	err := json.Unmarshal([]byte(strings.TrimSpace(content)), &config)
+	if err != nil {
+		return nil, E.Cause(err, "decode sip008 config")
+	}
+	var outbounds []option.Outbound
+	for _, server := range config.Servers {
+		if server.Server == "" || server.ServerPort == 0 || server.Method == "" {
+			continue
+		}
+		tag := server.Remarks
+		if tag == "" {
+			tag = server.ID
+		}
+		if tag == "" {
+			tag = server.Server
+		}
+		options := option.ShadowsocksOutboundOptions{
+			ServerOptions: option.ServerOptions{
+				Server:     server.Server,
+				ServerPort: server.ServerPort,
+			},
+			Method:   server.Method,
+			Password: server.Password,
+		}
+		switch server.Plugin {
+		case "":
+		case "simple-obfs", "obfs-local":
+			options.Plugin = "obfs-local"
+			options.PluginOptions = server.PluginOpts
+		case "v2ray-plugin":
+			options.Plugin = "v2ray-plugin"
+			options.PluginOptions = server.PluginOpts
+		default:
+			continue
+		}
+		outbounds = append(outbounds, option.Outbound{
+			Type:    C.TypeShadowsocks,
+			Tag:     tag,
+			Options: &options,
+		})
+	}
+	return outbounds, nil
+}
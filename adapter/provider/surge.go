@@ -0,0 +1,340 @@
+package provider
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+)
+
+var quantumultXParser = regexp.MustCompile(`(?i)^(shadowsocks|vmess|trojan|http)\s*=\s*[^,=]+:\d+\s*,`)
+
+// isSurgeContent reports whether content is a Surge or Quantumult X proxy list.
+func isSurgeContent(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		switch strings.ToLower(line) {
+		case "[proxy]", "[server_local]":
+			return true
+		}
+		if quantumultXParser.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// newSurgeParser parses the [Proxy] section of a Surge profile and the
+// [server_local] section of a Quantumult X profile. Content without section
+// headers is treated as a bare list of proxy lines.
+func newSurgeParser(content string) ([]option.Outbound, error) {
+	var (
+		outbounds   []option.Outbound
+		hasSection  = false
+		inSection   = true
+		proxyConfig map[string]any
+	)
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "[") {
+			hasSection = true
+			break
+		}
+	}
+	if hasSection {
+		inSection = false
+	}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			switch strings.ToLower(line) {
+			case "[proxy]", "[server_local]":
+				inSection = true
+			default:
+				inSection = false
+			}
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if quantumultXParser.MatchString(line) {
+			proxyConfig = convertQuantumultXProxy(line)
+		} else {
+			proxyConfig = convertSurgeProxy(line)
+		}
+		if proxyConfig == nil {
+			continue
+		}
+		outbounds = append(outbounds, newClashProxyParser(proxyConfig)...)
+	}
+	return outbounds, nil
+}
+
+func splitSurgeParams(content string) ([]string, map[string]string) {
+	var positional []string
+	params := make(map[string]string)
+	for _, item := range strings.Split(content, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, found := strings.Cut(item, "=")
+		if !found {
+			positional = append(positional, item)
+			continue
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return positional, params
+}
+
+func surgeBool(value string) bool {
+	enabled, _ := strconv.ParseBool(value)
+	return enabled
+}
+
+// convertSurgeProxy converts a Surge proxy line like
+// `name = type, server, port, key=value, ...` into Clash proxy fields.
+func convertSurgeProxy(line string) map[string]any {
+	name, definition, found := strings.Cut(line, "=")
+	if !found {
+		return nil
+	}
+	positional, params := splitSurgeParams(definition)
+	if len(positional) < 3 {
+		return nil
+	}
+	proxyType := strings.ToLower(positional[0])
+	proxy := map[string]any{
+		"name":   strings.TrimSpace(name),
+		"server": positional[1],
+		"port":   positional[2],
+	}
+	positional = positional[3:]
+	if surgeBool(params["tfo"]) {
+		proxy["tfo"] = true
+	}
+	if sni, exists := params["sni"]; exists && sni != "off" {
+		proxy["sni"] = sni
+	}
+	if surgeBool(params["skip-cert-verify"]) {
+		proxy["skip-cert-verify"] = true
+	}
+	if surgeBool(params["tls"]) {
+		proxy["tls"] = true
+	}
+	switch proxyType {
+	case "ss", "shadowsocks", "custom":
+		proxy["type"] = "ss"
+		proxy["cipher"] = params["encrypt-method"]
+		proxy["password"] = params["password"]
+		if proxyType == "custom" && len(positional) >= 2 {
+			proxy["cipher"] = positional[0]
+			proxy["password"] = positional[1]
+		}
+		if obfs, exists := params["obfs"]; exists {
+			pluginOpts := map[string]any{
+				"mode": obfs,
+			}
+			if host, exists := params["obfs-host"]; exists {
+				pluginOpts["host"] = host
+			}
+			proxy["plugin"] = "obfs"
+			proxy["plugin-opts"] = pluginOpts
+		}
+		if surgeBool(params["udp-over-tcp"]) {
+			proxy["udp-over-tcp"] = true
+		}
+	case "vmess":
+		proxy["type"] = "vmess"
+		proxy["uuid"] = params["username"]
+		proxy["alterId"] = 0
+		proxy["cipher"] = "auto"
+		if proxy["sni"] != nil {
+			proxy["servername"] = proxy["sni"]
+		}
+		convertSurgeWebsocket(proxy, params)
+	case "trojan":
+		proxy["type"] = "trojan"
+		proxy["password"] = params["password"]
+		convertSurgeWebsocket(proxy, params)
+	case "http", "https":
+		proxy["type"] = "http"
+		convertSurgeAuthentication(proxy, positional, params)
+		if proxyType == "https" {
+			proxy["tls"] = true
+		}
+	case "socks5":
+		proxy["type"] = "socks5"
+		convertSurgeAuthentication(proxy, positional, params)
+	case "hysteria2":
+		proxy["type"] = "hysteria2"
+		proxy["password"] = params["password"]
+		proxy["tls"] = true
+		if down, err := strconv.Atoi(params["download-bandwidth"]); err == nil {
+			proxy["down"] = down
+		}
+	case "tuic-v5", "tuic":
+		if params["uuid"] == "" {
+			return nil
+		}
+		proxy["type"] = "tuic"
+		proxy["uuid"] = params["uuid"]
+		proxy["password"] = params["password"]
+		proxy["tls"] = true
+		if alpn, exists := params["alpn"]; exists {
+			proxy["alpn"] = []any{alpn}
+		}
+	default:
+		return nil
+	}
+	return proxy
+}
+
+func convertSurgeAuthentication(proxy map[string]any, positional []string, params map[string]string) {
+	if len(positional) >= 2 {
+		proxy["username"] = positional[0]
+		proxy["password"] = positional[1]
+	}
+	if username, exists := params["username"]; exists {
+		proxy["username"] = username
+	}
+	if password, exists := params["password"]; exists {
+		proxy["password"] = password
+	}
+}
+
+func convertSurgeWebsocket(proxy map[string]any, params map[string]string) {
+	if !surgeBool(params["ws"]) {
+		return
+	}
+	wsOpts := map[string]any{}
+	if path, exists := params["ws-path"]; exists {
+		wsOpts["path"] = path
+	}
+	if headers, exists := params["ws-headers"]; exists {
+		headerMap := map[string]any{}
+		for _, header := range strings.Split(headers, "|") {
+			key, value, found := strings.Cut(header, ":")
+			if found {
+				headerMap[strings.Trim(strings.TrimSpace(key), `"`)] = strings.Trim(strings.TrimSpace(value), `"`)
+			}
+		}
+		wsOpts["headers"] = headerMap
+	}
+	proxy["network"] = "ws"
+	proxy["ws-opts"] = wsOpts
+}
+
+// convertQuantumultXProxy converts a Quantumult X server line like
+// `type=server:port, key=value, ..., tag=name` into Clash proxy fields.
+func convertQuantumultXProxy(line string) map[string]any {
+	proxyType, definition, _ := strings.Cut(line, "=")
+	proxyType = strings.ToLower(strings.TrimSpace(proxyType))
+	address, definition, _ := strings.Cut(definition, ",")
+	server, port, err := net.SplitHostPort(strings.TrimSpace(address))
+	if err != nil {
+		return nil
+	}
+	_, params := splitSurgeParams(definition)
+	name := params["tag"]
+	if name == "" {
+		name = address
+	}
+	proxy := map[string]any{
+		"name":   name,
+		"server": server,
+		"port":   port,
+	}
+	if surgeBool(params["fast-open"]) {
+		proxy["tfo"] = true
+	}
+	if host, exists := params["tls-host"]; exists {
+		proxy["sni"] = host
+	}
+	if verification, exists := params["tls-verification"]; exists && !surgeBool(verification) {
+		proxy["skip-cert-verify"] = true
+	}
+	if surgeBool(params["over-tls"]) {
+		proxy["tls"] = true
+	}
+	obfs := params["obfs"]
+	switch proxyType {
+	case "shadowsocks":
+		proxy["type"] = "ss"
+		proxy["cipher"] = params["method"]
+		proxy["password"] = params["password"]
+		switch obfs {
+		case "http", "tls":
+			pluginOpts := map[string]any{
+				"mode": obfs,
+			}
+			if host, exists := params["obfs-host"]; exists {
+				pluginOpts["host"] = host
+			}
+			proxy["plugin"] = "obfs"
+			proxy["plugin-opts"] = pluginOpts
+		case "ws", "wss":
+			pluginOpts := map[string]any{
+				"mode": "websocket",
+			}
+			if obfs == "wss" {
+				pluginOpts["tls"] = true
+			}
+			if host, exists := params["obfs-host"]; exists {
+				pluginOpts["host"] = host
+			}
+			if path, exists := params["obfs-uri"]; exists {
+				pluginOpts["path"] = path
+			}
+			proxy["plugin"] = "v2ray-plugin"
+			proxy["plugin-opts"] = pluginOpts
+		}
+	case "vmess":
+		proxy["type"] = "vmess"
+		proxy["uuid"] = params["password"]
+		proxy["alterId"] = 0
+		proxy["cipher"] = params["method"]
+		if proxy["cipher"] == "" {
+			proxy["cipher"] = "auto"
+		}
+		convertQuantumultXTransport(proxy, obfs, params)
+	case "trojan":
+		proxy["type"] = "trojan"
+		proxy["password"] = params["password"]
+		convertQuantumultXTransport(proxy, obfs, params)
+	case "http":
+		proxy["type"] = "http"
+		proxy["username"] = params["username"]
+		proxy["password"] = params["password"]
+	default:
+		return nil
+	}
+	return proxy
+}
+
+func convertQuantumultXTransport(proxy map[string]any, obfs string, params map[string]string) {
+	switch obfs {
+	case "over-tls":
+		proxy["tls"] = true
+	case "ws", "wss":
+		wsOpts := map[string]any{}
+		if path, exists := params["obfs-uri"]; exists {
+			wsOpts["path"] = path
+		}
+		if host, exists := params["obfs-host"]; exists {
+			wsOpts["headers"] = map[string]any{"Host": host}
+			if _, exists = proxy["sni"]; !exists && obfs == "wss" {
+				proxy["sni"] = host
+			}
+		}
+		proxy["network"] = "ws"
+		proxy["ws-opts"] = wsOpts
+		proxy["tls"] = obfs == "wss"
+	}
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"net/netip"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func newWireGuardEndpointOptions(options *option.LegacyWireGuardOutboundOptions) *option.WireGuardEndpointOptions {
+	endpointOptions := &option.WireGuardEndpointOptions{
+		System:        options.SystemInterface,
+		Name:          options.InterfaceName,
+		MTU:           options.MTU,
+		Address:       options.LocalAddress,
+		PrivateKey:    options.PrivateKey,
+		Workers:       options.Workers,
+		DialerOptions: options.DialerOptions,
+	}
+	for _, peer := range options.Peers {
+		endpointOptions.Peers = append(endpointOptions.Peers, option.WireGuardPeer{
+			Address:      peer.Server,
+			Port:         peer.ServerPort,
+			PublicKey:    peer.PublicKey,
+			PreSharedKey: peer.PreSharedKey,
+			AllowedIPs:   peer.AllowedIPs,
+			Reserved:     peer.Reserved,
+		})
+	}
+	if len(endpointOptions.Peers) == 0 {
+		endpointOptions.Peers = []option.WireGuardPeer{{
+			Address:      options.Server,
+			Port:         options.ServerPort,
+			PublicKey:    options.PeerPublicKey,
+			PreSharedKey: options.PreSharedKey,
+			AllowedIPs:   []netip.Prefix{netip.PrefixFrom(netip.IPv4Unspecified(), 0), netip.PrefixFrom(netip.IPv6Unspecified(), 0)},
+			Reserved:     options.Reserved,
+		}}
+	}
+	return endpointOptions
+}
+
+// newLegacyWireGuardOptions converts a single peer WireGuard endpoint back
+// into the outbound form used by share links and Clash proxies.
+func newLegacyWireGuardOptions(options *option.WireGuardEndpointOptions) (*option.LegacyWireGuardOutboundOptions, error) {
+	if len(options.Peers) != 1 {
+		return nil, E.New("WireGuard endpoint with ", len(options.Peers), " peers is not supported")
+	}
+	peer := options.Peers[0]
+	if peer.Address == "" {
+		return nil, E.New("WireGuard endpoint peer without address is not supported")
+	}
+	return &option.LegacyWireGuardOutboundOptions{
+		DialerOptions:   options.DialerOptions,
+		SystemInterface: options.System,
+		InterfaceName:   options.Name,
+		LocalAddress:    options.Address,
+		PrivateKey:      options.PrivateKey,
+		ServerOptions: option.ServerOptions{
+			Server:     peer.Address,
+			ServerPort: peer.Port,
+		},
+		PeerPublicKey: peer.PublicKey,
+		PreSharedKey:  peer.PreSharedKey,
+		Reserved:      peer.Reserved,
+		Workers:       options.Workers,
+		MTU:           options.MTU,
+	}, nil
+}
@@ -0,0 +1,9 @@
+package adapter
+
+// UserQuotaService is a connection tracker enforcing traffic quotas of
+// inbound users. The router rejects connections before matching rules if
+// CheckUser returns an error.
+type UserQuotaService interface {
+	ConnectionTracker
+	CheckUser(metadata *InboundContext) error
+}
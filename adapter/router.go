@@ -2,10 +2,14 @@ package adapter
 
 import (
 	"context"
+	"io"
 	"net"
+	"net/netip"
 	"time"
 
+	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-tun"
+	"github.com/sagernet/sing/common/logger"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/x/list"
 
@@ -25,12 +29,46 @@ type Router interface {
 	NeedFindProcess() bool
 	NeedFindNeighbor() bool
 	NeighborResolver() NeighborResolver
+	ASNReader() ASNReader
 	Trackers() []ConnectionTracker
 	AppendTracker(tracker ConnectionTracker)
 	ResetNetwork()
 	Reload()
 }
 
+// ScriptRouter is implemented by routers supporting the script rule action.
+type ScriptRouter interface {
+	Script() string
+	UpdateScript(source string) error
+	// EvaluateScript runs source, or the current script if source is empty,
+	// against metadata and returns the selected outbound tag.
+	EvaluateScript(source string, metadata InboundContext) (string, error)
+}
+
+// DryRunRouter is implemented by routers supporting `sing-box route test`.
+type DryRunRouter interface {
+	// DryRun matches metadata against the rules without a connection and
+	// returns the selected rule, which is nil for the default outbound, and
+	// the selected outbound, which is nil for actions other than route.
+	DryRun(ctx context.Context, metadata *InboundContext) (Rule, Outbound, error)
+}
+
+// RuleStatsRouter is implemented by routers keeping statistics of route rules.
+type RuleStatsRouter interface {
+	// RuleStats returns the statistics of each rule in the order of Rules.
+	RuleStats() []RuleStats
+	ResetRuleStats()
+}
+
+// RuleStats is the statistics of a route rule. Traffic is counted on
+// connections the rule made the final decision for.
+type RuleStats struct {
+	Matches  uint64
+	Upload   uint64
+	Download uint64
+	LastHit  time.Time
+}
+
 type ConnectionTracker interface {
 	RoutedConnection(ctx context.Context, conn net.Conn, metadata InboundContext, matchedRule Rule, matchOutbound Outbound) net.Conn
 	RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata InboundContext, matchedRule Rule, matchOutbound Outbound) N.PacketConn
@@ -73,6 +111,11 @@ type DNSRuleSetUpdateValidator interface {
 	ValidateRuleSetMetadataUpdate(tag string, metadata RuleSetMetadata) error
 }
 
+// RuleSetConvertor converts third-party rule lists into rule-sets.
+type RuleSetConvertor interface {
+	ConvertRuleSet(format string, reader io.Reader, logger logger.Logger) (option.PlainRuleSetCompat, error)
+}
+
 // ip_version is not a headless-rule item, so ContainsIPVersionRule is intentionally absent.
 type RuleSetMetadata struct {
 	ContainsProcessRule      bool
@@ -86,3 +129,8 @@ type RuleSetMetadata struct {
 	// response is available.
 	ContainsNonIPCIDRRule bool
 }
+
+// ASNReader looks up the autonomous system of addresses.
+type ASNReader interface {
+	LookupASN(addr netip.Addr) (number uint32, organization string, loaded bool)
+}
@@ -0,0 +1,27 @@
+package adapter
+
+import "context"
+
+// RuleTracer receives the rules evaluated by the router and the DNS router
+// for a dry run, see `sing-box route test`.
+type RuleTracer interface {
+	// TraceRule is called for every rule evaluated in scope, which is route
+	// for the router and dns with the query type for the DNS router.
+	TraceRule(scope string, index int, rule Rule, matched bool)
+	// Sniff replaces protocol sniffing for dry runs, which have no connection.
+	Sniff(metadata *InboundContext) error
+}
+
+type ruleTracerKey struct{}
+
+func ContextWithRuleTracer(ctx context.Context, tracer RuleTracer) context.Context {
+	return context.WithValue(ctx, (*ruleTracerKey)(nil), tracer)
+}
+
+func RuleTracerFromContext(ctx context.Context) RuleTracer {
+	tracer := ctx.Value((*ruleTracerKey)(nil))
+	if tracer == nil {
+		return nil
+	}
+	return tracer.(RuleTracer)
+}
@@ -16,10 +16,12 @@ import (
 	"github.com/sagernet/sing-box/adapter/provider"
 	boxService "github.com/sagernet/sing-box/adapter/service"
 	"github.com/sagernet/sing-box/common/certificate"
+	"github.com/sagernet/sing-box/common/convertor"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/httpclient"
 	"github.com/sagernet/sing-box/common/taskmonitor"
 	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/common/urltest"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/dns"
 	"github.com/sagernet/sing-box/experimental"
@@ -205,6 +207,7 @@ func New(options Options) (*Box, error) {
 	service.MustRegister[adapter.DNSTransportManager](ctx, dnsTransportManager)
 	service.MustRegister[adapter.ServiceManager](ctx, serviceManager)
 	service.MustRegister[adapter.CertificateProviderManager](ctx, certificateProviderManager)
+	service.MustRegister[adapter.RuleSetConvertor](ctx, convertor.Convertor{})
 	dnsRouter, err := dns.NewRouter(ctx, logFactory, dnsOptions)
 	if err != nil {
 		return nil, E.Cause(err, "initialize DNS router")
@@ -414,6 +417,14 @@ func New(options Options) (*Box, error) {
 		cacheFile := cachefile.New(ctx, logFactory.NewLogger("cache-file"), common.PtrValueOrDefault(experimentalOptions.CacheFile))
 		service.MustRegister[adapter.CacheFile](ctx, cacheFile)
 		internalServices = append(internalServices, cacheFile)
+		urlTestHistory := service.PtrFromContext[urltest.HistoryStorage](ctx)
+		if urlTestHistory == nil {
+			urlTestHistory = urltest.NewHistoryStorage()
+			service.MustRegisterPtr(ctx, urlTestHistory)
+		}
+		if service.FromContext[adapter.URLTestHistoryStorage](ctx) == nil {
+			service.MustRegister[adapter.URLTestHistoryStorage](ctx, urlTestHistory)
+		}
 	}
 	if needClashAPI {
 		clashAPIOptions := common.PtrValueOrDefault(experimentalOptions.ClashAPI)
@@ -645,6 +656,10 @@ func (s *Box) Router() adapter.Router {
 	return s.router
 }
 
+func (s *Box) DNSRouter() adapter.DNSRouter {
+	return s.dnsRouter
+}
+
 func (s *Box) Inbound() adapter.InboundManager {
 	return s.inbound
 }
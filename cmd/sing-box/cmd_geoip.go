@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-box/log"
 	E "github.com/sagernet/sing/common/exceptions"
 
@@ -11,13 +12,16 @@ import (
 var (
 	geoipReader          *maxminddb.Reader
 	commandGeoIPFlagFile string
+	commandGeoIPFlagASN  bool
 )
 
+const commandGeoIPDefaultASNFile = "GeoLite2-ASN.mmdb"
+
 var commandGeoip = &cobra.Command{
 	Use:   "geoip",
 	Short: "GeoIP tools",
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		err := geoipPreRun()
+		err := geoipPreRun(cmd)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -26,10 +30,14 @@ var commandGeoip = &cobra.Command{
 
 func init() {
 	commandGeoip.PersistentFlags().StringVarP(&commandGeoIPFlagFile, "file", "f", "geoip.db", "geoip file")
+	commandGeoip.PersistentFlags().BoolVar(&commandGeoIPFlagASN, "asn", false, "use GeoLite2-ASN compatible database ("+commandGeoIPDefaultASNFile+" by default)")
 	mainCommand.AddCommand(commandGeoip)
 }
 
-func geoipPreRun() error {
+func geoipPreRun(cmd *cobra.Command) error {
+	if commandGeoIPFlagASN {
+		return geoipASNPreRun(cmd)
+	}
 	reader, err := maxminddb.Open(commandGeoIPFlagFile)
 	if err != nil {
 		return err
@@ -41,3 +49,20 @@ func geoipPreRun() error {
 	geoipReader = reader
 	return nil
 }
+
+func geoipASNPreRun(cmd *cobra.Command) error {
+	path := commandGeoIPFlagFile
+	if !cmd.Flags().Changed("file") {
+		path = commandGeoIPDefaultASNFile
+	}
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return err
+	}
+	if !geoip.IsASNDatabase(reader.Metadata.DatabaseType) {
+		reader.Close()
+		return E.New("incorrect database type, expected ASN database, got ", reader.Metadata.DatabaseType)
+	}
+	geoipReader = reader
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"strings"
 
+	"github.com/sagernet/sing-box/common/geoip"
+	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
@@ -21,11 +23,18 @@ var flagGeoipExportOutput string
 const flagGeoipExportDefaultOutput = "geoip-<country>.srs"
 
 var commandGeoipExport = &cobra.Command{
-	Use:   "export <country>",
-	Short: "Export geoip country as rule-set",
-	Args:  cobra.ExactArgs(1),
+	Use:   "export <country | asn...>",
+	Short: "Export geoip country, or ASNs and organizations with --asn, as rule-set",
+	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		err := geoipExport(args[0])
+		var err error
+		if commandGeoIPFlagASN {
+			err = geoipExportASN(args)
+		} else if len(args) > 1 {
+			err = E.New("only one country code is accepted")
+		} else {
+			err = geoipExport(args[0])
+		}
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -97,3 +106,72 @@ func geoipExport(countryCode string) error {
 	}
 	return encoder.Encode(plainRuleSet)
 }
+
+// geoipExportASN writes networks of the matched autonomous systems as a
+// binary rule-set, or a source rule-set if the output is stdout or not
+// ending with .srs.
+func geoipExportASN(values []string) error {
+	matcher := geoip.NewASNMatcher(values)
+	networks := geoipReader.Networks(maxminddb.SkipAliasedNetworks)
+	var (
+		ipCIDR []string
+		record geoip.ASNRecord
+	)
+	for networks.Next() {
+		record = geoip.ASNRecord{}
+		ipNet, err := networks.Network(&record)
+		if err != nil {
+			return err
+		}
+		if record.Number != 0 && matcher.Match(record.Number, record.Organization) {
+			ipCIDR = append(ipCIDR, ipNet.String())
+		}
+	}
+	if err := networks.Err(); err != nil {
+		return err
+	}
+	if len(ipCIDR) == 0 {
+		return E.New("no network found for ", strings.Join(values, " "))
+	}
+	ruleSet := option.PlainRuleSet{
+		Rules: []option.HeadlessRule{
+			{
+				Type: C.RuleTypeDefault,
+				DefaultOptions: option.DefaultHeadlessRule{
+					IPCIDR: ipCIDR,
+				},
+			},
+		},
+	}
+	outputPath := flagGeoipExportOutput
+	if outputPath == flagGeoipExportDefaultOutput {
+		outputPath = "geoip-asn-" + strings.Join(values, "-") + ".srs"
+	}
+	if outputPath == "stdout" {
+		return writeGeoipRuleSetSource(os.Stdout, ruleSet)
+	}
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(outputPath, ".srs") {
+		err = srs.Write(outputFile, ruleSet, C.RuleSetVersion2)
+	} else {
+		err = writeGeoipRuleSetSource(outputFile, ruleSet)
+	}
+	outputFile.Close()
+	if err != nil {
+		os.Remove(outputPath)
+		return err
+	}
+	return nil
+}
+
+func writeGeoipRuleSetSource(writer io.Writer, ruleSet option.PlainRuleSet) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(option.PlainRuleSetCompat{
+		Version: C.RuleSetVersion2,
+		Options: ruleSet,
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
 
 	"github.com/spf13/cobra"
 )
@@ -24,6 +25,9 @@ func init() {
 }
 
 func listGeoip() error {
+	if commandGeoIPFlagASN {
+		return E.New("list is not supported by ASN database")
+	}
 	for _, code := range geoipReader.Metadata.Languages {
 		os.Stdout.WriteString(code + "\n")
 	}
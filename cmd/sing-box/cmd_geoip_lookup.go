@@ -4,8 +4,10 @@ import (
 	"net/netip"
 	"os"
 
+	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-box/log"
 	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 	N "github.com/sagernet/sing/common/network"
 
 	"github.com/spf13/cobra"
@@ -13,7 +15,7 @@ import (
 
 var commandGeoipLookup = &cobra.Command{
 	Use:   "lookup <address>",
-	Short: "Lookup if an IP address is contained in the GeoIP database",
+	Short: "Lookup if an IP address is contained in the GeoIP database, or its ASN with --asn",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		err := geoipLookup(args[0])
@@ -36,6 +38,16 @@ func geoipLookup(address string) error {
 		os.Stdout.WriteString("private\n")
 		return nil
 	}
+	if commandGeoIPFlagASN {
+		var record geoip.ASNRecord
+		_ = geoipReader.Lookup(addr.AsSlice(), &record)
+		if record.Number != 0 {
+			os.Stdout.WriteString(F.ToString("AS", record.Number, " ", record.Organization, "\n"))
+			return nil
+		}
+		os.Stdout.WriteString("unknown\n")
+		return nil
+	}
 	var code string
 	_ = geoipReader.Lookup(addr.AsSlice(), &code)
 	if code != "" {
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sagernet/sing-box"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagRouteTestInbound  string
+	flagRouteTestNetwork  string
+	flagRouteTestSource   string
+	flagRouteTestProcess  string
+	flagRouteTestUser     string
+	flagRouteTestProtocol string
+	flagRouteTestDomain   string
+	flagRouteTestLookup   bool
+)
+
+var commandRouteTest = &cobra.Command{
+	Use:   "test <destination>",
+	Short: "Explain which rules and outbound a connection would take",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := routeTest(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandRouteTest.Flags().StringVarP(&flagRouteTestInbound, "inbound", "i", "", "Inbound tag")
+	commandRouteTest.Flags().StringVarP(&flagRouteTestNetwork, "network", "n", N.NetworkTCP, "Network, tcp or udp")
+	commandRouteTest.Flags().StringVarP(&flagRouteTestSource, "source", "s", "", "Source address")
+	commandRouteTest.Flags().StringVar(&flagRouteTestProcess, "process", "", "Process name or path")
+	commandRouteTest.Flags().StringVar(&flagRouteTestUser, "user", "", "Inbound user")
+	commandRouteTest.Flags().StringVar(&flagRouteTestProtocol, "sniff-protocol", "", "Protocol returned by sniff actions")
+	commandRouteTest.Flags().StringVar(&flagRouteTestDomain, "sniff-domain", "", "Domain returned by sniff actions")
+	commandRouteTest.Flags().BoolVar(&flagRouteTestLookup, "lookup", false, "Also resolve the destination domain with DNS rules")
+	commandRoute.AddCommand(commandRouteTest)
+}
+
+type routeTestTracer struct {
+	access sync.Mutex
+}
+
+func (t *routeTestTracer) TraceRule(scope string, index int, rule adapter.Rule, matched bool) {
+	result := "miss"
+	if matched {
+		result = "match"
+	}
+	var description string
+	if ruleDescription := rule.String(); ruleDescription != "" {
+		description = F.ToString(scope, "[", index, "] ", result, ": ", ruleDescription, " => ", rule.Action())
+	} else {
+		description = F.ToString(scope, "[", index, "] ", result, ": => ", rule.Action())
+	}
+	t.access.Lock()
+	defer t.access.Unlock()
+	os.Stdout.WriteString(description + "\n")
+}
+
+func (t *routeTestTracer) Sniff(metadata *adapter.InboundContext) error {
+	if flagRouteTestProtocol == "" && flagRouteTestDomain == "" {
+		return E.New("nothing sniffed")
+	}
+	metadata.Protocol = flagRouteTestProtocol
+	metadata.SniffHost = flagRouteTestDomain
+	t.access.Lock()
+	defer t.access.Unlock()
+	os.Stdout.WriteString(F.ToString("sniffed protocol: ", metadata.Protocol, ", domain: ", metadata.SniffHost, "\n"))
+	return nil
+}
+
+func routeTest(destination string) error {
+	var metadata adapter.InboundContext
+	switch flagRouteTestNetwork {
+	case N.NetworkTCP, N.NetworkUDP:
+		metadata.Network = flagRouteTestNetwork
+	default:
+		return E.New("unknown network: ", flagRouteTestNetwork)
+	}
+	metadata.Destination = M.ParseSocksaddr(destination)
+	if !metadata.Destination.IsValid() {
+		return E.New("invalid destination: ", destination)
+	}
+	if metadata.Destination.Port == 0 {
+		metadata.Destination.Port = 443
+	}
+	if flagRouteTestSource != "" {
+		metadata.Source = M.ParseSocksaddr(flagRouteTestSource)
+		if !metadata.Source.IsIP() {
+			return E.New("invalid source address: ", flagRouteTestSource)
+		}
+	}
+	if flagRouteTestProcess != "" {
+		metadata.ProcessInfo = &adapter.ConnectionOwner{
+			ProcessPath: flagRouteTestProcess,
+			UserId:      -1,
+		}
+	}
+	metadata.User = flagRouteTestUser
+	instance, err := createPreStartedClient()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+	if flagRouteTestInbound != "" {
+		inbound, loaded := instance.Inbound().Get(flagRouteTestInbound)
+		if !loaded {
+			return E.New("inbound not found: ", flagRouteTestInbound)
+		}
+		metadata.Inbound = inbound.Tag()
+		metadata.InboundType = inbound.Type()
+	}
+	router, isDryRunRouter := instance.Router().(adapter.DryRunRouter)
+	if !isDryRunRouter {
+		return E.New("dry run is not supported by router")
+	}
+	ctx := adapter.ContextWithRuleTracer(globalCtx, new(routeTestTracer))
+	ctx = adapter.WithContext(ctx, &metadata)
+	selectedRule, selectedOutbound, routeErr := router.DryRun(ctx, &metadata)
+	var result strings.Builder
+	result.WriteString(F.ToString("destination: ", metadata.Destination, "\n"))
+	if len(metadata.DestinationAddresses) > 0 {
+		result.WriteString(F.ToString("resolved: ", strings.Join(F.MapToString(metadata.DestinationAddresses), " "), "\n"))
+	}
+	if metadata.Protocol != "" {
+		result.WriteString(F.ToString("protocol: ", metadata.Protocol, "\n"))
+	}
+	if selectedRule == nil {
+		result.WriteString("rule: final\n")
+	} else {
+		result.WriteString(F.ToString("rule: ", selectedRule, " => ", selectedRule.Action(), "\n"))
+	}
+	if selectedOutbound != nil {
+		result.WriteString(F.ToString("outbound: ", routeTestOutboundChain(instance, selectedOutbound), "\n"))
+	} else if selectedRule != nil {
+		result.WriteString(F.ToString("outbound: none (", selectedRule.Action(), ")\n"))
+	}
+	os.Stdout.WriteString(result.String())
+	if routeErr != nil {
+		return routeErr
+	}
+	if flagRouteTestLookup && metadata.Destination.IsFqdn() {
+		return routeTestLookup(ctx, instance, metadata.Destination.Fqdn)
+	}
+	return nil
+}
+
+func routeTestOutboundChain(instance *box.Box, outbound adapter.Outbound) string {
+	chain := []string{outbound.Tag()}
+	for range 16 {
+		group, isGroup := outbound.(adapter.OutboundGroup)
+		if !isGroup {
+			break
+		}
+		var loaded bool
+		outbound, loaded = instance.Outbound().Outbound(group.Now())
+		if !loaded {
+			break
+		}
+		chain = append(chain, outbound.Tag())
+	}
+	return strings.Join(chain, " -> ")
+}
+
+func routeTestLookup(ctx context.Context, instance *box.Box, domain string) error {
+	addresses, err := instance.DNSRouter().Lookup(ctx, domain, adapter.DNSQueryOptions{})
+	if err != nil {
+		return err
+	}
+	os.Stdout.WriteString(F.ToString("lookup: ", strings.Join(F.MapToString(addresses), " "), "\n"))
+	return nil
+}
@@ -3,13 +3,15 @@ package main
 import (
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
-	"github.com/sagernet/sing-box/common/convertor/adguard"
+	"github.com/sagernet/sing-box/common/convertor"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 
 	"github.com/spf13/cobra"
@@ -22,7 +24,7 @@ var (
 
 var commandRuleSetConvert = &cobra.Command{
 	Use:   "convert [source-path]",
-	Short: "Convert adguard DNS filter to rule-set",
+	Short: "Convert AdGuard, Clash, Surge, dnsmasq or hosts rules to rule-set",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		err := convertRuleSet(args[0])
@@ -34,7 +36,7 @@ var commandRuleSetConvert = &cobra.Command{
 
 func init() {
 	commandRuleSet.AddCommand(commandRuleSetConvert)
-	commandRuleSetConvert.Flags().StringVarP(&flagRuleSetConvertType, "type", "t", "", "Source type, available: adguard")
+	commandRuleSetConvert.Flags().StringVarP(&flagRuleSetConvertType, "type", "t", "", "Source type, available: adguard, clash, surge, dnsmasq, hosts")
 	commandRuleSetConvert.Flags().StringVarP(&flagRuleSetConvertOutput, "output", "o", flagRuleSetCompileDefaultOutput, "Output file")
 }
 
@@ -51,22 +53,17 @@ func convertRuleSet(sourcePath string) error {
 			return err
 		}
 	}
-	var rules []option.HeadlessRule
-	switch flagRuleSetConvertType {
-	case "adguard":
-		rules, err = adguard.ToOptions(reader, log.StdLogger())
-	case "":
+	if flagRuleSetConvertType == "" {
 		return E.New("source type is required")
-	default:
-		return E.New("unsupported source type: ", flagRuleSetConvertType)
 	}
+	rules, err := convertor.ToOptions(flagRuleSetConvertType, reader, log.StdLogger())
 	if err != nil {
 		return err
 	}
 	var outputPath string
 	if flagRuleSetConvertOutput == flagRuleSetCompileDefaultOutput {
-		if strings.HasSuffix(sourcePath, ".txt") {
-			outputPath = sourcePath[:len(sourcePath)-4] + ".srs"
+		if extension := filepath.Ext(sourcePath); common.Contains([]string{".txt", ".list", ".yaml", ".yml", ".conf"}, extension) {
+			outputPath = strings.TrimSuffix(sourcePath, extension) + ".srs"
 		} else {
 			outputPath = sourcePath + ".srs"
 		}
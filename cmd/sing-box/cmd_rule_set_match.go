@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -77,6 +78,11 @@ func ruleSetMatch(sourcePath string, domain string) error {
 		if err != nil {
 			return err
 		}
+	case C.RuleSetFormatAdGuard, C.RuleSetFormatClash, C.RuleSetFormatSurge, C.RuleSetFormatDnsmasq, C.RuleSetFormatHosts:
+		ruleSet, err = convertor.ToRuleSet(flagRuleSetMatchFormat, bytes.NewReader(content), log.StdLogger())
+		if err != nil {
+			return err
+		}
 	default:
 		return E.New("unknown rule-set format: ", flagRuleSetMatchFormat)
 	}
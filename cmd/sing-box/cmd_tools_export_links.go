@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter/provider"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandExportLinksFlagFormat string
+	commandExportLinksFlagInput  string
+)
+
+var commandExportLinks = &cobra.Command{
+	Use:   "export-links [tag]...",
+	Short: "Export outbounds as share links or Clash proxies",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := exportLinks(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandExportLinks.Flags().StringVarP(&commandExportLinksFlagFormat, "format", "f", provider.ExportFormatURI, "Output format: uri, clash")
+	commandExportLinks.Flags().StringVarP(&commandExportLinksFlagInput, "input", "i", "", "Read outbounds from subscription file instead of configuration")
+	commandTools.AddCommand(commandExportLinks)
+}
+
+func exportLinks(tags []string) error {
+	var outbounds []option.Outbound
+	if commandExportLinksFlagInput != "" {
+		var (
+			content []byte
+			err     error
+		)
+		if commandExportLinksFlagInput == "stdin" {
+			content, err = io.ReadAll(os.Stdin)
+		} else {
+			content, err = os.ReadFile(commandExportLinksFlagInput)
+		}
+		if err != nil {
+			return E.Cause(err, "read input")
+		}
+		outbounds, _, err = provider.ParseContent(globalCtx, string(content))
+		if err != nil {
+			return E.Cause(err, "parse input")
+		}
+	} else {
+		options, err := readConfigAndMerge()
+		if err != nil {
+			return err
+		}
+		outbounds = options.Outbounds
+		for _, endpoint := range options.Endpoints {
+			outbounds = append(outbounds, option.Outbound{
+				Type:    endpoint.Type,
+				Tag:     endpoint.Tag,
+				Options: endpoint.Options,
+			})
+		}
+	}
+	if len(tags) > 0 {
+		for _, tag := range tags {
+			if !common.Any(outbounds, func(it option.Outbound) bool {
+				return it.Tag == tag
+			}) {
+				return E.New("outbound not found: ", tag)
+			}
+		}
+		outbounds = common.Filter(outbounds, func(it option.Outbound) bool {
+			return common.Contains(tags, it.Tag)
+		})
+	}
+	strict := len(tags) > 0
+	content, err := provider.ExportOutbounds(outbounds, commandExportLinksFlagFormat, func(outbound option.Outbound, err error) error {
+		if strict {
+			return E.Cause(err, "export outbound[", outbound.Tag, "]")
+		}
+		log.Warn("skip outbound[", outbound.Tag, "]: ", err)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(content) > 0 && commandExportLinksFlagFormat == provider.ExportFormatURI {
+		content = append(content, '\n')
+	}
+	os.Stdout.Write(content)
+	return nil
+}
@@ -0,0 +1,97 @@
+package clash
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/convertor/classical"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+
+	"gopkg.in/yaml.v3"
+)
+
+type ruleProvider struct {
+	Payload []string `yaml:"payload"`
+}
+
+// ToOptions converts a Clash rule-provider of any behavior, in yaml or text
+// format. The behavior is detected per line: classical rules contain a comma,
+// ipcidr rules are addresses or CIDRs, and everything else is a domain rule.
+func ToOptions(reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	var ruleLines []string
+	if isYAML(content) {
+		var provider ruleProvider
+		err = yaml.Unmarshal(content, &provider)
+		if err != nil {
+			return nil, E.Cause(err, "parse yaml rule-provider")
+		}
+		ruleLines = provider.Payload
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			ruleLines = append(ruleLines, scanner.Text())
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		builder      classical.Builder
+		ignoredLines int
+	)
+	for _, ruleLine := range ruleLines {
+		ruleLine = strings.TrimSpace(ruleLine)
+		if ruleLine == "" || strings.HasPrefix(ruleLine, "#") {
+			continue
+		}
+		err = addLine(&builder, ruleLine)
+		if err != nil {
+			logger.Debug("ignored unsupported rule: ", ruleLine, ": ", err)
+			ignoredLines++
+		}
+	}
+	if builder.Count() == 0 {
+		return nil, E.New("Clash rule-provider is empty or all rules are unsupported")
+	}
+	if ignoredLines > 0 {
+		logger.Info("parsed rules: ", builder.Count(), "/", builder.Count()+ignoredLines)
+	}
+	return builder.Build(), nil
+}
+
+func isYAML(content []byte) bool {
+	for line := range bytes.SplitSeq(content, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("payload:")) {
+			return true
+		}
+	}
+	return false
+}
+
+func addLine(builder *classical.Builder, ruleLine string) error {
+	if strings.ContainsRune(ruleLine, ',') {
+		return builder.AddLine(ruleLine)
+	}
+	if _, err := classical.ParsePrefix(ruleLine); err == nil {
+		return builder.AddIPCIDR(ruleLine)
+	}
+	switch {
+	case strings.HasPrefix(ruleLine, "+."):
+		return builder.AddDomainSuffix(ruleLine[2:])
+	case strings.HasPrefix(ruleLine, "."):
+		return builder.AddDomainSuffix(ruleLine)
+	case strings.ContainsRune(ruleLine, '*'):
+		// a star matches exactly one label
+		return builder.AddDomainRegex(classical.WildcardToRegex(ruleLine, "[^.]+"))
+	default:
+		return builder.AddDomain(ruleLine)
+	}
+}
@@ -0,0 +1,112 @@
+package clash_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor/clash"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newRuleSet(t *testing.T, rules []option.HeadlessRule) func(metadata *adapter.InboundContext) bool {
+	headlessRules := make([]adapter.HeadlessRule, len(rules))
+	for i, ruleOptions := range rules {
+		headlessRule, err := rule.NewHeadlessRule(context.Background(), ruleOptions)
+		require.NoError(t, err)
+		headlessRules[i] = headlessRule
+	}
+	return func(metadata *adapter.InboundContext) bool {
+		for _, headlessRule := range headlessRules {
+			if headlessRule.Match(metadata) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestDomainYAML(t *testing.T) {
+	t.Parallel()
+	rules, err := clash.ToOptions(strings.NewReader(`
+payload:
+  - 'example.com'
+  - '+.example.org'
+  - '.example.net'
+  - '*.example.edu'
+`), logger.NOP())
+	require.NoError(t, err)
+	match := newRuleSet(t, rules)
+	for _, domain := range []string{
+		"example.com",
+		"example.org",
+		"www.example.org",
+		"a.b.example.org",
+		"www.example.net",
+		"a.b.example.net",
+		"www.example.edu",
+	} {
+		require.True(t, match(&adapter.InboundContext{Domain: domain}), domain)
+	}
+	for _, domain := range []string{
+		"www.example.com",
+		"example.net",
+		"example.edu",
+		"a.b.example.edu",
+		"notexample.org",
+	} {
+		require.False(t, match(&adapter.InboundContext{Domain: domain}), domain)
+	}
+}
+
+func TestIPCIDRText(t *testing.T) {
+	t.Parallel()
+	rules, err := clash.ToOptions(strings.NewReader(`# comment
+10.0.0.0/8
+1.1.1.1
+2001:db8::/32
+`), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.EqualValues(t, []string{"10.0.0.0/8", "1.1.1.1/32", "2001:db8::/32"}, rules[0].DefaultOptions.IPCIDR)
+	match := newRuleSet(t, rules)
+	require.True(t, match(&adapter.InboundContext{Destination: M.ParseSocksaddrHostPort("10.1.2.3", 443)}))
+	require.True(t, match(&adapter.InboundContext{Destination: M.ParseSocksaddrHostPort("1.1.1.1", 443)}))
+	require.False(t, match(&adapter.InboundContext{Destination: M.ParseSocksaddrHostPort("1.1.1.2", 443)}))
+}
+
+func TestClassical(t *testing.T) {
+	t.Parallel()
+	rules, err := clash.ToOptions(strings.NewReader(`
+payload:
+  - DOMAIN,example.com
+  - DOMAIN-SUFFIX,example.org
+  - DOMAIN-KEYWORD,sagernet
+  - IP-CIDR,192.168.0.0/16,no-resolve
+  - DST-PORT,8080
+  - GEOIP,CN
+  - PROCESS-NAME,curl
+`), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+	require.EqualValues(t, []uint16{8080}, rules[1].DefaultOptions.Port)
+	require.EqualValues(t, []string{"curl"}, rules[2].DefaultOptions.ProcessName)
+	match := newRuleSet(t, rules)
+	require.True(t, match(&adapter.InboundContext{Domain: "www.example.org"}))
+	require.True(t, match(&adapter.InboundContext{Domain: "sing.sagernet.net"}))
+	require.True(t, match(&adapter.InboundContext{Destination: M.ParseSocksaddrHostPort("192.168.1.1", 443)}))
+	require.True(t, match(&adapter.InboundContext{Destination: M.ParseSocksaddrHostPort("8.8.8.8", 8080)}))
+	require.False(t, match(&adapter.InboundContext{Domain: "www.example.com", Destination: M.ParseSocksaddrHostPort("www.example.com", 443)}))
+}
+
+func TestEmpty(t *testing.T) {
+	t.Parallel()
+	_, err := clash.ToOptions(strings.NewReader("payload:\n  - GEOIP,CN\n"), logger.NOP())
+	require.Error(t, err)
+}
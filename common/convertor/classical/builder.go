@@ -0,0 +1,258 @@
+package classical
+
+import (
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+// Builder collects Clash and Surge style classical rules.
+//
+// Items of different kinds are AND'ed inside a headless rule, so Build
+// emits one rule per kind, which are OR'ed inside the rule-set.
+type Builder struct {
+	domain           []string
+	domainSuffix     []string
+	domainKeyword    []string
+	domainRegex      []string
+	ipCIDR           []string
+	sourceIPCIDR     []string
+	port             []uint16
+	portRange        []string
+	sourcePort       []uint16
+	sourcePortRange  []string
+	processName      []string
+	processPath      []string
+	processPathRegex []string
+	count            int
+}
+
+// Count returns the number of accepted items.
+func (b *Builder) Count() int {
+	return b.count
+}
+
+func (b *Builder) AddDomain(domain string) error {
+	domain = strings.ToLower(domain)
+	if !M.IsDomainName(domain) {
+		return E.New("invalid domain: ", domain)
+	}
+	b.domain = append(b.domain, domain)
+	b.count++
+	return nil
+}
+
+// AddDomainSuffix adds a suffix matching the domain and all subdomains,
+// or only subdomains if prefixed with a dot.
+func (b *Builder) AddDomainSuffix(suffix string) error {
+	suffix = strings.ToLower(suffix)
+	if !M.IsDomainName(strings.TrimPrefix(suffix, ".")) {
+		return E.New("invalid domain suffix: ", suffix)
+	}
+	b.domainSuffix = append(b.domainSuffix, suffix)
+	b.count++
+	return nil
+}
+
+func (b *Builder) AddDomainKeyword(keyword string) error {
+	if keyword == "" {
+		return E.New("empty domain keyword")
+	}
+	b.domainKeyword = append(b.domainKeyword, strings.ToLower(keyword))
+	b.count++
+	return nil
+}
+
+func (b *Builder) AddDomainRegex(expr string) error {
+	_, err := regexp.Compile(expr)
+	if err != nil {
+		return E.Cause(err, "invalid domain regex")
+	}
+	b.domainRegex = append(b.domainRegex, expr)
+	b.count++
+	return nil
+}
+
+// AddDomainWildcard adds a pattern where star matches any characters and
+// question mark matches one character.
+func (b *Builder) AddDomainWildcard(pattern string) error {
+	return b.AddDomainRegex(WildcardToRegex(pattern, ".*"))
+}
+
+func (b *Builder) AddIPCIDR(cidr string) error {
+	prefix, err := ParsePrefix(cidr)
+	if err != nil {
+		return err
+	}
+	b.ipCIDR = append(b.ipCIDR, prefix.String())
+	b.count++
+	return nil
+}
+
+func (b *Builder) AddSourceIPCIDR(cidr string) error {
+	prefix, err := ParsePrefix(cidr)
+	if err != nil {
+		return err
+	}
+	b.sourceIPCIDR = append(b.sourceIPCIDR, prefix.String())
+	b.count++
+	return nil
+}
+
+// AddLine adds a classical rule such as DOMAIN-SUFFIX,example.com.
+// Policies and options after the value are ignored.
+func (b *Builder) AddLine(line string) error {
+	parts := strings.Split(line, ",")
+	if len(parts) < 2 {
+		return E.New("invalid rule: ", line)
+	}
+	ruleType := strings.ToUpper(strings.TrimSpace(parts[0]))
+	value := strings.TrimSpace(parts[1])
+	switch ruleType {
+	case "DOMAIN":
+		return b.AddDomain(value)
+	case "DOMAIN-SUFFIX":
+		return b.AddDomainSuffix(value)
+	case "DOMAIN-KEYWORD":
+		return b.AddDomainKeyword(value)
+	case "DOMAIN-REGEX":
+		return b.AddDomainRegex(value)
+	case "DOMAIN-WILDCARD":
+		return b.AddDomainWildcard(value)
+	case "IP-CIDR", "IP-CIDR6":
+		return b.AddIPCIDR(value)
+	case "SRC-IP-CIDR":
+		return b.AddSourceIPCIDR(value)
+	case "DST-PORT", "DEST-PORT":
+		return addPort(value, &b.port, &b.portRange, &b.count)
+	case "SRC-PORT":
+		return addPort(value, &b.sourcePort, &b.sourcePortRange, &b.count)
+	case "PROCESS-NAME":
+		if strings.ContainsRune(value, '/') || strings.ContainsRune(value, '\\') {
+			// Surge accepts full paths in PROCESS-NAME
+			b.processPath = append(b.processPath, value)
+		} else {
+			b.processName = append(b.processName, value)
+		}
+		b.count++
+		return nil
+	case "PROCESS-PATH":
+		b.processPath = append(b.processPath, value)
+		b.count++
+		return nil
+	case "PROCESS-PATH-REGEX":
+		_, err := regexp.Compile(value)
+		if err != nil {
+			return E.Cause(err, "invalid process path regex")
+		}
+		b.processPathRegex = append(b.processPathRegex, value)
+		b.count++
+		return nil
+	default:
+		return E.New("unsupported rule type: ", ruleType)
+	}
+}
+
+func addPort(value string, ports *[]uint16, portRanges *[]string, count *int) error {
+	for item := range strings.SplitSeq(value, "/") {
+		item = strings.TrimSpace(item)
+		if from, to, isRange := strings.Cut(item, "-"); isRange {
+			fromPort, err := strconv.ParseUint(from, 10, 16)
+			if err != nil {
+				return E.Cause(err, "invalid port range: ", item)
+			}
+			toPort, err := strconv.ParseUint(to, 10, 16)
+			if err != nil {
+				return E.Cause(err, "invalid port range: ", item)
+			}
+			*portRanges = append(*portRanges, strconv.FormatUint(fromPort, 10)+":"+strconv.FormatUint(toPort, 10))
+		} else {
+			port, err := strconv.ParseUint(item, 10, 16)
+			if err != nil {
+				return E.Cause(err, "invalid port: ", item)
+			}
+			*ports = append(*ports, uint16(port))
+		}
+	}
+	*count++
+	return nil
+}
+
+func (b *Builder) Build() []option.HeadlessRule {
+	var rules []option.HeadlessRule
+	appendRule := func(options option.DefaultHeadlessRule) {
+		rules = append(rules, option.HeadlessRule{
+			Type:           C.RuleTypeDefault,
+			DefaultOptions: options,
+		})
+	}
+	if len(b.domain) > 0 || len(b.domainSuffix) > 0 || len(b.domainKeyword) > 0 || len(b.domainRegex) > 0 || len(b.ipCIDR) > 0 {
+		appendRule(option.DefaultHeadlessRule{
+			Domain:        b.domain,
+			DomainSuffix:  b.domainSuffix,
+			DomainKeyword: b.domainKeyword,
+			DomainRegex:   b.domainRegex,
+			IPCIDR:        b.ipCIDR,
+		})
+	}
+	if len(b.sourceIPCIDR) > 0 {
+		appendRule(option.DefaultHeadlessRule{SourceIPCIDR: b.sourceIPCIDR})
+	}
+	if len(b.port) > 0 || len(b.portRange) > 0 {
+		appendRule(option.DefaultHeadlessRule{Port: b.port, PortRange: b.portRange})
+	}
+	if len(b.sourcePort) > 0 || len(b.sourcePortRange) > 0 {
+		appendRule(option.DefaultHeadlessRule{SourcePort: b.sourcePort, SourcePortRange: b.sourcePortRange})
+	}
+	if len(b.processName) > 0 {
+		appendRule(option.DefaultHeadlessRule{ProcessName: b.processName})
+	}
+	if len(b.processPath) > 0 {
+		appendRule(option.DefaultHeadlessRule{ProcessPath: b.processPath})
+	}
+	if len(b.processPathRegex) > 0 {
+		appendRule(option.DefaultHeadlessRule{ProcessPathRegex: b.processPathRegex})
+	}
+	return rules
+}
+
+// ParsePrefix parses a CIDR or a single address.
+func ParsePrefix(value string) (netip.Prefix, error) {
+	if strings.ContainsRune(value, '/') {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, E.Cause(err, "invalid ip cidr")
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, E.Cause(err, "invalid ip cidr")
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// WildcardToRegex converts a domain wildcard pattern to an anchored regular
+// expression, with star replaced by the given expression.
+func WildcardToRegex(pattern string, star string) string {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for _, char := range strings.ToLower(pattern) {
+		switch char {
+		case '*':
+			builder.WriteString(star)
+		case '?':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	builder.WriteString("$")
+	return builder.String()
+}
@@ -0,0 +1,55 @@
+package convertor
+
+import (
+	"io"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor/adguard"
+	"github.com/sagernet/sing-box/common/convertor/clash"
+	"github.com/sagernet/sing-box/common/convertor/dnsmasq"
+	"github.com/sagernet/sing-box/common/convertor/hosts"
+	"github.com/sagernet/sing-box/common/convertor/surge"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+var _ adapter.RuleSetConvertor = Convertor{}
+
+// Convertor converts rule lists of the built-in third-party formats.
+type Convertor struct{}
+
+func (c Convertor) ConvertRuleSet(format string, reader io.Reader, logger logger.Logger) (option.PlainRuleSetCompat, error) {
+	return ToRuleSet(format, reader, logger)
+}
+
+// ToOptions converts a third-party rule list of the given rule-set format.
+func ToOptions(format string, reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	switch format {
+	case C.RuleSetFormatAdGuard:
+		return adguard.ToOptions(reader, logger)
+	case C.RuleSetFormatClash:
+		return clash.ToOptions(reader, logger)
+	case C.RuleSetFormatSurge:
+		return surge.ToOptions(reader, logger)
+	case C.RuleSetFormatDnsmasq:
+		return dnsmasq.ToOptions(reader, logger)
+	case C.RuleSetFormatHosts:
+		return hosts.ToOptions(reader, logger)
+	default:
+		return nil, E.New("unsupported source type: ", format)
+	}
+}
+
+// ToRuleSet converts a third-party rule list into a rule-set of the current version.
+func ToRuleSet(format string, reader io.Reader, logger logger.Logger) (option.PlainRuleSetCompat, error) {
+	rules, err := ToOptions(format, reader, logger)
+	if err != nil {
+		return option.PlainRuleSetCompat{}, err
+	}
+	return option.PlainRuleSetCompat{
+		Version: C.RuleSetVersionCurrent,
+		Options: option.PlainRuleSet{Rules: rules},
+	}, nil
+}
@@ -0,0 +1,85 @@
+package dnsmasq
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/convertor/classical"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+// ToOptions converts the domains of dnsmasq server, local, address, ipset
+// and nftset options, such as server=/example.com/114.114.114.114, which match
+// the domain and all subdomains.
+func ToOptions(reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	scanner := bufio.NewScanner(reader)
+	var (
+		builder      classical.Builder
+		ignoredLines int
+	)
+	for scanner.Scan() {
+		ruleLine := strings.TrimSpace(scanner.Text())
+		if ruleLine == "" || strings.HasPrefix(ruleLine, "#") {
+			continue
+		}
+		err := addLine(&builder, ruleLine)
+		if err != nil {
+			logger.Debug("ignored unsupported line: ", ruleLine, ": ", err)
+			ignoredLines++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if builder.Count() == 0 {
+		return nil, E.New("dnsmasq config is empty or all lines are unsupported")
+	}
+	if ignoredLines > 0 {
+		logger.Info("parsed lines: ", builder.Count(), "/", builder.Count()+ignoredLines)
+	}
+	return builder.Build(), nil
+}
+
+func addLine(builder *classical.Builder, ruleLine string) error {
+	name, value, loaded := strings.Cut(ruleLine, "=")
+	if !loaded {
+		return E.New("missing value")
+	}
+	switch strings.TrimSpace(name) {
+	case "server", "local", "address", "ipset", "nftset":
+	default:
+		return E.New("unsupported option: ", name)
+	}
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "/") {
+		return E.New("missing domain")
+	}
+	lastSlash := strings.LastIndexByte(value, '/')
+	if lastSlash == 0 {
+		return E.New("missing domain")
+	}
+	var added bool
+	for domain := range strings.SplitSeq(value[1:lastSlash], "/") {
+		var err error
+		switch {
+		case domain == "" || domain == "#":
+			// matches unqualified names or all domains
+			continue
+		case strings.HasPrefix(domain, "*."):
+			err = builder.AddDomainSuffix(domain[1:])
+		default:
+			err = builder.AddDomainSuffix(domain)
+		}
+		if err != nil {
+			return err
+		}
+		added = true
+	}
+	if !added {
+		return E.New("missing domain")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package dnsmasq_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor/dnsmasq"
+	"github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter(t *testing.T) {
+	t.Parallel()
+	rules, err := dnsmasq.ToOptions(strings.NewReader(`# comment
+server=/example.com/114.114.114.114
+server=/example.org/example.net/114.114.114.114#53
+address=/*.example.edu/0.0.0.0
+ipset=/example.gov/proxy
+server=/#/8.8.8.8
+server=8.8.4.4
+cache-size=1000
+`), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.EqualValues(t, []string{"example.com", "example.org", "example.net", ".example.edu", "example.gov"}, rules[0].DefaultOptions.DomainSuffix)
+	headlessRule, err := rule.NewHeadlessRule(context.Background(), rules[0])
+	require.NoError(t, err)
+	for _, domain := range []string{"example.com", "www.example.com", "example.net", "www.example.edu", "example.gov"} {
+		require.True(t, headlessRule.Match(&adapter.InboundContext{Domain: domain}), domain)
+	}
+	for _, domain := range []string{"example.edu", "notexample.com", "example.io"} {
+		require.False(t, headlessRule.Match(&adapter.InboundContext{Domain: domain}), domain)
+	}
+}
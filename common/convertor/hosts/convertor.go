@@ -0,0 +1,73 @@
+package hosts
+
+import (
+	"bufio"
+	"io"
+	"net/netip"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/convertor/classical"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+var localHostnames = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+	"ip6-localnet":          true,
+	"ip6-mcastprefix":       true,
+	"ip6-allnodes":          true,
+	"ip6-allrouters":        true,
+	"ip6-allhosts":          true,
+	"0.0.0.0":               true,
+}
+
+// ToOptions converts the hostnames of a hosts file, such as a blocklist of
+// 0.0.0.0 entries, into exact domain rules. Local hostnames are skipped.
+func ToOptions(reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	scanner := bufio.NewScanner(reader)
+	var (
+		builder      classical.Builder
+		ignoredLines int
+	)
+	for scanner.Scan() {
+		ruleLine := scanner.Text()
+		if commentIndex := strings.IndexByte(ruleLine, '#'); commentIndex >= 0 {
+			ruleLine = ruleLine[:commentIndex]
+		}
+		fields := strings.Fields(ruleLine)
+		if len(fields) == 0 {
+			continue
+		}
+		if _, err := netip.ParseAddr(fields[0]); err != nil || len(fields) < 2 {
+			logger.Debug("ignored invalid line: ", ruleLine)
+			ignoredLines++
+			continue
+		}
+		for _, hostname := range fields[1:] {
+			if localHostnames[strings.ToLower(hostname)] {
+				continue
+			}
+			err := builder.AddDomain(hostname)
+			if err != nil {
+				logger.Debug("ignored invalid hostname: ", hostname)
+				ignoredLines++
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if builder.Count() == 0 {
+		return nil, E.New("hosts file is empty or all entries are unsupported")
+	}
+	if ignoredLines > 0 {
+		logger.Info("parsed hostnames: ", builder.Count(), "/", builder.Count()+ignoredLines)
+	}
+	return builder.Build(), nil
+}
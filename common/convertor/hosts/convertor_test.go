@@ -0,0 +1,32 @@
+package hosts_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/convertor/hosts"
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter(t *testing.T) {
+	t.Parallel()
+	rules, err := hosts.ToOptions(strings.NewReader(`# blocklist
+127.0.0.1 localhost
+::1 localhost ip6-localhost ip6-loopback
+0.0.0.0 0.0.0.0
+0.0.0.0 ads.example.com tracker.example.com # trailing comment
+0.0.0.0 Ads.Example.org
+example.net
+`), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.EqualValues(t, []string{"ads.example.com", "tracker.example.com", "ads.example.org"}, rules[0].DefaultOptions.Domain)
+}
+
+func TestEmpty(t *testing.T) {
+	t.Parallel()
+	_, err := hosts.ToOptions(strings.NewReader("127.0.0.1 localhost\n"), logger.NOP())
+	require.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package surge
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/convertor/classical"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+// ToOptions converts a Surge rule list or domain set. Lines with a comma are
+// classical rules, other lines are domain set entries where a leading dot
+// matches the domain and all subdomains.
+func ToOptions(reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	scanner := bufio.NewScanner(reader)
+	var (
+		builder      classical.Builder
+		ignoredLines int
+	)
+	for scanner.Scan() {
+		ruleLine := strings.TrimSpace(scanner.Text())
+		if ruleLine == "" || strings.HasPrefix(ruleLine, "#") || strings.HasPrefix(ruleLine, "//") || strings.HasPrefix(ruleLine, ";") {
+			continue
+		}
+		var err error
+		if strings.ContainsRune(ruleLine, ',') {
+			err = builder.AddLine(ruleLine)
+		} else if strings.HasPrefix(ruleLine, ".") {
+			err = builder.AddDomainSuffix(ruleLine[1:])
+		} else {
+			err = builder.AddDomain(ruleLine)
+		}
+		if err != nil {
+			logger.Debug("ignored unsupported rule: ", ruleLine, ": ", err)
+			ignoredLines++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if builder.Count() == 0 {
+		return nil, E.New("Surge rule list is empty or all rules are unsupported")
+	}
+	if ignoredLines > 0 {
+		logger.Info("parsed rules: ", builder.Count(), "/", builder.Count()+ignoredLines)
+	}
+	return builder.Build(), nil
+}
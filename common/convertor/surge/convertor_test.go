@@ -0,0 +1,57 @@
+package surge_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor/surge"
+	"github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleList(t *testing.T) {
+	t.Parallel()
+	rules, err := surge.ToOptions(strings.NewReader(`# comment
+// comment
+DOMAIN-SUFFIX,example.org
+DOMAIN-WILDCARD,*.exam?le.com
+IP-CIDR6,2001:db8::/32,no-resolve
+SRC-PORT,1000-2000
+USER-AGENT,curl*
+`), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.EqualValues(t, []string{"1000:2000"}, rules[1].DefaultOptions.SourcePortRange)
+	headlessRule, err := rule.NewHeadlessRule(context.Background(), rules[0])
+	require.NoError(t, err)
+	for _, domain := range []string{"example.org", "www.example.org", "www.example.com", "a.b.examxle.com"} {
+		require.True(t, headlessRule.Match(&adapter.InboundContext{Domain: domain}), domain)
+	}
+	for _, domain := range []string{"example.com", "www.example.net"} {
+		require.False(t, headlessRule.Match(&adapter.InboundContext{Domain: domain}), domain)
+	}
+	require.True(t, headlessRule.Match(&adapter.InboundContext{Destination: M.ParseSocksaddrHostPort("2001:db8::1", 443)}))
+}
+
+func TestDomainSet(t *testing.T) {
+	t.Parallel()
+	rules, err := surge.ToOptions(strings.NewReader(`
+example.com
+.example.org
+`), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	headlessRule, err := rule.NewHeadlessRule(context.Background(), rules[0])
+	require.NoError(t, err)
+	for _, domain := range []string{"example.com", "example.org", "www.example.org"} {
+		require.True(t, headlessRule.Match(&adapter.InboundContext{Domain: domain}), domain)
+	}
+	for _, domain := range []string{"www.example.com", "notexample.org"} {
+		require.False(t, headlessRule.Match(&adapter.InboundContext{Domain: domain}), domain)
+	}
+}
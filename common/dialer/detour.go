@@ -7,7 +7,6 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
-	"github.com/sagernet/sing-box/protocol/group"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/bufio"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -94,7 +93,7 @@ func (d *DetourDialer) DialContext(ctx context.Context, network string, destinat
 				return nil, E.New("loop on detour: ", tag)
 			}
 			for {
-				if groupAdapter, ok := outbound.(group.Adapter); ok {
+				if groupAdapter, ok := outbound.(adapter.OutboundGroup); ok {
 					tag = groupAdapter.Now()
 					if tag == metadata.Outbound {
 						return nil, E.New("loop on detour: ", tag)
@@ -0,0 +1,89 @@
+package geoip
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// ASNRecord is the record of GeoLite2-ASN compatible databases.
+type ASNRecord struct {
+	Number       uint32 `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// IsASNDatabase reports whether databaseType has ASN records, such as
+// GeoLite2-ASN, GeoIP2-ISP and DBIP-ASN-Lite.
+func IsASNDatabase(databaseType string) bool {
+	return strings.Contains(databaseType, "ASN") || strings.Contains(databaseType, "ISP")
+}
+
+type ASNReader struct {
+	reader *maxminddb.Reader
+}
+
+func OpenASN(path string) (*ASNReader, error) {
+	database, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !IsASNDatabase(database.Metadata.DatabaseType) {
+		database.Close()
+		return nil, E.New("incorrect database type, expected ASN database, got ", database.Metadata.DatabaseType)
+	}
+	return &ASNReader{database}, nil
+}
+
+func (r *ASNReader) LookupASN(addr netip.Addr) (number uint32, organization string, loaded bool) {
+	var record ASNRecord
+	err := r.reader.Lookup(addr.Unmap().AsSlice(), &record)
+	if err != nil || record.Number == 0 {
+		return
+	}
+	return record.Number, record.Organization, true
+}
+
+func (r *ASNReader) Close() error {
+	return r.reader.Close()
+}
+
+// ASNMatcher matches autonomous system numbers such as 13335 or AS13335, and
+// organization names containing any other value, case-insensitively.
+type ASNMatcher struct {
+	numbers       map[uint32]bool
+	organizations []string
+}
+
+func NewASNMatcher(values []string) *ASNMatcher {
+	matcher := &ASNMatcher{
+		numbers: make(map[uint32]bool),
+	}
+	for _, value := range values {
+		number, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(value), "AS"), 10, 32)
+		if err == nil {
+			matcher.numbers[uint32(number)] = true
+		} else {
+			matcher.organizations = append(matcher.organizations, strings.ToLower(value))
+		}
+	}
+	return matcher
+}
+
+func (m *ASNMatcher) Match(number uint32, organization string) bool {
+	if m.numbers[number] {
+		return true
+	}
+	if len(m.organizations) > 0 {
+		organization = strings.ToLower(organization)
+		for _, keyword := range m.organizations {
+			if strings.Contains(organization, keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package geoip_test
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/common/geoip"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestASNMatcher(t *testing.T) {
+	t.Parallel()
+	matcher := geoip.NewASNMatcher([]string{"13335", "AS16509", "as15169", "Hetzner"})
+	require.True(t, matcher.Match(13335, "CLOUDFLARENET"))
+	require.True(t, matcher.Match(16509, "AMAZON-02"))
+	require.True(t, matcher.Match(15169, "GOOGLE"))
+	require.True(t, matcher.Match(24940, "Hetzner Online GmbH"))
+	require.False(t, matcher.Match(8075, "MICROSOFT-CORP-MSN-AS-BLOCK"))
+}
+
+func TestIsASNDatabase(t *testing.T) {
+	t.Parallel()
+	require.True(t, geoip.IsASNDatabase("GeoLite2-ASN"))
+	require.True(t, geoip.IsASNDatabase("GeoIP2-ISP"))
+	require.True(t, geoip.IsASNDatabase("DBIP-ASN-Lite (compat=GeoLite2-ASN)"))
+	require.False(t, geoip.IsASNDatabase("sing-geoip"))
+	require.False(t, geoip.IsASNDatabase("GeoLite2-Country"))
+}
@@ -0,0 +1,66 @@
+package ja3
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func buildClientHello(ciphers []uint16, extensions [][]byte) []byte {
+	var body []byte
+	body = binary.BigEndian.AppendUint16(body, 0x0303)
+	body = append(body, make([]byte, 32)...)
+	body = append(body, 0)
+	body = binary.BigEndian.AppendUint16(body, uint16(len(ciphers)*2))
+	for _, cipher := range ciphers {
+		body = binary.BigEndian.AppendUint16(body, cipher)
+	}
+	body = append(body, 1, 0)
+	var extensionData []byte
+	for _, extension := range extensions {
+		extensionData = append(extensionData, extension...)
+	}
+	body = binary.BigEndian.AppendUint16(body, uint16(len(extensionData)))
+	body = append(body, extensionData...)
+	handshake := []byte{handshakeType, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}
+	handshake = append(handshake, body...)
+	record := []byte{contentType, 0x03, 0x01}
+	record = binary.BigEndian.AppendUint16(record, uint16(len(handshake)))
+	return append(record, handshake...)
+}
+
+func buildExtension(extensionType uint16, data ...byte) []byte {
+	extension := binary.BigEndian.AppendUint16(nil, extensionType)
+	extension = binary.BigEndian.AppendUint16(extension, uint16(len(data)))
+	return append(extension, data...)
+}
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+	payload := buildClientHello([]uint16{0x2a2a, 0x1301, 0x1302, 0xc02b}, [][]byte{
+		buildExtension(0x1a1a),
+		buildExtension(sniExtensionType, 0, 14, 0, 0, 11, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm'),
+		buildExtension(ecExtensionType, 0, 6, 0x0a, 0x0a, 0, 29, 0, 23),
+		buildExtension(ecpfExtensionType, 1, 0),
+		buildExtension(signatureAlgorithmsExtensionType, 0, 4, 0x04, 0x03, 0x08, 0x04),
+		buildExtension(alpnExtensionType, 0, 12, 2, 'h', '2', 8, 'h', 't', 't', 'p', '/', '1', '.', '1'),
+		buildExtension(versionExtensionType, 6, 0x0a, 0x0a, 0x03, 0x04, 0x03, 0x03),
+	})
+	clientHello, err := Compute(payload)
+	require.NoError(t, err)
+	require.Equal(t, "example.com", clientHello.ServerName)
+	require.Equal(t, []string{"h2", "http/1.1"}, clientHello.ALPNProtocols)
+	require.Equal(t, "771,4865-4866-49195,0-10-11-13-16-43,29-23,0", clientHello.String())
+	require.Equal(t, "11138d9933242c3a03b6aad35a296476", clientHello.Hash())
+	require.Equal(t, "t13d0306h2_5559582ccdc4_fb71836bce29", clientHello.JA4(JA4ProtocolTCP))
+	require.Equal(t, "q13d0306h2_5559582ccdc4_fb71836bce29", clientHello.JA4(JA4ProtocolQUIC))
+}
+
+func TestFingerprintWithoutExtensions(t *testing.T) {
+	t.Parallel()
+	clientHello, err := Compute(buildClientHello([]uint16{0xc02b}, nil))
+	require.NoError(t, err)
+	require.Equal(t, "771,49195,,,", clientHello.String())
+	require.Equal(t, "t12i010000_648b5c445417_000000000000", clientHello.JA4(JA4ProtocolTCP))
+}
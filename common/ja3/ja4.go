@@ -0,0 +1,114 @@
+package ja3
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+const (
+	JA4ProtocolTCP  = 't'
+	JA4ProtocolQUIC = 'q'
+)
+
+// JA4 returns the JA4 fingerprint of the ClientHello, see
+// https://github.com/FoxIO-LLC/ja4/blob/main/technical_details/JA4.md
+func (j *ClientHello) JA4(protocol byte) string {
+	ciphers := filterGrease(j.CipherSuites)
+	extensions := filterGrease(j.Extensions)
+	var builder strings.Builder
+	builder.WriteByte(protocol)
+	builder.WriteString(j.ja4Version())
+	if slices.Contains(extensions, sniExtensionType) {
+		builder.WriteByte('d')
+	} else {
+		builder.WriteByte('i')
+	}
+	fmt.Fprintf(&builder, "%02d%02d", min(len(ciphers), 99), min(len(extensions), 99))
+	builder.WriteString(j.ja4ALPN())
+	builder.WriteByte('_')
+	slices.Sort(ciphers)
+	builder.WriteString(ja4Hash(ja4HexList(ciphers)))
+	builder.WriteByte('_')
+	extensions = slices.DeleteFunc(extensions, func(it uint16) bool {
+		return it == sniExtensionType || it == alpnExtensionType
+	})
+	if len(extensions) == 0 {
+		builder.WriteString(ja4Hash(""))
+		return builder.String()
+	}
+	slices.Sort(extensions)
+	extensionList := ja4HexList(extensions)
+	if signatureAlgorithms := filterGrease(j.SignatureAlgorithms); len(signatureAlgorithms) > 0 {
+		extensionList += "_" + ja4HexList(signatureAlgorithms)
+	}
+	builder.WriteString(ja4Hash(extensionList))
+	return builder.String()
+}
+
+func (j *ClientHello) ja4Version() string {
+	version := j.Version
+	var supportedVersion uint16
+	for _, it := range j.Versions {
+		if !isGrease(it) && it > supportedVersion {
+			supportedVersion = it
+		}
+	}
+	if supportedVersion != 0 {
+		version = supportedVersion
+	}
+	switch version {
+	case 0x0304:
+		return "13"
+	case 0x0303:
+		return "12"
+	case 0x0302:
+		return "11"
+	case 0x0301:
+		return "10"
+	case 0x0300:
+		return "s3"
+	default:
+		return "00"
+	}
+}
+
+func (j *ClientHello) ja4ALPN() string {
+	if len(j.ALPNProtocols) == 0 || j.ALPNProtocols[0] == "" {
+		return "00"
+	}
+	alpn := j.ALPNProtocols[0]
+	first, last := alpn[0], alpn[len(alpn)-1]
+	if !isAlphanumeric(first) || !isAlphanumeric(last) {
+		alpnHex := hex.EncodeToString([]byte(alpn))
+		return alpnHex[:1] + alpnHex[len(alpnHex)-1:]
+	}
+	return string([]byte{first, last})
+}
+
+func isAlphanumeric(char byte) bool {
+	return char >= '0' && char <= '9' || char >= 'A' && char <= 'Z' || char >= 'a' && char <= 'z'
+}
+
+func filterGrease(values []uint16) []uint16 {
+	return slices.DeleteFunc(slices.Clone(values), isGrease)
+}
+
+func ja4HexList(values []uint16) string {
+	items := make([]string, 0, len(values))
+	for _, value := range values {
+		items = append(items, fmt.Sprintf("%04x", value))
+	}
+	return strings.Join(items, ",")
+}
+
+func ja4Hash(value string) string {
+	if value == "" {
+		return "000000000000"
+	}
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:6])
+}
@@ -0,0 +1,100 @@
+// Package minisign verifies signatures created by minisign.
+//
+// See https://jedisct1.github.io/minisign/ for the format.
+package minisign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	untrustedCommentPrefix = "untrusted comment: "
+	trustedCommentPrefix   = "trusted comment: "
+)
+
+var (
+	algorithmLegacy = [2]byte{'E', 'd'}
+	algorithmHashed = [2]byte{'E', 'D'}
+)
+
+type PublicKey struct {
+	keyID [8]byte
+	key   ed25519.PublicKey
+}
+
+// ParsePublicKey parses a base64 encoded public key, or the content of a
+// public key file.
+func ParsePublicKey(content string) (*PublicKey, error) {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, untrustedCommentPrefix) {
+		_, content, _ = strings.Cut(content, "\n")
+		content = strings.TrimSpace(content)
+	}
+	keyBytes, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return nil, E.Cause(err, "decode public key")
+	}
+	if len(keyBytes) != 2+8+ed25519.PublicKeySize {
+		return nil, E.New("invalid public key length: ", len(keyBytes))
+	}
+	if [2]byte(keyBytes[:2]) != algorithmLegacy {
+		return nil, E.New("unsupported public key algorithm: ", string(keyBytes[:2]))
+	}
+	return &PublicKey{
+		keyID: [8]byte(keyBytes[2:10]),
+		key:   ed25519.PublicKey(keyBytes[10:]),
+	}, nil
+}
+
+// Verify checks signature, the content of a .minisig file, against message.
+func (k *PublicKey) Verify(message []byte, signature []byte) error {
+	lines := strings.Split(strings.ReplaceAll(string(signature), "\r\n", "\n"), "\n")
+	if len(lines) < 4 {
+		return E.New("invalid signature file")
+	}
+	if !strings.HasPrefix(lines[0], untrustedCommentPrefix) {
+		return E.New("invalid signature file: missing untrusted comment")
+	}
+	signatureBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return E.Cause(err, "decode signature")
+	}
+	if len(signatureBytes) != 2+8+ed25519.SignatureSize {
+		return E.New("invalid signature length: ", len(signatureBytes))
+	}
+	if [8]byte(signatureBytes[2:10]) != k.keyID {
+		return E.New("signature key ID mismatch")
+	}
+	trustedComment, loaded := strings.CutPrefix(lines[2], trustedCommentPrefix)
+	if !loaded {
+		return E.New("invalid signature file: missing trusted comment")
+	}
+	globalSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil {
+		return E.Cause(err, "decode global signature")
+	}
+	signedMessage := message
+	switch [2]byte(signatureBytes[:2]) {
+	case algorithmLegacy:
+	case algorithmHashed:
+		messageHash := blake2b.Sum512(message)
+		signedMessage = messageHash[:]
+	default:
+		return E.New("unsupported signature algorithm: ", string(signatureBytes[:2]))
+	}
+	messageSignature := signatureBytes[10:]
+	if !ed25519.Verify(k.key, signedMessage, messageSignature) {
+		return E.New("invalid signature")
+	}
+	if !ed25519.Verify(k.key, bytes.Join([][]byte{messageSignature, []byte(trustedComment)}, nil), globalSignature) {
+		return E.New("invalid global signature")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package minisign
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+)
+
+func signForTest(privateKey ed25519.PrivateKey, keyID []byte, algorithm string, message []byte, trustedComment string) []byte {
+	signedMessage := message
+	if algorithm == "ED" {
+		messageHash := blake2b.Sum512(message)
+		signedMessage = messageHash[:]
+	}
+	signature := ed25519.Sign(privateKey, signedMessage)
+	globalSignature := ed25519.Sign(privateKey, append(append([]byte{}, signature...), trustedComment...))
+	signatureBytes := append(append([]byte(algorithm), keyID...), signature...)
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(signatureBytes) + "\n" +
+		"trusted comment: " + trustedComment + "\n" +
+		base64.StdEncoding.EncodeToString(globalSignature) + "\n")
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+	publicKeyBytes, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	encodedKey := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), publicKeyBytes...))
+	publicKey, err := ParsePublicKey("untrusted comment: minisign public key\n" + encodedKey + "\n")
+	require.NoError(t, err)
+	message := []byte("rule-set content")
+	for _, algorithm := range []string{"Ed", "ED"} {
+		signature := signForTest(privateKey, keyID, algorithm, message, "timestamp:1700000000")
+		require.NoError(t, publicKey.Verify(message, signature), algorithm)
+		require.Error(t, publicKey.Verify([]byte("tampered content"), signature), algorithm)
+	}
+	signature := signForTest(privateKey, []byte{8, 7, 6, 5, 4, 3, 2, 1}, "ED", message, "")
+	require.ErrorContains(t, publicKey.Verify(message, signature), "key ID mismatch")
+	_, err = ParsePublicKey("invalid")
+	require.Error(t, err)
+}
+
+// The minisign tool was not available to produce these vectors. They were
+// built independently of this package: Ed25519 signatures by OpenSSL 3.0,
+// BLAKE2b-512 prehashing by Python's hashlib, following the minisign file
+// format. The key seed is SHA-256("sing-box minisign test key").
+const (
+	knownAnswerPublicKey = "untrusted comment: minisign public key 1807F6E5D4C3B2A1\n" +
+		"RWShssPU5fYHGHthHuMkyOTjjhyQY94HzOwg5j2xH8wCMr71hGSCUEX/\n"
+	knownAnswerMessage = "sing-box rule-set\n"
+)
+
+var knownAnswerSignatures = map[string]string{
+	"Ed": "untrusted comment: signature from minisign secret key\n" +
+		"RWShssPU5fYHGCAZXz/WpUCgpoA3zAE9spNArvZf91iZC2xLCERILu7ScyN5GNlTJ1YMOVE3l8LNwsAwuDDrdNfzTEBMdCj7uAY=\n" +
+		"trusted comment: timestamp:1700000000\tfile:rule-set.srs\n" +
+		"vs3DPBhWgxqYEqgXoTn+dx+yEHnBgwwfBH7XpDjdBrNziD/1onVUay3ZZp2tljje4A3lfPoNOaC3DqtNGoSQDg==\n",
+	"ED": "untrusted comment: signature from minisign secret key\n" +
+		"RUShssPU5fYHGP6EQuZmvRM1uS//gPGxPm6Ua9rsUeKmERAdL1JCrH8AwiqnFr7jU7gmU+Knz1YNS2f0vi2EgQpTuxRLvPYzQQs=\n" +
+		"trusted comment: timestamp:1700000000\tfile:rule-set.srs\thashed\n" +
+		"M+YPaJJKtPNqWoTtgRrgVboqnFJ2Vw1Q4Lj3QFdJa5tn9U5ULEUvW0hXgeYMvEs1j1H6XkuysXeIQkdn7NC2BA==\n",
+}
+
+func TestVerifyKnownAnswer(t *testing.T) {
+	t.Parallel()
+	publicKey, err := ParsePublicKey(knownAnswerPublicKey)
+	require.NoError(t, err)
+	for algorithm, signature := range knownAnswerSignatures {
+		require.NoError(t, publicKey.Verify([]byte(knownAnswerMessage), []byte(signature)), algorithm)
+		require.Error(t, publicKey.Verify([]byte("sing-box rule-set"), []byte(signature)), algorithm)
+		forged := strings.Replace(signature, "timestamp:1700000000", "timestamp:1800000000", 1)
+		require.Error(t, publicKey.Verify([]byte(knownAnswerMessage), []byte(forged)), algorithm)
+	}
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const minBurst = 64 * 1024
+
+// Bucket is a token bucket of bytes, which may be shared by connections.
+type Bucket struct {
+	access sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket returns a bucket refilled at rate bytes per second, allowing
+// bursts of one second of traffic.
+func NewBucket(rate uint64) *Bucket {
+	burst := float64(rate)
+	if burst < minBurst {
+		burst = minBurst
+	}
+	return &Bucket{
+		rate:   float64(rate),
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// NewBucketWithBurst returns a bucket refilled at rate tokens per second,
+// holding at most burst tokens.
+func NewBucketWithBurst(rate uint64, burst uint64) *Bucket {
+	return &Bucket{
+		rate:   float64(rate),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (b *Bucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// reserve takes n tokens and returns how long the caller must wait until the
+// bucket is no longer in debt.
+func (b *Bucket) reserve(n int) time.Duration {
+	b.access.Lock()
+	defer b.access.Unlock()
+	b.refill(time.Now())
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Allow takes n tokens if available, without going into debt.
+func (b *Bucket) Allow(n int) bool {
+	b.access.Lock()
+	defer b.access.Unlock()
+	b.refill(time.Now())
+	if b.tokens < float64(n) {
+		return false
+	}
+	b.tokens -= float64(n)
+	return true
+}
+
+// Wait takes n tokens from the bucket, blocking until they are available.
+func (b *Bucket) Wait(ctx context.Context, n int) error {
+	delay := b.reserve(n)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func waitAll(ctx context.Context, buckets []*Bucket, n int) error {
+	for _, bucket := range buckets {
+		err := bucket.Wait(ctx, n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// NewConn limits the inbound side of a connection: data read from it is
+// counted as upload and data written to it as download.
+func NewConn(conn net.Conn, upload []*Bucket, download []*Bucket) net.Conn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &limitedConn{
+		Conn:     conn,
+		ctx:      ctx,
+		cancel:   cancel,
+		upload:   upload,
+		download: download,
+	}
+}
+
+type limitedConn struct {
+	net.Conn
+	ctx      context.Context
+	cancel   context.CancelFunc
+	upload   []*Bucket
+	download []*Bucket
+}
+
+func (c *limitedConn) Read(p []byte) (n int, err error) {
+	n, err = c.Conn.Read(p)
+	if n > 0 {
+		waitErr := waitAll(c.ctx, c.upload, n)
+		if err == nil && waitErr != nil {
+			err = net.ErrClosed
+		}
+	}
+	return
+}
+
+func (c *limitedConn) Write(p []byte) (n int, err error) {
+	err = waitAll(c.ctx, c.download, len(p))
+	if err != nil {
+		return 0, net.ErrClosed
+	}
+	return c.Conn.Write(p)
+}
+
+func (c *limitedConn) Close() error {
+	c.cancel()
+	return c.Conn.Close()
+}
+
+func (c *limitedConn) Upstream() any {
+	return c.Conn
+}
+
+// NewPacketConn is the packet version of NewConn.
+func NewPacketConn(conn N.PacketConn, upload []*Bucket, download []*Bucket) N.PacketConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &limitedPacketConn{
+		PacketConn: conn,
+		ctx:        ctx,
+		cancel:     cancel,
+		upload:     upload,
+		download:   download,
+	}
+}
+
+type limitedPacketConn struct {
+	N.PacketConn
+	ctx      context.Context
+	cancel   context.CancelFunc
+	upload   []*Bucket
+	download []*Bucket
+}
+
+func (c *limitedPacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	destination, err = c.PacketConn.ReadPacket(buffer)
+	if err != nil {
+		return
+	}
+	err = waitAll(c.ctx, c.upload, buffer.Len())
+	if err != nil {
+		err = net.ErrClosed
+	}
+	return
+}
+
+func (c *limitedPacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	err := waitAll(c.ctx, c.download, buffer.Len())
+	if err != nil {
+		buffer.Release()
+		return net.ErrClosed
+	}
+	return c.PacketConn.WritePacket(buffer, destination)
+}
+
+func (c *limitedPacketConn) Close() error {
+	c.cancel()
+	return c.PacketConn.Close()
+}
+
+func (c *limitedPacketConn) Upstream() any {
+	return c.PacketConn
+}
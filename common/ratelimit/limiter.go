@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"sync"
+
+	C "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// Limiter hands out the buckets of a bandwidth limit by scope.
+type Limiter struct {
+	upload   uint64
+	download uint64
+	scope    string
+	access   sync.Mutex
+	buckets  map[string]*bucketPair
+}
+
+type bucketPair struct {
+	upload   *Bucket
+	download *Bucket
+}
+
+// NewLimiter creates a limiter of upload and download bytes per second, zero
+// for unlimited directions.
+func NewLimiter(upload uint64, download uint64, scope string) (*Limiter, error) {
+	switch scope {
+	case "":
+		scope = C.BandwidthLimitScopeConnection
+	case C.BandwidthLimitScopeConnection, C.BandwidthLimitScopeUser, C.BandwidthLimitScopeInbound, C.BandwidthLimitScopeRule:
+	default:
+		return nil, E.New("unknown bandwidth limit scope: ", scope)
+	}
+	if upload == 0 && download == 0 {
+		return nil, E.New("missing upload or download bandwidth")
+	}
+	return &Limiter{
+		upload:   upload,
+		download: download,
+		scope:    scope,
+		buckets:  make(map[string]*bucketPair),
+	}, nil
+}
+
+func (l *Limiter) Scope() string {
+	return l.scope
+}
+
+// Buckets returns the buckets of a connection, which are nil for unlimited
+// directions. Connections without a user are limited individually in the
+// user scope.
+func (l *Limiter) Buckets(inbound string, user string) (upload *Bucket, download *Bucket) {
+	var key string
+	switch l.scope {
+	case C.BandwidthLimitScopeUser:
+		if user == "" {
+			return l.newBuckets()
+		}
+		key = user
+	case C.BandwidthLimitScopeInbound:
+		key = inbound
+	case C.BandwidthLimitScopeRule:
+	default:
+		return l.newBuckets()
+	}
+	l.access.Lock()
+	defer l.access.Unlock()
+	pair, loaded := l.buckets[key]
+	if !loaded {
+		pair = new(bucketPair)
+		pair.upload, pair.download = l.newBuckets()
+		l.buckets[key] = pair
+	}
+	return pair.upload, pair.download
+}
+
+func (l *Limiter) newBuckets() (upload *Bucket, download *Bucket) {
+	if l.upload > 0 {
+		upload = NewBucket(l.upload)
+	}
+	if l.download > 0 {
+		download = NewBucket(l.download)
+	}
+	return
+}
@@ -0,0 +1,62 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/common/ratelimit"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket(t *testing.T) {
+	t.Parallel()
+	bucket := ratelimit.NewBucket(64 * 1024)
+	start := time.Now()
+	require.NoError(t, bucket.Wait(context.Background(), 64*1024))
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+	require.NoError(t, bucket.Wait(context.Background(), 32*1024))
+	require.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Error(t, bucket.Wait(ctx, 64*1024))
+}
+
+func TestLimiterScope(t *testing.T) {
+	t.Parallel()
+	limiter, err := ratelimit.NewLimiter(1024, 0, C.BandwidthLimitScopeUser)
+	require.NoError(t, err)
+	upload, download := limiter.Buckets("in", "alice")
+	require.NotNil(t, upload)
+	require.Nil(t, download)
+	sameUpload, _ := limiter.Buckets("other", "alice")
+	require.Same(t, upload, sameUpload)
+	otherUpload, _ := limiter.Buckets("in", "bob")
+	require.NotSame(t, upload, otherUpload)
+	anonymous, _ := limiter.Buckets("in", "")
+	anotherAnonymous, _ := limiter.Buckets("in", "")
+	require.NotSame(t, anonymous, anotherAnonymous)
+
+	limiter, err = ratelimit.NewLimiter(1024, 1024, C.BandwidthLimitScopeRule)
+	require.NoError(t, err)
+	upload, _ = limiter.Buckets("a", "alice")
+	sameUpload, _ = limiter.Buckets("b", "bob")
+	require.Same(t, upload, sameUpload)
+
+	_, err = ratelimit.NewLimiter(0, 0, "")
+	require.Error(t, err)
+	_, err = ratelimit.NewLimiter(1024, 0, "unknown")
+	require.Error(t, err)
+}
+
+func TestBucketAllow(t *testing.T) {
+	t.Parallel()
+	bucket := ratelimit.NewBucketWithBurst(10, 2)
+	require.True(t, bucket.Allow(1))
+	require.True(t, bucket.Allow(1))
+	require.False(t, bucket.Allow(1))
+	time.Sleep(150 * time.Millisecond)
+	require.True(t, bucket.Allow(1))
+}
@@ -0,0 +1,254 @@
+package script
+
+import (
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var builtinFunctions = map[string]Function{
+	"len":           builtinLen,
+	"lower":         stringFunction(strings.ToLower),
+	"upper":         stringFunction(strings.ToUpper),
+	"has_prefix":    builtinHasPrefix,
+	"has_suffix":    builtinHasSuffix,
+	"domain_suffix": builtinDomainSuffix,
+	"match":         builtinMatch,
+	"ip_in_cidr":    builtinIPInCIDR,
+	"int":           builtinInt,
+	"string":        builtinString,
+}
+
+// StringList converts a string slice into a script list value.
+func StringList(values []string) []any {
+	list := make([]any, 0, len(values))
+	for _, value := range values {
+		list = append(list, value)
+	}
+	return list
+}
+
+// Arguments checks the argument count of a function call.
+func Arguments(arguments []any, count int) error {
+	if len(arguments) != count {
+		return E.New("expected ", count, " arguments, got ", len(arguments))
+	}
+	return nil
+}
+
+// StringArgument returns the argument at index if it is a string.
+func StringArgument(arguments []any, index int) (string, error) {
+	value, isString := arguments[index].(string)
+	if !isString {
+		return "", E.New("argument ", index+1, " must be string, got ", typeName(arguments[index]))
+	}
+	return value, nil
+}
+
+func stringArguments(arguments []any, minCount int) ([]string, error) {
+	if len(arguments) < minCount {
+		return nil, E.New("expected at least ", minCount, " arguments, got ", len(arguments))
+	}
+	values := make([]string, 0, len(arguments))
+	for index := range arguments {
+		value, err := StringArgument(arguments, index)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func stringFunction(transform func(string) string) Function {
+	return func(arguments []any) (any, error) {
+		err := Arguments(arguments, 1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := StringArgument(arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		return transform(value), nil
+	}
+}
+
+func builtinLen(arguments []any) (any, error) {
+	err := Arguments(arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+	switch value := arguments[0].(type) {
+	case nil:
+		return int64(0), nil
+	case string:
+		return int64(len(value)), nil
+	case []any:
+		return int64(len(value)), nil
+	case map[string]any:
+		return int64(len(value)), nil
+	default:
+		return nil, E.New("object of type ", typeName(value), " has no len()")
+	}
+}
+
+func builtinHasPrefix(arguments []any) (any, error) {
+	values, err := stringArguments(arguments, 2)
+	if err != nil {
+		return nil, err
+	}
+	for _, prefix := range values[1:] {
+		if strings.HasPrefix(values[0], prefix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func builtinHasSuffix(arguments []any) (any, error) {
+	values, err := stringArguments(arguments, 2)
+	if err != nil {
+		return nil, err
+	}
+	for _, suffix := range values[1:] {
+		if strings.HasSuffix(values[0], suffix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// builtinDomainSuffix follows the semantics of the domain_suffix rule item:
+// a suffix matches the domain itself and all of its subdomains,
+// while a suffix starting with a dot only matches subdomains.
+func builtinDomainSuffix(arguments []any) (any, error) {
+	values, err := stringArguments(arguments, 2)
+	if err != nil {
+		return nil, err
+	}
+	domain := strings.ToLower(values[0])
+	for _, suffix := range values[1:] {
+		suffix = strings.ToLower(suffix)
+		if strings.HasPrefix(suffix, ".") {
+			if strings.HasSuffix(domain, suffix) {
+				return true, nil
+			}
+		} else if domain == suffix || strings.HasSuffix(domain, "."+suffix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func builtinMatch(arguments []any) (any, error) {
+	err := Arguments(arguments, 2)
+	if err != nil {
+		return nil, err
+	}
+	values, err := stringArguments(arguments, 2)
+	if err != nil {
+		return nil, err
+	}
+	pattern, err := compileRegexp(values[0])
+	if err != nil {
+		return nil, err
+	}
+	return pattern.MatchString(values[1]), nil
+}
+
+const regexpCacheSize = 256
+
+var (
+	regexpCacheAccess sync.Mutex
+	regexpCache       = make(map[string]*regexp.Regexp)
+)
+
+// compileRegexp caches compiled patterns, since scripts run for every
+// connection and usually match against a few constant patterns.
+func compileRegexp(expr string) (*regexp.Regexp, error) {
+	regexpCacheAccess.Lock()
+	pattern, loaded := regexpCache[expr]
+	regexpCacheAccess.Unlock()
+	if loaded {
+		return pattern, nil
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	regexpCacheAccess.Lock()
+	if len(regexpCache) >= regexpCacheSize {
+		clear(regexpCache)
+	}
+	regexpCache[expr] = pattern
+	regexpCacheAccess.Unlock()
+	return pattern, nil
+}
+
+func builtinIPInCIDR(arguments []any) (any, error) {
+	values, err := stringArguments(arguments, 2)
+	if err != nil {
+		return nil, err
+	}
+	if values[0] == "" {
+		return false, nil
+	}
+	address, err := netip.ParseAddr(values[0])
+	if err != nil {
+		return nil, err
+	}
+	address = address.Unmap()
+	for _, cidr := range values[1:] {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		if prefix.Contains(address) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func builtinInt(arguments []any) (any, error) {
+	err := Arguments(arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+	switch value := arguments[0].(type) {
+	case int64:
+		return value, nil
+	case bool:
+		if value {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case string:
+		return strconv.ParseInt(value, 10, 64)
+	default:
+		return nil, E.New("cannot convert ", typeName(value), " to int")
+	}
+}
+
+func builtinString(arguments []any) (any, error) {
+	err := Arguments(arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+	switch value := arguments[0].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case int64, bool:
+		return F.ToString(value), nil
+	default:
+		return nil, E.New("cannot convert ", typeName(value), " to string")
+	}
+}
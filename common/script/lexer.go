@@ -0,0 +1,162 @@
+package script
+
+import (
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+type tokenKind uint8
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenInt
+	tokenOperator
+)
+
+type position struct {
+	line   int
+	column int
+}
+
+func (p position) String() string {
+	return F.ToString(p.line, ":", p.column)
+}
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   position
+	// newline reports whether a line break precedes the token,
+	// used to terminate statements without explicit separators.
+	newline bool
+}
+
+var operators = []string{
+	"==", "!=", "<=", ">=", "&&", "||",
+	"(", ")", "{", "}", "[", "]", ",", ".", ";",
+	"!", "<", ">", "+", "-", "=",
+}
+
+func tokenize(source string) ([]token, error) {
+	var (
+		tokens  []token
+		line    = 1
+		column  = 1
+		newline = true
+	)
+	advance := func(n int) {
+		for _, c := range source[:n] {
+			if c == '\n' {
+				line++
+				column = 1
+			} else {
+				column++
+			}
+		}
+		source = source[n:]
+	}
+	for len(source) > 0 {
+		c := source[0]
+		switch {
+		case c == '\n':
+			newline = true
+			advance(1)
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			advance(1)
+			continue
+		case c == '#' || strings.HasPrefix(source, "//"):
+			end := strings.IndexByte(source, '\n')
+			if end == -1 {
+				end = len(source)
+			}
+			advance(end)
+			continue
+		}
+		pos := position{line, column}
+		switch {
+		case isIdentStart(c):
+			end := 1
+			for end < len(source) && isIdentPart(source[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: source[:end], pos: pos, newline: newline})
+			advance(end)
+		case c >= '0' && c <= '9':
+			end := 1
+			for end < len(source) && source[end] >= '0' && source[end] <= '9' {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenInt, value: source[:end], pos: pos, newline: newline})
+			advance(end)
+		case c == '"' || c == '\'':
+			value, length, err := readString(source)
+			if err != nil {
+				return nil, E.Cause(err, pos)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value, pos: pos, newline: newline})
+			advance(length)
+		default:
+			var matched string
+			for _, operator := range operators {
+				if strings.HasPrefix(source, operator) {
+					matched = operator
+					break
+				}
+			}
+			if matched == "" {
+				return nil, E.New(pos, ": unexpected character ", strconv.QuoteRune(rune(c)))
+			}
+			tokens = append(tokens, token{kind: tokenOperator, value: matched, pos: pos, newline: newline})
+			advance(len(matched))
+		}
+		newline = false
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: position{line, column}, newline: true})
+	return tokens, nil
+}
+
+func readString(source string) (string, int, error) {
+	quote := source[0]
+	var builder strings.Builder
+	for i := 1; i < len(source); i++ {
+		c := source[i]
+		switch c {
+		case quote:
+			return builder.String(), i + 1, nil
+		case '\n':
+			return "", 0, E.New("unterminated string")
+		case '\\':
+			i++
+			if i == len(source) {
+				return "", 0, E.New("unterminated string")
+			}
+			switch source[i] {
+			case 'n':
+				builder.WriteByte('\n')
+			case 't':
+				builder.WriteByte('\t')
+			case '\\', '"', '\'':
+				builder.WriteByte(source[i])
+			default:
+				return "", 0, E.New("unknown escape sequence \\", string(source[i]))
+			}
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return "", 0, E.New("unterminated string")
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
@@ -0,0 +1,530 @@
+package script
+
+import (
+	"strconv"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type statement interface {
+	position() position
+}
+
+type letStatement struct {
+	pos   position
+	name  string
+	value expression
+}
+
+type assignStatement struct {
+	pos   position
+	name  string
+	value expression
+}
+
+type ifStatement struct {
+	pos       position
+	condition expression
+	then      []statement
+	otherwise []statement
+}
+
+type returnStatement struct {
+	pos   position
+	value expression
+}
+
+func (s *letStatement) position() position    { return s.pos }
+func (s *assignStatement) position() position { return s.pos }
+func (s *ifStatement) position() position     { return s.pos }
+func (s *returnStatement) position() position { return s.pos }
+
+type expression interface {
+	position() position
+}
+
+type literalExpression struct {
+	pos   position
+	value any
+}
+
+type identExpression struct {
+	pos  position
+	name string
+}
+
+type listExpression struct {
+	pos      position
+	elements []expression
+}
+
+type unaryExpression struct {
+	pos      position
+	operator string
+	operand  expression
+}
+
+type binaryExpression struct {
+	pos      position
+	operator string
+	left     expression
+	right    expression
+}
+
+type memberExpression struct {
+	pos    position
+	object expression
+	name   string
+}
+
+type indexExpression struct {
+	pos    position
+	object expression
+	index  expression
+}
+
+type callExpression struct {
+	pos       position
+	function  string
+	arguments []expression
+}
+
+func (e *literalExpression) position() position { return e.pos }
+func (e *identExpression) position() position   { return e.pos }
+func (e *listExpression) position() position    { return e.pos }
+func (e *unaryExpression) position() position   { return e.pos }
+func (e *binaryExpression) position() position  { return e.pos }
+func (e *memberExpression) position() position  { return e.pos }
+func (e *indexExpression) position() position   { return e.pos }
+func (e *callExpression) position() position    { return e.pos }
+
+var keywords = map[string]bool{
+	"let":    true,
+	"if":     true,
+	"else":   true,
+	"return": true,
+	"true":   true,
+	"false":  true,
+	"nil":    true,
+	"in":     true,
+	"not":    true,
+}
+
+// maxDepth bounds nesting of blocks and expressions so that
+// hostile input cannot exhaust the goroutine stack while parsing.
+const maxDepth = 64
+
+type parser struct {
+	tokens []token
+	index  int
+	depth  int
+}
+
+func parse(source string) ([]statement, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	var statements []statement
+	for p.peek().kind != tokenEOF {
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, stmt)
+	}
+	return statements, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.index]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.index]
+	if t.kind != tokenEOF {
+		p.index++
+	}
+	return t
+}
+
+func (p *parser) isOperator(value string) bool {
+	t := p.peek()
+	return t.kind == tokenOperator && t.value == value
+}
+
+func (p *parser) isKeyword(value string) bool {
+	t := p.peek()
+	return t.kind == tokenIdent && t.value == value
+}
+
+func (p *parser) expectOperator(value string) (token, error) {
+	t := p.next()
+	if t.kind != tokenOperator || t.value != value {
+		return t, unexpected(t, "`"+value+"`")
+	}
+	return t, nil
+}
+
+func (p *parser) expectIdent() (token, error) {
+	t := p.next()
+	if t.kind != tokenIdent || keywords[t.value] {
+		return t, unexpected(t, "identifier")
+	}
+	return t, nil
+}
+
+func unexpected(t token, expected string) error {
+	if t.kind == tokenEOF {
+		return E.New(t.pos, ": unexpected end of script, expected ", expected)
+	}
+	return E.New(t.pos, ": unexpected ", strconv.Quote(t.value), ", expected ", expected)
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return E.New(p.peek().pos, ": script nested too deeply")
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+// endStatement consumes an optional `;` and checks that the statement is not
+// followed by another token on the same line.
+func (p *parser) endStatement() error {
+	if p.isOperator(";") {
+		p.next()
+		return nil
+	}
+	t := p.peek()
+	if t.newline || t.kind == tokenEOF || t.kind == tokenOperator && t.value == "}" {
+		return nil
+	}
+	return unexpected(t, "end of statement")
+}
+
+func (p *parser) parseStatement() (statement, error) {
+	err := p.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	t := p.peek()
+	switch {
+	case p.isKeyword("let"):
+		p.next()
+		name, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.expectOperator("=")
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &letStatement{pos: t.pos, name: name.value, value: value}, p.endStatement()
+	case p.isKeyword("if"):
+		return p.parseIf()
+	case p.isKeyword("return"):
+		p.next()
+		next := p.peek()
+		if next.newline || next.kind == tokenEOF || next.kind == tokenOperator && (next.value == "}" || next.value == ";") {
+			return &returnStatement{pos: t.pos}, p.endStatement()
+		}
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &returnStatement{pos: t.pos, value: value}, p.endStatement()
+	case t.kind == tokenIdent && !keywords[t.value]:
+		p.next()
+		if !p.isOperator("=") {
+			return nil, unexpected(p.peek(), "`=`")
+		}
+		p.next()
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &assignStatement{pos: t.pos, name: t.value, value: value}, p.endStatement()
+	default:
+		return nil, unexpected(t, "statement")
+	}
+}
+
+func (p *parser) parseIf() (statement, error) {
+	t := p.next()
+	condition, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	then, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &ifStatement{pos: t.pos, condition: condition, then: then}
+	if p.isKeyword("else") {
+		p.next()
+		if p.isKeyword("if") {
+			err = p.enter()
+			if err != nil {
+				return nil, err
+			}
+			elseIf, err := p.parseIf()
+			p.leave()
+			if err != nil {
+				return nil, err
+			}
+			stmt.otherwise = []statement{elseIf}
+		} else {
+			stmt.otherwise, err = p.parseBlock()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return stmt, nil
+}
+
+func (p *parser) parseBlock() ([]statement, error) {
+	_, err := p.expectOperator("{")
+	if err != nil {
+		return nil, err
+	}
+	var statements []statement
+	for !p.isOperator("}") {
+		if p.peek().kind == tokenEOF {
+			return nil, unexpected(p.peek(), "`}`")
+		}
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, stmt)
+	}
+	p.next()
+	return statements, nil
+}
+
+func (p *parser) parseExpression() (expression, error) {
+	err := p.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		t := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpression{pos: t.pos, operator: t.value, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expression, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		t := p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpression{pos: t.pos, operator: t.value, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseComparison() (expression, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	var operator string
+	switch {
+	case t.kind == tokenOperator && (t.value == "==" || t.value == "!=" || t.value == "<" || t.value == "<=" || t.value == ">" || t.value == ">="):
+		operator = t.value
+		p.next()
+	case p.isKeyword("in"):
+		operator = "in"
+		p.next()
+	case p.isKeyword("not"):
+		p.next()
+		if !p.isKeyword("in") {
+			return nil, unexpected(p.peek(), "`in`")
+		}
+		p.next()
+		operator = "not in"
+	default:
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryExpression{pos: t.pos, operator: operator, left: left, right: right}, nil
+}
+
+func (p *parser) parseAdditive() (expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("+") || p.isOperator("-") {
+		t := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpression{pos: t.pos, operator: t.value, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (expression, error) {
+	if p.isOperator("!") || p.isOperator("-") {
+		err := p.enter()
+		if err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		t := p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpression{pos: t.pos, operator: t.value, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (expression, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokenOperator || t.newline {
+			return expr, nil
+		}
+		switch t.value {
+		case ".":
+			p.next()
+			name, err := p.expectIdent()
+			if err != nil {
+				return nil, err
+			}
+			expr = &memberExpression{pos: t.pos, object: expr, name: name.value}
+		case "[":
+			p.next()
+			index, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			_, err = p.expectOperator("]")
+			if err != nil {
+				return nil, err
+			}
+			expr = &indexExpression{pos: t.pos, object: expr, index: index}
+		default:
+			return expr, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (expression, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return &literalExpression{pos: t.pos, value: t.value}, nil
+	case tokenInt:
+		value, err := strconv.ParseInt(t.value, 10, 64)
+		if err != nil {
+			return nil, E.New(t.pos, ": invalid integer ", t.value)
+		}
+		return &literalExpression{pos: t.pos, value: value}, nil
+	case tokenIdent:
+		switch t.value {
+		case "true":
+			return &literalExpression{pos: t.pos, value: true}, nil
+		case "false":
+			return &literalExpression{pos: t.pos, value: false}, nil
+		case "nil":
+			return &literalExpression{pos: t.pos}, nil
+		}
+		if keywords[t.value] {
+			return nil, unexpected(t, "expression")
+		}
+		if p.isOperator("(") && !p.peek().newline {
+			p.next()
+			arguments, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			return &callExpression{pos: t.pos, function: t.value, arguments: arguments}, nil
+		}
+		return &identExpression{pos: t.pos, name: t.value}, nil
+	case tokenOperator:
+		switch t.value {
+		case "(":
+			expr, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			_, err = p.expectOperator(")")
+			if err != nil {
+				return nil, err
+			}
+			return expr, nil
+		case "[":
+			elements, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listExpression{pos: t.pos, elements: elements}, nil
+		}
+	}
+	return nil, unexpected(t, "expression")
+}
+
+func (p *parser) parseList(end string) ([]expression, error) {
+	var elements []expression
+	for !p.isOperator(end) {
+		element, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+		if !p.isOperator(",") {
+			break
+		}
+		p.next()
+	}
+	_, err := p.expectOperator(end)
+	if err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
@@ -0,0 +1,427 @@
+// Package script implements a small sandboxed expression language used for
+// routing decisions.
+//
+// A script is a list of statements:
+//
+//	let name = expression
+//	name = expression
+//	if expression { ... } else if expression { ... } else { ... }
+//	return expression
+//
+// Values are strings, integers, booleans, lists and read-only objects.
+// There are no loops and no access to the host beyond the variables and
+// functions supplied by the caller, so every script terminates.
+package script
+
+import (
+	"reflect"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+const (
+	// MaxSteps limits the number of evaluated nodes per run.
+	MaxSteps = 100000
+	// MaxValueSize limits the length of strings and lists built by a run.
+	MaxValueSize = 1 << 16
+)
+
+type Function func(arguments []any) (any, error)
+
+type Environment struct {
+	Variables map[string]any
+	Functions map[string]Function
+}
+
+type Program struct {
+	source     string
+	statements []statement
+}
+
+func Compile(source string) (*Program, error) {
+	statements, err := parse(source)
+	if err != nil {
+		return nil, E.Cause(err, "compile script")
+	}
+	return &Program{source: source, statements: statements}, nil
+}
+
+func (p *Program) Source() string {
+	return p.source
+}
+
+// Run evaluates the program and returns the value of the first executed
+// return statement, or nil if the program finishes without one.
+func (p *Program) Run(environment Environment) (any, error) {
+	s := &state{
+		environment: environment,
+		scope:       make(map[string]any),
+	}
+	value, _, err := s.executeBlock(p.statements)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+type state struct {
+	environment Environment
+	scope       map[string]any
+	steps       int
+}
+
+func (s *state) step(pos position) error {
+	s.steps++
+	if s.steps > MaxSteps {
+		return E.New(pos, ": step limit exceeded")
+	}
+	return nil
+}
+
+func (s *state) executeBlock(statements []statement) (any, bool, error) {
+	for _, stmt := range statements {
+		value, returned, err := s.execute(stmt)
+		if err != nil || returned {
+			return value, returned, err
+		}
+	}
+	return nil, false, nil
+}
+
+func (s *state) execute(stmt statement) (any, bool, error) {
+	err := s.step(stmt.position())
+	if err != nil {
+		return nil, false, err
+	}
+	switch stmt := stmt.(type) {
+	case *letStatement:
+		if _, loaded := s.environment.Variables[stmt.name]; loaded {
+			return nil, false, E.New(stmt.pos, ": cannot redeclare builtin variable ", stmt.name)
+		}
+		value, err := s.evaluate(stmt.value)
+		if err != nil {
+			return nil, false, err
+		}
+		s.scope[stmt.name] = value
+	case *assignStatement:
+		if _, loaded := s.scope[stmt.name]; !loaded {
+			return nil, false, E.New(stmt.pos, ": assignment to undeclared variable ", stmt.name)
+		}
+		value, err := s.evaluate(stmt.value)
+		if err != nil {
+			return nil, false, err
+		}
+		s.scope[stmt.name] = value
+	case *ifStatement:
+		condition, err := s.evaluate(stmt.condition)
+		if err != nil {
+			return nil, false, err
+		}
+		if truthy(condition) {
+			return s.executeBlock(stmt.then)
+		}
+		return s.executeBlock(stmt.otherwise)
+	case *returnStatement:
+		if stmt.value == nil {
+			return nil, true, nil
+		}
+		value, err := s.evaluate(stmt.value)
+		if err != nil {
+			return nil, false, err
+		}
+		return value, true, nil
+	}
+	return nil, false, nil
+}
+
+func (s *state) evaluate(expr expression) (any, error) {
+	err := s.step(expr.position())
+	if err != nil {
+		return nil, err
+	}
+	switch expr := expr.(type) {
+	case *literalExpression:
+		return expr.value, nil
+	case *identExpression:
+		if value, loaded := s.scope[expr.name]; loaded {
+			return value, nil
+		}
+		if value, loaded := s.environment.Variables[expr.name]; loaded {
+			return value, nil
+		}
+		return nil, E.New(expr.pos, ": undefined variable ", expr.name)
+	case *listExpression:
+		list := make([]any, 0, len(expr.elements))
+		for _, element := range expr.elements {
+			value, err := s.evaluate(element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case *unaryExpression:
+		operand, err := s.evaluate(expr.operand)
+		if err != nil {
+			return nil, err
+		}
+		switch expr.operator {
+		case "!":
+			return !truthy(operand), nil
+		case "-":
+			number, isInt := operand.(int64)
+			if !isInt {
+				return nil, E.New(expr.pos, ": bad operand type for unary -: ", typeName(operand))
+			}
+			return -number, nil
+		}
+	case *binaryExpression:
+		return s.evaluateBinary(expr)
+	case *memberExpression:
+		object, err := s.evaluate(expr.object)
+		if err != nil {
+			return nil, err
+		}
+		fields, isObject := object.(map[string]any)
+		if !isObject {
+			return nil, E.New(expr.pos, ": ", typeName(object), " has no field ", expr.name)
+		}
+		value, loaded := fields[expr.name]
+		if !loaded {
+			return nil, E.New(expr.pos, ": object has no field ", expr.name)
+		}
+		return value, nil
+	case *indexExpression:
+		object, err := s.evaluate(expr.object)
+		if err != nil {
+			return nil, err
+		}
+		index, err := s.evaluate(expr.index)
+		if err != nil {
+			return nil, err
+		}
+		switch object := object.(type) {
+		case []any:
+			position, isInt := index.(int64)
+			if !isInt {
+				return nil, E.New(expr.pos, ": list index must be int, got ", typeName(index))
+			}
+			if position < 0 {
+				position += int64(len(object))
+			}
+			if position < 0 || position >= int64(len(object)) {
+				return nil, E.New(expr.pos, ": list index out of range")
+			}
+			return object[position], nil
+		case map[string]any:
+			key, isString := index.(string)
+			if !isString {
+				return nil, E.New(expr.pos, ": object key must be string, got ", typeName(index))
+			}
+			return object[key], nil
+		default:
+			return nil, E.New(expr.pos, ": ", typeName(object), " is not indexable")
+		}
+	case *callExpression:
+		function, loaded := s.environment.Functions[expr.function]
+		if !loaded {
+			function, loaded = builtinFunctions[expr.function]
+		}
+		if !loaded {
+			return nil, E.New(expr.pos, ": undefined function ", expr.function)
+		}
+		arguments := make([]any, 0, len(expr.arguments))
+		for _, argument := range expr.arguments {
+			value, err := s.evaluate(argument)
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, value)
+		}
+		value, err := function(arguments)
+		if err != nil {
+			return nil, E.Cause(err, expr.pos, ": ", expr.function)
+		}
+		return value, nil
+	}
+	return nil, E.New(expr.position(), ": unsupported expression")
+}
+
+func (s *state) evaluateBinary(expr *binaryExpression) (any, error) {
+	left, err := s.evaluate(expr.left)
+	if err != nil {
+		return nil, err
+	}
+	switch expr.operator {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+		right, err := s.evaluate(expr.right)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+		right, err := s.evaluate(expr.right)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	}
+	right, err := s.evaluate(expr.right)
+	if err != nil {
+		return nil, err
+	}
+	switch expr.operator {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in", "not in":
+		contained, err := contains(right, left)
+		if err != nil {
+			return nil, E.Cause(err, expr.pos)
+		}
+		return contained == (expr.operator == "in"), nil
+	case "+":
+		switch leftValue := left.(type) {
+		case int64:
+			if rightValue, isInt := right.(int64); isInt {
+				return leftValue + rightValue, nil
+			}
+		case string:
+			if rightValue, isString := right.(string); isString {
+				if len(leftValue)+len(rightValue) > MaxValueSize {
+					return nil, E.New(expr.pos, ": value size limit exceeded")
+				}
+				return leftValue + rightValue, nil
+			}
+		case []any:
+			if rightValue, isList := right.([]any); isList {
+				if len(leftValue)+len(rightValue) > MaxValueSize {
+					return nil, E.New(expr.pos, ": value size limit exceeded")
+				}
+				return append(append(make([]any, 0, len(leftValue)+len(rightValue)), leftValue...), rightValue...), nil
+			}
+		}
+	case "-":
+		leftValue, leftIsInt := left.(int64)
+		rightValue, rightIsInt := right.(int64)
+		if leftIsInt && rightIsInt {
+			return leftValue - rightValue, nil
+		}
+	case "<", "<=", ">", ">=":
+		var compare int
+		switch leftValue := left.(type) {
+		case int64:
+			rightValue, isInt := right.(int64)
+			if !isInt {
+				break
+			}
+			switch {
+			case leftValue < rightValue:
+				compare = -1
+			case leftValue > rightValue:
+				compare = 1
+			}
+			return compareResult(expr.operator, compare), nil
+		case string:
+			rightValue, isString := right.(string)
+			if !isString {
+				break
+			}
+			return compareResult(expr.operator, strings.Compare(leftValue, rightValue)), nil
+		}
+	}
+	return nil, E.New(expr.pos, ": unsupported operand types for ", expr.operator, ": ", typeName(left), " and ", typeName(right))
+}
+
+func compareResult(operator string, compare int) bool {
+	switch operator {
+	case "<":
+		return compare < 0
+	case "<=":
+		return compare <= 0
+	case ">":
+		return compare > 0
+	default:
+		return compare >= 0
+	}
+}
+
+func truthy(value any) bool {
+	switch value := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return value
+	case int64:
+		return value != 0
+	case string:
+		return value != ""
+	case []any:
+		return len(value) > 0
+	case map[string]any:
+		return len(value) > 0
+	default:
+		return true
+	}
+}
+
+func equal(left any, right any) bool {
+	return reflect.DeepEqual(left, right)
+}
+
+func contains(container any, element any) (bool, error) {
+	switch container := container.(type) {
+	case nil:
+		return false, nil
+	case string:
+		substring, isString := element.(string)
+		if !isString {
+			return false, E.New("`in <string>` requires string as left operand, got ", typeName(element))
+		}
+		return strings.Contains(container, substring), nil
+	case []any:
+		for _, item := range container {
+			if equal(item, element) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]any:
+		key, isString := element.(string)
+		if !isString {
+			return false, nil
+		}
+		_, loaded := container[key]
+		return loaded, nil
+	default:
+		return false, E.New("argument of type ", typeName(container), " is not a container")
+	}
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "object"
+	default:
+		return F.ToString(reflect.TypeOf(value))
+	}
+}
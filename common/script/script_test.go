@@ -0,0 +1,138 @@
+package script
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testEnvironment() Environment {
+	return Environment{
+		Variables: map[string]any{
+			"metadata": map[string]any{
+				"network":          "tcp",
+				"host":             "www.example.com",
+				"destination_ip":   "10.0.0.1",
+				"destination_port": int64(443),
+			},
+		},
+		Functions: map[string]Function{
+			"rule_set": func(arguments []any) (any, error) {
+				tag, err := StringArgument(arguments, 0)
+				if err != nil {
+					return nil, err
+				}
+				return tag == "geosite-example", nil
+			},
+			"provider": func(arguments []any) (any, error) {
+				return StringList([]string{"hk-01", "jp-01"}), nil
+			},
+		},
+	}
+}
+
+func TestScript(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name   string
+		source string
+		result any
+	}{
+		{"empty", "", nil},
+		{"literal", `return "direct"`, "direct"},
+		{"bare return", "return\nreturn \"proxy\"", nil},
+		{"member", "return metadata.host", "www.example.com"},
+		{"index", `return metadata["network"]`, "tcp"},
+		{"if", `if metadata.destination_port == 443 { return "tls" } else { return "plain" }`, "tls"},
+		{"else if", `
+if metadata.network == "udp" {
+	return "udp"
+} else if domain_suffix(metadata.host, "example.com") {
+	return "example"
+}
+return "final"`, "example"},
+		{"dot suffix", `return domain_suffix("example.com", ".example.com")`, false},
+		{"let and assign", "let out = \"a\"\nif true { out = out + \"b\" }\nreturn out", "ab"},
+		{"logic", `return !false && (1 < 2 || undefined_function())`, true},
+		{"in list", `return "jp-01" in provider("airport")`, true},
+		{"not in", `return "us-01" not in provider("airport")`, true},
+		{"list index", `return provider("airport")[-1]`, "jp-01"},
+		{"in string", `return "example" in metadata.host`, true},
+		{"cidr", `return ip_in_cidr(metadata.destination_ip, "192.168.0.0/16", "10.0.0.0/8")`, true},
+		{"rule set", `if rule_set("geosite-example") { return "example" }`, "example"},
+		{"builtins", `return upper(lower("A")) + string(len([1, 2, 3])) + string(int("4") - 1)`, "A33"},
+		{"regex", `return match("^www\\.", metadata.host)`, true},
+		{"comment", "# comment\nreturn 1 // trailing", int64(1)},
+		{"semicolon", `let a = 1; let b = 2; return a + b`, int64(3)},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			program, err := Compile(testCase.source)
+			require.NoError(t, err)
+			result, err := program.Run(testEnvironment())
+			require.NoError(t, err)
+			require.Equal(t, testCase.result, result)
+		})
+	}
+}
+
+func TestScriptCompileError(t *testing.T) {
+	t.Parallel()
+	for _, source := range []string{
+		`return "unterminated`,
+		`if true return "a"`,
+		`let = 1`,
+		`return 1 2`,
+		`metadata.host`,
+		`return (1`,
+		`if true { return 1`,
+		`return @`,
+		strings.Repeat("(", maxDepth+1) + "1" + strings.Repeat(")", maxDepth+1),
+	} {
+		_, err := Compile(source)
+		require.Error(t, err, source)
+	}
+}
+
+func TestScriptRuntimeError(t *testing.T) {
+	t.Parallel()
+	for _, source := range []string{
+		`return unknown`,
+		`return unknown_function()`,
+		`return metadata.unknown`,
+		`return 1 + "a"`,
+		`return [1][1]`,
+		`let metadata = 1`,
+		`value = 1`,
+		`return has_prefix(1, "a")`,
+		`return ip_in_cidr("10.0.0.1", "invalid")`,
+	} {
+		program, err := Compile(source)
+		require.NoError(t, err, source)
+		_, err = program.Run(testEnvironment())
+		require.Error(t, err, source)
+	}
+}
+
+func TestScriptStepLimit(t *testing.T) {
+	t.Parallel()
+	program, err := Compile("let a = 1\n" + strings.Repeat("a = a + 1\n", MaxSteps))
+	require.NoError(t, err)
+	_, err = program.Run(Environment{})
+	require.ErrorContains(t, err, "step limit exceeded")
+}
+
+func TestScriptValueSizeLimit(t *testing.T) {
+	t.Parallel()
+	for _, source := range []string{
+		"let a = \"a\"\n" + strings.Repeat("a = a + a\n", 40),
+		"let a = [1]\n" + strings.Repeat("a = a + a\n", 40),
+	} {
+		program, err := Compile(source)
+		require.NoError(t, err)
+		_, err = program.Run(Environment{})
+		require.ErrorContains(t, err, "value size limit exceeded")
+	}
+}
@@ -24,6 +24,9 @@ func HTTPHost(_ context.Context, metadata *adapter.InboundContext, reader io.Rea
 		}
 	}
 	metadata.Protocol = C.ProtocolHTTP
+	metadata.HTTPMethod = request.Method
+	metadata.HTTPPath = request.URL.Path
+	metadata.HTTPUserAgent = request.UserAgent()
 	host := M.ParseSocksaddr(request.Host).AddrString()
 	if _, err = netip.ParseAddr(host); err != nil {
 		metadata.SniffHost = host
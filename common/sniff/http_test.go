@@ -28,3 +28,14 @@ func TestSniffHTTP1WithPort(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, metadata.SniffHost, "www.gov.cn")
 }
+
+func TestSniffHTTP1Request(t *testing.T) {
+	t.Parallel()
+	pkt := "POST http://deb.debian.org/debian/dists/stable/InRelease?x=1 HTTP/1.1\r\nHost: deb.debian.org\r\nUser-Agent: Debian APT-HTTP/1.3 (2.6.1)\r\n\r\n"
+	var metadata adapter.InboundContext
+	err := sniff.HTTPHost(context.Background(), &metadata, strings.NewReader(pkt))
+	require.NoError(t, err)
+	require.Equal(t, "POST", metadata.HTTPMethod)
+	require.Equal(t, "/debian/dists/stable/InRelease", metadata.HTTPPath)
+	require.Equal(t, "Debian APT-HTTP/1.3 (2.6.1)", metadata.HTTPUserAgent)
+}
@@ -310,6 +310,8 @@ find:
 	if _, err = netip.ParseAddr(fingerprint.ServerName); err != nil {
 		metadata.SniffHost = fingerprint.ServerName
 	}
+	metadata.JA3 = fingerprint.Hash()
+	metadata.JA4 = fingerprint.JA4(ja3.JA4ProtocolQUIC)
 	for metadata.Client == "" {
 		if len(frameTypeList) == 1 {
 			metadata.Client = C.ClientFirefox
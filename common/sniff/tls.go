@@ -1,6 +1,7 @@
 package sniff
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"errors"
@@ -8,14 +9,18 @@ import (
 	"net/netip"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ja3"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing/common/bufio"
 	E "github.com/sagernet/sing/common/exceptions"
 )
 
 func TLSClientHello(ctx context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
-	var clientHello *tls.ClientHelloInfo
-	err := tls.Server(bufio.NewReadOnlyConn(reader), &tls.Config{
+	var (
+		clientHello *tls.ClientHelloInfo
+		payload     bytes.Buffer
+	)
+	err := tls.Server(bufio.NewReadOnlyConn(io.TeeReader(reader, &payload)), &tls.Config{
 		GetConfigForClient: func(argHello *tls.ClientHelloInfo) (*tls.Config, error) {
 			clientHello = argHello
 			return nil, nil
@@ -26,6 +31,11 @@ func TLSClientHello(ctx context.Context, metadata *adapter.InboundContext, reade
 		if _, err = netip.ParseAddr(clientHello.ServerName); err != nil {
 			metadata.SniffHost = clientHello.ServerName
 		}
+		// ClientHello messages split into multiple records are not fingerprinted
+		if fingerprint, fingerprintErr := ja3.Compute(payload.Bytes()); fingerprintErr == nil {
+			metadata.JA3 = fingerprint.Hash()
+			metadata.JA4 = fingerprint.JA4(ja3.JA4ProtocolTCP)
+		}
 		return nil
 	}
 	if errors.Is(err, io.ErrUnexpectedEOF) {
@@ -47,6 +47,7 @@ const (
 	ruleItemNetworkInterfaceAddress
 	ruleItemDefaultInterfaceAddress
 	ruleItemPackageNameRegex
+	ruleItemTLSFingerprint
 	ruleItemFinal uint8 = 0xFF
 )
 
@@ -218,6 +219,8 @@ func readDefaultRule(reader varbin.Reader, recover bool) (rule option.DefaultHea
 			rule.PackageName, err = readRuleItemString(reader)
 		case ruleItemPackageNameRegex:
 			rule.PackageNameRegex, err = readRuleItemString(reader)
+		case ruleItemTLSFingerprint:
+			rule.TLSFingerprint, err = readRuleItemString(reader)
 		case ruleItemWIFISSID:
 			rule.WIFISSID, err = readRuleItemString(reader)
 		case ruleItemWIFIBSSID:
@@ -406,6 +409,15 @@ func writeDefaultRule(writer varbin.Writer, rule option.DefaultHeadlessRule, gen
 			return err
 		}
 	}
+	if len(rule.TLSFingerprint) > 0 {
+		if generateVersion < C.RuleSetVersion6 {
+			return E.New("`tls_fingerprint` rule item is only supported in version 6 or later")
+		}
+		err = writeRuleItemString(writer, ruleItemTLSFingerprint, rule.TLSFingerprint)
+		if err != nil {
+			return err
+		}
+	}
 	if len(rule.NetworkType) > 0 {
 		if generateVersion < C.RuleSetVersion3 {
 			return E.New("`network_type` rule item is only supported in version 3 or later")
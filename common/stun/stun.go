@@ -104,6 +104,8 @@ type Options struct {
 	Dialer     N.Dialer
 	Context    context.Context
 	OnProgress func(Progress)
+	// BindingOnly skips NAT type detection after the binding request.
+	BindingOnly bool
 }
 
 type Progress struct {
@@ -473,7 +475,7 @@ func Run(options Options) (*Result, error) {
 	})
 
 	otherAddr := resp.otherAddr
-	if !otherAddr.IsValid() {
+	if options.BindingOnly || !otherAddr.IsValid() {
 		result.NATTypeSupported = false
 		reportProgress(Progress{
 			Phase:        PhaseDone,
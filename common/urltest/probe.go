@@ -0,0 +1,241 @@
+package urltest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/sagernet/sing-box/common/stun"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const maxProbeBodySize = 64 * 1024
+
+// Probe measures the delay of an outbound with a configurable method.
+// A nil Probe issues the default HTTP request.
+type Probe struct {
+	probeType      string
+	server         M.Socksaddr
+	domain         string
+	expectedStatus []int
+	expectedBody   *regexp.Regexp
+}
+
+func NewProbe(options *option.ProbeOptions) (*Probe, error) {
+	if options == nil {
+		return nil, nil
+	}
+	probe := &Probe{
+		probeType:      options.Type,
+		domain:         options.Domain,
+		expectedStatus: options.ExpectedStatus,
+	}
+	if probe.probeType == "" {
+		probe.probeType = C.ProbeTypeHTTP
+	}
+	var defaultPort uint16
+	server := options.Server
+	switch probe.probeType {
+	case C.ProbeTypeHTTP:
+		if server != "" {
+			return nil, E.New("server is not supported by http probe, use url instead")
+		}
+		if options.ExpectedBody != "" {
+			expectedBody, err := regexp.Compile(options.ExpectedBody)
+			if err != nil {
+				return nil, E.Cause(err, "parse expected_body")
+			}
+			probe.expectedBody = expectedBody
+		}
+	case C.ProbeTypeTCP:
+		defaultPort = 80
+	case C.ProbeTypeTLS:
+		defaultPort = 443
+	case C.ProbeTypeDNS:
+		defaultPort = 53
+		if server == "" {
+			server = "1.1.1.1"
+		}
+	case C.ProbeTypeSTUN:
+		defaultPort = 3478
+		if server == "" {
+			server = stun.DefaultServer
+		}
+	default:
+		return nil, E.New("unknown probe type: ", probe.probeType)
+	}
+	if probe.probeType != C.ProbeTypeHTTP && (len(options.ExpectedStatus) > 0 || options.ExpectedBody != "") {
+		return nil, E.New("expected_status and expected_body are only supported by http probe")
+	}
+	if options.Domain != "" && probe.probeType != C.ProbeTypeDNS {
+		return nil, E.New("domain is only supported by dns probe")
+	}
+	if server != "" {
+		probe.server = M.ParseSocksaddr(server)
+		if !probe.server.IsValid() {
+			return nil, E.New("invalid probe server: ", server)
+		}
+		if probe.server.Port == 0 {
+			probe.server.Port = defaultPort
+		}
+	}
+	return probe, nil
+}
+
+// Test returns the delay of detour in milliseconds. link is the URL of the
+// group or provider, which is also used as the target of tcp and tls probes
+// and the domain of dns probes when not configured.
+func (p *Probe) Test(ctx context.Context, link string, detour N.Dialer) (uint16, error) {
+	if p == nil {
+		return URLTest(ctx, link, detour)
+	}
+	switch p.probeType {
+	case C.ProbeTypeTCP:
+		return p.testConnect(ctx, link, detour, false)
+	case C.ProbeTypeTLS:
+		return p.testConnect(ctx, link, detour, true)
+	case C.ProbeTypeDNS:
+		return p.testDNS(ctx, link, detour)
+	case C.ProbeTypeSTUN:
+		return p.testSTUN(ctx, detour)
+	default:
+		return urlTest(ctx, link, detour, p)
+	}
+}
+
+func (p *Probe) checkResponse(resp *http.Response) error {
+	defer resp.Body.Close()
+	if p == nil {
+		return nil
+	}
+	if len(p.expectedStatus) > 0 && !slices.Contains(p.expectedStatus, resp.StatusCode) {
+		return E.New("unexpected status: ", resp.Status)
+	}
+	if p.expectedBody != nil {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBodySize))
+		if err != nil {
+			return E.Cause(err, "read body")
+		}
+		if !p.expectedBody.Match(body) {
+			return E.New("unexpected body")
+		}
+	}
+	return nil
+}
+
+func (p *Probe) testConnect(ctx context.Context, link string, detour N.Dialer, handshakeTLS bool) (t uint16, err error) {
+	destination := p.server
+	if !destination.IsValid() {
+		if link == "" {
+			link = "https://www.gstatic.com/generate_204"
+		}
+		destination, err = linkDestination(link)
+		if err != nil {
+			return
+		}
+	}
+	start := time.Now()
+	conn, err := detour.DialContext(ctx, N.NetworkTCP, destination)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if deadline, loaded := ctx.Deadline(); loaded {
+		conn.SetDeadline(deadline)
+	}
+	if handshakeTLS {
+		var tlsConfig tls.Config
+		tlsConfig, err = tls.NewClient(ctx, logger.NOP(), destination.AddrString(), option.OutboundTLSOptions{
+			Enabled: true,
+		})
+		if err != nil {
+			return
+		}
+		_, err = tls.ClientHandshake(ctx, conn, tlsConfig)
+	} else if N.NeedHandshakeForWrite(conn) {
+		// lazy outbounds only connect on the first write
+		_, err = conn.Write(nil)
+	}
+	if err != nil {
+		return
+	}
+	t = uint16(time.Since(start) / time.Millisecond)
+	return
+}
+
+func (p *Probe) testDNS(ctx context.Context, link string, detour N.Dialer) (t uint16, err error) {
+	domain := p.domain
+	if domain == "" {
+		if link == "" {
+			link = "https://www.gstatic.com/generate_204"
+		}
+		var linkURL *url.URL
+		linkURL, err = url.Parse(link)
+		if err != nil {
+			return
+		}
+		domain = linkURL.Hostname()
+	}
+	message := new(mDNS.Msg)
+	message.SetQuestion(mDNS.Fqdn(domain), mDNS.TypeA)
+	request, err := message.Pack()
+	if err != nil {
+		return
+	}
+	start := time.Now()
+	conn, err := detour.DialContext(ctx, N.NetworkUDP, p.server)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if deadline, loaded := ctx.Deadline(); loaded {
+		conn.SetDeadline(deadline)
+	}
+	_, err = conn.Write(request)
+	if err != nil {
+		return
+	}
+	buffer := make([]byte, mDNS.MaxMsgSize)
+	var response mDNS.Msg
+	for {
+		var n int
+		n, err = conn.Read(buffer)
+		if err != nil {
+			return
+		}
+		if response.Unpack(buffer[:n]) == nil && response.Id == message.Id {
+			break
+		}
+	}
+	if response.Rcode != mDNS.RcodeSuccess {
+		err = E.New("unexpected rcode: ", mDNS.RcodeToString[response.Rcode])
+		return
+	}
+	t = uint16(time.Since(start) / time.Millisecond)
+	return
+}
+
+func (p *Probe) testSTUN(ctx context.Context, detour N.Dialer) (uint16, error) {
+	result, err := stun.Run(stun.Options{
+		Server:      p.server.String(),
+		Dialer:      detour,
+		Context:     ctx,
+		BindingOnly: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return uint16(result.LatencyMs), nil
+}
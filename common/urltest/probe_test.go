@@ -0,0 +1,175 @@
+package urltest
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProbe(t *testing.T) {
+	t.Parallel()
+	probe, err := NewProbe(nil)
+	require.NoError(t, err)
+	require.Nil(t, probe)
+
+	probe, err = NewProbe(&option.ProbeOptions{Type: "dns"})
+	require.NoError(t, err)
+	require.Equal(t, "1.1.1.1:53", probe.server.String())
+
+	probe, err = NewProbe(&option.ProbeOptions{Type: "tls", Server: "example.com"})
+	require.NoError(t, err)
+	require.Equal(t, "example.com:443", probe.server.String())
+
+	for _, options := range []option.ProbeOptions{
+		{Type: "icmp"},
+		{Type: "tcp", ExpectedStatus: []int{204}},
+		{Type: "http", Server: "example.com:80"},
+		{Type: "tcp", Domain: "example.com"},
+		{ExpectedBody: "("},
+	} {
+		_, err = NewProbe(&options)
+		require.Error(t, err, options)
+	}
+}
+
+func TestProbeHTTP(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write([]byte("status: ok"))
+		}
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, testCase := range []struct {
+		options option.ProbeOptions
+		success bool
+	}{
+		{option.ProbeOptions{}, true},
+		{option.ProbeOptions{ExpectedStatus: []int{200, 204}}, true},
+		{option.ProbeOptions{ExpectedStatus: []int{204}}, false},
+		{option.ProbeOptions{ExpectedBody: "^status: ok$"}, true},
+		{option.ProbeOptions{ExpectedBody: "failed"}, false},
+	} {
+		probe, err := NewProbe(&testCase.options)
+		require.NoError(t, err)
+		_, err = probe.Test(ctx, server.URL, N.SystemDialer)
+		if testCase.success {
+			require.NoError(t, err, testCase.options)
+		} else {
+			require.Error(t, err, testCase.options)
+		}
+	}
+}
+
+func TestProbeTCP(t *testing.T) {
+	t.Parallel()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, acceptErr := listener.Accept()
+			if acceptErr != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	probe, err := NewProbe(&option.ProbeOptions{Type: "tcp", Server: listener.Addr().String()})
+	require.NoError(t, err)
+	_, err = probe.Test(ctx, "", N.SystemDialer)
+	require.NoError(t, err)
+
+	// the target defaults to the host of the URL
+	probe, err = NewProbe(&option.ProbeOptions{Type: "tcp"})
+	require.NoError(t, err)
+	_, err = probe.Test(ctx, "http://"+listener.Addr().String()+"/", N.SystemDialer)
+	require.NoError(t, err)
+}
+
+type testCertificateStore struct {
+	pool *x509.CertPool
+}
+
+func (s *testCertificateStore) Name() string                         { return "test" }
+func (s *testCertificateStore) Start(stage adapter.StartStage) error { return nil }
+func (s *testCertificateStore) Close() error                         { return nil }
+func (s *testCertificateStore) Pool() *x509.CertPool                 { return s.pool }
+func (s *testCertificateStore) ExclusiveAnchors() bool               { return true }
+
+func TestProbeTLS(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	probe, err := NewProbe(&option.ProbeOptions{Type: "tls", Server: server.Listener.Addr().String()})
+	require.NoError(t, err)
+	_, err = probe.Test(ctx, "", N.SystemDialer)
+	require.Error(t, err)
+
+	ctx = service.ContextWith[adapter.CertificateStore](ctx, &testCertificateStore{pool: pool})
+	_, err = probe.Test(ctx, "", N.SystemDialer)
+	require.NoError(t, err)
+}
+
+func TestProbeDNS(t *testing.T) {
+	t.Parallel()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, mDNS.MaxMsgSize)
+		for {
+			n, addr, readErr := conn.ReadFrom(buffer)
+			if readErr != nil {
+				return
+			}
+			var request mDNS.Msg
+			if request.Unpack(buffer[:n]) != nil {
+				continue
+			}
+			response := new(mDNS.Msg)
+			response.SetReply(&request)
+			if request.Question[0].Name != "example.com." {
+				response.Rcode = mDNS.RcodeServerFailure
+			}
+			packed, _ := response.Pack()
+			conn.WriteTo(packed, addr)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	probe, err := NewProbe(&option.ProbeOptions{Type: "dns", Server: conn.LocalAddr().String()})
+	require.NoError(t, err)
+	_, err = probe.Test(ctx, "https://example.com/", N.SystemDialer)
+	require.NoError(t, err)
+
+	probe, err = NewProbe(&option.ProbeOptions{Type: "dns", Server: conn.LocalAddr().String(), Domain: "example.org"})
+	require.NoError(t, err)
+	_, err = probe.Test(ctx, "", N.SystemDialer)
+	require.ErrorContains(t, err, "SERVFAIL")
+}
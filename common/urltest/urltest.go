@@ -19,15 +19,27 @@ import (
 
 var _ adapter.URLTestHistoryStorage = (*HistoryStorage)(nil)
 
+// historySaveDelay batches cache writes, since every failed dial of a group
+// member counts as a failed test.
+const historySaveDelay = 10 * time.Second
+
 type HistoryStorage struct {
 	access       sync.RWMutex
 	delayHistory map[string]*adapter.URLTestHistory
+	failures     map[string]uint32
+	cache        adapter.URLTestHistoryCache
 	updateHook   *observable.Subscriber[struct{}]
+
+	// pending holds histories to save, nil for deletion, until saveTimer fires.
+	pending   map[string]*adapter.SavedURLTestHistory
+	saveTimer *time.Timer
 }
 
 func NewHistoryStorage() *HistoryStorage {
 	return &HistoryStorage{
 		delayHistory: make(map[string]*adapter.URLTestHistory),
+		failures:     make(map[string]uint32),
+		pending:      make(map[string]*adapter.SavedURLTestHistory),
 	}
 }
 
@@ -35,6 +47,59 @@ func (s *HistoryStorage) SetHook(hook *observable.Subscriber[struct{}]) {
 	s.updateHook = hook
 }
 
+// Restore loads histories tested within timeout from cache, and writes
+// later updates through to it.
+func (s *HistoryStorage) Restore(cache adapter.URLTestHistoryCache, timeout time.Duration) {
+	var expired []string
+	s.access.Lock()
+	for tag, history := range cache.LoadURLTestHistories() {
+		if time.Since(history.Time) > timeout {
+			expired = append(expired, tag)
+			continue
+		}
+		if history.Delay > 0 && s.delayHistory[tag] == nil {
+			s.delayHistory[tag] = &adapter.URLTestHistory{
+				Time:  history.Time,
+				Delay: history.Delay,
+			}
+		}
+		if history.Failures > 0 {
+			s.failures[tag] = history.Failures
+		}
+	}
+	s.cache = cache
+	s.notifyUpdated()
+	s.access.Unlock()
+	for _, tag := range expired {
+		cache.DeleteURLTestHistory(tag)
+	}
+}
+
+// Prune drops histories of outbounds that no longer exist.
+func (s *HistoryStorage) Prune(exists func(tag string) bool) {
+	removed := make(map[string]bool)
+	s.access.Lock()
+	for tag := range s.delayHistory {
+		if !exists(tag) {
+			removed[tag] = true
+		}
+	}
+	for tag := range s.failures {
+		if !exists(tag) {
+			removed[tag] = true
+		}
+	}
+	for tag := range removed {
+		delete(s.delayHistory, tag)
+		delete(s.failures, tag)
+		s.saveLater(tag, nil)
+	}
+	if len(removed) > 0 {
+		s.notifyUpdated()
+	}
+	s.access.Unlock()
+}
+
 func (s *HistoryStorage) LoadURLTestHistory(tag string) *adapter.URLTestHistory {
 	if s == nil {
 		return nil
@@ -44,9 +109,26 @@ func (s *HistoryStorage) LoadURLTestHistory(tag string) *adapter.URLTestHistory
 	return s.delayHistory[tag]
 }
 
+// LoadURLTestFailures returns the number of failed tests since the last
+// successful one.
+func (s *HistoryStorage) LoadURLTestFailures(tag string) uint32 {
+	if s == nil {
+		return 0
+	}
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return s.failures[tag]
+}
+
+// DeleteURLTestHistory marks the outbound as unavailable after a failed test.
 func (s *HistoryStorage) DeleteURLTestHistory(tag string) {
 	s.access.Lock()
 	delete(s.delayHistory, tag)
+	s.failures[tag]++
+	s.saveLater(tag, &adapter.SavedURLTestHistory{
+		Time:     time.Now(),
+		Failures: s.failures[tag],
+	})
 	s.notifyUpdated()
 	s.access.Unlock()
 }
@@ -54,10 +136,45 @@ func (s *HistoryStorage) DeleteURLTestHistory(tag string) {
 func (s *HistoryStorage) StoreURLTestHistory(tag string, history *adapter.URLTestHistory) {
 	s.access.Lock()
 	s.delayHistory[tag] = history
+	delete(s.failures, tag)
+	s.saveLater(tag, &adapter.SavedURLTestHistory{
+		Time:  history.Time,
+		Delay: history.Delay,
+	})
 	s.notifyUpdated()
 	s.access.Unlock()
 }
 
+// saveLater queues a cache write, and must be called with access held.
+func (s *HistoryStorage) saveLater(tag string, history *adapter.SavedURLTestHistory) {
+	if s.cache == nil {
+		return
+	}
+	s.pending[tag] = history
+	if s.saveTimer == nil {
+		s.saveTimer = time.AfterFunc(historySaveDelay, s.save)
+	}
+}
+
+func (s *HistoryStorage) save() {
+	s.access.Lock()
+	cache := s.cache
+	pending := s.pending
+	s.pending = make(map[string]*adapter.SavedURLTestHistory)
+	s.saveTimer = nil
+	s.access.Unlock()
+	if cache == nil {
+		return
+	}
+	for tag, history := range pending {
+		if history == nil {
+			cache.DeleteURLTestHistory(tag)
+		} else {
+			cache.SaveURLTestHistory(tag, history)
+		}
+	}
+}
+
 func (s *HistoryStorage) notifyUpdated() {
 	updateHook := s.updateHook
 	if updateHook != nil {
@@ -65,34 +182,36 @@ func (s *HistoryStorage) notifyUpdated() {
 	}
 }
 
+// Close saves pending histories and detaches the cache.
 func (s *HistoryStorage) Close() error {
+	s.access.Lock()
+	if s.saveTimer != nil {
+		s.saveTimer.Stop()
+	}
+	s.access.Unlock()
+	s.save()
 	s.access.Lock()
 	defer s.access.Unlock()
 	s.updateHook = nil
+	s.cache = nil
 	return nil
 }
 
 func URLTest(ctx context.Context, link string, detour N.Dialer) (t uint16, err error) {
+	return urlTest(ctx, link, detour, nil)
+}
+
+func urlTest(ctx context.Context, link string, detour N.Dialer, probe *Probe) (t uint16, err error) {
 	if link == "" {
 		link = "https://www.gstatic.com/generate_204"
 	}
-	linkURL, err := url.Parse(link)
+	destination, err := linkDestination(link)
 	if err != nil {
 		return
 	}
-	hostname := linkURL.Hostname()
-	port := linkURL.Port()
-	if port == "" {
-		switch linkURL.Scheme {
-		case "http":
-			port = "80"
-		case "https":
-			port = "443"
-		}
-	}
 
 	start := time.Now()
-	instance, err := detour.DialContext(ctx, "tcp", M.ParseSocksaddrHostPortStr(hostname, port))
+	instance, err := detour.DialContext(ctx, "tcp", destination)
 	if err != nil {
 		return
 	}
@@ -100,7 +219,11 @@ func URLTest(ctx context.Context, link string, detour N.Dialer) (t uint16, err e
 	if N.NeedHandshakeForWrite(instance) {
 		start = time.Now()
 	}
-	req, err := http.NewRequest(http.MethodHead, link, nil)
+	method := http.MethodHead
+	if probe != nil && probe.expectedBody != nil {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, link, nil)
 	if err != nil {
 		return
 	}
@@ -124,18 +247,37 @@ func URLTest(ctx context.Context, link string, detour N.Dialer) (t uint16, err e
 	if err != nil {
 		return
 	}
-	resp.Body.Close()
+	err = probe.checkResponse(resp)
+	if err != nil {
+		return
+	}
 	if C.URLTestUnifiedDelay {
 		second := time.Now()
 		var ignoredErr error
 		var secondResp *http.Response
 		secondResp, ignoredErr = client.Do(req.WithContext(ctx))
-		if ignoredErr == nil {
-			resp = secondResp
-			resp.Body.Close()
+		if ignoredErr == nil && probe.checkResponse(secondResp) == nil {
 			start = second
 		}
 	}
 	t = uint16(time.Since(start) / time.Millisecond)
 	return
 }
+
+func linkDestination(link string) (M.Socksaddr, error) {
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return M.Socksaddr{}, err
+	}
+	hostname := linkURL.Hostname()
+	port := linkURL.Port()
+	if port == "" {
+		switch linkURL.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+	return M.ParseSocksaddrHostPortStr(hostname, port), nil
+}
@@ -0,0 +1,84 @@
+package urltest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testHistoryCache map[string]*adapter.SavedURLTestHistory
+
+func (c testHistoryCache) LoadURLTestHistories() map[string]*adapter.SavedURLTestHistory {
+	histories := make(map[string]*adapter.SavedURLTestHistory, len(c))
+	for tag, history := range c {
+		histories[tag] = history
+	}
+	return histories
+}
+
+func (c testHistoryCache) SaveURLTestHistory(tag string, history *adapter.SavedURLTestHistory) error {
+	c[tag] = history
+	return nil
+}
+
+func (c testHistoryCache) DeleteURLTestHistory(tag string) error {
+	delete(c, tag)
+	return nil
+}
+
+func TestHistoryStorageRestore(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(time.Now().Unix(), 0)
+	cache := testHistoryCache{
+		"fresh":   {Time: now, Delay: 100},
+		"failing": {Time: now, Failures: 2},
+		"expired": {Time: now.Add(-2 * time.Hour), Delay: 50},
+		"removed": {Time: now, Delay: 200},
+	}
+	storage := NewHistoryStorage()
+	storage.Restore(cache, time.Hour)
+	require.Equal(t, &adapter.URLTestHistory{Time: now, Delay: 100}, storage.LoadURLTestHistory("fresh"))
+	require.Nil(t, storage.LoadURLTestHistory("failing"))
+	require.Equal(t, uint32(2), storage.LoadURLTestFailures("failing"))
+	require.Nil(t, storage.LoadURLTestHistory("expired"))
+	require.NotContains(t, cache, "expired")
+
+	storage.DeleteURLTestHistory("failing")
+	require.Equal(t, uint32(3), storage.LoadURLTestFailures("failing"))
+	require.Equal(t, uint32(2), cache["failing"].Failures)
+	storage.save()
+	require.Equal(t, uint32(3), cache["failing"].Failures)
+	storage.StoreURLTestHistory("failing", &adapter.URLTestHistory{Time: now, Delay: 300})
+	require.Equal(t, uint32(0), storage.LoadURLTestFailures("failing"))
+	storage.save()
+	require.Equal(t, &adapter.SavedURLTestHistory{Time: now, Delay: 300}, cache["failing"])
+
+	storage.Prune(func(tag string) bool {
+		return tag != "removed"
+	})
+	require.Nil(t, storage.LoadURLTestHistory("removed"))
+	require.Contains(t, cache, "removed")
+	require.NoError(t, storage.Close())
+	require.NotContains(t, cache, "removed")
+	require.Contains(t, cache, "fresh")
+	storage.DeleteURLTestHistory("fresh")
+	storage.save()
+	require.Equal(t, uint32(0), cache["fresh"].Failures)
+}
+
+func TestSavedURLTestHistoryBinary(t *testing.T) {
+	t.Parallel()
+	history := &adapter.SavedURLTestHistory{
+		Time:     time.Unix(1700000000, 0),
+		Delay:    123,
+		Failures: 300,
+	}
+	content, err := history.MarshalBinary()
+	require.NoError(t, err)
+	var decoded adapter.SavedURLTestHistory
+	require.NoError(t, decoded.UnmarshalBinary(content))
+	require.Equal(t, *history, decoded)
+}
@@ -29,6 +29,8 @@ const (
 	DNSTypeMDNS      = "mdns"
 	DNSTypeTailscale = "tailscale"
 	DNSTypeGroup     = "group"
+	DNSTypeDNSCrypt  = "dnscrypt"
+	DNSTypeODoH      = "odoh"
 )
 
 const (
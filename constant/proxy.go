@@ -31,6 +31,7 @@ const (
 	TypeDERP               = "derp"
 	TypeResolved           = "resolved"
 	TypeSSMAPI             = "ssm-api"
+	TypeQuota              = "quota"
 	TypeCCM                = "ccm"
 	TypeOCM                = "ocm"
 	TypeOOMKiller          = "oom-killer"
@@ -115,7 +116,23 @@ func ProxyDisplayName(proxyType string) string {
 	}
 }
 
+const (
+	ProbeTypeHTTP = "http"
+	ProbeTypeTCP  = "tcp"
+	ProbeTypeTLS  = "tls"
+	ProbeTypeDNS  = "dns"
+	ProbeTypeSTUN = "stun"
+)
+
 const (
 	ProviderTypeLocal  = "local"
 	ProviderTypeRemote = "remote"
 )
+
+const (
+	ProviderFormatSingBox = "sing-box"
+	ProviderFormatClash   = "clash"
+	ProviderFormatSIP008  = "sip008"
+	ProviderFormatSurge   = "surge"
+	ProviderFormatURI     = "uri"
+)
@@ -18,13 +18,22 @@ const (
 	RuleSetFormatBinary = "binary"
 )
 
+const (
+	RuleSetFormatAdGuard = "adguard"
+	RuleSetFormatClash   = "clash"
+	RuleSetFormatSurge   = "surge"
+	RuleSetFormatDnsmasq = "dnsmasq"
+	RuleSetFormatHosts   = "hosts"
+)
+
 const (
 	RuleSetVersion1 = 1 + iota
 	RuleSetVersion2
 	RuleSetVersion3
 	RuleSetVersion4
 	RuleSetVersion5
-	RuleSetVersionCurrent = RuleSetVersion5
+	RuleSetVersion6
+	RuleSetVersionCurrent = RuleSetVersion6
 )
 
 const (
@@ -39,6 +48,7 @@ const (
 	RuleActionTypeSniff        = "sniff"
 	RuleActionTypeResolve      = "resolve"
 	RuleActionTypePredefined   = "predefined"
+	RuleActionTypeScript       = "script"
 )
 
 const (
@@ -47,3 +57,10 @@ const (
 	RuleActionRejectMethodReply   = "reply"
 	RuleActionRejectMethodNullIP  = "null-ip"
 )
+
+const (
+	BandwidthLimitScopeConnection = "connection"
+	BandwidthLimitScopeUser       = "user"
+	BandwidthLimitScopeInbound    = "inbound"
+	BandwidthLimitScopeRule       = "rule"
+)
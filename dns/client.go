@@ -120,6 +120,9 @@ type Client struct {
 	cacheMaxTTL       uint32
 	cacheRoundRobin   bool
 	clientSubnet      netip.Prefix
+	dnssec            bool
+	dnssecAnchors     []*dns.DS
+	dnssecCache       freelru.Cache[dnssecCacheKey, *dnssecZone]
 	rdrc              adapter.RDRCStore
 	initRDRCFunc      func() adapter.RDRCStore
 	dnsCache          adapter.DNSCacheStore
@@ -141,6 +144,7 @@ type ClientOptions struct {
 	CacheMaxTTL       uint32
 	CacheRoundRobin   bool
 	ClientSubnet      netip.Prefix
+	DNSSEC            bool
 	RDRC              func() adapter.RDRCStore
 	DNSCache          func() adapter.DNSCacheStore
 	Logger            logger.ContextLogger
@@ -159,6 +163,7 @@ func NewClient(options ClientOptions) *Client {
 		cacheMaxTTL:       options.CacheMaxTTL,
 		cacheRoundRobin:   options.CacheRoundRobin,
 		clientSubnet:      options.ClientSubnet,
+		dnssec:            options.DNSSEC,
 		initRDRCFunc:      options.RDRC,
 		initDNSCacheFunc:  options.DNSCache,
 		logger:            options.Logger,
@@ -172,6 +177,10 @@ func NewClient(options ClientOptions) *Client {
 	if !client.disableCache && client.initDNSCacheFunc == nil {
 		client.initializeMemoryCache()
 	}
+	if client.dnssec {
+		client.dnssecAnchors = RootTrustAnchors
+		client.dnssecCache = common.Must1(freelru.NewSharded[dnssecCacheKey, *dnssecZone](cacheCapacity, maphash.NewHasher[dnssecCacheKey]().Hash32))
+	}
 	return client
 }
 
@@ -281,6 +290,10 @@ func applyMinMaxTTL(response *dns.Msg, minTTL uint32, maxTTL uint32) {
 }
 
 func addMsgStaleAnswerOpt(msg *dns.Msg) {
+	addMsgExtendedErrorOpt(msg, dns.ExtendedErrorCodeStaleAnswer, "")
+}
+
+func addMsgExtendedErrorOpt(msg *dns.Msg, infoCode uint16, extraText string) {
 	opt := msg.IsEdns0()
 	if opt == nil {
 		opt = &dns.OPT{
@@ -293,7 +306,8 @@ func addMsgStaleAnswerOpt(msg *dns.Msg) {
 		msg.Extra = append(msg.Extra, opt)
 	}
 	opt.Option = append(opt.Option, &dns.EDNS0_EDE{
-		InfoCode: dns.ExtendedErrorCodeStaleAnswer,
+		InfoCode:  infoCode,
+		ExtraText: extraText,
 	})
 }
 
@@ -321,7 +335,10 @@ func (c *Client) Exchange(ctx context.Context, transport adapter.DNSTransport, m
 			message.Extra[0].Header().Ttl == 0 &&
 			len(message.Extra[0].(*dns.OPT).Option) == 0) &&
 		!options.ClientSubnet.IsValid()
-	disableCache := !isSimpleRequest || c.disableCache || options.DisableCache
+	validateDNSSEC := c.dnssec && dnssecSupportedTransport(transport)
+	// unvalidated responses must not be served to validating queries from cache
+	disableCache := !isSimpleRequest || c.disableCache || options.DisableCache || validateDNSSEC && (options.DisableDNSSEC || message.CheckingDisabled)
+	validateDNSSEC = validateDNSSEC && !options.DisableDNSSEC
 	if !disableCache {
 		cacheKey := dnsCacheKey{Question: question, transportTag: transport.Tag()}
 		cond, loaded := c.cacheLock.LoadOrStore(cacheKey, make(chan struct{}))
@@ -364,8 +381,21 @@ func (c *Client) Exchange(ctx context.Context, transport adapter.DNSTransport, m
 			return nil, ErrResponseRejectedCached
 		}
 	}
-	response, err := c.exchangeToTransport(ctx, transport, message, options.Timeout)
+	var response *dns.Msg
+	var err error
+	if validateDNSSEC {
+		response, err = c.exchangeDNSSEC(ctx, transport, message, options.Timeout)
+	} else {
+		response, err = c.exchangeToTransport(ctx, transport, message, options.Timeout)
+	}
 	if err != nil {
+		var dnssecErr *dnssecError
+		if errors.As(err, &dnssecErr) {
+			if c.logger != nil {
+				c.logger.WarnContext(ctx, "validation failed for ", FqdnToDomain(question.Name), ": ", err)
+			}
+			return dnssecFailureResponse(message, dnssecErr), nil
+		}
 		return nil, err
 	}
 	disableCache = disableCache || (response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError)
@@ -641,7 +671,13 @@ func (c *Client) backgroundRefreshDNS(transport adapter.DNSTransport, question d
 	go func() {
 		defer c.backgroundRefresh.Delete(key)
 		ctx := contextWithTransportTag(c.ctx, transport.Tag())
-		response, err := c.exchangeToTransport(ctx, transport, message, options.Timeout)
+		var response *dns.Msg
+		var err error
+		if c.dnssec && !options.DisableDNSSEC && dnssecSupportedTransport(transport) {
+			response, err = c.exchangeDNSSEC(ctx, transport, message, options.Timeout)
+		} else {
+			response, err = c.exchangeToTransport(ctx, transport, message, options.Timeout)
+		}
 		if err != nil {
 			if c.logger != nil {
 				c.logger.DebugContext(ctx, "optimistic refresh failed for ", FqdnToDomain(question.Name), ": ", err)
@@ -0,0 +1,561 @@
+package dns
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/miekg/dns"
+)
+
+// RootTrustAnchors are the DS records of the root zone key signing keys
+// published by IANA (KSK-2017 and KSK-2024).
+var RootTrustAnchors = []*dns.DS{
+	{
+		Hdr:        dns.RR_Header{Name: ".", Rrtype: dns.TypeDS, Class: dns.ClassINET},
+		KeyTag:     20326,
+		Algorithm:  dns.RSASHA256,
+		DigestType: dns.SHA256,
+		Digest:     "E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	},
+	{
+		Hdr:        dns.RR_Header{Name: ".", Rrtype: dns.TypeDS, Class: dns.ClassINET},
+		KeyTag:     38696,
+		Algorithm:  dns.RSASHA256,
+		DigestType: dns.SHA256,
+		Digest:     "683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+	},
+}
+
+const (
+	dnssecMinKeyTTL = time.Minute
+	dnssecMaxKeyTTL = time.Hour
+)
+
+type dnssecError struct {
+	infoCode uint16
+	message  string
+}
+
+func newDNSSECError(infoCode uint16, message ...any) *dnssecError {
+	return &dnssecError{infoCode, E.New(message...).Error()}
+}
+
+func (e *dnssecError) Error() string {
+	return "DNSSEC: " + e.message
+}
+
+type dnssecCacheKey struct {
+	transportTag string
+	name         string
+}
+
+// dnssecZone is the validated state of a name on the delegation path.
+//
+// A name with keys is the apex of a secure zone, a name marked insecure is
+// the apex of a provably unsigned delegation (so everything below it is
+// insecure too), and any other name is not a zone cut.
+type dnssecZone struct {
+	name     string
+	keys     []*dns.DNSKEY
+	insecure bool
+}
+
+func (z *dnssecZone) secure() bool {
+	return len(z.keys) > 0
+}
+
+func dnssecSupportedTransport(transport adapter.DNSTransport) bool {
+	switch transport.Type() {
+	case C.DNSTypeLocal, C.DNSTypeHosts, C.DNSTypeFakeIP, C.DNSTypeMDNS, C.DNSTypeTailscale:
+		return false
+	default:
+		return true
+	}
+}
+
+// exchangeDNSSEC sends the query with the DO and CD bits set and validates
+// the response from the trust anchors, unless the query sets the CD bit to
+// validate by itself. DNSSEC records are removed from the response if the
+// query did not ask for them.
+func (c *Client) exchangeDNSSEC(ctx context.Context, transport adapter.DNSTransport, message *dns.Msg, timeout time.Duration) (*dns.Msg, error) {
+	var dnssecOK bool
+	if opt := message.IsEdns0(); opt != nil {
+		dnssecOK = opt.Do()
+	}
+	request := message.Copy()
+	if opt := request.IsEdns0(); opt != nil {
+		opt.SetDo()
+	} else {
+		request.SetEdns0(dns.DefaultMsgSize, true)
+	}
+	request.CheckingDisabled = true
+	response, err := c.exchangeToTransport(ctx, transport, request, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if message.CheckingDisabled {
+		response.AuthenticatedData = false
+	} else {
+		validator := &dnssecValidator{
+			client:    c,
+			transport: transport,
+			timeout:   timeout,
+			now:       time.Now(),
+		}
+		secure, err := validator.validate(ctx, message.Question[0], response)
+		if err != nil {
+			return nil, err
+		}
+		response.AuthenticatedData = secure
+	}
+	response.CheckingDisabled = message.CheckingDisabled
+	if !dnssecOK {
+		stripDNSSECRecords(response)
+	}
+	return response, nil
+}
+
+func stripDNSSECRecords(response *dns.Msg) {
+	isDNSSECRecord := func(it dns.RR) bool {
+		switch it.Header().Rrtype {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+			return false
+		default:
+			return true
+		}
+	}
+	response.Answer = common.Filter(response.Answer, isDNSSECRecord)
+	response.Ns = common.Filter(response.Ns, isDNSSECRecord)
+	response.Extra = common.Filter(response.Extra, isDNSSECRecord)
+	if opt := response.IsEdns0(); opt != nil {
+		opt.SetDo(false)
+	}
+}
+
+func dnssecFailureResponse(message *dns.Msg, err *dnssecError) *dns.Msg {
+	response := FixedResponseStatus(message, dns.RcodeServerFailure)
+	addMsgExtendedErrorOpt(response, err.infoCode, err.message)
+	return response
+}
+
+type dnssecValidator struct {
+	client    *Client
+	transport adapter.DNSTransport
+	timeout   time.Duration
+	now       time.Time
+}
+
+type dnssecRRSet struct {
+	records []dns.RR
+	sigs    []*dns.RRSIG
+	// verified is the signature that verified the RRset.
+	verified *dns.RRSIG
+}
+
+func groupRRSets(records []dns.RR) []*dnssecRRSet {
+	type rrsetKey struct {
+		name   string
+		rrType uint16
+	}
+	var (
+		rrsets []*dnssecRRSet
+		index  = make(map[rrsetKey]*dnssecRRSet)
+	)
+	lookup := func(key rrsetKey) *dnssecRRSet {
+		rrset := index[key]
+		if rrset == nil {
+			rrset = new(dnssecRRSet)
+			index[key] = rrset
+			rrsets = append(rrsets, rrset)
+		}
+		return rrset
+	}
+	for _, record := range records {
+		header := record.Header()
+		if header.Rrtype == dns.TypeOPT {
+			continue
+		}
+		name := dns.CanonicalName(header.Name)
+		if sig, isSig := record.(*dns.RRSIG); isSig {
+			rrset := lookup(rrsetKey{name, sig.TypeCovered})
+			rrset.sigs = append(rrset.sigs, sig)
+		} else {
+			rrset := lookup(rrsetKey{name, header.Rrtype})
+			rrset.records = append(rrset.records, record)
+		}
+	}
+	return common.Filter(rrsets, func(it *dnssecRRSet) bool {
+		return len(it.records) > 0
+	})
+}
+
+// validate checks the answer chain and returns true if all of it is signed
+// by secure zones. Answer RRsets outside of the CNAME/DNAME chain of the
+// question are removed from the response.
+//
+// Without an answer, the zone containing the last name of the chain decides:
+// a denial from a secure zone must carry the signed SOA of the zone and the
+// NSEC/NSEC3 records proving it, see verifyDenial. Authority RRsets never
+// make a response insecure, and unsigned ones outside of the zone are ignored.
+func (v *dnssecValidator) validate(ctx context.Context, question dns.Question, response *dns.Msg) (bool, error) {
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return false, nil
+	}
+	chain := newDNSSECAnswerChain(question, response.Answer)
+	response.Answer = common.Filter(response.Answer, chain.contains)
+	secure := true
+	var answerSigner string
+	for _, rrset := range groupRRSets(response.Answer) {
+		if chain.isSynthesized(rrset) {
+			// CNAMEs synthesized from a DNAME are not signed
+			continue
+		}
+		rrsetSecure, err := v.verifyRRSet(ctx, rrset)
+		if err != nil {
+			return false, err
+		}
+		if !rrsetSecure {
+			secure = false
+			continue
+		}
+		err = v.verifyWildcardExpansion(ctx, rrset, response.Ns)
+		if err != nil {
+			return false, err
+		}
+		header := rrset.records[0].Header()
+		if dns.CanonicalName(header.Name) == chain.name && (header.Rrtype == question.Qtype || question.Qtype == dns.TypeANY) {
+			answerSigner = dns.CanonicalName(rrset.verified.SignerName)
+		}
+	}
+	var (
+		zone *dnssecZone
+		err  error
+	)
+	if !chain.answered {
+		zone, err = v.zone(ctx, chain.name)
+	} else if secure && answerSigner != "" {
+		zone, err = v.zone(ctx, answerSigner)
+	}
+	if err != nil {
+		return false, err
+	}
+	var hasSOA bool
+	for _, rrset := range groupRRSets(response.Ns) {
+		header := rrset.records[0].Header()
+		owner := dns.CanonicalName(header.Name)
+		if len(rrset.sigs) == 0 && (header.Rrtype == dns.TypeNS || zone == nil || !zone.secure() || !dns.IsSubDomain(zone.name, owner)) {
+			// delegation records are not signed by the parent, and unsigned
+			// records of other zones cannot change the result
+			continue
+		}
+		rrsetSecure, err := v.verifyRRSet(ctx, rrset)
+		if err != nil {
+			return false, err
+		}
+		if rrsetSecure && header.Rrtype == dns.TypeSOA && zone != nil && owner == zone.name {
+			hasSOA = true
+		}
+	}
+	if chain.answered {
+		return secure, nil
+	}
+	if !zone.secure() {
+		return false, nil
+	}
+	if !hasSOA {
+		return false, newDNSSECError(dns.ExtendedErrorCodeNSECMissing, "missing signed SOA of ", FqdnToDomain(zone.name), " in denial of existence for ", FqdnToDomain(chain.name))
+	}
+	denialSecure, err := v.verifyDenial(response.Rcode, chain.name, question.Qtype, zone, response.Ns)
+	if err != nil {
+		return false, err
+	}
+	return secure && denialSecure, nil
+}
+
+// verifyRRSet returns false if the RRset belongs to an insecure zone.
+func (v *dnssecValidator) verifyRRSet(ctx context.Context, rrset *dnssecRRSet) (bool, error) {
+	header := rrset.records[0].Header()
+	description := formatRRSet(header)
+	if len(rrset.sigs) == 0 {
+		zone, err := v.zone(ctx, header.Name)
+		if err != nil {
+			return false, err
+		}
+		if zone.insecure {
+			return false, nil
+		}
+		return false, newDNSSECError(dns.ExtendedErrorCodeRRSIGsMissing, "missing signature for ", description)
+	}
+	var lastErr error
+	for _, sig := range rrset.sigs {
+		signerName := dns.CanonicalName(sig.SignerName)
+		if !dns.IsSubDomain(signerName, dns.CanonicalName(header.Name)) {
+			continue
+		}
+		zone, err := v.zone(ctx, signerName)
+		if err != nil {
+			return false, err
+		}
+		if zone.insecure {
+			return false, nil
+		}
+		if zone.name != signerName {
+			lastErr = newDNSSECError(dns.ExtendedErrorCodeDNSKEYMissing, "signer ", signerName, " of ", description, " is not a secure zone")
+			continue
+		}
+		err = v.verifySignatures(rrset, zone)
+		if err == nil {
+			return true, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = newDNSSECError(dns.ExtendedErrorCodeDNSBogus, "no valid signer for ", description)
+	}
+	return false, lastErr
+}
+
+func formatRRSet(header *dns.RR_Header) string {
+	return FqdnToDomain(header.Name) + " " + dns.Type(header.Rrtype).String()
+}
+
+func (v *dnssecValidator) verifySignatures(rrset *dnssecRRSet, zone *dnssecZone) error {
+	description := formatRRSet(rrset.records[0].Header())
+	var expired bool
+	for _, sig := range rrset.sigs {
+		if dns.CanonicalName(sig.SignerName) != zone.name {
+			continue
+		}
+		if !sig.ValidityPeriod(v.now) {
+			expired = true
+			continue
+		}
+		for _, key := range zone.keys {
+			if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm {
+				continue
+			}
+			if sig.Verify(key, rrset.records) == nil {
+				rrset.verified = sig
+				return nil
+			}
+		}
+	}
+	if expired {
+		return newDNSSECError(dns.ExtendedErrorCodeSignatureExpired, "signature expired for ", description)
+	}
+	return newDNSSECError(dns.ExtendedErrorCodeDNSBogus, "bad signature for ", description)
+}
+
+// zone walks the delegation path from the root to name, and returns the
+// deepest secure zone containing name, or the insecure delegation above it.
+func (v *dnssecValidator) zone(ctx context.Context, name string) (*dnssecZone, error) {
+	name = dns.CanonicalName(name)
+	current, err := v.loadZone(ctx, nil, ".")
+	if err != nil {
+		return nil, err
+	}
+	labelIndexes := dns.Split(name)
+	for i := len(labelIndexes) - 1; i >= 0; i-- {
+		zone, err := v.loadZone(ctx, current, name[labelIndexes[i]:])
+		if err != nil {
+			return nil, err
+		}
+		if zone.insecure {
+			return zone, nil
+		}
+		if zone.secure() {
+			current = zone
+		}
+	}
+	return current, nil
+}
+
+func (v *dnssecValidator) loadZone(ctx context.Context, parent *dnssecZone, name string) (*dnssecZone, error) {
+	cacheKey := dnssecCacheKey{transportTag: v.transport.Tag(), name: name}
+	if zone, loaded := v.client.dnssecCache.Get(cacheKey); loaded {
+		return zone, nil
+	}
+	var (
+		zone       *dnssecZone
+		timeToLive uint32
+		err        error
+	)
+	if parent == nil {
+		zone, timeToLive, err = v.loadKeys(ctx, name, v.client.dnssecAnchors)
+	} else {
+		zone, timeToLive, err = v.loadDelegation(ctx, parent, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	lifetime := min(max(time.Duration(timeToLive)*time.Second, dnssecMinKeyTTL), dnssecMaxKeyTTL)
+	v.client.dnssecCache.AddWithLifetime(cacheKey, zone, lifetime)
+	return zone, nil
+}
+
+func (v *dnssecValidator) exchange(ctx context.Context, name string, qType uint16) (*dns.Msg, error) {
+	message := new(dns.Msg)
+	message.SetQuestion(name, qType)
+	message.CheckingDisabled = true
+	message.SetEdns0(dns.DefaultMsgSize, true)
+	response, err := v.client.exchangeToTransport(ctx, v.transport, message, v.timeout)
+	if err != nil {
+		return nil, newDNSSECError(dns.ExtendedErrorCodeDNSSECIndeterminate, "query ", FqdnToDomain(name), " ", dns.Type(qType), ": ", err)
+	}
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, newDNSSECError(dns.ExtendedErrorCodeDNSSECIndeterminate, "query ", FqdnToDomain(name), " ", dns.Type(qType), ": ", RcodeError(response.Rcode))
+	}
+	return response, nil
+}
+
+// loadDelegation validates the DS records of name with the keys of the parent
+// zone, then loads the keys of name. Without DS records, name is either an
+// insecure delegation or not a zone cut, which is told apart by the signed
+// NSEC/NSEC3 records of the parent.
+func (v *dnssecValidator) loadDelegation(ctx context.Context, parent *dnssecZone, name string) (*dnssecZone, uint32, error) {
+	response, err := v.exchange(ctx, name, dns.TypeDS)
+	if err != nil {
+		return nil, 0, err
+	}
+	var (
+		dsRRSet *dnssecRRSet
+		isAlias bool
+	)
+	for _, rrset := range groupRRSets(response.Answer) {
+		header := rrset.records[0].Header()
+		if dns.CanonicalName(header.Name) != name {
+			continue
+		}
+		switch header.Rrtype {
+		case dns.TypeDS:
+			dsRRSet = rrset
+		case dns.TypeCNAME:
+			isAlias = true
+		}
+	}
+	if dsRRSet != nil {
+		err = v.verifySignatures(dsRRSet, parent)
+		if err != nil {
+			return nil, 0, err
+		}
+		dsRecords := common.Map(dsRRSet.records, func(it dns.RR) *dns.DS {
+			return it.(*dns.DS)
+		})
+		return v.loadKeys(ctx, name, dsRecords)
+	}
+	if isAlias {
+		return &dnssecZone{name: name}, computeTimeToLive(response), nil
+	}
+	var signed bool
+	for _, rrset := range groupRRSets(response.Ns) {
+		if len(rrset.sigs) == 0 {
+			continue
+		}
+		err = v.verifySignatures(rrset, parent)
+		if err != nil {
+			return nil, 0, err
+		}
+		signed = true
+	}
+	if !signed {
+		return nil, 0, newDNSSECError(dns.ExtendedErrorCodeNSECMissing, "missing signed denial of DS for ", FqdnToDomain(name))
+	}
+	return &dnssecZone{name: name, insecure: isInsecureDelegation(response.Ns, name)}, computeTimeToLive(response), nil
+}
+
+func isInsecureDelegation(records []dns.RR, name string) bool {
+	for _, record := range records {
+		switch denial := record.(type) {
+		case *dns.NSEC:
+			if dns.CanonicalName(denial.Hdr.Name) == name {
+				return isDelegationBitmap(denial.TypeBitMap)
+			}
+		case *dns.NSEC3:
+			if denial.Match(name) {
+				return isDelegationBitmap(denial.TypeBitMap)
+			}
+			// an opt-out span may contain unsigned delegations
+			if denial.Flags&1 != 0 && denial.Cover(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isDelegationBitmap(bitmap []uint16) bool {
+	var hasNS bool
+	for _, rrType := range bitmap {
+		switch rrType {
+		case dns.TypeNS:
+			hasNS = true
+		case dns.TypeDS, dns.TypeSOA:
+			return false
+		}
+	}
+	return hasNS
+}
+
+// loadKeys loads the DNSKEY RRset of name, and validates it with a key
+// matching one of the DS records. A zone with DS records of unsupported
+// algorithms only is treated as insecure.
+func (v *dnssecValidator) loadKeys(ctx context.Context, name string, dsRecords []*dns.DS) (*dnssecZone, uint32, error) {
+	dsRecords = common.Filter(dsRecords, func(it *dns.DS) bool {
+		return isSupportedDNSSECAlgorithm(it.Algorithm) && (it.DigestType == dns.SHA256 || it.DigestType == dns.SHA384)
+	})
+	if len(dsRecords) == 0 {
+		return &dnssecZone{name: name, insecure: true}, 0, nil
+	}
+	response, err := v.exchange(ctx, name, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, 0, err
+	}
+	var keyRRSet *dnssecRRSet
+	for _, rrset := range groupRRSets(response.Answer) {
+		header := rrset.records[0].Header()
+		if header.Rrtype == dns.TypeDNSKEY && dns.CanonicalName(header.Name) == name {
+			keyRRSet = rrset
+		}
+	}
+	if keyRRSet == nil {
+		return nil, 0, newDNSSECError(dns.ExtendedErrorCodeDNSKEYMissing, "missing DNSKEY for ", FqdnToDomain(name))
+	}
+	keys := common.Map(keyRRSet.records, func(it dns.RR) *dns.DNSKEY {
+		return it.(*dns.DNSKEY)
+	})
+	trustedKeys := common.Filter(keys, func(key *dns.DNSKEY) bool {
+		return common.Any(dsRecords, func(ds *dns.DS) bool {
+			if key.KeyTag() != ds.KeyTag || key.Algorithm != ds.Algorithm {
+				return false
+			}
+			keyDS := key.ToDS(ds.DigestType)
+			return keyDS != nil && strings.EqualFold(keyDS.Digest, ds.Digest)
+		})
+	})
+	if len(trustedKeys) == 0 {
+		return nil, 0, newDNSSECError(dns.ExtendedErrorCodeDNSKEYMissing, "no DNSKEY matches DS for ", FqdnToDomain(name))
+	}
+	err = v.verifySignatures(keyRRSet, &dnssecZone{name: name, keys: trustedKeys})
+	if err != nil {
+		return nil, 0, err
+	}
+	keys = common.Filter(keys, func(it *dns.DNSKEY) bool {
+		return it.Flags&dns.ZONE != 0 && it.Protocol == 3
+	})
+	return &dnssecZone{name: name, keys: keys}, keyRRSet.records[0].Header().Ttl, nil
+}
+
+func isSupportedDNSSECAlgorithm(algorithm uint8) bool {
+	switch algorithm {
+	case dns.RSASHA256, dns.RSASHA512, dns.ECDSAP256SHA256, dns.ECDSAP384SHA384, dns.ED25519:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,330 @@
+package dns
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	dnssecMaxChainLength = 16
+	// RFC 9276 allows validators to treat NSEC3 records with more iterations
+	// as insecure.
+	dnssecMaxNSEC3Iterations = 150
+)
+
+type dnssecRRSetKey struct {
+	name   string
+	rrType uint16
+}
+
+// dnssecAnswerChain is the CNAME/DNAME chain of a question in the answer
+// section. name is the last name of the chain, and answered is true if an
+// RRset of the question type exists for it.
+type dnssecAnswerChain struct {
+	name        string
+	answered    bool
+	rrsets      map[dnssecRRSetKey]bool
+	synthesized map[string]string
+}
+
+func newDNSSECAnswerChain(question dns.Question, answer []dns.RR) *dnssecAnswerChain {
+	chain := &dnssecAnswerChain{
+		name:        dns.CanonicalName(question.Name),
+		rrsets:      make(map[dnssecRRSetKey]bool),
+		synthesized: make(map[string]string),
+	}
+	visited := make(map[string]bool)
+	for range dnssecMaxChainLength {
+		if visited[chain.name] {
+			break
+		}
+		visited[chain.name] = true
+		var aliasTarget, dnameTarget string
+		for _, record := range answer {
+			header := record.Header()
+			owner := dns.CanonicalName(header.Name)
+			switch {
+			case header.Rrtype == dns.TypeRRSIG || header.Rrtype == dns.TypeOPT:
+			case owner == chain.name && (header.Rrtype == question.Qtype || question.Qtype == dns.TypeANY):
+				chain.rrsets[dnssecRRSetKey{owner, header.Rrtype}] = true
+				chain.answered = true
+			case owner == chain.name && header.Rrtype == dns.TypeCNAME:
+				chain.rrsets[dnssecRRSetKey{owner, header.Rrtype}] = true
+				aliasTarget = dns.CanonicalName(record.(*dns.CNAME).Target)
+			case header.Rrtype == dns.TypeDNAME && owner != chain.name && dns.IsSubDomain(owner, chain.name):
+				chain.rrsets[dnssecRRSetKey{owner, header.Rrtype}] = true
+				dnameTarget = substituteDNAME(chain.name, owner, dns.CanonicalName(record.(*dns.DNAME).Target))
+				chain.synthesized[chain.name] = dnameTarget
+			}
+		}
+		if chain.answered {
+			break
+		}
+		if dnameTarget != "" {
+			chain.name = dnameTarget
+		} else if aliasTarget != "" {
+			chain.name = aliasTarget
+		} else {
+			break
+		}
+	}
+	return chain
+}
+
+func substituteDNAME(name string, owner string, target string) string {
+	prefix := strings.TrimSuffix(name, owner)
+	if target == "." {
+		return prefix
+	}
+	return prefix + target
+}
+
+func (c *dnssecAnswerChain) contains(record dns.RR) bool {
+	header := record.Header()
+	rrType := header.Rrtype
+	if sig, isSig := record.(*dns.RRSIG); isSig {
+		rrType = sig.TypeCovered
+	}
+	return c.rrsets[dnssecRRSetKey{dns.CanonicalName(header.Name), rrType}]
+}
+
+// isSynthesized reports whether the RRset is an unsigned CNAME synthesized
+// from a DNAME of the chain.
+func (c *dnssecAnswerChain) isSynthesized(rrset *dnssecRRSet) bool {
+	cname, isCNAME := rrset.records[0].(*dns.CNAME)
+	if !isCNAME || len(rrset.records) != 1 || len(rrset.sigs) > 0 {
+		return false
+	}
+	target, loaded := c.synthesized[dns.CanonicalName(cname.Hdr.Name)]
+	return loaded && target == dns.CanonicalName(cname.Target)
+}
+
+// verifyDenial checks that the NSEC/NSEC3 records signed by the zone prove
+// the NXDOMAIN or NODATA response, as described in RFC 4035 section 5.4 and
+// RFC 5155 section 8. It returns false for NSEC3 opt-out spans, which may
+// contain unsigned delegations.
+func (v *dnssecValidator) verifyDenial(rcode int, name string, qType uint16, zone *dnssecZone, authority []dns.RR) (bool, error) {
+	nsecRecords, nsec3Records := v.denialRecords(zone, authority)
+	switch {
+	case len(nsecRecords) > 0:
+		if proveNSECDenial(rcode, name, qType, nsecRecords) {
+			return true, nil
+		}
+	case len(nsec3Records) > 0:
+		proven, optOut := proveNSEC3Denial(rcode, name, qType, nsec3Records)
+		if proven || optOut {
+			return proven, nil
+		}
+	default:
+		return false, newDNSSECError(dns.ExtendedErrorCodeNSECMissing, "missing NSEC/NSEC3 records of ", FqdnToDomain(zone.name), " for ", FqdnToDomain(name))
+	}
+	return false, newDNSSECError(dns.ExtendedErrorCodeDNSBogus, "failed to prove denial of existence for ", FqdnToDomain(name))
+}
+
+// verifyWildcardExpansion checks that the name of an RRset expanded from a
+// wildcard does not exist, as described in RFC 4035 section 5.3.4.
+func (v *dnssecValidator) verifyWildcardExpansion(ctx context.Context, rrset *dnssecRRSet, authority []dns.RR) error {
+	sig := rrset.verified
+	owner := dns.CanonicalName(rrset.records[0].Header().Name)
+	labels := dns.CountLabel(owner)
+	if sig == nil || int(sig.Labels) >= labels || strings.HasPrefix(owner, "*.") {
+		return nil
+	}
+	indexes := dns.Split(owner)
+	closestEncloser := "."
+	if sig.Labels > 0 {
+		closestEncloser = owner[indexes[labels-int(sig.Labels)]:]
+	}
+	nextCloser := owner[indexes[labels-int(sig.Labels)-1]:]
+	zone, err := v.zone(ctx, sig.SignerName)
+	if err != nil {
+		return err
+	}
+	nsecRecords, nsec3Records := v.denialRecords(zone, authority)
+	if slices.ContainsFunc(nsecRecords, func(it *dns.NSEC) bool {
+		return nsecCovers(it, owner) && nsecClosestEncloser(owner, it) == closestEncloser
+	}) || slices.ContainsFunc(nsec3Records, func(it *dns.NSEC3) bool {
+		return it.Cover(nextCloser)
+	}) {
+		return nil
+	}
+	return newDNSSECError(dns.ExtendedErrorCodeDNSBogus, "missing denial of ", FqdnToDomain(owner), " for wildcard expansion of ", formatRRSet(rrset.records[0].Header()))
+}
+
+// denialRecords returns the NSEC/NSEC3 records whose signatures verify with
+// the zone, as records of other zones cannot deny names of this zone.
+func (v *dnssecValidator) denialRecords(zone *dnssecZone, authority []dns.RR) ([]*dns.NSEC, []*dns.NSEC3) {
+	var (
+		nsecRecords  []*dns.NSEC
+		nsec3Records []*dns.NSEC3
+	)
+	for _, rrset := range groupRRSets(authority) {
+		switch rrset.records[0].Header().Rrtype {
+		case dns.TypeNSEC, dns.TypeNSEC3:
+		default:
+			continue
+		}
+		if v.verifySignatures(rrset, zone) != nil {
+			continue
+		}
+		for _, record := range rrset.records {
+			switch denial := record.(type) {
+			case *dns.NSEC:
+				nsecRecords = append(nsecRecords, denial)
+			case *dns.NSEC3:
+				if denial.Hash == dns.SHA1 && denial.Iterations <= dnssecMaxNSEC3Iterations {
+					nsec3Records = append(nsec3Records, denial)
+				}
+			}
+		}
+	}
+	return nsecRecords, nsec3Records
+}
+
+func proveNSECDenial(rcode int, name string, qType uint16, records []*dns.NSEC) bool {
+	if rcode == dns.RcodeSuccess {
+		for _, record := range records {
+			if dns.CanonicalName(record.Hdr.Name) == name {
+				return !hasTypeInBitmap(record.TypeBitMap, qType, dns.TypeCNAME)
+			}
+		}
+	}
+	var closestEncloser string
+	for _, record := range records {
+		if nsecCovers(record, name) {
+			closestEncloser = nsecClosestEncloser(name, record)
+			break
+		}
+	}
+	if closestEncloser == "" {
+		return false
+	}
+	wildcard := wildcardName(closestEncloser)
+	for _, record := range records {
+		if rcode == dns.RcodeSuccess {
+			// NODATA of a wildcard expansion
+			if dns.CanonicalName(record.Hdr.Name) == wildcard {
+				return !hasTypeInBitmap(record.TypeBitMap, qType, dns.TypeCNAME)
+			}
+		} else if nsecCovers(record, wildcard) {
+			return true
+		}
+	}
+	return false
+}
+
+// nsecCovers reports whether name falls strictly between the owner and the
+// next name of the record.
+func nsecCovers(record *dns.NSEC, name string) bool {
+	owner := dns.CanonicalName(record.Hdr.Name)
+	next := dns.CanonicalName(record.NextDomain)
+	if compareCanonicalName(owner, name) >= 0 {
+		return false
+	}
+	if owner != name && dns.IsSubDomain(owner, name) && isDelegationOrDNAME(record.TypeBitMap) {
+		// names below a zone cut are not denied by the parent
+		return false
+	}
+	// the last record of a zone points back to the apex
+	return compareCanonicalName(name, next) < 0 || compareCanonicalName(next, owner) <= 0
+}
+
+func nsecClosestEncloser(name string, record *dns.NSEC) string {
+	labels := max(dns.CompareDomainName(name, record.Hdr.Name), dns.CompareDomainName(name, record.NextDomain))
+	indexes := dns.Split(name)
+	if labels >= len(indexes) {
+		return ""
+	}
+	if labels == 0 {
+		return "."
+	}
+	return name[indexes[len(indexes)-labels]:]
+}
+
+// proveNSEC3Denial also reports whether the next closer name falls into an
+// opt-out span, which leaves NXDOMAIN and DS NODATA responses insecure.
+func proveNSEC3Denial(rcode int, name string, qType uint16, records []*dns.NSEC3) (proven bool, optOut bool) {
+	if rcode == dns.RcodeSuccess {
+		for _, record := range records {
+			if record.Match(name) {
+				return !hasTypeInBitmap(record.TypeBitMap, qType, dns.TypeCNAME), false
+			}
+		}
+	}
+	// closest encloser proof
+	var closestEncloser, nextCloser string
+	indexes := dns.Split(name)
+	for i := 1; i <= len(indexes) && closestEncloser == ""; i++ {
+		candidate := "."
+		if i < len(indexes) {
+			candidate = name[indexes[i]:]
+		}
+		for _, record := range records {
+			if record.Match(candidate) && !isDelegationOrDNAME(record.TypeBitMap) {
+				closestEncloser = candidate
+				nextCloser = name[indexes[i-1]:]
+				break
+			}
+		}
+	}
+	if closestEncloser == "" {
+		return false, false
+	}
+	nextCloserIndex := slices.IndexFunc(records, func(it *dns.NSEC3) bool {
+		return it.Cover(nextCloser)
+	})
+	if nextCloserIndex < 0 {
+		return false, false
+	}
+	optOut = records[nextCloserIndex].Flags&1 != 0
+	wildcard := wildcardName(closestEncloser)
+	if rcode == dns.RcodeSuccess {
+		// NODATA of a wildcard expansion
+		for _, record := range records {
+			if record.Match(wildcard) {
+				return !optOut && !hasTypeInBitmap(record.TypeBitMap, qType, dns.TypeCNAME), false
+			}
+		}
+		return false, optOut && qType == dns.TypeDS
+	}
+	if !slices.ContainsFunc(records, func(it *dns.NSEC3) bool {
+		return it.Cover(wildcard)
+	}) {
+		return false, false
+	}
+	return !optOut, optOut
+}
+
+func wildcardName(name string) string {
+	if name == "." {
+		return "*."
+	}
+	return "*." + name
+}
+
+func hasTypeInBitmap(bitmap []uint16, rrTypes ...uint16) bool {
+	return slices.ContainsFunc(bitmap, func(it uint16) bool {
+		return slices.Contains(rrTypes, it)
+	})
+}
+
+func isDelegationOrDNAME(bitmap []uint16) bool {
+	return hasTypeInBitmap(bitmap, dns.TypeDNAME) || hasTypeInBitmap(bitmap, dns.TypeNS) && !hasTypeInBitmap(bitmap, dns.TypeSOA)
+}
+
+// compareCanonicalName compares names in the canonical order of RFC 4034
+// section 6.1, which sorts by labels from the right.
+func compareCanonicalName(a string, b string) int {
+	aLabels := dns.SplitDomainName(dns.CanonicalName(a))
+	bLabels := dns.SplitDomainName(dns.CanonicalName(b))
+	for i := 1; i <= len(aLabels) && i <= len(bLabels); i++ {
+		result := strings.Compare(aLabels[len(aLabels)-i], bLabels[len(bLabels)-i])
+		if result != 0 {
+			return result
+		}
+	}
+	return len(aLabels) - len(bLabels)
+}
@@ -0,0 +1,338 @@
+package dns
+
+import (
+	"context"
+	"crypto"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testSignedZone struct {
+	name   string
+	key    *dns.DNSKEY
+	signer crypto.Signer
+}
+
+func newTestSignedZone(t *testing.T, name string) *testSignedZone {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: name, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     dns.ZONE | dns.SEP,
+		Protocol:  3,
+		Algorithm: dns.ED25519,
+	}
+	privateKey, err := key.Generate(256)
+	require.NoError(t, err)
+	return &testSignedZone{name: name, key: key, signer: privateKey.(crypto.Signer)}
+}
+
+func (z *testSignedZone) sign(t *testing.T, rrset ...dns.RR) []dns.RR {
+	now := time.Now()
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: rrset[0].Header().Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: 3600},
+		KeyTag:     z.key.KeyTag(),
+		SignerName: z.name,
+		Algorithm:  z.key.Algorithm,
+		Inception:  uint32(now.Add(-time.Hour).Unix()),
+		Expiration: uint32(now.Add(time.Hour).Unix()),
+	}
+	require.NoError(t, sig.Sign(z.signer, rrset))
+	return append(rrset, sig)
+}
+
+func (z *testSignedZone) ds() dns.RR {
+	ds := z.key.ToDS(dns.SHA256)
+	ds.Hdr.Ttl = 3600
+	return ds
+}
+
+func (z *testSignedZone) soa() dns.RR {
+	return &dns.SOA{
+		Hdr:    dns.RR_Header{Name: z.name, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 3600},
+		Ns:     "ns." + z.name,
+		Mbox:   "hostmaster." + z.name,
+		Minttl: 300,
+	}
+}
+
+type dnssecTestTransport struct {
+	answers   map[dns.Question][]dns.RR
+	authority map[dns.Question][]dns.RR
+	rcodes    map[dns.Question]int
+}
+
+func (t *dnssecTestTransport) Start(adapter.StartStage) error { return nil }
+func (t *dnssecTestTransport) Close() error                   { return nil }
+func (t *dnssecTestTransport) Type() string                   { return C.DNSTypeUDP }
+func (t *dnssecTestTransport) Tag() string                    { return "upstream" }
+func (t *dnssecTestTransport) Dependencies() []string         { return nil }
+func (t *dnssecTestTransport) Reset()                         {}
+
+func (t *dnssecTestTransport) Exchange(ctx context.Context, message *dns.Msg) (*dns.Msg, error) {
+	response := new(dns.Msg)
+	response.SetReply(message)
+	question := message.Question[0]
+	response.Answer = t.answers[question]
+	response.Ns = t.authority[question]
+	response.Rcode = t.rcodes[question]
+	if opt := message.IsEdns0(); opt == nil || !opt.Do() {
+		stripDNSSECRecords(response)
+	}
+	return response.Copy(), nil
+}
+
+func newDNSSECTestClient(t *testing.T) (*Client, *dnssecTestTransport) {
+	root := newTestSignedZone(t, ".")
+	com := newTestSignedZone(t, "com.")
+	example := newTestSignedZone(t, "example.com.")
+	question := func(name string, qType uint16) dns.Question {
+		return dns.Question{Name: name, Qtype: qType, Qclass: dns.ClassINET}
+	}
+	address := func(name string, ip string) dns.RR {
+		return &dns.A{
+			Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP(ip),
+		}
+	}
+	insecureDenial := &dns.NSEC{
+		Hdr:        dns.RR_Header{Name: "insecure.com.", Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+		NextDomain: "z.com.",
+		TypeBitMap: []uint16{dns.TypeNS, dns.TypeRRSIG, dns.TypeNSEC},
+	}
+	nsec := func(name string, next string, types ...uint16) dns.RR {
+		return &dns.NSEC{
+			Hdr:        dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+			NextDomain: next,
+			TypeBitMap: append(types, dns.TypeRRSIG, dns.TypeNSEC),
+		}
+	}
+	// the record of the zone apex covers no other name
+	apexHash := dns.HashName("example.com.", dns.SHA1, 0, "")
+	apexNextHash := apexHash[:len(apexHash)-1] + string(apexHash[len(apexHash)-1]+1)
+	nsec3 := func(name string, next string, flags uint8, types ...uint16) dns.RR {
+		owner := name
+		if !strings.HasPrefix(name, "0") {
+			owner = dns.HashName(name, dns.SHA1, 0, "") + ".example.com."
+		}
+		return &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: owner, Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 300},
+			Hash:       dns.SHA1,
+			Flags:      flags,
+			NextDomain: next,
+			HashLength: 20,
+			TypeBitMap: types,
+		}
+	}
+	cname := func(name string, target string) dns.RR {
+		return &dns.CNAME{
+			Hdr:    dns.RR_Header{Name: name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 60},
+			Target: target,
+		}
+	}
+	tampered := example.sign(t, address("tampered.example.com.", "1.1.1.1"))
+	tampered[0].(*dns.A).A = net.ParseIP("6.6.6.6")
+	expanded := func(name string) []dns.RR {
+		rrset := example.sign(t, address("*.example.com.", "3.3.3.3"))
+		for _, record := range rrset {
+			record.Header().Name = name
+		}
+		return rrset
+	}
+	signedDenial := example.sign(t, example.soa())
+	transport := &dnssecTestTransport{
+		answers: map[dns.Question][]dns.RR{
+			question(".", dns.TypeDNSKEY):                root.sign(t, root.key),
+			question("com.", dns.TypeDS):                 root.sign(t, com.ds()),
+			question("com.", dns.TypeDNSKEY):             com.sign(t, com.key),
+			question("example.com.", dns.TypeDS):         com.sign(t, example.ds()),
+			question("example.com.", dns.TypeDNSKEY):     example.sign(t, example.key),
+			question("example.com.", dns.TypeA):          example.sign(t, address("example.com.", "1.1.1.1")),
+			question("tampered.example.com.", dns.TypeA): tampered,
+			question("unsigned.example.com.", dns.TypeA): {address("unsigned.example.com.", "1.1.1.1")},
+			question("insecure.com.", dns.TypeA):         {address("insecure.com.", "2.2.2.2")},
+			question("alias.example.com.", dns.TypeA): slices.Concat(
+				example.sign(t, cname("alias.example.com.", "example.com.")),
+				example.sign(t, address("example.com.", "1.1.1.1")),
+				example.sign(t, address("injected.example.com.", "6.6.6.6")),
+				[]dns.RR{address("unsigned-injected.com.", "6.6.6.6")},
+			),
+			question("www.dname.example.com.", dns.TypeA): slices.Concat(
+				example.sign(t, &dns.DNAME{
+					Hdr:    dns.RR_Header{Name: "dname.example.com.", Rrtype: dns.TypeDNAME, Class: dns.ClassINET, Ttl: 60},
+					Target: "example.com.",
+				}),
+				[]dns.RR{cname("www.dname.example.com.", "www.example.com.")},
+				example.sign(t, address("www.example.com.", "1.1.1.1")),
+			),
+			question("wildcard.example.com.", dns.TypeA):          expanded("wildcard.example.com."),
+			question("wildcard-replayed.example.com.", dns.TypeA): expanded("wildcard-replayed.example.com."),
+			question("www.forged-dname.example.com.", dns.TypeA): slices.Concat(
+				example.sign(t, &dns.DNAME{
+					Hdr:    dns.RR_Header{Name: "forged-dname.example.com.", Rrtype: dns.TypeDNAME, Class: dns.ClassINET, Ttl: 60},
+					Target: "example.com.",
+				}),
+				[]dns.RR{cname("www.forged-dname.example.com.", "other.example.net.")},
+			),
+		},
+		authority: map[dns.Question][]dns.RR{
+			question("insecure.com.", dns.TypeDS):                 append(com.sign(t, com.soa()), com.sign(t, insecureDenial)...),
+			question("unsigned.example.com.", dns.TypeDS):         example.sign(t, example.soa()),
+			question("forged-dname.example.com.", dns.TypeDS):     example.sign(t, example.soa()),
+			question("www.forged-dname.example.com.", dns.TypeDS): example.sign(t, example.soa()),
+			question("replayed-soa.example.com.", dns.TypeA):      example.sign(t, example.soa()),
+			question("foreign-soa.example.com.", dns.TypeA):       com.sign(t, com.soa()),
+			question("forged-nodata.example.com.", dns.TypeA):     {example.soa()},
+			question("wildcard.example.com.", dns.TypeA):          example.sign(t, nsec("a.example.com.", "z.example.com.", dns.TypeA)),
+			question("nodata-injected.example.com.", dns.TypeA): slices.Concat(
+				example.sign(t, example.soa()),
+				example.sign(t, nsec("nodata-injected.example.com.", "z.example.com.", dns.TypeTXT)),
+				[]dns.RR{address("insecure.com.", "6.6.6.6")},
+			),
+			question("nxdomain-injected.example.com.", dns.TypeA): slices.Concat(
+				example.sign(t, example.soa()),
+				[]dns.RR{address("insecure.com.", "6.6.6.6")},
+			),
+			question("nodata.example.com.", dns.TypeA): slices.Concat(
+				example.sign(t, example.soa()),
+				example.sign(t, nsec("nodata.example.com.", "z.example.com.", dns.TypeTXT)),
+			),
+			question("cname.example.com.", dns.TypeA): slices.Concat(
+				example.sign(t, example.soa()),
+				example.sign(t, nsec("cname.example.com.", "z.example.com.", dns.TypeCNAME)),
+			),
+			question("nxdomain.example.com.", dns.TypeA): slices.Concat(
+				example.sign(t, example.soa()),
+				example.sign(t, nsec("example.com.", "z.example.com.", dns.TypeSOA, dns.TypeNS, dns.TypeA)),
+			),
+			question("nxdomain-wildcard.example.com.", dns.TypeA): slices.Concat(
+				example.sign(t, example.soa()),
+				example.sign(t, nsec("a.example.com.", "z.example.com.", dns.TypeA)),
+			),
+			question("nxdomain-foreign.example.com.", dns.TypeA): slices.Concat(
+				example.sign(t, example.soa()),
+				com.sign(t, nsec("example.com.", "z.com.", dns.TypeNS, dns.TypeDS)),
+			),
+			question("nsec3.example.com.", dns.TypeA): slices.Concat(
+				example.sign(t, example.soa()),
+				example.sign(t, nsec3("example.com.", apexNextHash, 0, dns.TypeSOA, dns.TypeNS)),
+				example.sign(t, nsec3("00000000000000000000000000000000.example.com.", "VVVVVVVVVVVVVVVVVVVVVVVVVVVVVVVV", 0)),
+			),
+			question("nsec3-opt-out.example.com.", dns.TypeA): slices.Concat(
+				example.sign(t, example.soa()),
+				example.sign(t, nsec3("example.com.", apexNextHash, 0, dns.TypeSOA, dns.TypeNS)),
+				example.sign(t, nsec3("00000000000000000000000000000000.example.com.", "VVVVVVVVVVVVVVVVVVVVVVVVVVVVVVVV", 1)),
+			),
+		},
+		rcodes: map[dns.Question]int{
+			question("foreign-soa.example.com.", dns.TypeA):       dns.RcodeNameError,
+			question("nxdomain-injected.example.com.", dns.TypeA): dns.RcodeNameError,
+			question("nxdomain.example.com.", dns.TypeA):          dns.RcodeNameError,
+			question("nxdomain-wildcard.example.com.", dns.TypeA): dns.RcodeNameError,
+			question("nxdomain-foreign.example.com.", dns.TypeA):  dns.RcodeNameError,
+			question("nsec3.example.com.", dns.TypeA):             dns.RcodeNameError,
+			question("nsec3-opt-out.example.com.", dns.TypeA):     dns.RcodeNameError,
+		},
+	}
+	// the zone walk of denied names finds no zone cut below example.com.
+	for _, name := range []string{
+		"nodata", "nodata-injected", "forged-nodata", "cname", "replayed-soa", "foreign-soa", "nxdomain", "nxdomain-wildcard",
+		"nxdomain-foreign", "nxdomain-injected", "nsec3", "nsec3-opt-out",
+	} {
+		transport.authority[question(name+".example.com.", dns.TypeDS)] = signedDenial
+	}
+	client := NewClient(ClientOptions{
+		Context:      context.Background(),
+		DisableCache: true,
+		DNSSEC:       true,
+	})
+	client.dnssecAnchors = []*dns.DS{root.key.ToDS(dns.SHA256)}
+	return client, transport
+}
+
+func TestClientDNSSEC(t *testing.T) {
+	t.Parallel()
+	client, transport := newDNSSECTestClient(t)
+	for _, testCase := range []struct {
+		name          string
+		rcode         int
+		secure        bool
+		answers       int
+		extendedError uint16
+	}{
+		{name: "example.com.", rcode: dns.RcodeSuccess, secure: true},
+		{name: "nodata.example.com.", rcode: dns.RcodeSuccess, secure: true},
+		{name: "nodata-injected.example.com.", rcode: dns.RcodeSuccess, secure: true},
+		{name: "cname.example.com.", rcode: dns.RcodeServerFailure, extendedError: dns.ExtendedErrorCodeDNSBogus},
+		{name: "replayed-soa.example.com.", rcode: dns.RcodeServerFailure, extendedError: dns.ExtendedErrorCodeNSECMissing},
+		{name: "foreign-soa.example.com.", rcode: dns.RcodeServerFailure, extendedError: dns.ExtendedErrorCodeNSECMissing},
+		{name: "nxdomain.example.com.", rcode: dns.RcodeNameError, secure: true},
+		{name: "nxdomain-wildcard.example.com.", rcode: dns.RcodeServerFailure, extendedError: dns.ExtendedErrorCodeDNSBogus},
+		{name: "nxdomain-foreign.example.com.", rcode: dns.RcodeServerFailure, extendedError: dns.ExtendedErrorCodeNSECMissing},
+		{name: "nxdomain-injected.example.com.", rcode: dns.RcodeServerFailure, extendedError: dns.ExtendedErrorCodeNSECMissing},
+		{name: "wildcard.example.com.", rcode: dns.RcodeSuccess, secure: true, answers: 1},
+		{name: "wildcard-replayed.example.com.", rcode: dns.RcodeServerFailure, extendedError: dns.ExtendedErrorCodeDNSBogus},
+		{name: "nsec3.example.com.", rcode: dns.RcodeNameError, secure: true},
+		{name: "nsec3-opt-out.example.com.", rcode: dns.RcodeNameError},
+		{name: "alias.example.com.", rcode: dns.RcodeSuccess, secure: true, answers: 2},
+		{name: "www.dname.example.com.", rcode: dns.RcodeSuccess, secure: true, answers: 3},
+		{name: "www.forged-dname.example.com.", rcode: dns.RcodeServerFailure, extendedError: dns.ExtendedErrorCodeRRSIGsMissing},
+		{name: "insecure.com.", rcode: dns.RcodeSuccess},
+		{name: "tampered.example.com.", rcode: dns.RcodeServerFailure, extendedError: dns.ExtendedErrorCodeDNSBogus},
+		{name: "unsigned.example.com.", rcode: dns.RcodeServerFailure, extendedError: dns.ExtendedErrorCodeRRSIGsMissing},
+		{name: "forged-nodata.example.com.", rcode: dns.RcodeServerFailure, extendedError: dns.ExtendedErrorCodeRRSIGsMissing},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			message := new(dns.Msg)
+			message.SetQuestion(testCase.name, dns.TypeA)
+			response, err := client.Exchange(context.Background(), transport, message, adapter.DNSQueryOptions{}, nil)
+			require.NoError(t, err)
+			require.Equal(t, testCase.rcode, response.Rcode)
+			require.Equal(t, testCase.secure, response.AuthenticatedData)
+			if testCase.answers > 0 {
+				require.Len(t, response.Answer, testCase.answers)
+			}
+			for _, record := range append(response.Answer, response.Ns...) {
+				require.NotEqual(t, dns.TypeRRSIG, record.Header().Rrtype)
+			}
+			if testCase.extendedError != 0 {
+				opt := response.IsEdns0()
+				require.NotNil(t, opt)
+				require.Len(t, opt.Option, 1)
+				require.Equal(t, testCase.extendedError, opt.Option[0].(*dns.EDNS0_EDE).InfoCode)
+			}
+		})
+	}
+}
+
+func TestClientDNSSECDisabled(t *testing.T) {
+	t.Parallel()
+	client, transport := newDNSSECTestClient(t)
+	message := new(dns.Msg)
+	message.SetQuestion("tampered.example.com.", dns.TypeA)
+	response, err := client.Exchange(context.Background(), transport, message, adapter.DNSQueryOptions{DisableDNSSEC: true}, nil)
+	require.NoError(t, err)
+	require.Equal(t, dns.RcodeSuccess, response.Rcode)
+	require.Len(t, response.Answer, 1)
+}
+
+func TestClientDNSSECCheckingDisabled(t *testing.T) {
+	t.Parallel()
+	client, transport := newDNSSECTestClient(t)
+	message := new(dns.Msg)
+	message.SetQuestion("tampered.example.com.", dns.TypeA)
+	message.CheckingDisabled = true
+	response, err := client.Exchange(context.Background(), transport, message, adapter.DNSQueryOptions{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, dns.RcodeSuccess, response.Rcode)
+	require.True(t, response.CheckingDisabled)
+	require.False(t, response.AuthenticatedData)
+	require.Len(t, response.Answer, 1)
+}
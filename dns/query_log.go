@@ -0,0 +1,191 @@
+package dns
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/common/observable"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const (
+	defaultQueryLogCapacity = 1000
+	maxQueryLogCapacity     = 100000
+	queryLogSaveInterval    = 5 * time.Minute
+)
+
+var _ adapter.DNSQueryLog = (*queryLog)(nil)
+
+type queryLog struct {
+	logger    logger.Logger
+	access    sync.RWMutex
+	entries   []adapter.DNSQueryLogEntry
+	capacity  int
+	head      int
+	lastID    uint64
+	observer  *observable.Observer[adapter.DNSQueryLogEntry]
+	cacheFile adapter.CacheFile
+	saveDone  chan struct{}
+}
+
+func newQueryLog(logger logger.Logger, capacity uint32) (*queryLog, error) {
+	if capacity == 0 {
+		capacity = defaultQueryLogCapacity
+	} else if capacity > maxQueryLogCapacity {
+		return nil, E.New("query log capacity too large: ", capacity, ", max ", maxQueryLogCapacity)
+	}
+	subscriber := observable.NewSubscriber[adapter.DNSQueryLogEntry](128)
+	return &queryLog{
+		logger:   logger,
+		entries:  make([]adapter.DNSQueryLogEntry, 0, capacity),
+		capacity: int(capacity),
+		observer: observable.NewObserver[adapter.DNSQueryLogEntry](subscriber, 64),
+	}, nil
+}
+
+// restore loads saved entries, and saves entries periodically until closed.
+func (l *queryLog) restore(cacheFile adapter.CacheFile) {
+	savedEntries := cacheFile.LoadDNSQueryLog()
+	if len(savedEntries) > l.capacity {
+		savedEntries = savedEntries[len(savedEntries)-l.capacity:]
+	}
+	l.access.Lock()
+	l.entries = append(l.entries[:0], savedEntries...)
+	l.head = len(l.entries) % l.capacity
+	if len(savedEntries) > 0 {
+		l.lastID = savedEntries[len(savedEntries)-1].ID
+	}
+	l.access.Unlock()
+	l.cacheFile = cacheFile
+	l.saveDone = make(chan struct{})
+	go l.loopSave()
+}
+
+func (l *queryLog) add(entry adapter.DNSQueryLogEntry) {
+	l.access.Lock()
+	l.lastID++
+	entry.ID = l.lastID
+	if len(l.entries) < l.capacity {
+		l.entries = append(l.entries, entry)
+	} else {
+		l.entries[l.head] = entry
+	}
+	l.head = (l.head + 1) % l.capacity
+	l.access.Unlock()
+	l.observer.Emit(entry)
+}
+
+func (l *queryLog) Entries(filter adapter.DNSQueryLogFilter) []adapter.DNSQueryLogEntry {
+	l.access.RLock()
+	defer l.access.RUnlock()
+	var entries []adapter.DNSQueryLogEntry
+	for i := 1; i <= len(l.entries); i++ {
+		entry := &l.entries[(l.head-i+len(l.entries))%len(l.entries)]
+		if !filter.Match(entry) {
+			continue
+		}
+		entries = append(entries, *entry)
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+	}
+	return entries
+}
+
+func (l *queryLog) Clear() {
+	l.access.Lock()
+	l.entries = l.entries[:0]
+	l.head = 0
+	l.access.Unlock()
+}
+
+func (l *queryLog) Subscribe() (subscription observable.Subscription[adapter.DNSQueryLogEntry], done <-chan struct{}, err error) {
+	return l.observer.Subscribe()
+}
+
+func (l *queryLog) UnSubscribe(subscription observable.Subscription[adapter.DNSQueryLogEntry]) {
+	l.observer.UnSubscribe(subscription)
+}
+
+// savedEntries returns the entries in ascending order of ID.
+func (l *queryLog) savedEntries() []adapter.DNSQueryLogEntry {
+	l.access.RLock()
+	defer l.access.RUnlock()
+	entries := make([]adapter.DNSQueryLogEntry, 0, len(l.entries))
+	if len(l.entries) == l.capacity {
+		entries = append(entries, l.entries[l.head:]...)
+		return append(entries, l.entries[:l.head]...)
+	}
+	return append(entries, l.entries...)
+}
+
+func (l *queryLog) loopSave() {
+	ticker := time.NewTicker(queryLogSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.saveDone:
+			return
+		case <-ticker.C:
+			err := l.cacheFile.SaveDNSQueryLog(l.savedEntries())
+			if err != nil {
+				l.logger.Error(E.Cause(err, "save DNS query log"))
+			}
+		}
+	}
+}
+
+func (l *queryLog) Close() error {
+	var err error
+	if l.saveDone != nil {
+		close(l.saveDone)
+		err = l.cacheFile.SaveDNSQueryLog(l.savedEntries())
+		if err != nil {
+			err = E.Cause(err, "save DNS query log")
+		}
+	}
+	l.observer.Close()
+	return err
+}
+
+func newQueryLogEntry(ctx context.Context, message *mDNS.Msg, startedAt time.Time, response *mDNS.Msg, transport adapter.DNSTransport, rule adapter.DNSRule, err error) adapter.DNSQueryLogEntry {
+	entry := adapter.DNSQueryLogEntry{
+		Time:      startedAt,
+		Duration:  time.Since(startedAt),
+		Domain:    FqdnToDomain(message.Question[0].Name),
+		QueryType: mDNS.Type(message.Question[0].Qtype).String(),
+	}
+	if metadata := adapter.ContextFrom(ctx); metadata != nil {
+		entry.Client = metadata.Source.Addr.Unmap()
+		entry.Inbound = metadata.Inbound
+	}
+	if rule != nil {
+		if ruleDescription := rule.String(); ruleDescription != "" {
+			entry.Rule = F.ToString(rule, " => ", rule.Action())
+		} else {
+			entry.Rule = rule.Action().String()
+		}
+	}
+	if transport != nil {
+		entry.Transport = transport.Tag()
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	if response == nil {
+		return entry
+	}
+	entry.Rcode = mDNS.RcodeToString[response.Rcode]
+	for _, record := range response.Answer {
+		header := record.Header()
+		entry.Answers = append(entry.Answers, mDNS.Type(header.Rrtype).String()+" "+record.String()[len(header.String()):])
+	}
+	return entry
+}
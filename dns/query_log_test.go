@@ -0,0 +1,102 @@
+package dns
+
+import (
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLog(t *testing.T) {
+	t.Parallel()
+	queryLog, err := newQueryLog(log.NewNOPFactory().Logger(), 3)
+	require.NoError(t, err)
+	defer queryLog.Close()
+	subscription, done, err := queryLog.Subscribe()
+	require.NoError(t, err)
+	for i := range 5 {
+		queryLog.add(adapter.DNSQueryLogEntry{
+			Client: netip.AddrFrom4([4]byte{192, 168, 1, byte(i)}),
+			Domain: "example" + strconv.Itoa(i) + ".com",
+			Rcode:  "NOERROR",
+		})
+	}
+	select {
+	case entry := <-subscription:
+		require.Equal(t, uint64(1), entry.ID)
+	case <-done:
+		t.Fatal("subscription closed")
+	case <-time.After(time.Second):
+		t.Fatal("no entry emitted")
+	}
+
+	entries := queryLog.Entries(adapter.DNSQueryLogFilter{})
+	require.Len(t, entries, 3)
+	for i, entry := range entries {
+		require.Equal(t, uint64(5-i), entry.ID)
+	}
+	require.Equal(t, []adapter.DNSQueryLogEntry{entries[1]}, queryLog.Entries(adapter.DNSQueryLogFilter{Domain: "EXAMPLE3"}))
+	require.Equal(t, []adapter.DNSQueryLogEntry{entries[0]}, queryLog.Entries(adapter.DNSQueryLogFilter{Client: netip.MustParsePrefix("192.168.1.4/32")}))
+	require.Equal(t, entries[1:], queryLog.Entries(adapter.DNSQueryLogFilter{Before: 5}))
+	require.Equal(t, entries[:2], queryLog.Entries(adapter.DNSQueryLogFilter{Rcode: "noerror", Limit: 2}))
+	require.Empty(t, queryLog.Entries(adapter.DNSQueryLogFilter{Rcode: "error"}))
+
+	savedEntries := queryLog.savedEntries()
+	require.Len(t, savedEntries, 3)
+	for i, entry := range savedEntries {
+		require.Equal(t, uint64(i+3), entry.ID)
+		entryBinary, err := entry.MarshalBinary()
+		require.NoError(t, err)
+		var loadedEntry adapter.DNSQueryLogEntry
+		require.NoError(t, loadedEntry.UnmarshalBinary(entryBinary))
+		require.Equal(t, entry.Domain, loadedEntry.Domain)
+		require.Equal(t, entry.Client, loadedEntry.Client)
+	}
+
+	queryLog.Clear()
+	require.Empty(t, queryLog.Entries(adapter.DNSQueryLogFilter{}))
+}
+
+func TestQueryLogCapacity(t *testing.T) {
+	t.Parallel()
+	_, err := newQueryLog(log.NewNOPFactory().Logger(), maxQueryLogCapacity+1)
+	require.Error(t, err)
+	queryLog, err := newQueryLog(log.NewNOPFactory().Logger(), 0)
+	require.NoError(t, err)
+	require.Equal(t, defaultQueryLogCapacity, queryLog.capacity)
+	require.NoError(t, queryLog.Close())
+}
+
+func TestQueryLogClose(t *testing.T) {
+	t.Parallel()
+	queryLog, err := newQueryLog(log.NewNOPFactory().Logger(), 3)
+	require.NoError(t, err)
+	_, done, err := queryLog.Subscribe()
+	require.NoError(t, err)
+	require.NoError(t, queryLog.Close())
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("subscription not closed")
+	}
+	_, _, err = queryLog.Subscribe()
+	require.Error(t, err)
+}
+
+func TestQueryLogEntry(t *testing.T) {
+	t.Parallel()
+	entry := adapter.DNSQueryLogEntry{ID: 1, Domain: "WWW.Example.com"}
+	require.True(t, adapter.DNSQueryLogFilter{Domain: "example.COM"}.Match(&entry))
+	require.False(t, adapter.DNSQueryLogFilter{Domain: "example.net"}.Match(&entry))
+
+	entryBinary, err := entry.MarshalBinary()
+	require.NoError(t, err)
+	entryBinary[0] = 2
+	var loadedEntry adapter.DNSQueryLogEntry
+	require.Error(t, loadedEntry.UnmarshalBinary(entryBinary))
+}
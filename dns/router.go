@@ -32,6 +32,7 @@ import (
 var (
 	_ adapter.DNSRouter                 = (*Router)(nil)
 	_ adapter.DNSRuleSetUpdateValidator = (*Router)(nil)
+	_ adapter.DNSQueryLogRouter         = (*Router)(nil)
 )
 
 type Router struct {
@@ -44,6 +45,7 @@ type Router struct {
 	rules                 []adapter.DNSRule
 	defaultDomainStrategy C.DomainStrategy
 	dnsReverseMapping     freelru.Cache[netip.Addr, string]
+	queryLog              *queryLog
 	platformInterface     adapter.PlatformInterface
 	legacyDNSMode         bool
 	rulesAccess           sync.RWMutex
@@ -89,6 +91,7 @@ func NewRouter(ctx context.Context, logFactory log.Factory, options option.DNSOp
 		CacheMaxTTL:       options.DNSClientOptions.CacheMaxTTL,
 		CacheRoundRobin:   options.DNSClientOptions.CacheRoundRobin,
 		ClientSubnet:      options.DNSClientOptions.ClientSubnet.Build(netip.Prefix{}),
+		DNSSEC:            options.DNSClientOptions.DNSSEC,
 		RDRC: func() adapter.RDRCStore {
 			cacheFile := service.FromContext[adapter.CacheFile](ctx)
 			if cacheFile == nil {
@@ -120,6 +123,13 @@ func NewRouter(ctx context.Context, logFactory log.Factory, options option.DNSOp
 		}
 		router.dnsReverseMapping = common.Must1(freelru.NewSharded[netip.Addr, string](capacity, maphash.NewHasher[netip.Addr]().Hash32))
 	}
+	if options.QueryLog != nil && options.QueryLog.Enabled {
+		var err error
+		router.queryLog, err = newQueryLog(router.logger, options.QueryLog.Capacity)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return router, nil
 }
 
@@ -163,6 +173,12 @@ func (r *Router) Start(stage adapter.StartStage) error {
 		if legacyDNSMode && modeFlags.neededFromStrategy {
 			deprecated.Report(r.ctx, deprecated.OptionLegacyDNSRuleStrategy)
 		}
+		if r.queryLog != nil {
+			cacheFile := service.FromContext[adapter.CacheFile](r.ctx)
+			if cacheFile != nil && cacheFile.StoreDNSQueryLog() {
+				r.queryLog.restore(cacheFile)
+			}
+		}
 	}
 	return nil
 }
@@ -178,9 +194,19 @@ func (r *Router) Close() error {
 	r.rules = nil
 	r.rulesAccess.Unlock()
 	closeRules(runtimeRules)
+	if r.queryLog != nil {
+		return r.queryLog.Close()
+	}
 	return nil
 }
 
+func (r *Router) QueryLog() adapter.DNSQueryLog {
+	if r.queryLog == nil {
+		return nil
+	}
+	return r.queryLog
+}
+
 func (r *Router) buildRules(startRules bool) ([]adapter.DNSRule, bool, dnsRuleModeFlags, error) {
 	for i, ruleOptions := range r.rawRules {
 		err := R.ValidateNoNestedDNSRuleActions(ruleOptions)
@@ -285,6 +311,7 @@ func (r *Router) matchDNS(ctx context.Context, rules []adapter.DNSRule, allowFak
 	if metadata == nil {
 		panic("no context")
 	}
+	tracer := adapter.RuleTracerFromContext(ctx)
 	var currentRuleIndex int
 	if ruleIndex != -1 {
 		currentRuleIndex = ruleIndex + 1
@@ -296,7 +323,15 @@ func (r *Router) matchDNS(ctx context.Context, rules []adapter.DNSRule, allowFak
 		}
 		metadata.ResetRuleCache()
 		metadata.DestinationAddressMatchFromResponse = false
-		if currentRule.LegacyPreMatch(metadata) {
+		matched := currentRule.LegacyPreMatch(metadata)
+		if tracer != nil {
+			scope := "dns"
+			if metadata.QueryType != 0 {
+				scope += " " + mDNS.TypeToString[metadata.QueryType]
+			}
+			tracer.TraceRule(scope, currentRuleIndex, currentRule, matched)
+		}
+		if matched {
 			if ruleDescription := currentRule.String(); ruleDescription != "" {
 				r.logger.DebugContext(ctx, "match[", currentRuleIndex, "] ", currentRule, " => ", currentRule.Action())
 			} else {
@@ -319,6 +354,9 @@ func (r *Router) matchDNS(ctx context.Context, rules []adapter.DNSRule, allowFak
 				if isFakeIP || action.DisableCache {
 					options.DisableCache = true
 				}
+				if action.DisableDNSSEC {
+					options.DisableDNSSEC = true
+				}
 				if action.RewriteTTL != nil {
 					options.RewriteTTL = action.RewriteTTL
 				}
@@ -336,6 +374,9 @@ func (r *Router) matchDNS(ctx context.Context, rules []adapter.DNSRule, allowFak
 				if action.DisableCache {
 					options.DisableCache = true
 				}
+				if action.DisableDNSSEC {
+					options.DisableDNSSEC = true
+				}
 				if action.RewriteTTL != nil {
 					options.RewriteTTL = action.RewriteTTL
 				}
@@ -366,6 +407,9 @@ func (r *Router) applyDNSRouteOptions(options *adapter.DNSQueryOptions, routeOpt
 	if routeOptions.DisableOptimisticCache {
 		options.DisableOptimisticCache = true
 	}
+	if routeOptions.DisableDNSSEC {
+		options.DisableDNSSEC = true
+	}
 	if routeOptions.RewriteTTL != nil {
 		options.RewriteTTL = routeOptions.RewriteTTL
 	}
@@ -412,6 +456,7 @@ func (r *Router) logRuleMatch(ctx context.Context, ruleIndex int, currentRule ad
 type exchangeWithRulesResult struct {
 	response     *mDNS.Msg
 	transport    adapter.DNSTransport
+	rule         adapter.DNSRule
 	rejectAction *R.RuleActionReject
 	err          error
 }
@@ -426,11 +471,16 @@ func (r *Router) exchangeWithRules(ctx context.Context, rules []adapter.DNSRule,
 	effectiveOptions := options
 	var evaluatedResponse *mDNS.Msg
 	var evaluatedTransport adapter.DNSTransport
+	tracer := adapter.RuleTracerFromContext(ctx)
 	for currentRuleIndex, currentRule := range rules {
 		metadata.ResetRuleCache()
 		metadata.DNSResponse = evaluatedResponse
 		metadata.DestinationAddressMatchFromResponse = false
-		if !currentRule.Match(metadata) {
+		matched := currentRule.Match(metadata)
+		if tracer != nil {
+			tracer.TraceRule("dns "+mDNS.TypeToString[message.Question[0].Qtype], currentRuleIndex, currentRule, matched)
+		}
+		if !matched {
 			continue
 		}
 		r.logRuleMatch(ctx, currentRuleIndex, currentRule)
@@ -463,10 +513,12 @@ func (r *Router) exchangeWithRules(ctx context.Context, rules []adapter.DNSRule,
 		case *R.RuleActionRespond:
 			if evaluatedResponse == nil {
 				return exchangeWithRulesResult{
-					err: E.New(dnsRespondMissingResponseMessage),
+					rule: currentRule,
+					err:  E.New(dnsRespondMissingResponseMessage),
 				}
 			}
 			return exchangeWithRulesResult{
+				rule:      currentRule,
 				response:  evaluatedResponse,
 				transport: evaluatedTransport,
 			}
@@ -486,6 +538,7 @@ func (r *Router) exchangeWithRules(ctx context.Context, rules []adapter.DNSRule,
 			}
 			response, err := r.client.Exchange(adapter.OverrideContext(ctx), transport, message, exchangeOptions, nil)
 			return exchangeWithRulesResult{
+				rule:      currentRule,
 				response:  response,
 				transport: transport,
 				err:       err,
@@ -494,6 +547,7 @@ func (r *Router) exchangeWithRules(ctx context.Context, rules []adapter.DNSRule,
 			switch action.Method {
 			case C.RuleActionRejectMethodDefault:
 				return exchangeWithRulesResult{
+					rule: currentRule,
 					response: &mDNS.Msg{
 						MsgHdr: mDNS.MsgHdr{
 							Id:       message.Id,
@@ -506,6 +560,7 @@ func (r *Router) exchangeWithRules(ctx context.Context, rules []adapter.DNSRule,
 				}
 			case C.RuleActionRejectMethodDrop:
 				return exchangeWithRulesResult{
+					rule:         currentRule,
 					rejectAction: action,
 					err:          tun.ErrDrop,
 				}
@@ -520,12 +575,14 @@ func (r *Router) exchangeWithRules(ctx context.Context, rules []adapter.DNSRule,
 					response = FixedResponse(message.Id, message.Question[0], nil, 0)
 				}
 				return exchangeWithRulesResult{
+					rule:         currentRule,
 					response:     response,
 					rejectAction: action,
 				}
 			}
 		case *R.RuleActionPredefined:
 			return exchangeWithRulesResult{
+				rule:     currentRule,
 				response: action.Response(message),
 			}
 		}
@@ -640,6 +697,17 @@ func (r *Router) lookupWithRulesType(ctx context.Context, rules []adapter.DNSRul
 }
 
 func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapter.DNSQueryOptions) (*mDNS.Msg, error) {
+	if r.queryLog == nil || len(message.Question) != 1 {
+		response, _, _, err := r.exchange(ctx, message, options)
+		return response, err
+	}
+	startedAt := time.Now()
+	response, transport, rule, err := r.exchange(ctx, message, options)
+	r.queryLog.add(newQueryLogEntry(ctx, message, startedAt, response, transport, rule, err))
+	return response, err
+}
+
+func (r *Router) exchange(ctx context.Context, message *mDNS.Msg, options adapter.DNSQueryOptions) (*mDNS.Msg, adapter.DNSTransport, adapter.DNSRule, error) {
 	if len(message.Question) != 1 {
 		r.logger.WarnContext(ctx, "bad question size: ", len(message.Question))
 		responseMessage := mDNS.Msg{
@@ -650,12 +718,12 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 			},
 			Question: message.Question,
 		}
-		return &responseMessage, nil
+		return &responseMessage, nil, nil, nil
 	}
 	r.rulesAccess.RLock()
 	if r.closing {
 		r.rulesAccess.RUnlock()
-		return nil, E.New("dns router closed")
+		return nil, nil, nil, E.New("dns router closed")
 	}
 	rules := r.rules
 	legacyDNSMode := r.legacyDNSMode
@@ -664,6 +732,7 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 	var (
 		response  *mDNS.Msg
 		transport adapter.DNSTransport
+		rule      adapter.DNSRule
 		err       error
 	)
 	var metadata *adapter.InboundContext
@@ -687,13 +756,9 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 		response, err = r.client.Exchange(ctx, transport, message, options, nil)
 	} else if !legacyDNSMode {
 		exchangeResult := r.exchangeWithRules(ctx, rules, message, options, true)
-		response, transport, err = exchangeResult.response, exchangeResult.transport, exchangeResult.err
+		response, transport, rule, err = exchangeResult.response, exchangeResult.transport, exchangeResult.rule, exchangeResult.err
 	} else {
-		var (
-			rule      adapter.DNSRule
-			ruleIndex int
-		)
-		ruleIndex = -1
+		ruleIndex := -1
 		for {
 			dnsCtx := adapter.OverrideContext(ctx)
 			dnsOptions := options
@@ -714,17 +779,17 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 								Response: true,
 							},
 							Question: []mDNS.Question{message.Question[0]},
-						}, nil
+						}, transport, rule, nil
 					case C.RuleActionRejectMethodDrop:
-						return nil, tun.ErrDrop
+						return nil, transport, rule, tun.ErrDrop
 					case C.RuleActionRejectMethodNullIP:
 						switch message.Question[0].Qtype {
 						case mDNS.TypeA:
-							return FixedResponse(message.Id, message.Question[0], []netip.Addr{netip.IPv4Unspecified()}, ttl), nil
+							return FixedResponse(message.Id, message.Question[0], []netip.Addr{netip.IPv4Unspecified()}, ttl), transport, rule, nil
 						case mDNS.TypeAAAA:
-							return FixedResponse(message.Id, message.Question[0], []netip.Addr{netip.IPv6Unspecified()}, ttl), nil
+							return FixedResponse(message.Id, message.Question[0], []netip.Addr{netip.IPv6Unspecified()}, ttl), transport, rule, nil
 						default:
-							return FixedResponse(message.Id, message.Question[0], nil, ttl), nil
+							return FixedResponse(message.Id, message.Question[0], nil, ttl), transport, rule, nil
 						}
 					}
 				case *R.RuleActionPredefined:
@@ -760,7 +825,7 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 	}
 done:
 	if err != nil {
-		return nil, err
+		return nil, transport, rule, err
 	}
 	if r.dnsReverseMapping != nil && len(message.Question) > 0 && response != nil && len(response.Answer) > 0 {
 		if transport == nil || transport.Type() != C.DNSTypeFakeIP {
@@ -774,7 +839,7 @@ done:
 			}
 		}
 	}
-	return response, nil
+	return response, transport, rule, nil
 }
 
 func (r *Router) Lookup(ctx context.Context, domain string, options adapter.DNSQueryOptions) ([]netip.Addr, error) {
@@ -1104,8 +1169,8 @@ func validateLegacyDNSModeDisabledRuleTree(router adapter.Router, rule option.DN
 
 func validateLegacyDNSModeDisabledDefaultRule(router adapter.Router, rule option.DefaultDNSRule, metadataOverrides map[string]adapter.RuleSetMetadata) (bool, error) {
 	hasResponseRecords := hasResponseMatchFields(rule)
-	if (hasResponseRecords || len(rule.IPCIDR) > 0 || rule.IPIsPrivate || rule.IPAcceptAny) && !rule.MatchResponse {
-		return false, E.New("Response Match Fields (ip_cidr, ip_is_private, ip_accept_any, response_rcode, response_answer, response_ns, response_extra) require match_response to be enabled")
+	if (hasResponseRecords || len(rule.IPCIDR) > 0 || len(rule.IPASN) > 0 || rule.IPIsPrivate || rule.IPAcceptAny) && !rule.MatchResponse {
+		return false, E.New("Response Match Fields (ip_cidr, ip_asn, ip_is_private, ip_accept_any, response_rcode, response_answer, response_ns, response_extra) require match_response to be enabled")
 	}
 	// rule_set entries are only rejected when every referenced set is pure-IP;
 	// mixed sets still fall through because their non-IP branches remain matchable
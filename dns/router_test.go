@@ -112,12 +112,18 @@ func (r *fakeRouter) RuleSet(tag string) (adapter.RuleSet, bool) {
 	return ruleSet, loaded
 }
 
-func (r *fakeRouter) Rules() []adapter.Rule                      { return nil }
-func (r *fakeRouter) NeedFindProcess() bool                      { return false }
-func (r *fakeRouter) NeedFindNeighbor() bool                     { return false }
-func (r *fakeRouter) NeighborResolver() adapter.NeighborResolver { return nil }
-func (r *fakeRouter) AppendTracker(adapter.ConnectionTracker)    {}
-func (r *fakeRouter) ResetNetwork()                              {}
+func (r *fakeRouter) Rules() []adapter.Rule                            { return nil }
+func (r *fakeRouter) NeedFindProcess() bool                            { return false }
+func (r *fakeRouter) NeedFindNeighbor() bool                           { return false }
+func (r *fakeRouter) NeighborResolver() adapter.NeighborResolver       { return nil }
+func (r *fakeRouter) ASNReader() adapter.ASNReader                     { return nil }
+func (r *fakeRouter) AppendTracker(adapter.ConnectionTracker)          {}
+func (r *fakeRouter) ResetNetwork()                                    {}
+func (r *fakeRouter) OutboundManager() adapter.OutboundManager         { return nil }
+func (r *fakeRouter) ProviderManager() adapter.OutboundProviderManager { return nil }
+func (r *fakeRouter) RuleSets() []adapter.RuleSet                      { return nil }
+func (r *fakeRouter) Trackers() []adapter.ConnectionTracker            { return nil }
+func (r *fakeRouter) Reload()                                          {}
 
 type fakeRuleSet struct {
 	access                   sync.Mutex
@@ -131,6 +137,11 @@ type fakeRuleSet struct {
 }
 
 func (s *fakeRuleSet) Name() string                                                  { return "fake-rule-set" }
+func (s *fakeRuleSet) Type() string                                                  { return C.RuleSetTypeInline }
+func (s *fakeRuleSet) Format() string                                                { return C.RuleSetFormatSource }
+func (s *fakeRuleSet) UpdatedTime() time.Time                                        { return time.Time{} }
+func (s *fakeRuleSet) Update(context.Context) error                                  { return nil }
+func (s *fakeRuleSet) RuleCount() uint64                                             { return 0 }
 func (s *fakeRuleSet) StartContext(context.Context, *adapter.HTTPStartContext) error { return nil }
 func (s *fakeRuleSet) PostStart() error                                              { return nil }
 func (s *fakeRuleSet) Metadata() adapter.RuleSetMetadata {
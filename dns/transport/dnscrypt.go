@@ -0,0 +1,260 @@
+package transport
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/binary"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/dialer"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio/deadline"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const (
+	dnscryptCertRefreshInterval = time.Hour
+	dnscryptMinUDPQuerySize     = 256
+)
+
+var _ adapter.DNSTransport = (*DNSCryptTransport)(nil)
+
+func RegisterDNSCrypt(registry *dns.TransportRegistry) {
+	dns.RegisterTransport[option.DNSCryptDNSServerOptions](registry, C.DNSTypeDNSCrypt, NewDNSCrypt)
+}
+
+type DNSCryptTransport struct {
+	dns.TransportAdapter
+	logger       logger.ContextLogger
+	dialer       N.Dialer
+	serverAddr   M.Socksaddr
+	providerName string
+	providerKey  ed25519.PublicKey
+	plain        *UDPTransport
+
+	certAccess    sync.Mutex
+	cert          *dnscryptCert
+	certUpdatedAt time.Time
+}
+
+func NewDNSCrypt(ctx context.Context, logger log.ContextLogger, tag string, options option.DNSCryptDNSServerOptions) (adapter.DNSTransport, error) {
+	if options.Stamp == "" {
+		return nil, E.New("missing stamp")
+	}
+	stamp, err := parseDNSCryptStamp(options.Stamp)
+	if err != nil {
+		return nil, E.Cause(err, "parse stamp")
+	}
+	remoteOptions := option.RemoteDNSServerOptions{
+		RawLocalDNSServerOptions: options.RawLocalDNSServerOptions,
+		DNSServerAddressOptions: option.DNSServerAddressOptions{
+			Server:     stamp.serverAddr.AddrString(),
+			ServerPort: stamp.serverAddr.Port,
+		},
+	}
+	transportDialer, err := dns.NewRemoteDialer(ctx, remoteOptions)
+	if err != nil {
+		return nil, err
+	}
+	transportAdapter := dns.NewTransportAdapterWithRemoteOptions(C.DNSTypeDNSCrypt, tag, remoteOptions)
+	return &DNSCryptTransport{
+		TransportAdapter: transportAdapter,
+		logger:           logger,
+		dialer:           transportDialer,
+		serverAddr:       stamp.serverAddr,
+		providerName:     stamp.providerName,
+		providerKey:      stamp.publicKey,
+		plain:            NewUDPRaw(logger, transportAdapter, transportDialer, stamp.serverAddr),
+	}, nil
+}
+
+func (t *DNSCryptTransport) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	return dialer.InitializeDetour(t.dialer)
+}
+
+func (t *DNSCryptTransport) Close() error {
+	return t.plain.Close()
+}
+
+func (t *DNSCryptTransport) Reset() {
+	t.plain.Reset()
+}
+
+func (t *DNSCryptTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	cert, err := t.certificate(ctx)
+	if err != nil {
+		return nil, E.Cause(err, "fetch certificate")
+	}
+	response, err := t.exchange(ctx, cert, message, N.NetworkUDP)
+	if err != nil {
+		return nil, err
+	}
+	if response.Truncated {
+		t.logger.InfoContext(ctx, "response truncated, retrying with TCP")
+		return t.exchange(ctx, cert, message, N.NetworkTCP)
+	}
+	return response, nil
+}
+
+// certificate returns the current resolver certificate, and fetches a new one
+// once per refresh interval or when it expires, so that rotated keys are
+// picked up.
+func (t *DNSCryptTransport) certificate(ctx context.Context) (*dnscryptCert, error) {
+	t.certAccess.Lock()
+	defer t.certAccess.Unlock()
+	now := time.Now()
+	if t.cert != nil && now.Sub(t.certUpdatedAt) < dnscryptCertRefreshInterval && now.Before(t.cert.notAfter) {
+		return t.cert, nil
+	}
+	cert, err := t.fetchCertificate(ctx, now)
+	if err != nil {
+		if t.cert != nil && now.Before(t.cert.notAfter) {
+			t.logger.WarnContext(ctx, "refresh certificate: ", err)
+			t.certUpdatedAt = now
+			return t.cert, nil
+		}
+		return nil, err
+	}
+	if t.cert == nil || t.cert.serial != cert.serial {
+		t.logger.DebugContext(ctx, "using certificate serial ", cert.serial, " valid until ", cert.notAfter.Format(time.RFC3339))
+	}
+	t.cert = cert
+	t.certUpdatedAt = now
+	return cert, nil
+}
+
+func (t *DNSCryptTransport) fetchCertificate(ctx context.Context, now time.Time) (*dnscryptCert, error) {
+	message := new(mDNS.Msg)
+	message.SetQuestion(t.providerName, mDNS.TypeTXT)
+	response, err := t.plain.Exchange(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	var bestCert *dnscryptCert
+	for _, record := range response.Answer {
+		txt, isTXT := record.(*mDNS.TXT)
+		if !isTXT {
+			continue
+		}
+		cert, err := parseDNSCryptCert(unescapeTXT(strings.Join(txt.Txt, "")), t.providerKey, now)
+		if err != nil {
+			t.logger.DebugContext(ctx, "skip certificate: ", err)
+			continue
+		}
+		if bestCert == nil || cert.serial > bestCert.serial || cert.serial == bestCert.serial && cert.esVersion > bestCert.esVersion {
+			bestCert = cert
+		}
+	}
+	if bestCert == nil {
+		return nil, E.New("no valid certificate found for ", t.providerName)
+	}
+	err = bestCert.generateKey()
+	if err != nil {
+		return nil, err
+	}
+	return bestCert, nil
+}
+
+func (t *DNSCryptTransport) exchange(ctx context.Context, cert *dnscryptCert, message *mDNS.Msg, network string) (*mDNS.Msg, error) {
+	rawMessage, err := message.Pack()
+	if err != nil {
+		return nil, err
+	}
+	var minSize int
+	if network == N.NetworkUDP {
+		minSize = dnscryptMinUDPQuerySize
+	}
+	query, clientNonce, err := cert.encryptQuery(rawMessage, minSize)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := t.dialer.DialContext(ctx, network, t.serverAddr)
+	if err != nil {
+		return nil, E.Cause(err, "dial ", network, " connection")
+	}
+	defer conn.Close()
+	defer setConnDeadline(ctx, conn, deadline.NeedAdditionalReadDeadline(conn))()
+	var rawResponse []byte
+	if network == N.NetworkTCP {
+		buffer := buf.NewSize(2 + len(query))
+		defer buffer.Release()
+		binary.BigEndian.PutUint16(buffer.Extend(2), uint16(len(query)))
+		buffer.Write(query)
+		_, err = conn.Write(buffer.Bytes())
+		if err != nil {
+			return nil, E.Cause(err, "write request")
+		}
+		var responseLength uint16
+		err = binary.Read(conn, binary.BigEndian, &responseLength)
+		if err != nil {
+			return nil, E.Cause(err, "read response")
+		}
+		rawResponse = make([]byte, responseLength)
+		_, err = io.ReadFull(conn, rawResponse)
+		if err != nil {
+			return nil, E.Cause(err, "read response")
+		}
+	} else {
+		_, err = conn.Write(query)
+		if err != nil {
+			return nil, E.Cause(err, "write request")
+		}
+		buffer := buf.NewSize(buf.UDPBufferSize)
+		defer buffer.Release()
+		_, err = buffer.ReadOnceFrom(conn)
+		if err != nil {
+			return nil, E.Cause(err, "read response")
+		}
+		rawResponse = buffer.Bytes()
+	}
+	plaintext, err := cert.decryptResponse(rawResponse, clientNonce)
+	if err != nil {
+		return nil, err
+	}
+	var response mDNS.Msg
+	err = response.Unpack(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// unescapeTXT reverses the presentation format escaping applied by miekg/dns
+// to binary TXT data.
+func unescapeTXT(s string) []byte {
+	data := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			data = append(data, s[i])
+			continue
+		}
+		i++
+		if i+2 < len(s) && isDigit(s[i]) && isDigit(s[i+1]) && isDigit(s[i+2]) {
+			data = append(data, (s[i]-'0')*100+(s[i+1]-'0')*10+(s[i+2]-'0'))
+			i += 2
+		} else {
+			data = append(data, s[i])
+		}
+	}
+	return data
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
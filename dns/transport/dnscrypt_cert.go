@@ -0,0 +1,221 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/poly1305" //nolint:staticcheck
+)
+
+const (
+	dnscryptESVersionXSalsa20Poly1305  = 0x0001
+	dnscryptESVersionXChacha20Poly1305 = 0x0002
+)
+
+const (
+	dnscryptCertSize    = 124
+	dnscryptNonceSize   = 24
+	dnscryptHalfNonce   = dnscryptNonceSize / 2
+	dnscryptTagSize     = 16
+	dnscryptPaddingUnit = 64
+)
+
+var (
+	dnscryptCertMagic     = []byte("DNSC")
+	dnscryptResolverMagic = []byte("r6fnvWj8")
+)
+
+// dnscryptCert is a resolver certificate, with the client key pair and the
+// shared key derived from it.
+//
+// https://dnscrypt.info/protocol
+type dnscryptCert struct {
+	esVersion   uint16
+	resolverKey []byte
+	clientMagic []byte
+	serial      uint32
+	notBefore   time.Time
+	notAfter    time.Time
+
+	clientPublicKey []byte
+	sharedKey       [32]byte
+}
+
+func parseDNSCryptCert(data []byte, providerKey []byte, now time.Time) (*dnscryptCert, error) {
+	if len(data) < dnscryptCertSize {
+		return nil, E.New("invalid certificate length: ", len(data))
+	}
+	if !bytes.Equal(data[:4], dnscryptCertMagic) {
+		return nil, E.New("invalid certificate magic")
+	}
+	esVersion := binary.BigEndian.Uint16(data[4:6])
+	switch esVersion {
+	case dnscryptESVersionXSalsa20Poly1305, dnscryptESVersionXChacha20Poly1305:
+	default:
+		return nil, E.New("unsupported encryption system: ", esVersion)
+	}
+	if !ed25519.Verify(providerKey, data[72:], data[8:72]) {
+		return nil, E.New("invalid certificate signature")
+	}
+	cert := &dnscryptCert{
+		esVersion:   esVersion,
+		resolverKey: bytes.Clone(data[72:104]),
+		clientMagic: bytes.Clone(data[104:112]),
+		serial:      binary.BigEndian.Uint32(data[112:116]),
+		notBefore:   time.Unix(int64(binary.BigEndian.Uint32(data[116:120])), 0),
+		notAfter:    time.Unix(int64(binary.BigEndian.Uint32(data[120:124])), 0),
+	}
+	if now.Before(cert.notBefore) || now.After(cert.notAfter) {
+		return nil, E.New("certificate serial ", cert.serial, " is not valid now")
+	}
+	return cert, nil
+}
+
+// generateKey generates a new client key pair for the certificate, so that
+// queries are unlinkable across certificate rotations.
+func (c *dnscryptCert) generateKey() error {
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	c.clientPublicKey = privateKey.PublicKey().Bytes()
+	c.sharedKey, err = dnscryptSharedKey(c.esVersion, privateKey.Bytes(), c.resolverKey)
+	return err
+}
+
+func dnscryptSharedKey(esVersion uint16, privateKey []byte, publicKey []byte) ([32]byte, error) {
+	var sharedKey [32]byte
+	switch esVersion {
+	case dnscryptESVersionXSalsa20Poly1305:
+		box.Precompute(&sharedKey, (*[32]byte)(publicKey), (*[32]byte)(privateKey))
+	case dnscryptESVersionXChacha20Poly1305:
+		dhKey, err := curve25519.X25519(privateKey, publicKey)
+		if err != nil {
+			return sharedKey, err
+		}
+		subKey, err := chacha20.HChaCha20(dhKey, make([]byte, 16))
+		if err != nil {
+			return sharedKey, err
+		}
+		copy(sharedKey[:], subKey)
+	}
+	return sharedKey, nil
+}
+
+// encryptQuery returns client-magic || client-pk || client-nonce || box.
+func (c *dnscryptCert) encryptQuery(message []byte, minSize int) ([]byte, []byte, error) {
+	var nonce [dnscryptNonceSize]byte
+	_, err := rand.Read(nonce[:dnscryptHalfNonce])
+	if err != nil {
+		return nil, nil, err
+	}
+	query := make([]byte, 0, len(c.clientMagic)+len(c.clientPublicKey)+dnscryptHalfNonce+dnscryptTagSize+max(minSize, len(message)+dnscryptPaddingUnit))
+	query = append(query, c.clientMagic...)
+	query = append(query, c.clientPublicKey...)
+	query = append(query, nonce[:dnscryptHalfNonce]...)
+	query = dnscryptSeal(query, c.esVersion, &c.sharedKey, &nonce, dnscryptPad(message, minSize))
+	return query, nonce[:dnscryptHalfNonce], nil
+}
+
+// decryptResponse parses resolver-magic || nonce || box, and checks that the
+// nonce starts with the client nonce of the query.
+func (c *dnscryptCert) decryptResponse(response []byte, clientNonce []byte) ([]byte, error) {
+	headerSize := len(dnscryptResolverMagic) + dnscryptNonceSize
+	if len(response) < headerSize+dnscryptTagSize {
+		return nil, E.New("response too short")
+	}
+	if !bytes.Equal(response[:len(dnscryptResolverMagic)], dnscryptResolverMagic) {
+		return nil, E.New("invalid response magic")
+	}
+	var nonce [dnscryptNonceSize]byte
+	copy(nonce[:], response[len(dnscryptResolverMagic):headerSize])
+	if !bytes.Equal(nonce[:dnscryptHalfNonce], clientNonce) {
+		return nil, E.New("unexpected response nonce")
+	}
+	plaintext, err := dnscryptOpen(c.esVersion, &c.sharedKey, &nonce, response[headerSize:])
+	if err != nil {
+		return nil, err
+	}
+	return dnscryptUnpad(plaintext)
+}
+
+// dnscryptPad applies ISO/IEC 7816-4 padding to a multiple of 64 bytes.
+func dnscryptPad(message []byte, minSize int) []byte {
+	size := max(minSize, len(message)+1)
+	size = (size + dnscryptPaddingUnit - 1) / dnscryptPaddingUnit * dnscryptPaddingUnit
+	padded := make([]byte, size)
+	copy(padded, message)
+	padded[len(message)] = 0x80
+	return padded
+}
+
+func dnscryptUnpad(padded []byte) ([]byte, error) {
+	end := len(padded) - 1
+	for end >= 0 && padded[end] == 0 {
+		end--
+	}
+	if end < 0 || padded[end] != 0x80 {
+		return nil, E.New("invalid padding")
+	}
+	return padded[:end], nil
+}
+
+func dnscryptSeal(out []byte, esVersion uint16, key *[32]byte, nonce *[dnscryptNonceSize]byte, plaintext []byte) []byte {
+	if esVersion == dnscryptESVersionXSalsa20Poly1305 {
+		return secretbox.Seal(out, plaintext, nonce, key)
+	}
+	// XChaCha20 in the NaCl secretbox construction: the first 32 bytes of the
+	// key stream are the Poly1305 key, and the tag precedes the ciphertext.
+	stream := make([]byte, 32+len(plaintext))
+	copy(stream[32:], plaintext)
+	cipher, err := chacha20.NewUnauthenticatedCipher(key[:], nonce[:])
+	if err != nil {
+		panic(err)
+	}
+	cipher.XORKeyStream(stream, stream)
+	var polyKey [32]byte
+	copy(polyKey[:], stream[:32])
+	var tag [dnscryptTagSize]byte
+	poly1305.Sum(&tag, stream[32:], &polyKey)
+	out = append(out, tag[:]...)
+	return append(out, stream[32:]...)
+}
+
+func dnscryptOpen(esVersion uint16, key *[32]byte, nonce *[dnscryptNonceSize]byte, box []byte) ([]byte, error) {
+	if esVersion == dnscryptESVersionXSalsa20Poly1305 {
+		plaintext, ok := secretbox.Open(nil, box, nonce, key)
+		if !ok {
+			return nil, E.New("decrypt response: authentication failed")
+		}
+		return plaintext, nil
+	}
+	if len(box) < dnscryptTagSize {
+		return nil, E.New("response too short")
+	}
+	var tag [dnscryptTagSize]byte
+	copy(tag[:], box[:dnscryptTagSize])
+	ciphertext := box[dnscryptTagSize:]
+	stream := make([]byte, 32+len(ciphertext))
+	copy(stream[32:], ciphertext)
+	cipher, err := chacha20.NewUnauthenticatedCipher(key[:], nonce[:])
+	if err != nil {
+		return nil, err
+	}
+	cipher.XORKeyStream(stream, stream)
+	var polyKey [32]byte
+	copy(polyKey[:], stream[:32])
+	if !poly1305.Verify(&tag, ciphertext, &polyKey) {
+		return nil, E.New("decrypt response: authentication failed")
+	}
+	return stream[32:], nil
+}
@@ -0,0 +1,97 @@
+package transport
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+const dnsStampProtocolDNSCrypt = 0x01
+
+// dnscryptStamp is a parsed sdns:// stamp of a DNSCrypt server.
+//
+// https://dnscrypt.info/stamps-specifications
+type dnscryptStamp struct {
+	props        uint64
+	serverAddr   M.Socksaddr
+	publicKey    []byte
+	providerName string
+}
+
+func parseDNSCryptStamp(stamp string) (*dnscryptStamp, error) {
+	content, found := strings.CutPrefix(stamp, "sdns://")
+	if !found {
+		return nil, E.New("missing sdns:// prefix")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(content)
+	if err != nil {
+		return nil, E.Cause(err, "decode stamp")
+	}
+	if len(data) < 9 {
+		return nil, E.New("stamp too short")
+	}
+	if data[0] != dnsStampProtocolDNSCrypt {
+		return nil, E.New("unsupported stamp protocol: ", data[0])
+	}
+	parsed := &dnscryptStamp{
+		props: binary.LittleEndian.Uint64(data[1:9]),
+	}
+	data = data[9:]
+	var fields [3][]byte
+	for i := range fields {
+		if len(data) == 0 {
+			return nil, E.New("stamp too short")
+		}
+		length := int(data[0])
+		if len(data) < 1+length {
+			return nil, E.New("stamp too short")
+		}
+		fields[i] = data[1 : 1+length]
+		data = data[1+length:]
+	}
+	if len(data) > 0 {
+		return nil, E.New("unexpected trailing data in stamp")
+	}
+	parsed.serverAddr, err = parseStampAddress(string(fields[0]), 443)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields[1]) != 32 {
+		return nil, E.New("invalid provider public key length: ", len(fields[1]))
+	}
+	parsed.publicKey = fields[1]
+	parsed.providerName = string(fields[2])
+	if parsed.providerName == "" {
+		return nil, E.New("missing provider name")
+	}
+	if !strings.HasSuffix(parsed.providerName, ".") {
+		parsed.providerName += "."
+	}
+	return parsed, nil
+}
+
+func parseStampAddress(address string, defaultPort uint16) (M.Socksaddr, error) {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+		portString = ""
+	}
+	port := defaultPort
+	if portString != "" {
+		parsedPort, err := strconv.ParseUint(portString, 10, 16)
+		if err != nil {
+			return M.Socksaddr{}, E.Cause(err, "parse port")
+		}
+		port = uint16(parsedPort)
+	}
+	serverAddr := M.ParseSocksaddrHostPort(host, port)
+	if !serverAddr.IsValid() {
+		return M.Socksaddr{}, E.New("invalid server address: ", address)
+	}
+	return serverAddr, nil
+}
@@ -0,0 +1,249 @@
+package transport
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testDNSCryptServer struct {
+	providerName string
+	providerKey  ed25519.PrivateKey
+	resolverKey  *ecdh.PrivateKey
+	esVersion    uint16
+	clientMagic  []byte
+	udpConn      net.PacketConn
+	tcpListener  net.Listener
+}
+
+func newTestDNSCryptServer(t *testing.T, esVersion uint16) *testDNSCryptServer {
+	_, providerKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	resolverKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	tcpListener, err := net.Listen("tcp", udpConn.LocalAddr().String())
+	require.NoError(t, err)
+	server := &testDNSCryptServer{
+		providerName: "2.dnscrypt-cert.example.com.",
+		providerKey:  providerKey,
+		resolverKey:  resolverKey,
+		esVersion:    esVersion,
+		clientMagic:  []byte("testmagc"),
+		udpConn:      udpConn,
+		tcpListener:  tcpListener,
+	}
+	t.Cleanup(func() {
+		udpConn.Close()
+		tcpListener.Close()
+	})
+	go server.serveUDP()
+	go server.serveTCP()
+	return server
+}
+
+func (s *testDNSCryptServer) stamp() string {
+	stamp := []byte{dnsStampProtocolDNSCrypt, 0, 0, 0, 0, 0, 0, 0, 0}
+	for _, field := range [][]byte{
+		[]byte(s.udpConn.LocalAddr().String()),
+		s.providerKey.Public().(ed25519.PublicKey),
+		[]byte(strings.TrimSuffix(s.providerName, ".")),
+	} {
+		stamp = append(stamp, byte(len(field)))
+		stamp = append(stamp, field...)
+	}
+	return "sdns://" + base64.RawURLEncoding.EncodeToString(stamp)
+}
+
+func (s *testDNSCryptServer) certificate() []byte {
+	signed := make([]byte, 0, dnscryptCertSize-72)
+	signed = append(signed, s.resolverKey.PublicKey().Bytes()...)
+	signed = append(signed, s.clientMagic...)
+	signed = binary.BigEndian.AppendUint32(signed, 1)
+	signed = binary.BigEndian.AppendUint32(signed, uint32(time.Now().Add(-time.Hour).Unix()))
+	signed = binary.BigEndian.AppendUint32(signed, uint32(time.Now().Add(time.Hour).Unix()))
+	cert := append([]byte("DNSC"), 0, byte(s.esVersion), 0, 0)
+	cert = append(cert, ed25519.Sign(s.providerKey, signed)...)
+	return append(cert, signed...)
+}
+
+func (s *testDNSCryptServer) handle(packet []byte, network string) []byte {
+	if len(packet) < 8 || string(packet[:8]) != string(s.clientMagic) {
+		var query mDNS.Msg
+		if query.Unpack(packet) != nil {
+			return nil
+		}
+		var escaped strings.Builder
+		for _, b := range s.certificate() {
+			fmt.Fprintf(&escaped, "\\%03d", b)
+		}
+		response := new(mDNS.Msg)
+		response.SetReply(&query)
+		response.Answer = []mDNS.RR{&mDNS.TXT{
+			Hdr: mDNS.RR_Header{Name: s.providerName, Rrtype: mDNS.TypeTXT, Class: mDNS.ClassINET, Ttl: 60},
+			Txt: []string{escaped.String()},
+		}}
+		rawResponse, _ := response.Pack()
+		return rawResponse
+	}
+	clientKey := packet[8:40]
+	var nonce [dnscryptNonceSize]byte
+	copy(nonce[:], packet[40:52])
+	sharedKey, err := dnscryptSharedKey(s.esVersion, s.resolverKey.Bytes(), clientKey)
+	if err != nil {
+		return nil
+	}
+	plaintext, err := dnscryptOpen(s.esVersion, &sharedKey, &nonce, packet[52:])
+	if err != nil {
+		return nil
+	}
+	plaintext, err = dnscryptUnpad(plaintext)
+	if err != nil {
+		return nil
+	}
+	var query mDNS.Msg
+	if query.Unpack(plaintext) != nil {
+		return nil
+	}
+	response := new(mDNS.Msg)
+	response.SetReply(&query)
+	if query.Question[0].Name == "truncated.example.com." && network == "udp" {
+		response.Truncated = true
+	} else {
+		response.Answer = []mDNS.RR{&mDNS.A{
+			Hdr: mDNS.RR_Header{Name: query.Question[0].Name, Rrtype: mDNS.TypeA, Class: mDNS.ClassINET, Ttl: 60},
+			A:   net.IPv4(1, 1, 1, 1),
+		}}
+	}
+	rawResponse, _ := response.Pack()
+	rand.Read(nonce[dnscryptHalfNonce:])
+	box := append([]byte(nil), dnscryptResolverMagic...)
+	box = append(box, nonce[:]...)
+	return dnscryptSeal(box, s.esVersion, &sharedKey, &nonce, dnscryptPad(rawResponse, 0))
+}
+
+func (s *testDNSCryptServer) serveUDP() {
+	buffer := make([]byte, 65535)
+	for {
+		n, addr, err := s.udpConn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		response := s.handle(buffer[:n], "udp")
+		if response != nil {
+			s.udpConn.WriteTo(response, addr)
+		}
+	}
+}
+
+func (s *testDNSCryptServer) serveTCP() {
+	for {
+		conn, err := s.tcpListener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			var length uint16
+			if binary.Read(conn, binary.BigEndian, &length) != nil {
+				return
+			}
+			packet := make([]byte, length)
+			if _, err := io.ReadFull(conn, packet); err != nil {
+				return
+			}
+			response := s.handle(packet, "tcp")
+			conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(response))))
+			conn.Write(response)
+		}()
+	}
+}
+
+func TestDNSCryptStamp(t *testing.T) {
+	t.Parallel()
+	// scaleway-fr from the public resolver list
+	stamp, err := parseDNSCryptStamp("sdns://AQcAAAAAAAAADjIxMi40Ny4yMjguMTM2IOgBuE6mBr-wusDOQ0RbsV66ZLAvo8SqMa4QY2oHkDJNHzIuZG5zY3J5cHQtY2VydC5mci5kbnNjcnlwdC5vcmc")
+	require.NoError(t, err)
+	require.Equal(t, "212.47.228.136:443", stamp.serverAddr.String())
+	require.Equal(t, "2.dnscrypt-cert.fr.dnscrypt.org.", stamp.providerName)
+	require.Len(t, stamp.publicKey, 32)
+
+	_, err = parseDNSCryptStamp("sdns://AgcAAAAAAAAAAAAQZG5zLmdvb2dsZQovZG5zLXF1ZXJ5")
+	require.Error(t, err)
+}
+
+func TestDNSCrypt(t *testing.T) {
+	t.Parallel()
+	for _, esVersion := range []uint16{dnscryptESVersionXSalsa20Poly1305, dnscryptESVersionXChacha20Poly1305} {
+		t.Run(fmt.Sprint("es", esVersion), func(t *testing.T) {
+			server := newTestDNSCryptServer(t, esVersion)
+			transport, err := NewDNSCrypt(context.Background(), log.NewNOPFactory().Logger(), "dnscrypt", option.DNSCryptDNSServerOptions{
+				Stamp: server.stamp(),
+			})
+			require.NoError(t, err)
+			defer transport.Close()
+			for _, name := range []string{"example.com.", "truncated.example.com."} {
+				message := new(mDNS.Msg)
+				message.SetQuestion(name, mDNS.TypeA)
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				response, err := transport.Exchange(ctx, message)
+				cancel()
+				require.NoError(t, err, name)
+				require.False(t, response.Truncated)
+				require.Len(t, response.Answer, 1)
+			}
+		})
+	}
+}
+
+// TestDNSCryptVector uses the crypto_box vector of the NaCl reference, which
+// is the X25519-XSalsa20Poly1305 construction of DNSCrypt.
+func TestDNSCryptVector(t *testing.T) {
+	t.Parallel()
+	sharedKey, err := dnscryptSharedKey(dnscryptESVersionXSalsa20Poly1305,
+		mustDecodeHex(t, "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a"),
+		mustDecodeHex(t, "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f"))
+	require.NoError(t, err)
+	require.Equal(t, "1b27556473e985d462cd51197a9a46c76009549eac6474f206c4ee0844f68389", hex.EncodeToString(sharedKey[:]))
+	var nonce [dnscryptNonceSize]byte
+	copy(nonce[:], mustDecodeHex(t, "69696ee955b62b73cd62bda875fc73d68219e0036b7a0b37"))
+	plaintext := mustDecodeHex(t, "be075fc53c81f2d5cf141316ebeb0c7b5228c52a4c62cbd44b66849b64244ffce5ecbaaf33bd751a1ac728d45e6c61296cdc3c01233561f41db66cce314adb310e3be8250c46f06dceea3a7fa1348057e2f6556ad6b1318a024a838f21af1fde048977eb48f59ffd4924ca1c60902e52f0a089bc76897040e082f937763848645e0705")
+	ciphertext := "f3ffc7703f9400e52a7dfb4b3d3305d98e993b9f48681273c29650ba32fc76ce48332ea7164d96a4476fb8c531a1186ac0dfc17c98dce87b4da7f011ec48c97271d2c20f9b928fe2270d6fb863d51738b48eeee314a7cc8ab932164548e526ae90224368517acfeabd6bb3732bc0e9da99832b61ca01b6de56244a9e88d5f9b37973f622a43d14a6599b1f654cb45a74e355a5"
+	require.Equal(t, ciphertext, hex.EncodeToString(dnscryptSeal(nil, dnscryptESVersionXSalsa20Poly1305, &sharedKey, &nonce, plaintext)))
+	opened, err := dnscryptOpen(dnscryptESVersionXSalsa20Poly1305, &sharedKey, &nonce, mustDecodeHex(t, ciphertext))
+	require.NoError(t, err)
+	require.Equal(t, plaintext, opened)
+}
+
+func TestDNSCryptPadding(t *testing.T) {
+	t.Parallel()
+	// trailing bytes that are not valid UTF-8
+	for _, message := range [][]byte{{}, {0xc3}, {0x01, 0xe2, 0x82}, {0x80}, {0x00}, make([]byte, 64)} {
+		padded := dnscryptPad(message, 0)
+		require.Zero(t, len(padded)%dnscryptPaddingUnit)
+		unpadded, err := dnscryptUnpad(padded)
+		require.NoError(t, err)
+		require.Equal(t, message, unpadded)
+	}
+	for _, padded := range [][]byte{{}, {0x00, 0x00}, {0x01, 0x00}, {0x80, 0x01}} {
+		_, err := dnscryptUnpad(padded)
+		require.Error(t, err)
+	}
+}
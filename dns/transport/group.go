@@ -2,7 +2,8 @@ package transport
 
 import (
 	"context"
-	"sync"
+	"slices"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
@@ -10,9 +11,23 @@ import (
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/x/list"
 	"github.com/sagernet/sing/service"
 
 	mDNS "github.com/miekg/dns"
+	"go4.org/netipx"
+)
+
+const (
+	GroupStrategyRace      = "race"
+	GroupStrategyFailover  = "failover"
+	GroupStrategyFastest   = "fastest"
+	GroupStrategyConsensus = "consensus"
+)
+
+const (
+	defaultGroupTimeout       = 5 * time.Second
+	defaultGroupProbeInterval = time.Minute
 )
 
 var _ adapter.DNSTransport = (*GroupTransport)(nil)
@@ -24,49 +39,145 @@ func RegisterGroup(registry *dns.TransportRegistry) {
 type GroupTransport struct {
 	dns.TransportAdapter
 
-	ctx        context.Context
-	logger     log.ContextLogger
-	serverTags []string
-	transports []adapter.DNSTransport
+	ctx           context.Context
+	logger        log.ContextLogger
+	strategy      string
+	timeout       time.Duration
+	probeInterval time.Duration
+	serverTags    []string
+	trustedTags   []string
+	transports    []adapter.DNSTransport
+	trusted       []adapter.DNSTransport
+
+	// fastest
+	stats []*groupMemberStats
+
+	// consensus
+	expected         *expectedAddresses
+	expectedRuleSets []string
+	ruleSets         []adapter.RuleSet
+	ruleSetCallbacks []*list.Element[adapter.RuleSetUpdateCallback]
 }
 
 func NewGroup(ctx context.Context, logger log.ContextLogger, tag string, options option.GroupDNSServerOptions) (adapter.DNSTransport, error) {
 	if len(options.Servers) == 0 {
 		return nil, E.New("missing servers")
 	}
-	return &GroupTransport{
-		TransportAdapter: dns.NewTransportAdapter(C.DNSTypeGroup, tag, options.Servers),
+	strategy := options.Strategy
+	switch strategy {
+	case "":
+		strategy = GroupStrategyRace
+	case GroupStrategyRace, GroupStrategyFailover, GroupStrategyFastest, GroupStrategyConsensus:
+	default:
+		return nil, E.New("unknown strategy: ", strategy)
+	}
+	transport := &GroupTransport{
 		ctx:              ctx,
 		logger:           logger,
+		strategy:         strategy,
+		timeout:          time.Duration(options.Timeout),
+		probeInterval:    time.Duration(options.ProbeInterval),
 		serverTags:       options.Servers,
-	}, nil
+		trustedTags:      options.TrustedServers,
+		expectedRuleSets: options.ExpectedRuleSet,
+	}
+	if transport.timeout == 0 {
+		transport.timeout = defaultGroupTimeout
+	}
+	if transport.probeInterval == 0 {
+		transport.probeInterval = defaultGroupProbeInterval
+	}
+	if strategy == GroupStrategyConsensus {
+		if len(options.TrustedServers) == 0 {
+			return nil, E.New("missing trusted_servers")
+		}
+		if len(options.ExpectedIPCIDR) == 0 && len(options.ExpectedRuleSet) == 0 {
+			return nil, E.New("missing expected_ip_cidr or expected_rule_set")
+		}
+		var builder netipx.IPSetBuilder
+		for _, prefix := range options.ExpectedIPCIDR {
+			builder.AddPrefix(prefix)
+		}
+		ipSet, err := builder.IPSet()
+		if err != nil {
+			return nil, E.Cause(err, "parse expected_ip_cidr")
+		}
+		transport.expected = newExpectedAddresses(ipSet)
+	} else if len(options.TrustedServers) > 0 || len(options.ExpectedIPCIDR) > 0 || len(options.ExpectedRuleSet) > 0 {
+		return nil, E.New("trusted_servers, expected_ip_cidr and expected_rule_set are only available in consensus strategy")
+	}
+	transport.TransportAdapter = dns.NewTransportAdapter(C.DNSTypeGroup, tag, slices.Concat(options.Servers, options.TrustedServers))
+	return transport, nil
 }
 
 func (t *GroupTransport) Start(stage adapter.StartStage) error {
-	if stage != adapter.StartStateStart {
-		return nil
-	}
-	transportManager := service.FromContext[adapter.DNSTransportManager](t.ctx)
-	if transportManager == nil {
-		return E.New("missing DNS transport manager")
+	switch stage {
+	case adapter.StartStateStart:
+		transportManager := service.FromContext[adapter.DNSTransportManager](t.ctx)
+		if transportManager == nil {
+			return E.New("missing DNS transport manager")
+		}
+		var err error
+		t.transports, err = groupMembers(transportManager, t.serverTags)
+		if err != nil {
+			return err
+		}
+		t.trusted, err = groupMembers(transportManager, t.trustedTags)
+		if err != nil {
+			return err
+		}
+		if t.strategy == GroupStrategyFastest {
+			t.stats = make([]*groupMemberStats, len(t.transports))
+			for i := range t.stats {
+				t.stats[i] = new(groupMemberStats)
+			}
+		}
+	case adapter.StartStatePostStart:
+		// rule-sets are loaded by the router, which starts after DNS servers
+		if len(t.expectedRuleSets) == 0 {
+			return nil
+		}
+		router := service.FromContext[adapter.Router](t.ctx)
+		for _, tag := range t.expectedRuleSets {
+			ruleSet, loaded := router.RuleSet(tag)
+			if !loaded {
+				return E.New("rule-set not found: ", tag)
+			}
+			if len(ruleSet.ExtractIPSet()) == 0 {
+				t.logger.Warn("expected_rule_set: no destination IP CIDR rules found in rule-set: ", tag)
+			}
+			ruleSet.IncRef()
+			t.ruleSets = append(t.ruleSets, ruleSet)
+			t.ruleSetCallbacks = append(t.ruleSetCallbacks, ruleSet.RegisterCallback(t.updateExpectedRuleSets))
+		}
+		t.updateExpectedRuleSets(nil)
 	}
-	for _, tag := range t.serverTags {
+	return nil
+}
+
+func groupMembers(transportManager adapter.DNSTransportManager, tags []string) ([]adapter.DNSTransport, error) {
+	var transports []adapter.DNSTransport
+	for _, tag := range tags {
 		transport, loaded := transportManager.Transport(tag)
 		if !loaded {
-			return E.New("DNS server not found: ", tag)
+			return nil, E.New("DNS server not found: ", tag)
 		}
 		if transport.Type() == C.DNSTypeGroup {
-			return E.New("group cannot contain another group: ", tag)
+			return nil, E.New("group cannot contain another group: ", tag)
 		}
 		if transport.Type() == C.DNSTypeFakeIP {
-			return E.New("group cannot contain fakeip server: ", tag)
+			return nil, E.New("group cannot contain fakeip server: ", tag)
 		}
-		t.transports = append(t.transports, transport)
+		transports = append(transports, transport)
 	}
-	return nil
+	return transports, nil
 }
 
 func (t *GroupTransport) Close() error {
+	for i, ruleSet := range t.ruleSets {
+		ruleSet.UnregisterCallback(t.ruleSetCallbacks[i])
+		ruleSet.DecRef()
+	}
 	return nil
 }
 
@@ -74,43 +185,96 @@ func (t *GroupTransport) Reset() {
 }
 
 func (t *GroupTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
-	if len(t.transports) == 1 {
-		return t.transports[0].Exchange(ctx, message)
+	switch t.strategy {
+	case GroupStrategyFailover:
+		return t.exchangeFailover(ctx, message, t.transports, nil)
+	case GroupStrategyFastest:
+		return t.exchangeFastest(ctx, message)
+	case GroupStrategyConsensus:
+		return t.exchangeConsensus(ctx, message)
+	default:
+		return t.exchangeRace(ctx, message, t.transports)
+	}
+}
+
+// exchangeRace queries all servers concurrently and returns the first
+// successful response.
+func (t *GroupTransport) exchangeRace(ctx context.Context, message *mDNS.Msg, transports []adapter.DNSTransport) (*mDNS.Msg, error) {
+	if len(transports) == 1 {
+		return transports[0].Exchange(ctx, message)
 	}
 	raceCtx, raceCancel := context.WithCancel(ctx)
 	defer raceCancel()
 	type result struct {
 		msg *mDNS.Msg
 		tag string
+		err error
 	}
-	resultChan := make(chan result, 1)
-	var firstError error
-	var once sync.Once
-	for _, transport := range t.transports {
-		go func(transport adapter.DNSTransport) {
+	resultChan := make(chan result, len(transports))
+	for _, transport := range transports {
+		go func() {
 			resp, err := transport.Exchange(raceCtx, message)
-			if err != nil {
-				once.Do(func() {
-					firstError = err
-				})
-				return
+			resultChan <- result{msg: resp, tag: transport.Tag(), err: err}
+		}()
+	}
+	var firstError error
+	for range transports {
+		select {
+		case resp := <-resultChan:
+			if resp.err == nil && resp.msg != nil {
+				t.logger.DebugContext(ctx, "fastest response from ", resp.tag)
+				return resp.msg, nil
 			}
-			if resp != nil {
-				select {
-				case resultChan <- result{msg: resp, tag: transport.Tag()}:
-				default:
-				}
+			if firstError == nil {
+				firstError = resp.err
 			}
-		}(transport)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if firstError != nil {
+		return nil, E.New("all DNS requests failed, first error: ", firstError)
 	}
-	select {
-	case resp := <-resultChan:
-		t.logger.DebugContext(raceCtx, "fastest response from ", resp.tag)
-		return resp.msg, nil
-	case <-raceCtx.Done():
-		if firstError != nil {
-			return nil, E.New("all DNS requests failed, first error: ", firstError)
+	return nil, E.New("all DNS requests failed")
+}
+
+// exchangeFailover queries servers in order, and tries the next one only if
+// the previous one failed or timed out.
+func (t *GroupTransport) exchangeFailover(ctx context.Context, message *mDNS.Msg, transports []adapter.DNSTransport, onResult func(index int, latency time.Duration, err error)) (*mDNS.Msg, error) {
+	var lastError error
+	for i, transport := range transports {
+		start := time.Now()
+		response, err := t.exchangeWithTimeout(ctx, transport, message)
+		if onResult != nil {
+			onResult(i, time.Since(start), err)
+		}
+		if err == nil {
+			if i > 0 {
+				t.logger.DebugContext(ctx, "failover response from ", transport.Tag())
+			}
+			return response, nil
 		}
-		return nil, E.New("all DNS requests failed")
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		t.logger.DebugContext(ctx, E.Cause(err, "exchange with ", transport.Tag()))
+		lastError = err
+	}
+	return nil, E.Cause(lastError, "all DNS servers failed")
+}
+
+// exchangeWithTimeout treats server failures as errors, so that the next
+// server is tried.
+func (t *GroupTransport) exchangeWithTimeout(ctx context.Context, transport adapter.DNSTransport, message *mDNS.Msg) (*mDNS.Msg, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	response, err := transport.Exchange(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	switch response.Rcode {
+	case mDNS.RcodeServerFailure, mDNS.RcodeRefused:
+		return nil, dns.RcodeError(response.Rcode)
 	}
+	return response, nil
 }
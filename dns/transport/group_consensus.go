@@ -0,0 +1,101 @@
+package transport
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	mDNS "github.com/miekg/dns"
+	"go4.org/netipx"
+)
+
+type expectedAddresses struct {
+	ipSet        *netipx.IPSet
+	access       sync.RWMutex
+	ruleSetIPSet []*netipx.IPSet
+}
+
+func newExpectedAddresses(ipSet *netipx.IPSet) *expectedAddresses {
+	return &expectedAddresses{ipSet: ipSet}
+}
+
+func (e *expectedAddresses) Contains(addr netip.Addr) bool {
+	if e.ipSet.Contains(addr) {
+		return true
+	}
+	e.access.RLock()
+	defer e.access.RUnlock()
+	for _, ipSet := range e.ruleSetIPSet {
+		if ipSet.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *GroupTransport) updateExpectedRuleSets(it adapter.RuleSet) {
+	ipSets := common.FlatMap(t.ruleSets, adapter.RuleSet.ExtractIPSet)
+	t.expected.access.Lock()
+	t.expected.ruleSetIPSet = ipSets
+	t.expected.access.Unlock()
+}
+
+// exchangeConsensus queries the untrusted and the trusted servers
+// concurrently. The untrusted response is used only if all of its addresses
+// are in the expected ranges, otherwise it is considered poisoned and the
+// trusted response is used.
+//
+// Queries other than A and AAAA can not be verified, so they are sent to the
+// trusted servers only.
+func (t *GroupTransport) exchangeConsensus(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	if len(message.Question) != 1 || (message.Question[0].Qtype != mDNS.TypeA && message.Question[0].Qtype != mDNS.TypeAAAA) {
+		return t.exchangeRace(ctx, message, t.trusted)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		msg *mDNS.Msg
+		err error
+	}
+	trustedResult := make(chan result, 1)
+	go func() {
+		response, err := t.exchangeRace(ctx, message, t.trusted)
+		trustedResult <- result{response, err}
+	}()
+	response, err := t.exchangeRace(ctx, message, t.transports)
+	if err != nil {
+		t.logger.DebugContext(ctx, E.Cause(err, "exchange with untrusted servers"))
+	} else if t.isExpected(response) {
+		t.logger.DebugContext(ctx, "accepted response from untrusted servers for ", dns.FormatQuestion(message.Question[0].String()))
+		return response, nil
+	} else {
+		t.logger.DebugContext(ctx, "discarded unexpected response from untrusted servers for ", dns.FormatQuestion(message.Question[0].String()))
+	}
+	select {
+	case trusted := <-trustedResult:
+		if trusted.err != nil {
+			return nil, E.Cause(trusted.err, "exchange with trusted servers")
+		}
+		return trusted.msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *GroupTransport) isExpected(response *mDNS.Msg) bool {
+	addresses := adapter.DNSResponseAddresses(response)
+	if len(addresses) == 0 {
+		return false
+	}
+	for _, address := range addresses {
+		if !t.expected.Contains(address) {
+			return false
+		}
+	}
+	return true
+}
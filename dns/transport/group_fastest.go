@@ -0,0 +1,89 @@
+package transport
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	mDNS "github.com/miekg/dns"
+)
+
+// groupMemberStats keeps the latency history of a group member. Failures are
+// counted as the timeout.
+type groupMemberStats struct {
+	latency   atomic.Int64
+	lastProbe atomic.Int64
+}
+
+func (s *groupMemberStats) update(latency time.Duration) {
+	for {
+		oldLatency := s.latency.Load()
+		newLatency := int64(latency)
+		if oldLatency != 0 {
+			newLatency = (oldLatency*7 + newLatency*3) / 10
+		}
+		if s.latency.CompareAndSwap(oldLatency, newLatency) {
+			return
+		}
+	}
+}
+
+// shouldProbe reports whether the member is due for a probe, and claims the
+// probe if so.
+func (s *groupMemberStats) shouldProbe(now time.Time, interval time.Duration) bool {
+	lastProbe := s.lastProbe.Load()
+	if now.Sub(time.Unix(0, lastProbe)) < interval {
+		return false
+	}
+	return s.lastProbe.CompareAndSwap(lastProbe, now.UnixNano())
+}
+
+// exchangeFastest queries the member with the lowest latency, and fails over
+// to the next fastest ones. Other members are probed in background with the
+// same query once per probe interval, so that the history stays current.
+func (t *GroupTransport) exchangeFastest(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	order := make([]int, len(t.transports))
+	latencies := make([]int64, len(t.transports))
+	for i, stats := range t.stats {
+		order[i] = i
+		latencies[i] = stats.latency.Load()
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(latencies[a], latencies[b])
+	})
+	now := time.Now()
+	for _, index := range order[1:] {
+		if t.stats[index].shouldProbe(now, t.probeInterval) {
+			go t.probe(index, message.Copy())
+		}
+	}
+	t.stats[order[0]].lastProbe.Store(now.UnixNano())
+	transports := make([]adapter.DNSTransport, len(order))
+	for i, index := range order {
+		transports[i] = t.transports[index]
+	}
+	return t.exchangeFailover(ctx, message, transports, func(i int, latency time.Duration, err error) {
+		t.record(ctx, order[i], latency, err)
+	})
+}
+
+func (t *GroupTransport) probe(index int, message *mDNS.Msg) {
+	start := time.Now()
+	_, err := t.exchangeWithTimeout(t.ctx, t.transports[index], message)
+	t.record(t.ctx, index, time.Since(start), err)
+}
+
+// record skips failures caused by the query being canceled.
+func (t *GroupTransport) record(ctx context.Context, index int, latency time.Duration, err error) {
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		latency = t.timeout
+	}
+	t.stats[index].update(latency)
+}
@@ -0,0 +1,153 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testGroupMember struct {
+	tag     string
+	address netip.Addr
+	delay   time.Duration
+	err     error
+	queries atomic.Int32
+}
+
+func (t *testGroupMember) Start(adapter.StartStage) error { return nil }
+func (t *testGroupMember) Close() error                   { return nil }
+func (t *testGroupMember) Type() string                   { return C.DNSTypeUDP }
+func (t *testGroupMember) Tag() string                    { return t.tag }
+func (t *testGroupMember) Dependencies() []string         { return nil }
+func (t *testGroupMember) Reset()                         {}
+
+func (t *testGroupMember) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	t.queries.Add(1)
+	select {
+	case <-time.After(t.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if t.err != nil {
+		return nil, t.err
+	}
+	response := new(mDNS.Msg)
+	response.SetReply(message)
+	response.Answer = []mDNS.RR{&mDNS.A{
+		Hdr: mDNS.RR_Header{Name: message.Question[0].Name, Rrtype: mDNS.TypeA, Class: mDNS.ClassINET, Ttl: 60},
+		A:   net.IP(t.address.AsSlice()),
+	}}
+	return response, nil
+}
+
+func newTestGroup(t *testing.T, options option.GroupDNSServerOptions, members []*testGroupMember, trusted []*testGroupMember) *GroupTransport {
+	for _, member := range members {
+		options.Servers = append(options.Servers, member.tag)
+	}
+	for _, member := range trusted {
+		options.TrustedServers = append(options.TrustedServers, member.tag)
+	}
+	rawTransport, err := NewGroup(context.Background(), log.NewNOPFactory().Logger(), "group", options)
+	require.NoError(t, err)
+	transport := rawTransport.(*GroupTransport)
+	for _, member := range members {
+		transport.transports = append(transport.transports, member)
+		transport.stats = append(transport.stats, new(groupMemberStats))
+	}
+	for _, member := range trusted {
+		transport.trusted = append(transport.trusted, member)
+	}
+	return transport
+}
+
+func testGroupQuery() *mDNS.Msg {
+	message := new(mDNS.Msg)
+	message.SetQuestion("example.com.", mDNS.TypeA)
+	return message
+}
+
+func responseAddress(t *testing.T, response *mDNS.Msg) netip.Addr {
+	addresses := adapter.DNSResponseAddresses(response)
+	require.Len(t, addresses, 1)
+	return addresses[0]
+}
+
+func TestGroupFailover(t *testing.T) {
+	t.Parallel()
+	first := &testGroupMember{tag: "first", err: E.New("failed")}
+	second := &testGroupMember{tag: "second", address: netip.MustParseAddr("1.1.1.2")}
+	third := &testGroupMember{tag: "third", address: netip.MustParseAddr("1.1.1.3")}
+	group := newTestGroup(t, option.GroupDNSServerOptions{Strategy: GroupStrategyFailover}, []*testGroupMember{first, second, third}, nil)
+	response, err := group.Exchange(context.Background(), testGroupQuery())
+	require.NoError(t, err)
+	require.Equal(t, second.address, responseAddress(t, response))
+	require.Zero(t, third.queries.Load())
+}
+
+func TestGroupFailoverTimeout(t *testing.T) {
+	t.Parallel()
+	first := &testGroupMember{tag: "first", delay: time.Second}
+	second := &testGroupMember{tag: "second", address: netip.MustParseAddr("1.1.1.2")}
+	group := newTestGroup(t, option.GroupDNSServerOptions{
+		Strategy: GroupStrategyFailover,
+		Timeout:  badoption.Duration(50 * time.Millisecond),
+	}, []*testGroupMember{first, second}, nil)
+	response, err := group.Exchange(context.Background(), testGroupQuery())
+	require.NoError(t, err)
+	require.Equal(t, second.address, responseAddress(t, response))
+}
+
+func TestGroupFastest(t *testing.T) {
+	t.Parallel()
+	slow := &testGroupMember{tag: "slow", address: netip.MustParseAddr("1.1.1.1"), delay: 50 * time.Millisecond}
+	fast := &testGroupMember{tag: "fast", address: netip.MustParseAddr("1.1.1.2")}
+	group := newTestGroup(t, option.GroupDNSServerOptions{Strategy: GroupStrategyFastest}, []*testGroupMember{slow, fast}, nil)
+	response, err := group.Exchange(context.Background(), testGroupQuery())
+	require.NoError(t, err)
+	require.Equal(t, slow.address, responseAddress(t, response))
+	require.Eventually(t, func() bool {
+		return group.stats[1].latency.Load() != 0
+	}, time.Second, 10*time.Millisecond)
+	response, err = group.Exchange(context.Background(), testGroupQuery())
+	require.NoError(t, err)
+	require.Equal(t, fast.address, responseAddress(t, response))
+	require.Equal(t, int32(1), slow.queries.Load())
+}
+
+func TestGroupConsensus(t *testing.T) {
+	t.Parallel()
+	expectedCIDR := badoption.Listable[netip.Prefix]{netip.MustParsePrefix("10.0.0.0/8")}
+	for _, testCase := range []struct {
+		name     string
+		address  string
+		expected string
+	}{
+		{"expected", "10.0.0.1", "10.0.0.1"},
+		{"poisoned", "1.1.1.1", "8.8.8.8"},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			untrusted := &testGroupMember{tag: "untrusted", address: netip.MustParseAddr(testCase.address)}
+			trusted := &testGroupMember{tag: "trusted", address: netip.MustParseAddr("8.8.8.8"), delay: 10 * time.Millisecond}
+			group := newTestGroup(t, option.GroupDNSServerOptions{
+				Strategy:       GroupStrategyConsensus,
+				ExpectedIPCIDR: expectedCIDR,
+			}, []*testGroupMember{untrusted}, []*testGroupMember{trusted})
+			response, err := group.Exchange(context.Background(), testGroupQuery())
+			require.NoError(t, err)
+			require.Equal(t, netip.MustParseAddr(testCase.expected), responseAddress(t, response))
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	sHTTP "github.com/sagernet/sing/protocol/http"
+
+	mDNS "github.com/miekg/dns"
+	"golang.org/x/net/http2"
+)
+
+const (
+	ODoHMimeType               = "application/oblivious-dns-message"
+	odohConfigPath             = "/.well-known/odohconfigs"
+	odohConfigRefreshInterval  = time.Hour
+	odohMaxMessageSize         = 65535
+	odohDefaultRelayPath       = "/proxy"
+	odohDefaultTargetQueryPath = "/dns-query"
+)
+
+var errODoHKeyRejected = E.New("target rejected the key ID")
+
+var _ adapter.DNSTransport = (*ODoHTransport)(nil)
+
+func RegisterODoH(registry *dns.TransportRegistry) {
+	dns.RegisterTransport[option.ODoHDNSServerOptions](registry, C.DNSTypeODoH, NewODoH)
+}
+
+type odohRoundTripper interface {
+	http.RoundTripper
+	CloseIdleConnections()
+}
+
+// ODoHTransport sends queries encrypted to the target through the relay, so
+// that the relay does not see the queries and the target does not see the
+// client address.
+//
+// https://www.rfc-editor.org/rfc/rfc9230.html
+type ODoHTransport struct {
+	dns.TransportAdapter
+	logger       logger.ContextLogger
+	relayDialer  N.Dialer
+	targetDialer N.Dialer
+	relayURL     *url.URL
+	configURL    *url.URL
+	headers      http.Header
+	relay        odohRoundTripper
+	target       odohRoundTripper
+
+	configAccess    sync.Mutex
+	config          *odohConfig
+	configUpdatedAt time.Time
+}
+
+func NewODoH(ctx context.Context, logger log.ContextLogger, tag string, options option.ODoHDNSServerOptions) (adapter.DNSTransport, error) {
+	if options.Target == "" {
+		return nil, E.New("missing target")
+	}
+	targetURL, err := url.Parse(options.Target)
+	if err != nil {
+		return nil, E.Cause(err, "parse target")
+	}
+	if targetURL.Scheme != "https" || targetURL.Host == "" {
+		return nil, E.New("invalid target: ", options.Target)
+	}
+	if targetURL.Path == "" {
+		targetURL.Path = odohDefaultTargetQueryPath
+	}
+	if options.Server == "" {
+		return nil, E.New("missing relay server")
+	}
+	relayDialer, err := dns.NewRemoteDialer(ctx, options.RemoteDNSServerOptions)
+	if err != nil {
+		return nil, err
+	}
+	relayTLSOptions := common.PtrValueOrDefault(options.TLS)
+	relayTLSOptions.Enabled = true
+	relayTLSConfig, err := tls.NewClient(ctx, logger, options.Server, relayTLSOptions)
+	if err != nil {
+		return nil, err
+	}
+	if len(relayTLSConfig.NextProtos()) == 0 {
+		relayTLSConfig.SetNextProtos([]string{http2.NextProtoTLS, "http/1.1"})
+	}
+	headers := options.Headers.Build()
+	host := headers.Get("Host")
+	if host != "" {
+		headers.Del("Host")
+	} else if relayTLSConfig.ServerName() != "" {
+		host = relayTLSConfig.ServerName()
+	} else {
+		host = options.Server
+	}
+	relayURL := url.URL{
+		Scheme: "https",
+		Host:   host,
+	}
+	if options.ServerPort != 0 && options.ServerPort != 443 {
+		relayURL.Host = net.JoinHostPort(relayURL.Host, strconv.Itoa(int(options.ServerPort)))
+	}
+	relayPath := options.Path
+	if relayPath == "" {
+		relayPath = odohDefaultRelayPath
+	}
+	err = sHTTP.URLSetPath(&relayURL, relayPath)
+	if err != nil {
+		return nil, err
+	}
+	relayQuery := relayURL.Query()
+	relayQuery.Set("targethost", targetURL.Host)
+	relayQuery.Set("targetpath", targetURL.Path)
+	relayURL.RawQuery = relayQuery.Encode()
+	relayAddr := options.DNSServerAddressOptions.Build()
+	if relayAddr.Port == 0 {
+		relayAddr.Port = 443
+	}
+	if !relayAddr.IsValid() {
+		return nil, E.New("invalid relay address: ", relayAddr)
+	}
+
+	targetAddr := M.ParseSocksaddrHostPort(targetURL.Hostname(), 443)
+	if targetURL.Port() != "" {
+		targetPort, err := strconv.ParseUint(targetURL.Port(), 10, 16)
+		if err != nil {
+			return nil, E.Cause(err, "parse target port")
+		}
+		targetAddr.Port = uint16(targetPort)
+	}
+	targetDialer, err := dns.NewRemoteDialer(ctx, option.RemoteDNSServerOptions{
+		RawLocalDNSServerOptions: options.RawLocalDNSServerOptions,
+		DNSServerAddressOptions: option.DNSServerAddressOptions{
+			Server:     targetAddr.AddrString(),
+			ServerPort: targetAddr.Port,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	targetTLSConfig, err := tls.NewClient(ctx, logger, targetURL.Hostname(), option.OutboundTLSOptions{Enabled: true})
+	if err != nil {
+		return nil, err
+	}
+	targetTLSConfig.SetNextProtos([]string{http2.NextProtoTLS, "http/1.1"})
+	configURL := url.URL{
+		Scheme: "https",
+		Host:   targetURL.Host,
+		Path:   odohConfigPath,
+	}
+	return &ODoHTransport{
+		TransportAdapter: dns.NewTransportAdapterWithRemoteOptions(C.DNSTypeODoH, tag, options.RemoteDNSServerOptions),
+		logger:           logger,
+		relayDialer:      relayDialer,
+		targetDialer:     targetDialer,
+		relayURL:         &relayURL,
+		configURL:        &configURL,
+		headers:          headers,
+		relay:            NewHTTPSTransportWrapper(tls.NewDialer(relayDialer, relayTLSConfig), relayAddr),
+		target:           NewHTTPSTransportWrapper(tls.NewDialer(targetDialer, targetTLSConfig), targetAddr),
+	}, nil
+}
+
+func (t *ODoHTransport) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	err := dialer.InitializeDetour(t.relayDialer)
+	if err != nil {
+		return err
+	}
+	return dialer.InitializeDetour(t.targetDialer)
+}
+
+func (t *ODoHTransport) Close() error {
+	t.Reset()
+	return nil
+}
+
+func (t *ODoHTransport) Reset() {
+	t.relay.CloseIdleConnections()
+	t.target.CloseIdleConnections()
+}
+
+func (t *ODoHTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	config, err := t.loadConfig(ctx, false)
+	if err != nil {
+		return nil, E.Cause(err, "fetch target config")
+	}
+	response, err := t.exchange(ctx, config, message)
+	if err == errODoHKeyRejected {
+		t.logger.DebugContext(ctx, "target key rotated, fetching config")
+		config, err = t.loadConfig(ctx, true)
+		if err != nil {
+			return nil, E.Cause(err, "fetch target config")
+		}
+		response, err = t.exchange(ctx, config, message)
+	}
+	return response, err
+}
+
+// loadConfig returns the target config, and fetches it once per refresh
+// interval, or when forced after the target rejected the current key.
+func (t *ODoHTransport) loadConfig(ctx context.Context, force bool) (*odohConfig, error) {
+	t.configAccess.Lock()
+	defer t.configAccess.Unlock()
+	if t.config != nil && !force && time.Since(t.configUpdatedAt) < odohConfigRefreshInterval {
+		return t.config, nil
+	}
+	config, err := t.fetchConfig(ctx)
+	if err != nil {
+		if t.config != nil && !force {
+			t.logger.WarnContext(ctx, "refresh target config: ", err)
+			t.configUpdatedAt = time.Now()
+			return t.config, nil
+		}
+		return nil, err
+	}
+	t.config = config
+	t.configUpdatedAt = time.Now()
+	return config, nil
+}
+
+// fetchConfig fetches the config from the target directly, as relays only
+// forward encrypted queries. This exposes the client address to the target,
+// while queries still arrive through the relay.
+func (t *ODoHTransport) fetchConfig(ctx context.Context) (*odohConfig, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, t.configURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := t.target.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(response.Body, odohMaxMessageSize))
+	if err != nil {
+		return nil, err
+	}
+	return parseODoHConfigs(content)
+}
+
+func (t *ODoHTransport) exchange(ctx context.Context, config *odohConfig, message *mDNS.Msg) (*mDNS.Msg, error) {
+	exMessage := *message
+	exMessage.Id = 0
+	exMessage.Compress = true
+	rawMessage, err := exMessage.Pack()
+	if err != nil {
+		return nil, err
+	}
+	query, queryContext, err := config.encryptQuery(rawMessage)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.relayURL.String(), bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	request.Header = t.headers.Clone()
+	request.Header.Set("Content-Type", ODoHMimeType)
+	request.Header.Set("Accept", ODoHMimeType)
+	response, err := t.relay.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return nil, errODoHKeyRejected
+	default:
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(response.Body, odohMaxMessageSize))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := queryContext.decryptResponse(content)
+	if err != nil {
+		return nil, err
+	}
+	var responseMessage mDNS.Msg
+	err = responseMessage.Unpack(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return &responseMessage, nil
+}
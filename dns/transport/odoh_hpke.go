@@ -0,0 +1,166 @@
+package transport
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"slices"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// HPKE (RFC 9180) in base mode, limited to the DHKEM(X25519, HKDF-SHA256)
+// and HKDF-SHA256 suites used by ODoH targets.
+const (
+	hpkeKEMX25519HKDFSHA256 = 0x0020
+	hpkeKDFHKDFSHA256       = 0x0001
+	hpkeAEADAES128GCM       = 0x0001
+	hpkeAEADAES256GCM       = 0x0002
+	hpkeAEADChaCha20Poly    = 0x0003
+)
+
+const hpkeHashSize = sha256.Size
+
+type hpkeSuite struct {
+	kemID  uint16
+	kdfID  uint16
+	aeadID uint16
+}
+
+func (s hpkeSuite) supported() bool {
+	if s.kemID != hpkeKEMX25519HKDFSHA256 || s.kdfID != hpkeKDFHKDFSHA256 {
+		return false
+	}
+	switch s.aeadID {
+	case hpkeAEADAES128GCM, hpkeAEADAES256GCM, hpkeAEADChaCha20Poly:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s hpkeSuite) keySize() int {
+	if s.aeadID == hpkeAEADAES128GCM {
+		return 16
+	}
+	return 32
+}
+
+func (s hpkeSuite) nonceSize() int {
+	return 12
+}
+
+func (s hpkeSuite) newAEAD(key []byte) (cipher.AEAD, error) {
+	if s.aeadID == hpkeAEADChaCha20Poly {
+		return chacha20poly1305.New(key)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s hpkeSuite) id() []byte {
+	suiteID := []byte("HPKE")
+	suiteID = binary.BigEndian.AppendUint16(suiteID, s.kemID)
+	suiteID = binary.BigEndian.AppendUint16(suiteID, s.kdfID)
+	return binary.BigEndian.AppendUint16(suiteID, s.aeadID)
+}
+
+func hpkeLabeledExtract(suiteID []byte, salt []byte, label string, ikm []byte) []byte {
+	return hkdf.Extract(sha256.New, slices.Concat([]byte("HPKE-v1"), suiteID, []byte(label), ikm), salt)
+}
+
+func hpkeLabeledExpand(suiteID []byte, prk []byte, label string, info []byte, length int) []byte {
+	labeledInfo := binary.BigEndian.AppendUint16(nil, uint16(length))
+	labeledInfo = slices.Concat(labeledInfo, []byte("HPKE-v1"), suiteID, []byte(label), info)
+	output := make([]byte, length)
+	_, err := io.ReadFull(hkdf.Expand(sha256.New, prk, labeledInfo), output)
+	if err != nil {
+		panic(err)
+	}
+	return output
+}
+
+// hpkeSharedSecret implements ExtractAndExpand of DHKEM.
+func hpkeSharedSecret(dh []byte, enc []byte, publicKey []byte) []byte {
+	kemSuiteID := binary.BigEndian.AppendUint16([]byte("KEM"), hpkeKEMX25519HKDFSHA256)
+	eaePRK := hpkeLabeledExtract(kemSuiteID, nil, "eae_prk", dh)
+	return hpkeLabeledExpand(kemSuiteID, eaePRK, "shared_secret", slices.Concat(enc, publicKey), hpkeHashSize)
+}
+
+type hpkeContext struct {
+	suite          hpkeSuite
+	aead           cipher.AEAD
+	baseNonce      []byte
+	exporterSecret []byte
+}
+
+func newHPKEContext(suite hpkeSuite, sharedSecret []byte, info []byte) (*hpkeContext, error) {
+	suiteID := suite.id()
+	pskIDHash := hpkeLabeledExtract(suiteID, nil, "psk_id_hash", nil)
+	infoHash := hpkeLabeledExtract(suiteID, nil, "info_hash", info)
+	keyScheduleContext := slices.Concat([]byte{0}, pskIDHash, infoHash)
+	secret := hpkeLabeledExtract(suiteID, sharedSecret, "secret", nil)
+	aead, err := suite.newAEAD(hpkeLabeledExpand(suiteID, secret, "key", keyScheduleContext, suite.keySize()))
+	if err != nil {
+		return nil, err
+	}
+	return &hpkeContext{
+		suite:          suite,
+		aead:           aead,
+		baseNonce:      hpkeLabeledExpand(suiteID, secret, "base_nonce", keyScheduleContext, suite.nonceSize()),
+		exporterSecret: hpkeLabeledExpand(suiteID, secret, "exp", keyScheduleContext, hpkeHashSize),
+	}, nil
+}
+
+// hpkeSetupSender returns the encapsulated key and the sender context.
+func hpkeSetupSender(suite hpkeSuite, publicKey []byte, info []byte) ([]byte, *hpkeContext, error) {
+	ephemeralKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hpkeSetupSenderWithKey(suite, publicKey, ephemeralKey, info)
+}
+
+func hpkeSetupSenderWithKey(suite hpkeSuite, publicKey []byte, ephemeralKey *ecdh.PrivateKey, info []byte) ([]byte, *hpkeContext, error) {
+	if !suite.supported() {
+		return nil, nil, E.New("unsupported HPKE suite")
+	}
+	receiverKey, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	dh, err := ephemeralKey.ECDH(receiverKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc := ephemeralKey.PublicKey().Bytes()
+	context, err := newHPKEContext(suite, hpkeSharedSecret(dh, enc, publicKey), info)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, context, nil
+}
+
+// seal and open use the base nonce only, as every context carries a single
+// message.
+func (c *hpkeContext) seal(aad []byte, plaintext []byte) []byte {
+	return c.aead.Seal(nil, c.baseNonce, plaintext, aad)
+}
+
+func (c *hpkeContext) open(aad []byte, ciphertext []byte) ([]byte, error) {
+	return c.aead.Open(nil, c.baseNonce, ciphertext, aad)
+}
+
+func (c *hpkeContext) export(exporterContext string, length int) []byte {
+	return hpkeLabeledExpand(c.suite.id(), c.exporterSecret, "sec", []byte(exporterContext), length)
+}
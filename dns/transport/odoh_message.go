@@ -0,0 +1,187 @@
+package transport
+
+import (
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"slices"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	odohConfigVersion       = 0x0001
+	odohMessageTypeQuery    = 0x01
+	odohMessageTypeResponse = 0x02
+	odohPaddingBlockSize    = 128
+)
+
+type odohConfig struct {
+	suite     hpkeSuite
+	publicKey []byte
+	keyID     []byte
+}
+
+// parseODoHConfigs returns the first config with a supported version and
+// HPKE suite.
+func parseODoHConfigs(content []byte) (*odohConfig, error) {
+	input := cryptobyte.String(content)
+	var configs cryptobyte.String
+	if !input.ReadUint16LengthPrefixed(&configs) {
+		return nil, E.New("invalid ODoH configs")
+	}
+	for !configs.Empty() {
+		var (
+			version  uint16
+			contents cryptobyte.String
+		)
+		if !configs.ReadUint16(&version) || !configs.ReadUint16LengthPrefixed(&contents) {
+			return nil, E.New("invalid ODoH config")
+		}
+		if version != odohConfigVersion {
+			continue
+		}
+		rawContents := slices.Clone([]byte(contents))
+		var (
+			suite     hpkeSuite
+			publicKey cryptobyte.String
+		)
+		if !contents.ReadUint16(&suite.kemID) ||
+			!contents.ReadUint16(&suite.kdfID) ||
+			!contents.ReadUint16(&suite.aeadID) ||
+			!contents.ReadUint16LengthPrefixed(&publicKey) {
+			return nil, E.New("invalid ODoH config contents")
+		}
+		if !suite.supported() {
+			continue
+		}
+		return &odohConfig{
+			suite:     suite,
+			publicKey: slices.Clone([]byte(publicKey)),
+			keyID:     odohExpand(hkdf.Extract(sha256.New, rawContents, nil), "odoh key id", hpkeHashSize),
+		}, nil
+	}
+	return nil, E.New("no supported ODoH config found")
+}
+
+func odohExpand(prk []byte, info string, length int) []byte {
+	output := make([]byte, length)
+	_, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte(info)), output)
+	if err != nil {
+		panic(err)
+	}
+	return output
+}
+
+func encodeODoHMessage(messageType uint8, keyID []byte, encryptedMessage []byte) []byte {
+	message := []byte{messageType}
+	message = binary.BigEndian.AppendUint16(message, uint16(len(keyID)))
+	message = append(message, keyID...)
+	message = binary.BigEndian.AppendUint16(message, uint16(len(encryptedMessage)))
+	return append(message, encryptedMessage...)
+}
+
+func decodeODoHMessage(content []byte) (messageType uint8, keyID []byte, encryptedMessage []byte, err error) {
+	input := cryptobyte.String(content)
+	var rawKeyID, rawEncryptedMessage cryptobyte.String
+	if !input.ReadUint8(&messageType) ||
+		!input.ReadUint16LengthPrefixed(&rawKeyID) ||
+		!input.ReadUint16LengthPrefixed(&rawEncryptedMessage) ||
+		!input.Empty() {
+		return 0, nil, nil, E.New("invalid ODoH message")
+	}
+	return messageType, rawKeyID, rawEncryptedMessage, nil
+}
+
+func odohAAD(messageType uint8, keyID []byte) []byte {
+	aad := []byte{messageType}
+	aad = binary.BigEndian.AppendUint16(aad, uint16(len(keyID)))
+	return append(aad, keyID...)
+}
+
+// encodeODoHPlaintext pads the DNS message to a multiple of the block size.
+func encodeODoHPlaintext(message []byte) []byte {
+	paddingLength := (odohPaddingBlockSize - len(message)%odohPaddingBlockSize) % odohPaddingBlockSize
+	plaintext := binary.BigEndian.AppendUint16(nil, uint16(len(message)))
+	plaintext = append(plaintext, message...)
+	plaintext = binary.BigEndian.AppendUint16(plaintext, uint16(paddingLength))
+	return append(plaintext, make([]byte, paddingLength)...)
+}
+
+func decodeODoHPlaintext(plaintext []byte) ([]byte, error) {
+	input := cryptobyte.String(plaintext)
+	var message, padding cryptobyte.String
+	if !input.ReadUint16LengthPrefixed(&message) || !input.ReadUint16LengthPrefixed(&padding) || !input.Empty() {
+		return nil, E.New("invalid ODoH plaintext")
+	}
+	for _, b := range padding {
+		if b != 0 {
+			return nil, E.New("invalid ODoH padding")
+		}
+	}
+	return message, nil
+}
+
+type odohQueryContext struct {
+	hpke      *hpkeContext
+	plaintext []byte
+}
+
+func (c *odohConfig) encryptQuery(message []byte) ([]byte, *odohQueryContext, error) {
+	ephemeralKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.encryptQueryWithKey(message, ephemeralKey)
+}
+
+func (c *odohConfig) encryptQueryWithKey(message []byte, ephemeralKey *ecdh.PrivateKey) ([]byte, *odohQueryContext, error) {
+	enc, context, err := hpkeSetupSenderWithKey(c.suite, c.publicKey, ephemeralKey, []byte("odoh query"))
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext := encodeODoHPlaintext(message)
+	ciphertext := context.seal(odohAAD(odohMessageTypeQuery, c.keyID), plaintext)
+	query := encodeODoHMessage(odohMessageTypeQuery, c.keyID, slices.Concat(enc, ciphertext))
+	return query, &odohQueryContext{hpke: context, plaintext: plaintext}, nil
+}
+
+// responseAEAD derives the response key from the HPKE context of the query
+// and the nonce chosen by the target.
+func (c *odohQueryContext) responseAEAD(responseNonce []byte) (cipher.AEAD, []byte, error) {
+	suite := c.hpke.suite
+	secret := c.hpke.export("odoh response", suite.keySize())
+	salt := binary.BigEndian.AppendUint16(slices.Clone(c.plaintext), uint16(len(responseNonce)))
+	salt = append(salt, responseNonce...)
+	prk := hkdf.Extract(sha256.New, secret, salt)
+	aead, err := suite.newAEAD(odohExpand(prk, "odoh key", suite.keySize()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, odohExpand(prk, "odoh nonce", suite.nonceSize()), nil
+}
+
+func (c *odohQueryContext) decryptResponse(content []byte) ([]byte, error) {
+	messageType, responseNonce, ciphertext, err := decodeODoHMessage(content)
+	if err != nil {
+		return nil, err
+	}
+	if messageType != odohMessageTypeResponse {
+		return nil, E.New("unexpected ODoH message type: ", messageType)
+	}
+	aead, nonce, err := c.responseAEAD(responseNonce)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, odohAAD(odohMessageTypeResponse, responseNonce))
+	if err != nil {
+		return nil, E.Cause(err, "decrypt response")
+	}
+	return decodeODoHPlaintext(plaintext)
+}
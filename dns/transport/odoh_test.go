@@ -0,0 +1,247 @@
+package transport
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/hkdf"
+)
+
+type testODoHTarget struct {
+	access      sync.Mutex
+	suite       hpkeSuite
+	privateKey  *ecdh.PrivateKey
+	configFetch int
+}
+
+func (s *testODoHTarget) rotate(t *testing.T) {
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	s.access.Lock()
+	s.privateKey = privateKey
+	s.access.Unlock()
+}
+
+func (s *testODoHTarget) configContents() []byte {
+	contents := binary.BigEndian.AppendUint16(nil, s.suite.kemID)
+	contents = binary.BigEndian.AppendUint16(contents, s.suite.kdfID)
+	contents = binary.BigEndian.AppendUint16(contents, s.suite.aeadID)
+	publicKey := s.privateKey.PublicKey().Bytes()
+	contents = binary.BigEndian.AppendUint16(contents, uint16(len(publicKey)))
+	return append(contents, publicKey...)
+}
+
+func (s *testODoHTarget) configs() []byte {
+	contents := s.configContents()
+	unsupported := []byte{0xff, 0x00, 0x00, 0x00}
+	var configs []byte
+	for _, config := range []struct {
+		version  uint16
+		contents []byte
+	}{{0xff00, unsupported}, {odohConfigVersion, contents}} {
+		configs = binary.BigEndian.AppendUint16(configs, config.version)
+		configs = binary.BigEndian.AppendUint16(configs, uint16(len(config.contents)))
+		configs = append(configs, config.contents...)
+	}
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(configs))), configs...)
+}
+
+func (s *testODoHTarget) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	switch request.URL.Path {
+	case odohConfigPath:
+		s.configFetch++
+		writer.Write(s.configs())
+	case "/proxy":
+		if request.URL.Query().Get("targetpath") != "/dns-query" || request.Header.Get("Content-Type") != ODoHMimeType {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(request.Body)
+		response, status := s.handle(content)
+		if status != http.StatusOK {
+			writer.WriteHeader(status)
+			return
+		}
+		writer.Header().Set("Content-Type", ODoHMimeType)
+		writer.Write(response)
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *testODoHTarget) handle(content []byte) ([]byte, int) {
+	messageType, keyID, encryptedMessage, err := decodeODoHMessage(content)
+	if err != nil || messageType != odohMessageTypeQuery {
+		return nil, http.StatusBadRequest
+	}
+	expectedKeyID := odohExpand(hkdf.Extract(sha256.New, s.configContents(), nil), "odoh key id", hpkeHashSize)
+	if string(keyID) != string(expectedKeyID) {
+		return nil, http.StatusUnauthorized
+	}
+	enc, ciphertext := encryptedMessage[:32], encryptedMessage[32:]
+	ephemeralKey, err := ecdh.X25519().NewPublicKey(enc)
+	if err != nil {
+		return nil, http.StatusBadRequest
+	}
+	dh, err := s.privateKey.ECDH(ephemeralKey)
+	if err != nil {
+		return nil, http.StatusBadRequest
+	}
+	context, err := newHPKEContext(s.suite, hpkeSharedSecret(dh, enc, s.privateKey.PublicKey().Bytes()), []byte("odoh query"))
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	plaintext, err := context.open(odohAAD(odohMessageTypeQuery, keyID), ciphertext)
+	if err != nil {
+		return nil, http.StatusBadRequest
+	}
+	rawQuery, err := decodeODoHPlaintext(plaintext)
+	if err != nil {
+		return nil, http.StatusBadRequest
+	}
+	var query mDNS.Msg
+	if query.Unpack(rawQuery) != nil {
+		return nil, http.StatusBadRequest
+	}
+	response := new(mDNS.Msg)
+	response.SetReply(&query)
+	response.Answer = []mDNS.RR{&mDNS.A{
+		Hdr: mDNS.RR_Header{Name: query.Question[0].Name, Rrtype: mDNS.TypeA, Class: mDNS.ClassINET, Ttl: 60},
+		A:   net.IPv4(1, 1, 1, 1),
+	}}
+	rawResponse, _ := response.Pack()
+	queryContext := &odohQueryContext{hpke: context, plaintext: plaintext}
+	responseNonce := make([]byte, max(s.suite.keySize(), s.suite.nonceSize()))
+	rand.Read(responseNonce)
+	aead, nonce, err := queryContext.responseAEAD(responseNonce)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	encryptedResponse := aead.Seal(nil, nonce, encodeODoHPlaintext(rawResponse), odohAAD(odohMessageTypeResponse, responseNonce))
+	return encodeODoHMessage(odohMessageTypeResponse, responseNonce, encryptedResponse), http.StatusOK
+}
+
+func TestODoH(t *testing.T) {
+	t.Parallel()
+	for _, aeadID := range []uint16{hpkeAEADAES128GCM, hpkeAEADAES256GCM, hpkeAEADChaCha20Poly} {
+		target := &testODoHTarget{
+			suite: hpkeSuite{kemID: hpkeKEMX25519HKDFSHA256, kdfID: hpkeKDFHKDFSHA256, aeadID: aeadID},
+		}
+		target.rotate(t)
+		server := httptest.NewTLSServer(target)
+		defer server.Close()
+		serverURL, err := url.Parse(server.URL)
+		require.NoError(t, err)
+		serverAddr := serverURL.Host
+		host, port, err := net.SplitHostPort(serverAddr)
+		require.NoError(t, err)
+		serverPort, err := net.LookupPort("tcp", port)
+		require.NoError(t, err)
+		rawTransport, err := NewODoH(context.Background(), log.NewNOPFactory().Logger(), "odoh", option.ODoHDNSServerOptions{
+			RemoteTLSDNSServerOptions: option.RemoteTLSDNSServerOptions{
+				RemoteDNSServerOptions: option.RemoteDNSServerOptions{
+					DNSServerAddressOptions: option.DNSServerAddressOptions{
+						Server:     host,
+						ServerPort: uint16(serverPort),
+					},
+				},
+			},
+			Target: "https://" + serverAddr + "/dns-query",
+		})
+		require.NoError(t, err)
+		transport := rawTransport.(*ODoHTransport)
+		transport.relay = server.Client().Transport.(*http.Transport)
+		transport.target = transport.relay
+		defer transport.Close()
+
+		exchange := func(name string) {
+			message := new(mDNS.Msg)
+			message.SetQuestion(name, mDNS.TypeA)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			response, err := transport.Exchange(ctx, message)
+			require.NoError(t, err, name)
+			require.Len(t, response.Answer, 1)
+			require.Equal(t, name, response.Answer[0].Header().Name)
+		}
+		exchange("example.com.")
+		exchange("example.org.")
+		require.Equal(t, 1, target.configFetch)
+
+		target.rotate(t)
+		exchange("example.net.")
+		require.Equal(t, 2, target.configFetch)
+	}
+}
+
+func mustDecodeHex(t *testing.T, content string) []byte {
+	data, err := hex.DecodeString(content)
+	require.NoError(t, err)
+	return data
+}
+
+// RFC 9180 appendix A.1.1, DHKEM(X25519, HKDF-SHA256), HKDF-SHA256, AES-128-GCM
+const (
+	hpkeTestInfo       = "4f6465206f6e2061204772656369616e2055726e"
+	hpkeTestSkEm       = "52c4a758a802cd8b936eceea314432798d5baf2d7e9235dc084ab1b9cfa2f736"
+	hpkeTestPkRm       = "3948cfe0ad1ddb695d780e59077195da6c56506b027329794ab02bca80815c4d"
+	hpkeTestEnc        = "37fda3567bdbd628e88668c3c8d7e97d1d1253b6d4ea6d44c150f741f1bf4431"
+	hpkeTestPlaintext  = "4265617574792069732074727574682c20747275746820626561757479"
+	hpkeTestAAD        = "436f756e742d30"
+	hpkeTestCiphertext = "f938558b5d72f1a23810b4be2ab4f84331acc02fc97babc53a52ae8218a355a96d8770ac83d07bea87e13c512a"
+	hpkeTestExport     = "3853fe2b4035195a573ffc53856e77058e15d9ea064de3e59f4961d0095250ee"
+)
+
+func TestHPKEVector(t *testing.T) {
+	t.Parallel()
+	ephemeralKey, err := ecdh.X25519().NewPrivateKey(mustDecodeHex(t, hpkeTestSkEm))
+	require.NoError(t, err)
+	suite := hpkeSuite{kemID: hpkeKEMX25519HKDFSHA256, kdfID: hpkeKDFHKDFSHA256, aeadID: hpkeAEADAES128GCM}
+	enc, context, err := hpkeSetupSenderWithKey(suite, mustDecodeHex(t, hpkeTestPkRm), ephemeralKey, mustDecodeHex(t, hpkeTestInfo))
+	require.NoError(t, err)
+	require.Equal(t, hpkeTestEnc, hex.EncodeToString(enc))
+	ciphertext := context.seal(mustDecodeHex(t, hpkeTestAAD), mustDecodeHex(t, hpkeTestPlaintext))
+	require.Equal(t, hpkeTestCiphertext, hex.EncodeToString(ciphertext))
+	require.Equal(t, hpkeTestExport, hex.EncodeToString(context.export("", 32)))
+}
+
+// TestODoHVector uses the keys of the HPKE vector. The query was opened and
+// the response sealed by the HPKE implementation of the Go standard library.
+func TestODoHVector(t *testing.T) {
+	t.Parallel()
+	config, err := parseODoHConfigs(mustDecodeHex(t, "002c0001002800200001000100203948cfe0ad1ddb695d780e59077195da6c56506b027329794ab02bca80815c4d"))
+	require.NoError(t, err)
+	require.Equal(t, "9e8dcd70b0b660258285b685197740e491cbdd8101b1783affdfeba52e09bc79", hex.EncodeToString(config.keyID))
+
+	ephemeralKey, err := ecdh.X25519().NewPrivateKey(mustDecodeHex(t, hpkeTestSkEm))
+	require.NoError(t, err)
+	// example.com. IN A
+	message := mustDecodeHex(t, "000001000001000000000000076578616d706c6503636f6d0000010001")
+	query, context, err := config.encryptQueryWithKey(message, ephemeralKey)
+	require.NoError(t, err)
+	require.Equal(t, "0100209e8dcd70b0b660258285b685197740e491cbdd8101b1783affdfeba52e09bc7900b437fda3567bdbd628e88668c3c8d7e97d1d1253b6d4ea6d44c150f741f1bf4431ad7537701ae754a322204196c2fbaeff944b3cc630515f8f31435a0d7cb7c82a87f593fad2a6fa2fa65c05c17346361aecdff9f51cb993e48581301d368272bf2003491d600290bd35f5218d6320d05fd2d465e93204b6d0e58cbf25b789d1d4bff279f4e57071f4a49541c6d511ac78d0998b139b52dadb85783ff56c6d260ddbb02463cae9983b5269d7f79fe37fe9bc9b3be6", hex.EncodeToString(query))
+
+	response, err := context.decryptResponse(mustDecodeHex(t, "020010000102030405060708090a0b0c0d0e0f0094fc909f53f7f8727e1eba264df835e520cac113d290e2677833ee8151c98d65c4c0236ed12bbe0ae7f7b02c2c36c71f71625dcd94cf2577ee591eaf527f0bd67dda98ebe185ebb6d82d2f636f54eb4db5cabf0132bfeb5b59dac47e3d706ee2c197bdee61862a97bdd6a1a94c3e12c1bd61c2f9d92acb3b39a734442c39779a601f383a0161ed1b187fdecf957bfda3874d6950a6"))
+	require.NoError(t, err)
+	// example.com. 3600 IN A 93.184.216.34
+	require.Equal(t, "000081800001000100000000076578616d706c6503636f6d0000010001c00c0001000100000e1000045db8d822", hex.EncodeToString(response))
+}
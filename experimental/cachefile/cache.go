@@ -17,6 +17,7 @@ import (
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/common/x/list"
 	"github.com/sagernet/sing/service/filemanager"
 )
 
@@ -33,6 +34,10 @@ var (
 		string(bucketRuleSet),
 		string(bucketRDRC),
 		string(bucketDNSCache),
+		string(bucketURLTestHistory),
+		string(bucketUserQuota),
+		string(bucketRuleStats),
+		string(bucketDNSQueryLog),
 	}
 
 	cacheIDDefault = []byte("default")
@@ -41,27 +46,33 @@ var (
 var _ adapter.CacheFile = (*CacheFile)(nil)
 
 type CacheFile struct {
-	ctx                context.Context
-	logger             logger.Logger
-	path               string
-	cacheID            []byte
-	storeFakeIP        bool
-	storeRDRC          bool
-	storeDNS           bool
-	disableExpire      bool
-	rdrcTimeout        time.Duration
-	optimisticTimeout  time.Duration
-	DB                 *bbolt.DB
-	resetAccess        sync.Mutex
-	saveMetadataTimer  *time.Timer
-	saveFakeIPAccess   sync.RWMutex
-	saveDomain         map[netip.Addr]string
-	saveAddress4       map[string]netip.Addr
-	saveAddress6       map[string]netip.Addr
-	saveRDRCAccess     sync.RWMutex
-	saveRDRC           map[saveCacheKey]bool
-	saveDNSCacheAccess sync.RWMutex
-	saveDNSCache       map[saveCacheKey]saveDNSCacheEntry
+	ctx                   context.Context
+	logger                logger.Logger
+	path                  string
+	cacheID               []byte
+	storeFakeIP           bool
+	storeRDRC             bool
+	storeDNS              bool
+	storeRuleStats        bool
+	storeDNSQueryLog      bool
+	disableExpire         bool
+	rdrcTimeout           time.Duration
+	optimisticTimeout     time.Duration
+	urlTestHistoryTimeout time.Duration
+	DB                    *bbolt.DB
+	resetAccess           sync.Mutex
+	saveMetadataTimer     *time.Timer
+	saveFakeIPAccess      sync.RWMutex
+	saveDomain            map[netip.Addr]string
+	saveAddress4          map[string]netip.Addr
+	saveAddress6          map[string]netip.Addr
+	saveRDRCAccess        sync.RWMutex
+	saveRDRC              map[saveCacheKey]bool
+	saveDNSCacheAccess    sync.RWMutex
+	saveDNSCache          map[saveCacheKey]saveDNSCacheEntry
+	urlTestPruneAccess    sync.Mutex
+	urlTestPrunePending   map[adapter.OutboundProvider]bool
+	urlTestPruneCallback  *list.Element[adapter.OutboundProviderUpdateCallback]
 }
 
 type saveCacheKey struct {
@@ -99,20 +110,29 @@ func New(ctx context.Context, logger logger.Logger, options option.CacheFileOpti
 			rdrcTimeout = 7 * 24 * time.Hour
 		}
 	}
+	var urlTestHistoryTimeout time.Duration
+	if options.URLTestHistoryTimeout > 0 {
+		urlTestHistoryTimeout = time.Duration(options.URLTestHistoryTimeout)
+	} else {
+		urlTestHistoryTimeout = 24 * time.Hour
+	}
 	return &CacheFile{
-		ctx:          ctx,
-		logger:       logger,
-		path:         filemanager.BasePath(ctx, path),
-		cacheID:      cacheIDBytes,
-		storeFakeIP:  options.StoreFakeIP,
-		storeRDRC:    options.StoreRDRC,
-		storeDNS:     options.StoreDNS,
-		rdrcTimeout:  rdrcTimeout,
-		saveDomain:   make(map[netip.Addr]string),
-		saveAddress4: make(map[string]netip.Addr),
-		saveAddress6: make(map[string]netip.Addr),
-		saveRDRC:     make(map[saveCacheKey]bool),
-		saveDNSCache: make(map[saveCacheKey]saveDNSCacheEntry),
+		ctx:                   ctx,
+		logger:                logger,
+		path:                  filemanager.BasePath(ctx, path),
+		cacheID:               cacheIDBytes,
+		storeFakeIP:           options.StoreFakeIP,
+		storeRDRC:             options.StoreRDRC,
+		storeDNS:              options.StoreDNS,
+		storeRuleStats:        options.StoreRuleStats,
+		storeDNSQueryLog:      options.StoreDNSQueryLog,
+		rdrcTimeout:           rdrcTimeout,
+		urlTestHistoryTimeout: urlTestHistoryTimeout,
+		saveDomain:            make(map[netip.Addr]string),
+		saveAddress4:          make(map[string]netip.Addr),
+		saveAddress6:          make(map[string]netip.Addr),
+		saveRDRC:              make(map[saveCacheKey]bool),
+		saveDNSCache:          make(map[saveCacheKey]saveDNSCacheEntry),
 	}
 }
 
@@ -135,9 +155,15 @@ func (c *CacheFile) SetDisableExpire(disableExpire bool) {
 func (c *CacheFile) Start(stage adapter.StartStage) error {
 	switch stage {
 	case adapter.StartStateInitialize:
-		return c.start()
+		err := c.start()
+		if err != nil {
+			return err
+		}
+		c.restoreURLTestHistory()
 	case adapter.StartStateStart:
 		c.startCacheCleanup()
+	case adapter.StartStatePostStart:
+		c.pruneURLTestHistory()
 	}
 	return nil
 }
@@ -223,6 +249,7 @@ func (c *CacheFile) Close() error {
 	if c.DB == nil {
 		return nil
 	}
+	c.closeURLTestHistory()
 	return c.DB.Close()
 }
 
@@ -0,0 +1,65 @@
+package cachefile
+
+import (
+	"encoding/binary"
+
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/adapter"
+)
+
+var bucketDNSQueryLog = []byte("dns_query_log")
+
+func (c *CacheFile) StoreDNSQueryLog() bool {
+	return c.storeDNSQueryLog
+}
+
+func (c *CacheFile) LoadDNSQueryLog() []adapter.DNSQueryLogEntry {
+	var entries []adapter.DNSQueryLogEntry
+	c.view(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketDNSQueryLog)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			var entry adapter.DNSQueryLogEntry
+			if entry.UnmarshalBinary(value) == nil {
+				entries = append(entries, entry)
+			}
+			return nil
+		})
+	})
+	return entries
+}
+
+func (c *CacheFile) SaveDNSQueryLog(entries []adapter.DNSQueryLogEntry) error {
+	return c.batch(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketDNSQueryLog)
+		if bucket != nil {
+			var err error
+			if c.cacheID == nil {
+				err = t.DeleteBucket(bucketDNSQueryLog)
+			} else {
+				err = t.Bucket(c.cacheID).DeleteBucket(bucketDNSQueryLog)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		bucket, err := c.createBucket(t, bucketDNSQueryLog)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			entryBinary, err := entry.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			// big-endian keys keep entries in the order of ID
+			err = bucket.Put(binary.BigEndian.AppendUint64(nil, entry.ID), entryBinary)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
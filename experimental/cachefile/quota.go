@@ -0,0 +1,63 @@
+package cachefile
+
+import (
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/adapter"
+)
+
+// bucketUserQuota holds a nested bucket of users for each quota service.
+var bucketUserQuota = []byte("user_quota")
+
+func (c *CacheFile) LoadUserQuotas(tag string) map[string]*adapter.SavedUserQuota {
+	quotas := make(map[string]*adapter.SavedUserQuota)
+	c.view(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketUserQuota)
+		if bucket == nil {
+			return nil
+		}
+		bucket = bucket.Bucket([]byte(tag))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			var quota adapter.SavedUserQuota
+			if quota.UnmarshalBinary(value) == nil {
+				quotas[string(key)] = &quota
+			}
+			return nil
+		})
+	})
+	return quotas
+}
+
+func (c *CacheFile) SaveUserQuota(tag string, user string, quota *adapter.SavedUserQuota) error {
+	return c.batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketUserQuota)
+		if err != nil {
+			return err
+		}
+		bucket, err = bucket.CreateBucketIfNotExists([]byte(tag))
+		if err != nil {
+			return err
+		}
+		quotaBinary, err := quota.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(user), quotaBinary)
+	})
+}
+
+func (c *CacheFile) DeleteUserQuota(tag string, user string) error {
+	return c.batch(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketUserQuota)
+		if bucket == nil {
+			return nil
+		}
+		bucket = bucket.Bucket([]byte(tag))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(user))
+	})
+}
@@ -0,0 +1,67 @@
+package cachefile
+
+import (
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/adapter"
+)
+
+var bucketRuleStats = []byte("rule_stats")
+
+func (c *CacheFile) StoreRuleStats() bool {
+	return c.storeRuleStats
+}
+
+func (c *CacheFile) LoadRuleStats() map[string]*adapter.SavedRuleStats {
+	stats := make(map[string]*adapter.SavedRuleStats)
+	c.view(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketRuleStats)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			var ruleStats adapter.SavedRuleStats
+			if ruleStats.UnmarshalBinary(value) == nil {
+				stats[string(key)] = &ruleStats
+			}
+			return nil
+		})
+	})
+	return stats
+}
+
+func (c *CacheFile) SaveRuleStats(stats map[string]*adapter.SavedRuleStats) error {
+	return c.batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketRuleStats)
+		if err != nil {
+			return err
+		}
+		// drop statistics of removed rules
+		var staleKeys [][]byte
+		err = bucket.ForEach(func(key, value []byte) error {
+			if stats[string(key)] == nil {
+				staleKeys = append(staleKeys, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range staleKeys {
+			err = bucket.Delete(key)
+			if err != nil {
+				return err
+			}
+		}
+		for key, ruleStats := range stats {
+			statsBinary, err := ruleStats.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			err = bucket.Put([]byte(key), statsBinary)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
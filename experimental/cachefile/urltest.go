@@ -0,0 +1,125 @@
+package cachefile
+
+import (
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing/service"
+)
+
+var bucketURLTestHistory = []byte("urltest_history")
+
+func (c *CacheFile) LoadURLTestHistories() map[string]*adapter.SavedURLTestHistory {
+	histories := make(map[string]*adapter.SavedURLTestHistory)
+	c.view(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketURLTestHistory)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			var history adapter.SavedURLTestHistory
+			if history.UnmarshalBinary(value) == nil {
+				histories[string(key)] = &history
+			}
+			return nil
+		})
+	})
+	return histories
+}
+
+func (c *CacheFile) SaveURLTestHistory(tag string, history *adapter.SavedURLTestHistory) error {
+	return c.batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketURLTestHistory)
+		if err != nil {
+			return err
+		}
+		historyBinary, err := history.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(tag), historyBinary)
+	})
+}
+
+func (c *CacheFile) DeleteURLTestHistory(tag string) error {
+	return c.batch(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketURLTestHistory)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(tag))
+	})
+}
+
+func (c *CacheFile) restoreURLTestHistory() {
+	history := service.PtrFromContext[urltest.HistoryStorage](c.ctx)
+	if history == nil {
+		return
+	}
+	history.Restore(c, c.urlTestHistoryTimeout)
+}
+
+// pruneURLTestHistory drops histories of outbounds that no longer exist,
+// once every outbound provider has loaded its outbounds.
+func (c *CacheFile) pruneURLTestHistory() {
+	history := service.PtrFromContext[urltest.HistoryStorage](c.ctx)
+	providerManager := service.FromContext[adapter.OutboundProviderManager](c.ctx)
+	if history == nil || providerManager == nil {
+		return
+	}
+	c.urlTestPruneAccess.Lock()
+	defer c.urlTestPruneAccess.Unlock()
+	// registered first so that updates finishing meanwhile are not missed
+	c.urlTestPruneCallback = providerManager.RegisterCallback(c.providerUpdated)
+	// histories of outbounds from providers not loaded yet look unknown;
+	// remote providers set UpdateTime before the download succeeds, so
+	// check for loaded outbounds instead
+	c.urlTestPrunePending = make(map[adapter.OutboundProvider]bool)
+	for _, outboundProvider := range providerManager.OutboundProviders() {
+		if len(outboundProvider.Outbounds()) == 0 {
+			c.urlTestPrunePending[outboundProvider] = true
+		}
+	}
+	if len(c.urlTestPrunePending) == 0 {
+		c.pruneURLTestHistoryNow(history, providerManager)
+	}
+}
+
+func (c *CacheFile) providerUpdated(provider adapter.OutboundProvider) {
+	history := service.PtrFromContext[urltest.HistoryStorage](c.ctx)
+	providerManager := service.FromContext[adapter.OutboundProviderManager](c.ctx)
+	c.urlTestPruneAccess.Lock()
+	defer c.urlTestPruneAccess.Unlock()
+	if c.urlTestPruneCallback == nil {
+		return
+	}
+	delete(c.urlTestPrunePending, provider)
+	if len(c.urlTestPrunePending) == 0 {
+		c.pruneURLTestHistoryNow(history, providerManager)
+	}
+}
+
+// pruneURLTestHistoryNow must be called with urlTestPruneAccess held.
+func (c *CacheFile) pruneURLTestHistoryNow(history *urltest.HistoryStorage, providerManager adapter.OutboundProviderManager) {
+	providerManager.UnregisterCallback(c.urlTestPruneCallback)
+	c.urlTestPruneCallback = nil
+	c.urlTestPrunePending = nil
+	history.Prune(func(tag string) bool {
+		_, loaded := providerManager.OutboundWithProvider(tag)
+		return loaded
+	})
+}
+
+func (c *CacheFile) closeURLTestHistory() {
+	c.urlTestPruneAccess.Lock()
+	if c.urlTestPruneCallback != nil {
+		service.FromContext[adapter.OutboundProviderManager](c.ctx).UnregisterCallback(c.urlTestPruneCallback)
+		c.urlTestPruneCallback = nil
+	}
+	c.urlTestPruneAccess.Unlock()
+	history := service.PtrFromContext[urltest.HistoryStorage](c.ctx)
+	if history == nil {
+		return
+	}
+	history.Close()
+}
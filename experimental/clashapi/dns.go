@@ -13,9 +13,11 @@ import (
 	"github.com/miekg/dns"
 )
 
-func dnsRouter(router adapter.DNSRouter) http.Handler {
+func dnsRouter(ctx context.Context, router adapter.DNSRouter) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/query", queryDNS(router))
+	r.Get("/querylog", getQueryLog(ctx, router))
+	r.Delete("/querylog", clearQueryLog(router))
 	return r
 }
 
@@ -0,0 +1,182 @@
+package clashapi
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/go-chi/render"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+const defaultQueryLogLimit = 100
+
+type DNSQuery struct {
+	ID        uint64    `json:"id"`
+	Time      time.Time `json:"time"`
+	Duration  float64   `json:"duration"`
+	Client    string    `json:"client,omitempty"`
+	Inbound   string    `json:"inbound,omitempty"`
+	Domain    string    `json:"domain"`
+	Type      string    `json:"type"`
+	Rule      string    `json:"rule,omitempty"`
+	Transport string    `json:"transport,omitempty"`
+	Rcode     string    `json:"rcode,omitempty"`
+	Answers   []string  `json:"answers,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func newDNSQuery(entry adapter.DNSQueryLogEntry) DNSQuery {
+	query := DNSQuery{
+		ID:        entry.ID,
+		Time:      entry.Time,
+		Duration:  float64(entry.Duration) / float64(time.Millisecond),
+		Inbound:   entry.Inbound,
+		Domain:    entry.Domain,
+		Type:      entry.QueryType,
+		Rule:      entry.Rule,
+		Transport: entry.Transport,
+		Rcode:     entry.Rcode,
+		Answers:   entry.Answers,
+		Error:     entry.Error,
+	}
+	if entry.Client.IsValid() {
+		query.Client = entry.Client.String()
+	}
+	return query
+}
+
+func dnsQueryLog(router adapter.DNSRouter) adapter.DNSQueryLog {
+	queryLogRouter, isQueryLogRouter := router.(adapter.DNSQueryLogRouter)
+	if !isQueryLogRouter {
+		return nil
+	}
+	return queryLogRouter.QueryLog()
+}
+
+func parseQueryLogFilter(r *http.Request) (adapter.DNSQueryLogFilter, error) {
+	query := r.URL.Query()
+	filter := adapter.DNSQueryLogFilter{
+		Domain:    query.Get("domain"),
+		Rcode:     query.Get("rcode"),
+		Transport: query.Get("transport"),
+		Limit:     defaultQueryLogLimit,
+	}
+	if client := query.Get("client"); client != "" {
+		if strings.Contains(client, "/") {
+			prefix, err := netip.ParsePrefix(client)
+			if err != nil {
+				return filter, err
+			}
+			filter.Client = prefix.Masked()
+		} else {
+			addr, err := netip.ParseAddr(client)
+			if err != nil {
+				return filter, err
+			}
+			addr = addr.Unmap()
+			filter.Client = netip.PrefixFrom(addr, addr.BitLen())
+		}
+	}
+	if before := query.Get("before"); before != "" {
+		beforeID, err := strconv.ParseUint(before, 10, 64)
+		if err != nil {
+			return filter, err
+		}
+		filter.Before = beforeID
+	}
+	if limit := query.Get("limit"); limit != "" {
+		limitValue, err := strconv.ParseUint(limit, 10, 31)
+		if err != nil {
+			return filter, err
+		}
+		filter.Limit = int(limitValue)
+	}
+	return filter, nil
+}
+
+func getQueryLog(ctx context.Context, router adapter.DNSRouter) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryLog := dnsQueryLog(router)
+		if queryLog == nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, newError("DNS query log disabled"))
+			return
+		}
+		filter, err := parseQueryLogFilter(r)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		if r.Header.Get("Upgrade") != "websocket" {
+			entries := queryLog.Entries(filter)
+			queries := make([]DNSQuery, 0, len(entries))
+			for _, entry := range entries {
+				queries = append(queries, newDNSQuery(entry))
+			}
+			render.JSON(w, r, render.M{
+				"queries": queries,
+			})
+			return
+		}
+
+		subscription, done, err := queryLog.Subscribe()
+		if err != nil {
+			render.Status(r, http.StatusNoContent)
+			return
+		}
+		defer queryLog.UnSubscribe(subscription)
+
+		conn, _, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := &bytes.Buffer{}
+		var entry adapter.DNSQueryLogEntry
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case entry = <-subscription:
+			}
+			if !filter.Match(&entry) {
+				continue
+			}
+			buf.Reset()
+			err = json.NewEncoder(buf).Encode(newDNSQuery(entry))
+			if err != nil {
+				break
+			}
+			err = wsutil.WriteServerText(conn, buf.Bytes())
+			if err != nil {
+				break
+			}
+		}
+	}
+}
+
+func clearQueryLog(router adapter.DNSRouter) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryLog := dnsQueryLog(router)
+		if queryLog == nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, newError("DNS query log disabled"))
+			return
+		}
+		queryLog.Clear()
+		render.NoContent(w, r)
+	}
+}
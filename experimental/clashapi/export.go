@@ -0,0 +1,91 @@
+package clashapi
+
+import (
+	"net/http"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/provider"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/go-chi/render"
+)
+
+func exportProxy(server *Server) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		proxy := r.Context().Value(CtxKeyProxy).(adapter.Outbound)
+		outbound, loaded := lookupOutboundOptions(server, proxy.Tag())
+		if !loaded {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		writeExport(server, w, r, []option.Outbound{outbound}, true)
+	}
+}
+
+func exportProvider(server *Server) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		outboundProvider := r.Context().Value(CtxKeyProvider).(adapter.OutboundProvider)
+		optionsManager, isOptionsManager := outboundProvider.(adapter.OutboundOptionsManager)
+		if !isOptionsManager {
+			render.Status(r, http.StatusNotImplemented)
+			render.JSON(w, r, newError("export not supported"))
+			return
+		}
+		var outbounds []option.Outbound
+		for _, detour := range outboundProvider.Outbounds() {
+			outbound, loaded := optionsManager.OutboundOptions(detour.Tag())
+			if loaded {
+				outbounds = append(outbounds, outbound)
+			}
+		}
+		writeExport(server, w, r, outbounds, false)
+	}
+}
+
+func lookupOutboundOptions(server *Server, tag string) (option.Outbound, bool) {
+	if optionsManager, isOptionsManager := server.outbound.(adapter.OutboundOptionsManager); isOptionsManager {
+		if outbound, loaded := optionsManager.OutboundOptions(tag); loaded {
+			return outbound, true
+		}
+	}
+	for _, outboundProvider := range server.provider.OutboundProviders() {
+		optionsManager, isOptionsManager := outboundProvider.(adapter.OutboundOptionsManager)
+		if !isOptionsManager {
+			continue
+		}
+		if outbound, loaded := optionsManager.OutboundOptions(tag); loaded {
+			return outbound, true
+		}
+	}
+	return option.Outbound{}, false
+}
+
+// writeExport renders outbounds as share links or as a Clash proxies list.
+// Unsupported outbounds fail the request when strict is set and are skipped
+// otherwise.
+func writeExport(server *Server, w http.ResponseWriter, r *http.Request, outbounds []option.Outbound, strict bool) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = provider.ExportFormatURI
+	}
+	content, err := provider.ExportOutbounds(outbounds, format, func(outbound option.Outbound, err error) error {
+		if strict {
+			return err
+		}
+		server.logger.Warn("export: skip outbound[", outbound.Tag, "]: ", err)
+		return nil
+	})
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, newError(err.Error()))
+		return
+	}
+	if format == provider.ExportFormatClash {
+		w.Header().Set("Content-Type", "text/yaml; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
@@ -24,6 +24,7 @@ func proxyProviderRouter(server *Server, router adapter.Router) http.Handler {
 		r.Get("/", getProvider(server))
 		r.Put("/", updateProvider(server, router))
 		r.Get("/healthcheck", healthCheckProvider(server))
+		r.Get("/export", exportProvider(server))
 	})
 	return r
 }
@@ -39,10 +40,18 @@ func providerInfo(server *Server, provider adapter.OutboundProvider) *render.M {
 			return proxyInfo(server, it)
 		}),
 	}
+	if format := provider.Format(); format != "" {
+		info["format"] = format
+	}
 	if provider.Type() == C.ProviderTypeRemote {
 		if subInfo := provider.SubInfo(); subInfo != nil {
 			info["subscriptionInfo"] = subInfo
 		}
+		if quota, isQuota := provider.(adapter.OutboundProviderQuota); isQuota {
+			if status := quota.QuotaStatus(); len(status.Warnings) > 0 || status.Exceeded {
+				info["quota"] = status
+			}
+		}
 	}
 	return &info
 }
@@ -29,6 +29,7 @@ func proxyRouter(server *Server, router adapter.Router) http.Handler {
 		r.Use(parseProxyName, findProxyByName(server))
 		r.Get("/", getProxy(server))
 		r.Get("/delay", getProxyDelay(server))
+		r.Get("/export", exportProxy(server))
 		r.Put("/", updateProxy)
 	})
 	return r
@@ -86,10 +87,31 @@ func proxyInfo(server *Server, detour adapter.Outbound) *badjson.JSONObject {
 		}
 		info.Put("now", group.Now())
 		info.Put("all", group.All())
+		if breakerGroup, isBreaker := group.(adapter.CircuitBreakerGroup); isBreaker {
+			// nil if the circuit breaker is disabled
+			if status := breakerGroup.CircuitBreakerStatus(); status != nil {
+				info.Put("circuitBreaker", circuitBreakerInfo(status))
+			}
+		}
 	}
 	return &info
 }
 
+func circuitBreakerInfo(status map[string]adapter.CircuitBreakerStatus) map[string]any {
+	info := make(map[string]any, len(status))
+	for tag, it := range status {
+		item := map[string]any{
+			"state":    it.State,
+			"failures": it.Failures,
+		}
+		if !it.RetryAt.IsZero() {
+			item["retryAt"] = it.RetryAt.Format("2006-01-02T15:04:05.999999999-07:00")
+		}
+		info[tag] = item
+	}
+	return info
+}
+
 func getProxies(server *Server) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var proxyMap badjson.JSONObject
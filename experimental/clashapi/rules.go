@@ -1,7 +1,11 @@
 package clashapi
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 
@@ -12,29 +16,112 @@ import (
 func ruleRouter(router adapter.Router) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/", getRules(router))
+	r.Delete("/stats", resetRuleStats(router))
 	return r
 }
 
 type Rule struct {
-	Type    string `json:"type"`
-	Payload string `json:"payload"`
-	Proxy   string `json:"proxy"`
+	Type     string     `json:"type"`
+	Payload  string     `json:"payload"`
+	Proxy    string     `json:"proxy"`
+	Matches  uint64     `json:"matches"`
+	Upload   uint64     `json:"upload"`
+	Download uint64     `json:"download"`
+	LastHit  *time.Time `json:"lastHit,omitempty"`
+}
+
+func ruleStats(router adapter.Router) []adapter.RuleStats {
+	statsRouter, isStatsRouter := router.(adapter.RuleStatsRouter)
+	if !isStatsRouter {
+		return nil
+	}
+	return statsRouter.RuleStats()
 }
 
 func getRules(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		rawRules := router.Rules()
+		stats := ruleStats(router)
 
 		var rules []Rule
-		for _, rule := range rawRules {
-			rules = append(rules, Rule{
+		for i, rule := range rawRules {
+			item := Rule{
 				Type:    rule.Type(),
 				Payload: rule.String(),
 				Proxy:   rule.Action().String(),
-			})
+			}
+			if i < len(stats) {
+				item.Matches = stats[i].Matches
+				item.Upload = stats[i].Upload
+				item.Download = stats[i].Download
+				if !stats[i].LastHit.IsZero() {
+					item.LastHit = &stats[i].LastHit
+				}
+			}
+			rules = append(rules, item)
 		}
 		render.JSON(w, r, render.M{
 			"rules": rules,
 		})
 	}
 }
+
+func resetRuleStats(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statsRouter, isStatsRouter := router.(adapter.RuleStatsRouter)
+		if !isStatsRouter {
+			render.Status(r, http.StatusNotImplemented)
+			render.JSON(w, r, newError("rule statistics not supported"))
+			return
+		}
+		statsRouter.ResetRuleStats()
+		render.NoContent(w, r)
+	}
+}
+
+// getMetrics exposes rule statistics in the Prometheus text format.
+func getMetrics(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rawRules := router.Rules()
+		stats := ruleStats(router)
+		labels := make([]string, len(stats))
+		for i := range stats {
+			labels[i] = fmt.Sprintf(`{index="%d",type=%s,payload=%s,action=%s}`,
+				i, metricLabel(rawRules[i].Type()), metricLabel(rawRules[i].String()), metricLabel(rawRules[i].Action().String()))
+		}
+		var builder strings.Builder
+		writeMetric := func(name string, metricType string, help string, value func(stats adapter.RuleStats) (uint64, bool)) {
+			fmt.Fprintf(&builder, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+			for i, it := range stats {
+				metricValue, loaded := value(it)
+				if loaded {
+					builder.WriteString(name + labels[i] + " " + strconv.FormatUint(metricValue, 10) + "\n")
+				}
+			}
+		}
+		writeMetric("sing_box_rule_matches_total", "counter", "Number of times the route rule matched.", func(stats adapter.RuleStats) (uint64, bool) {
+			return stats.Matches, true
+		})
+		writeMetric("sing_box_rule_upload_bytes_total", "counter", "Bytes uploaded by connections routed by the rule.", func(stats adapter.RuleStats) (uint64, bool) {
+			return stats.Upload, true
+		})
+		writeMetric("sing_box_rule_download_bytes_total", "counter", "Bytes downloaded by connections routed by the rule.", func(stats adapter.RuleStats) (uint64, bool) {
+			return stats.Download, true
+		})
+		writeMetric("sing_box_rule_last_hit_timestamp_seconds", "gauge", "Unix time the route rule last matched.", func(stats adapter.RuleStats) (uint64, bool) {
+			if stats.LastHit.IsZero() {
+				return 0, false
+			}
+			return uint64(stats.LastHit.Unix()), true
+		})
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write([]byte(builder.String()))
+	}
+}
+
+func metricLabel(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return `"` + value + `"`
+}
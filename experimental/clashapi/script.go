@@ -2,97 +2,192 @@ package clashapi
 
 import (
 	"net/http"
+	"net/netip"
+	"strconv"
+
+	"github.com/sagernet/sing-box/adapter"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
-func scriptRouter() http.Handler {
+func scriptRouter(router adapter.Router) http.Handler {
 	r := chi.NewRouter()
-	r.Post("/", testScript)
-	r.Patch("/", patchScript)
+	r.Get("/", getScript(router))
+	r.Post("/", testScript(router))
+	r.Patch("/", patchScript(router))
 	return r
 }
 
-/*type TestScriptRequest struct {
-	Script   *string    `json:"script"`
-	Metadata C.Metadata `json:"metadata"`
-}*/
+type ScriptMetadata struct {
+	Network         string `json:"network"`
+	Type            string `json:"type"`
+	InboundName     string `json:"inboundName"`
+	SourceIP        string `json:"sourceIP"`
+	DestinationIP   string `json:"destinationIP"`
+	SourcePort      string `json:"sourcePort"`
+	DestinationPort string `json:"destinationPort"`
+	Host            string `json:"host"`
+	SniffHost       string `json:"sniffHost"`
+	ProcessPath     string `json:"processPath"`
+}
+
+func (m ScriptMetadata) Build() (adapter.InboundContext, error) {
+	metadata := adapter.InboundContext{
+		Inbound:     m.InboundName,
+		InboundType: m.Type,
+		Network:     m.Network,
+		SniffHost:   m.SniffHost,
+	}
+	switch metadata.Network {
+	case "":
+		metadata.Network = N.NetworkTCP
+	case N.NetworkTCP, N.NetworkUDP:
+	default:
+		return metadata, newError("invalid network: " + m.Network)
+	}
+	var err error
+	metadata.Source, err = buildScriptAddress(m.SourceIP, m.SourcePort)
+	if err != nil {
+		return metadata, err
+	}
+	if m.Host != "" {
+		metadata.Destination = M.ParseSocksaddrHostPort(m.Host, 0)
+	}
+	destination, err := buildScriptAddress(m.DestinationIP, m.DestinationPort)
+	if err != nil {
+		return metadata, err
+	}
+	metadata.Destination.Port = destination.Port
+	if destination.Addr.IsValid() {
+		if metadata.Destination.IsFqdn() {
+			metadata.DestinationAddresses = []netip.Addr{destination.Addr}
+		} else {
+			metadata.Destination.Addr = destination.Addr
+		}
+	}
+	if !metadata.Destination.IsValid() {
+		return metadata, newError("metadata not valid: missing `host` or `destinationIP`")
+	}
+	if metadata.Destination.IsIPv4() {
+		metadata.IPVersion = 4
+	} else if metadata.Destination.IsIPv6() {
+		metadata.IPVersion = 6
+	}
+	if m.ProcessPath != "" {
+		metadata.ProcessInfo = &adapter.ConnectionOwner{
+			ProcessPath: m.ProcessPath,
+			UserId:      -1,
+		}
+	}
+	return metadata, nil
+}
+
+func buildScriptAddress(address string, port string) (M.Socksaddr, error) {
+	var socksaddr M.Socksaddr
+	if address != "" {
+		addr, err := netip.ParseAddr(address)
+		if err != nil {
+			return socksaddr, newError("invalid address: " + address)
+		}
+		socksaddr.Addr = addr.Unmap()
+	}
+	if port != "" {
+		portNumber, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return socksaddr, newError("invalid port: " + port)
+		}
+		socksaddr.Port = uint16(portNumber)
+	}
+	return socksaddr, nil
+}
+
+type TestScriptRequest struct {
+	Script   *string        `json:"script"`
+	Metadata ScriptMetadata `json:"metadata"`
+}
+
+func getScript(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scriptRouter, isScriptRouter := router.(adapter.ScriptRouter)
+		if !isScriptRouter {
+			render.Status(r, http.StatusNotImplemented)
+			render.JSON(w, r, newError("script not supported"))
+			return
+		}
+		render.JSON(w, r, render.M{
+			"script": scriptRouter.Script(),
+		})
+	}
+}
 
-func testScript(w http.ResponseWriter, r *http.Request) {
-	/*	req := TestScriptRequest{}
+func testScript(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scriptRouter, isScriptRouter := router.(adapter.ScriptRouter)
+		if !isScriptRouter {
+			render.Status(r, http.StatusNotImplemented)
+			render.JSON(w, r, newError("script not supported"))
+			return
+		}
+		req := TestScriptRequest{}
 		if err := render.DecodeJSON(r.Body, &req); err != nil {
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, ErrBadRequest)
 			return
 		}
-
-		fn := tunnel.ScriptFn()
-		if req.Script == nil && fn == nil {
+		if req.Script == nil && scriptRouter.Script() == "" {
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, newError("should send `script`"))
 			return
 		}
-
-		if !req.Metadata.Valid() {
+		metadata, err := req.Metadata.Build()
+		if err != nil {
 			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, newError("metadata not valid"))
+			render.JSON(w, r, newError(err.Error()))
 			return
 		}
-
+		var source string
 		if req.Script != nil {
-			var err error
-			fn, err = script.ParseScript(*req.Script)
-			if err != nil {
-				render.Status(r, http.StatusBadRequest)
-				render.JSON(w, r, newError(err.Error()))
-				return
-			}
+			source = *req.Script
 		}
-
-		ctx, _ := script.MakeContext(tunnel.ProxyProviders(), tunnel.RuleProviders())
-
-		thread := &starlark.Thread{}
-		ret, err := starlark.Call(thread, fn, starlark.Tuple{ctx, script.MakeMetadata(&req.Metadata)}, nil)
+		result, err := scriptRouter.EvaluateScript(source, metadata)
 		if err != nil {
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, newError(err.Error()))
 			return
 		}
-
-		elm, ok := ret.(starlark.String)
-		if !ok {
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, "script fn must return a string")
-			return
-		}
-
 		render.JSON(w, r, render.M{
-			"result": string(elm),
-		})*/
-	render.Status(r, http.StatusBadRequest)
-	render.JSON(w, r, newError("not implemented"))
+			"result": result,
+		})
+	}
 }
 
 type PatchScriptRequest struct {
 	Script string `json:"script"`
 }
 
-func patchScript(w http.ResponseWriter, r *http.Request) {
-	/*req := PatchScriptRequest{}
-	if err := render.DecodeJSON(r.Body, &req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, ErrBadRequest)
-		return
-	}
-
-	fn, err := script.ParseScript(req.Script)
-	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, newError(err.Error()))
-		return
+func patchScript(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scriptRouter, isScriptRouter := router.(adapter.ScriptRouter)
+		if !isScriptRouter {
+			render.Status(r, http.StatusNotImplemented)
+			render.JSON(w, r, newError("script not supported"))
+			return
+		}
+		req := PatchScriptRequest{}
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		err := scriptRouter.UpdateScript(req.Script)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
 	}
-
-	tunnel.UpdateScript(fn)*/
-	render.NoContent(w, r)
 }
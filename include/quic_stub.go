@@ -17,6 +17,7 @@ import (
 	"github.com/sagernet/sing-box/dns"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	dnsInbound "github.com/sagernet/sing-box/protocol/dns"
 	"github.com/sagernet/sing-box/protocol/naive"
 	"github.com/sagernet/sing-box/transport/v2ray"
 	"github.com/sagernet/sing/common/logger"
@@ -48,6 +49,12 @@ func registerQUICInbounds(registry *inbound.Registry) {
 	naive.ConfigureHTTP3ListenerFunc = func(ctx context.Context, logger logger.Logger, listener *listener.Listener, handler http.Handler, tlsConfig tls.ServerConfig, options option.NaiveInboundOptions) (io.Closer, error) {
 		return nil, C.ErrQUICNotIncluded
 	}
+	dnsInbound.ListenQUICFunc = func(ctx context.Context, logger logger.ContextLogger, listener *listener.Listener, tlsConfig tls.ServerConfig, handler func(ctx context.Context, stream io.ReadWriter, source M.Socksaddr) error) (io.Closer, error) {
+		return nil, C.ErrQUICNotIncluded
+	}
+	dnsInbound.ListenHTTP3Func = func(ctx context.Context, logger logger.ContextLogger, listener *listener.Listener, tlsConfig tls.ServerConfig, handler http.Handler) (io.Closer, error) {
+		return nil, C.ErrQUICNotIncluded
+	}
 }
 
 func registerQUICOutbounds(registry *outbound.Registry) {
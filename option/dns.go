@@ -15,13 +15,19 @@ import (
 )
 
 type RawDNSOptions struct {
-	Servers        []DNSServerOptions `json:"servers,omitempty"`
-	Rules          []DNSRule          `json:"rules,omitempty"`
-	Final          string             `json:"final,omitempty"`
-	ReverseMapping bool               `json:"reverse_mapping,omitempty"`
+	Servers        []DNSServerOptions  `json:"servers,omitempty"`
+	Rules          []DNSRule           `json:"rules,omitempty"`
+	Final          string              `json:"final,omitempty"`
+	ReverseMapping bool                `json:"reverse_mapping,omitempty"`
+	QueryLog       *DNSQueryLogOptions `json:"query_log,omitempty"`
 	DNSClientOptions
 }
 
+type DNSQueryLogOptions struct {
+	Enabled  bool   `json:"enabled,omitempty"`
+	Capacity uint32 `json:"capacity,omitempty"`
+}
+
 type DNSOptions struct {
 	RawDNSOptions
 }
@@ -59,6 +65,7 @@ type DNSClientOptions struct {
 	CacheMaxTTL      uint32                `json:"cache_max_ttl,omitempty"`
 	Optimistic       *OptimisticDNSOptions `json:"optimistic,omitempty"`
 	ClientSubnet     *badoption.Prefixable `json:"client_subnet,omitempty"`
+	DNSSEC           bool                  `json:"dnssec,omitempty"`
 }
 
 type _OptimisticDNSOptions struct {
@@ -221,5 +228,23 @@ type MDNSDNSServerOptions struct {
 }
 
 type GroupDNSServerOptions struct {
-	Servers []string `json:"servers"`
+	Servers         []string                         `json:"servers"`
+	Strategy        string                           `json:"strategy,omitempty"`
+	Timeout         badoption.Duration               `json:"timeout,omitempty"`
+	ProbeInterval   badoption.Duration               `json:"probe_interval,omitempty"`
+	TrustedServers  []string                         `json:"trusted_servers,omitempty"`
+	ExpectedIPCIDR  badoption.Listable[netip.Prefix] `json:"expected_ip_cidr,omitempty"`
+	ExpectedRuleSet badoption.Listable[string]       `json:"expected_rule_set,omitempty"`
+}
+
+type DNSCryptDNSServerOptions struct {
+	RawLocalDNSServerOptions
+	Stamp string `json:"stamp"`
+}
+
+type ODoHDNSServerOptions struct {
+	RemoteTLSDNSServerOptions
+	Path    string               `json:"path,omitempty"`
+	Headers badoption.HTTPHeader `json:"headers,omitempty"`
+	Target  string               `json:"target"`
 }
@@ -0,0 +1,14 @@
+package option
+
+type DNSInboundOptions struct {
+	ListenOptions
+	Network   NetworkList                 `json:"network,omitempty"`
+	Path      string                      `json:"path,omitempty"`
+	RateLimit *DNSInboundRateLimitOptions `json:"rate_limit,omitempty"`
+	InboundTLSOptionsContainer
+}
+
+type DNSInboundRateLimitOptions struct {
+	QueriesPerSecond uint32 `json:"queries_per_second"`
+	Burst            uint32 `json:"burst,omitempty"`
+}
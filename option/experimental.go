@@ -29,13 +29,16 @@ func (o *ExperimentalOptions) Apply() {
 }
 
 type CacheFileOptions struct {
-	Enabled     bool               `json:"enabled,omitempty"`
-	Path        string             `json:"path,omitempty"`
-	CacheID     string             `json:"cache_id,omitempty"`
-	StoreFakeIP bool               `json:"store_fakeip,omitempty"`
-	StoreRDRC   bool               `json:"store_rdrc,omitempty"`
-	RDRCTimeout badoption.Duration `json:"rdrc_timeout,omitempty"`
-	StoreDNS    bool               `json:"store_dns,omitempty"`
+	Enabled               bool               `json:"enabled,omitempty"`
+	Path                  string             `json:"path,omitempty"`
+	CacheID               string             `json:"cache_id,omitempty"`
+	StoreFakeIP           bool               `json:"store_fakeip,omitempty"`
+	StoreRDRC             bool               `json:"store_rdrc,omitempty"`
+	RDRCTimeout           badoption.Duration `json:"rdrc_timeout,omitempty"`
+	StoreDNS              bool               `json:"store_dns,omitempty"`
+	StoreRuleStats        bool               `json:"store_rule_stats,omitempty"`
+	StoreDNSQueryLog      bool               `json:"store_dns_query_log,omitempty"`
+	URLTestHistoryTimeout badoption.Duration `json:"urltest_history_timeout,omitempty"`
 }
 
 type ClashAPIOptions struct {
@@ -24,20 +24,29 @@ type SelectorOutboundOptions struct {
 
 type URLTestOutboundOptions struct {
 	GroupOutboundOptions
-	URL                       string             `json:"url,omitempty"`
-	Interval                  badoption.Duration `json:"interval,omitempty"`
-	Tolerance                 uint16             `json:"tolerance,omitempty"`
-	IdleTimeout               badoption.Duration `json:"idle_timeout,omitempty"`
-	InterruptExistConnections bool               `json:"interrupt_exist_connections,omitempty"`
+	URL                       string                 `json:"url,omitempty"`
+	Interval                  badoption.Duration     `json:"interval,omitempty"`
+	Tolerance                 uint16                 `json:"tolerance,omitempty"`
+	IdleTimeout               badoption.Duration     `json:"idle_timeout,omitempty"`
+	InterruptExistConnections bool                   `json:"interrupt_exist_connections,omitempty"`
+	CircuitBreaker            *CircuitBreakerOptions `json:"circuit_breaker,omitempty"`
+	Probe                     *ProbeOptions          `json:"probe,omitempty"`
 }
 
 type FallbackOutboundOptions struct {
 	GroupOutboundOptions
-	URL                       string             `json:"url,omitempty"`
-	Interval                  badoption.Duration `json:"interval,omitempty"`
-	MaxDelay                  badoption.Duration `json:"max_delay,omitempty"`
-	IdleTimeout               badoption.Duration `json:"idle_timeout,omitempty"`
-	InterruptExistConnections bool               `json:"interrupt_exist_connections,omitempty"`
+	URL                       string                 `json:"url,omitempty"`
+	Interval                  badoption.Duration     `json:"interval,omitempty"`
+	MaxDelay                  badoption.Duration     `json:"max_delay,omitempty"`
+	IdleTimeout               badoption.Duration     `json:"idle_timeout,omitempty"`
+	InterruptExistConnections bool                   `json:"interrupt_exist_connections,omitempty"`
+	CircuitBreaker            *CircuitBreakerOptions `json:"circuit_breaker,omitempty"`
+	Probe                     *ProbeOptions          `json:"probe,omitempty"`
+}
+
+type CircuitBreakerOptions struct {
+	MaxFailures uint32             `json:"max_failures,omitempty"`
+	Timeout     badoption.Duration `json:"timeout,omitempty"`
 }
 
 type LoadBalanceOutboundOptions struct {
@@ -48,4 +57,14 @@ type LoadBalanceOutboundOptions struct {
 	TTL                       badoption.Duration `json:"ttl,omitempty"`
 	InterruptExistConnections bool               `json:"interrupt_exist_connections,omitempty"`
 	Strategy                  string             `json:"strategy,omitempty"`
+	Weights                   map[string]uint32  `json:"weights,omitempty"`
+	Probe                     *ProbeOptions      `json:"probe,omitempty"`
+}
+
+type ProbeOptions struct {
+	Type           string                  `json:"type,omitempty"`
+	Server         string                  `json:"server,omitempty"`
+	Domain         string                  `json:"domain,omitempty"`
+	ExpectedStatus badoption.Listable[int] `json:"expected_status,omitempty"`
+	ExpectedBody   string                  `json:"expected_body,omitempty"`
 }
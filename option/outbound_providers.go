@@ -47,13 +47,20 @@ type LocalProviderOptions struct {
 }
 
 type RemoteProviderOptions struct {
-	Url       string             `json:"download_url"`
-	UserAgent string             `json:"download_ua,omitempty"`
-	Interval  badoption.Duration `json:"download_interval,omitempty"`
-	Detour    string             `json:"download_detour,omitempty"`
+	Url       string                `json:"download_url"`
+	UserAgent string                `json:"download_ua,omitempty"`
+	Interval  badoption.Duration    `json:"download_interval,omitempty"`
+	Detour    string                `json:"download_detour,omitempty"`
+	Quota     *ProviderQuotaOptions `json:"quota,omitempty"`
 	HealthcheckOptions
 }
 
+type ProviderQuotaOptions struct {
+	UsageThreshold  uint8              `json:"usage_threshold,omitempty"`
+	ExpireThreshold badoption.Duration `json:"expire_threshold,omitempty"`
+	DisableExceeded bool               `json:"disable_exceeded,omitempty"`
+}
+
 func (h OutboundProvider) MarshalJSON() ([]byte, error) {
 	var v any
 	switch h.Type {
@@ -92,4 +99,5 @@ type HealthcheckOptions struct {
 	EnableHealthcheck   bool               `json:"enable_healthcheck,omitempty"`
 	HealthcheckUrl      string             `json:"healthcheck_url,omitempty"`
 	HealthcheckInterval badoption.Duration `json:"healthcheck_interval,omitempty"`
+	HealthcheckProbe    *ProbeOptions      `json:"healthcheck_probe,omitempty"`
 }
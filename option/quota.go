@@ -0,0 +1,21 @@
+package option
+
+import (
+	"github.com/sagernet/sing/common/byteformats"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+type QuotaServiceOptions struct {
+	ListenOptions
+	Inbounds     badoption.Listable[string] `json:"inbounds,omitempty"`
+	Users        []QuotaUserOptions         `json:"users,omitempty"`
+	SaveInterval badoption.Duration         `json:"save_interval,omitempty"`
+	Secret       string                     `json:"secret,omitempty"`
+	InboundTLSOptionsContainer
+}
+
+type QuotaUserOptions struct {
+	Name   string                   `json:"name"`
+	Limit  *byteformats.MemoryBytes `json:"limit,omitempty"`
+	Expire string                   `json:"expire,omitempty"`
+}
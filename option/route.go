@@ -5,6 +5,7 @@ import "github.com/sagernet/sing/common/json/badoption"
 type RouteOptions struct {
 	GeoIP                      *GeoIPOptions                     `json:"geoip,omitempty"`
 	Geosite                    *GeositeOptions                   `json:"geosite,omitempty"`
+	ASN                        *ASNOptions                       `json:"asn,omitempty"`
 	Rules                      []Rule                            `json:"rules,omitempty"`
 	RuleSet                    []RuleSet                         `json:"rule_set,omitempty"`
 	Final                      string                            `json:"final,omitempty"`
@@ -21,6 +22,7 @@ type RouteOptions struct {
 	DefaultFallbackNetworkType badoption.Listable[InterfaceType] `json:"default_fallback_network_type,omitempty"`
 	DefaultFallbackDelay       badoption.Duration                `json:"default_fallback_delay,omitempty"`
 	DefaultHTTPClient          string                            `json:"default_http_client,omitempty"`
+	Script                     badoption.Listable[string]        `json:"script,omitempty"`
 }
 
 type GeoIPOptions struct {
@@ -29,6 +31,12 @@ type GeoIPOptions struct {
 	DownloadDetour string `json:"download_detour,omitempty"`
 }
 
+type ASNOptions struct {
+	Path        string             `json:"path,omitempty"`
+	DownloadURL string             `json:"download_url,omitempty"`
+	HTTPClient  *HTTPClientOptions `json:"http_client,omitempty"`
+}
+
 type GeositeOptions struct {
 	Path           string `json:"path,omitempty"`
 	DownloadURL    string `json:"download_url,omitempty"`
@@ -72,6 +72,12 @@ type RawDefaultRule struct {
 	AuthUser                 badoption.Listable[string]                                                  `json:"auth_user,omitempty"`
 	Protocol                 badoption.Listable[string]                                                  `json:"protocol,omitempty"`
 	Client                   badoption.Listable[string]                                                  `json:"client,omitempty"`
+	TLSFingerprint           badoption.Listable[string]                                                  `json:"tls_fingerprint,omitempty"`
+	HTTPMethod               badoption.Listable[string]                                                  `json:"http_method,omitempty"`
+	HTTPPath                 badoption.Listable[string]                                                  `json:"http_path,omitempty"`
+	HTTPPathRegex            badoption.Listable[string]                                                  `json:"http_path_regex,omitempty"`
+	HTTPUserAgent            badoption.Listable[string]                                                  `json:"http_user_agent,omitempty"`
+	HTTPUserAgentRegex       badoption.Listable[string]                                                  `json:"http_user_agent_regex,omitempty"`
 	Domain                   badoption.Listable[string]                                                  `json:"domain,omitempty"`
 	DomainSuffix             badoption.Listable[string]                                                  `json:"domain_suffix,omitempty"`
 	DomainKeyword            badoption.Listable[string]                                                  `json:"domain_keyword,omitempty"`
@@ -81,8 +87,10 @@ type RawDefaultRule struct {
 	GeoIP                    badoption.Listable[string]                                                  `json:"geoip,omitempty"`
 	SourceIPCIDR             badoption.Listable[string]                                                  `json:"source_ip_cidr,omitempty"`
 	SourceIPIsPrivate        bool                                                                        `json:"source_ip_is_private,omitempty"`
+	SourceIPASN              badoption.Listable[string]                                                  `json:"source_ip_asn,omitempty"`
 	IPCIDR                   badoption.Listable[string]                                                  `json:"ip_cidr,omitempty"`
 	IPIsPrivate              bool                                                                        `json:"ip_is_private,omitempty"`
+	IPASN                    badoption.Listable[string]                                                  `json:"ip_asn,omitempty"`
 	SourcePort               badoption.Listable[uint16]                                                  `json:"source_port,omitempty"`
 	SourcePortRange          badoption.Listable[string]                                                  `json:"source_port_range,omitempty"`
 	Port                     badoption.Listable[uint16]                                                  `json:"port,omitempty"`
@@ -95,6 +103,9 @@ type RawDefaultRule struct {
 	User                     badoption.Listable[string]                                                  `json:"user,omitempty"`
 	UserID                   badoption.Listable[int32]                                                   `json:"user_id,omitempty"`
 	ClashMode                string                                                                      `json:"clash_mode,omitempty"`
+	TimeRange                badoption.Listable[string]                                                  `json:"time_range,omitempty"`
+	Weekday                  badoption.Listable[string]                                                  `json:"weekday,omitempty"`
+	Timezone                 string                                                                      `json:"timezone,omitempty"`
 	NetworkType              badoption.Listable[InterfaceType]                                           `json:"network_type,omitempty"`
 	NetworkIsExpensive       bool                                                                        `json:"network_is_expensive,omitempty"`
 	NetworkIsConstrained     bool                                                                        `json:"network_is_constrained,omitempty"`
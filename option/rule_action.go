@@ -7,6 +7,7 @@ import (
 	"time"
 
 	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common/byteformats"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/json/badjson"
@@ -22,6 +23,7 @@ type _RuleAction struct {
 	RejectOptions       RejectActionOptions       `json:"-"`
 	SniffOptions        RouteActionSniff          `json:"-"`
 	ResolveOptions      RouteActionResolve        `json:"-"`
+	ScriptOptions       RouteActionScript         `json:"-"`
 }
 
 type RuleAction _RuleAction
@@ -49,6 +51,8 @@ func (r RuleAction) MarshalJSON() ([]byte, error) {
 		v = r.SniffOptions
 	case C.RuleActionTypeResolve:
 		v = r.ResolveOptions
+	case C.RuleActionTypeScript:
+		v = r.ScriptOptions
 	default:
 		return nil, E.New("unknown rule action: " + r.Action)
 	}
@@ -82,6 +86,8 @@ func (r *RuleAction) UnmarshalJSON(data []byte) error {
 		v = &r.SniffOptions
 	case C.RuleActionTypeResolve:
 		v = &r.ResolveOptions
+	case C.RuleActionTypeScript:
+		v = &r.ScriptOptions
 	default:
 		return E.New("unknown rule action: " + r.Action)
 	}
@@ -184,6 +190,14 @@ type RawRouteOptionsActionOptions struct {
 	TLSRecordFragment        bool               `json:"tls_record_fragment,omitempty"`
 	TLSSpoof                 string             `json:"tls_spoof,omitempty"`
 	TLSSpoofMethod           string             `json:"tls_spoof_method,omitempty"`
+
+	BandwidthLimit *BandwidthLimitOptions `json:"bandwidth_limit,omitempty"`
+}
+
+type BandwidthLimitOptions struct {
+	Upload   *byteformats.NetworkBytesCompat `json:"upload,omitempty"`
+	Download *byteformats.NetworkBytesCompat `json:"download,omitempty"`
+	Scope    string                          `json:"scope,omitempty"`
 }
 
 type RouteOptionsActionOptions RawRouteOptionsActionOptions
@@ -208,6 +222,7 @@ type DNSRouteActionOptions struct {
 	Strategy               DomainStrategy        `json:"strategy,omitempty"`
 	DisableCache           bool                  `json:"disable_cache,omitempty"`
 	DisableOptimisticCache bool                  `json:"disable_optimistic_cache,omitempty"`
+	DisableDNSSEC          bool                  `json:"disable_dnssec,omitempty"`
 	RewriteTTL             *uint32               `json:"rewrite_ttl,omitempty"`
 	ClientSubnet           *badoption.Prefixable `json:"client_subnet,omitempty"`
 }
@@ -217,6 +232,7 @@ type _DNSRouteOptionsActionOptions struct {
 	Timeout                badoption.Duration    `json:"timeout,omitempty"`
 	DisableCache           bool                  `json:"disable_cache,omitempty"`
 	DisableOptimisticCache bool                  `json:"disable_optimistic_cache,omitempty"`
+	DisableDNSSEC          bool                  `json:"disable_dnssec,omitempty"`
 	RewriteTTL             *uint32               `json:"rewrite_ttl,omitempty"`
 	ClientSubnet           *badoption.Prefixable `json:"client_subnet,omitempty"`
 }
@@ -339,6 +355,10 @@ type RouteActionResolve struct {
 	ClientSubnet           *badoption.Prefixable `json:"client_subnet,omitempty"`
 }
 
+type RouteActionScript struct {
+	Script badoption.Listable[string] `json:"script,omitempty"`
+}
+
 type DNSRouteActionPredefined struct {
 	Rcode  *DNSRCode                            `json:"rcode,omitempty"`
 	Answer badoption.Listable[DNSRecordOptions] `json:"answer,omitempty"`
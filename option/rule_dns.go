@@ -80,6 +80,7 @@ type RawDefaultDNSRule struct {
 	DomainRegex              badoption.Listable[string]                                                  `json:"domain_regex,omitempty"`
 	SourceIPCIDR             badoption.Listable[string]                                                  `json:"source_ip_cidr,omitempty"`
 	SourceIPIsPrivate        bool                                                                        `json:"source_ip_is_private,omitempty"`
+	SourceIPASN              badoption.Listable[string]                                                  `json:"source_ip_asn,omitempty"`
 	SourcePort               badoption.Listable[uint16]                                                  `json:"source_port,omitempty"`
 	SourcePortRange          badoption.Listable[string]                                                  `json:"source_port_range,omitempty"`
 	Port                     badoption.Listable[uint16]                                                  `json:"port,omitempty"`
@@ -93,6 +94,9 @@ type RawDefaultDNSRule struct {
 	UserID                   badoption.Listable[int32]                                                   `json:"user_id,omitempty"`
 	Outbound                 badoption.Listable[string]                                                  `json:"outbound,omitempty"`
 	ClashMode                string                                                                      `json:"clash_mode,omitempty"`
+	TimeRange                badoption.Listable[string]                                                  `json:"time_range,omitempty"`
+	Weekday                  badoption.Listable[string]                                                  `json:"weekday,omitempty"`
+	Timezone                 string                                                                      `json:"timezone,omitempty"`
 	NetworkType              badoption.Listable[InterfaceType]                                           `json:"network_type,omitempty"`
 	NetworkIsExpensive       bool                                                                        `json:"network_is_expensive,omitempty"`
 	NetworkIsConstrained     bool                                                                        `json:"network_is_constrained,omitempty"`
@@ -109,6 +113,7 @@ type RawDefaultDNSRule struct {
 	MatchResponse            bool                                                                        `json:"match_response,omitempty"`
 	IPCIDR                   badoption.Listable[string]                                                  `json:"ip_cidr,omitempty"`
 	IPIsPrivate              bool                                                                        `json:"ip_is_private,omitempty"`
+	IPASN                    badoption.Listable[string]                                                  `json:"ip_asn,omitempty"`
 	IPAcceptAny              bool                                                                        `json:"ip_accept_any,omitempty"`
 	ResponseRcode            *DNSRCode                                                                   `json:"response_rcode,omitempty"`
 	ResponseAnswer           badoption.Listable[DNSRecordOptions]                                        `json:"response_answer,omitempty"`
@@ -93,6 +93,7 @@ func (r *RuleSet) UnmarshalJSON(bytes []byte) error {
 		case "":
 			return E.New("missing format")
 		case C.RuleSetFormatSource, C.RuleSetFormatBinary:
+		case C.RuleSetFormatAdGuard, C.RuleSetFormatClash, C.RuleSetFormatSurge, C.RuleSetFormatDnsmasq, C.RuleSetFormatHosts:
 		default:
 			return E.New("unknown rule-set format: " + r.Format)
 		}
@@ -121,14 +122,22 @@ type LocalRuleSet struct {
 }
 
 type RemoteRuleSet struct {
-	URL            string             `json:"url"`
-	Path           string             `json:"path,omitempty"`
-	HTTPClient     *HTTPClientOptions `json:"http_client,omitempty"`
-	UpdateInterval badoption.Duration `json:"update_interval,omitempty"`
+	URL            string                `json:"url"`
+	Path           string                `json:"path,omitempty"`
+	HTTPClient     *HTTPClientOptions    `json:"http_client,omitempty"`
+	UpdateInterval badoption.Duration    `json:"update_interval,omitempty"`
+	Verify         *RuleSetVerifyOptions `json:"verify,omitempty"`
 	// Deprecated: use http_client instead
 	DownloadDetour string `json:"download_detour,omitempty"`
 }
 
+type RuleSetVerifyOptions struct {
+	SHA256               string `json:"sha256,omitempty"`
+	SHA256URL            string `json:"sha256_url,omitempty"`
+	MinisignPublicKey    string `json:"minisign_public_key,omitempty"`
+	MinisignSignatureURL string `json:"minisign_signature_url,omitempty"`
+}
+
 type _HeadlessRule struct {
 	Type           string              `json:"type,omitempty"`
 	DefaultOptions DefaultHeadlessRule `json:"-"`
@@ -202,6 +211,7 @@ type DefaultHeadlessRule struct {
 	ProcessPathRegex        badoption.Listable[string]                                                  `json:"process_path_regex,omitempty"`
 	PackageName             badoption.Listable[string]                                                  `json:"package_name,omitempty"`
 	PackageNameRegex        badoption.Listable[string]                                                  `json:"package_name_regex,omitempty"`
+	TLSFingerprint          badoption.Listable[string]                                                  `json:"tls_fingerprint,omitempty"`
 	NetworkType             badoption.Listable[InterfaceType]                                           `json:"network_type,omitempty"`
 	NetworkIsExpensive      bool                                                                        `json:"network_is_expensive,omitempty"`
 	NetworkIsConstrained    bool                                                                        `json:"network_is_constrained,omitempty"`
@@ -247,7 +257,7 @@ type PlainRuleSetCompat _PlainRuleSetCompat
 func (r PlainRuleSetCompat) MarshalJSON() ([]byte, error) {
 	var v any
 	switch r.Version {
-	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4, C.RuleSetVersion5:
+	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4, C.RuleSetVersion5, C.RuleSetVersion6:
 		v = r.Options
 	default:
 		return nil, E.New("unknown rule-set version: ", r.Version)
@@ -262,7 +272,7 @@ func (r *PlainRuleSetCompat) UnmarshalJSON(bytes []byte) error {
 	}
 	var v any
 	switch r.Version {
-	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4, C.RuleSetVersion5:
+	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4, C.RuleSetVersion5, C.RuleSetVersion6:
 		v = &r.Options
 	case 0:
 		return E.New("missing rule-set version")
@@ -279,7 +289,7 @@ func (r *PlainRuleSetCompat) UnmarshalJSON(bytes []byte) error {
 
 func (r PlainRuleSetCompat) Upgrade() (PlainRuleSet, error) {
 	switch r.Version {
-	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4, C.RuleSetVersion5:
+	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4, C.RuleSetVersion5, C.RuleSetVersion6:
 	default:
 		return PlainRuleSet{}, E.New("unknown rule-set version: " + F.ToString(r.Version))
 	}
@@ -0,0 +1,404 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/dns/transport"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-tun"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	aTLS "github.com/sagernet/sing/common/tls"
+	"github.com/sagernet/sing/service"
+
+	mDNS "github.com/miekg/dns"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	streamIdleTimeout = 2 * time.Minute
+	// maxStreamQueries limits the in-flight queries of a TCP, TLS or HTTP/2
+	// connection, further queries are not read until one is answered.
+	maxStreamQueries = 64
+	// maxInflightQueries limits the in-flight queries of the inbound across
+	// all connections, UDP queries beyond the limit are dropped.
+	maxInflightQueries = 1024
+)
+
+var (
+	ListenQUICFunc  func(ctx context.Context, logger logger.ContextLogger, listener *listener.Listener, tlsConfig tls.ServerConfig, handler func(ctx context.Context, stream io.ReadWriter, source M.Socksaddr) error) (io.Closer, error)
+	ListenHTTP3Func func(ctx context.Context, logger logger.ContextLogger, listener *listener.Listener, tlsConfig tls.ServerConfig, handler http.Handler) (io.Closer, error)
+)
+
+func RegisterInbound(registry *inbound.Registry) {
+	inbound.Register[option.DNSInboundOptions](registry, C.TypeDNS, NewInbound)
+}
+
+type Inbound struct {
+	inbound.Adapter
+	ctx              context.Context
+	router           adapter.DNSRouter
+	logger           log.ContextLogger
+	listener         *listener.Listener
+	network          []string
+	networkIsDefault bool
+	path             string
+	tlsConfig        tls.ServerConfig
+	rateLimiter      *clientRateLimiter
+	querySemaphore   *semaphore.Weighted
+	httpServer       *http.Server
+	quicServer       io.Closer
+}
+
+func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.DNSInboundOptions) (adapter.Inbound, error) {
+	inbound := &Inbound{
+		Adapter:          inbound.NewAdapter(C.TypeDNS, tag),
+		ctx:              ctx,
+		router:           service.FromContext[adapter.DNSRouter](ctx),
+		logger:           logger,
+		network:          options.Network.Build(),
+		networkIsDefault: options.Network == "",
+		path:             options.Path,
+		querySemaphore:   semaphore.NewWeighted(maxInflightQueries),
+	}
+	if options.TLS != nil && options.TLS.Enabled {
+		tlsConfig, err := tls.NewServerWithOptions(tls.ServerOptions{
+			Context: ctx,
+			Logger:  logger,
+			Options: common.PtrValueOrDefault(options.TLS),
+		})
+		if err != nil {
+			return nil, err
+		}
+		inbound.tlsConfig = tlsConfig
+	} else if inbound.path != "" && common.Contains(inbound.network, N.NetworkUDP) {
+		if !inbound.networkIsDefault {
+			return nil, E.New("TLS is required for DNS over HTTP/3")
+		}
+		inbound.network = []string{N.NetworkTCP}
+	}
+	if options.RateLimit != nil {
+		rateLimiter, err := newClientRateLimiter(*options.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+		inbound.rateLimiter = rateLimiter
+	}
+	inbound.listener = listener.New(listener.Options{
+		Context:           ctx,
+		Logger:            logger,
+		Network:           []string{N.NetworkTCP},
+		Listen:            options.ListenOptions,
+		ConnectionHandler: inbound,
+	})
+	return inbound, nil
+}
+
+func (h *Inbound) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	if h.tlsConfig != nil {
+		err := h.tlsConfig.Start()
+		if err != nil {
+			return E.Cause(err, "create TLS config")
+		}
+		if len(h.tlsConfig.NextProtos()) == 0 {
+			if h.path != "" {
+				h.tlsConfig.SetNextProtos([]string{http2.NextProtoTLS, "http/1.1", "h3"})
+			} else {
+				h.tlsConfig.SetNextProtos([]string{"dot", "doq"})
+			}
+		}
+	}
+	if common.Contains(h.network, N.NetworkTCP) {
+		if h.path != "" {
+			err := h.startHTTPServer()
+			if err != nil {
+				return err
+			}
+		} else {
+			err := h.listener.Start()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if common.Contains(h.network, N.NetworkUDP) {
+		err := h.startUDP()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Inbound) startHTTPServer() error {
+	tcpListener, err := h.listener.ListenTCP()
+	if err != nil {
+		return err
+	}
+	h.httpServer = &http.Server{
+		Handler: h2c.NewHandler(h, &http2.Server{
+			MaxConcurrentStreams: maxStreamQueries,
+		}),
+		ReadHeaderTimeout: C.TCPTimeout,
+		BaseContext: func(listener net.Listener) context.Context {
+			return h.ctx
+		},
+	}
+	go func() {
+		listener := tcpListener
+		if h.tlsConfig != nil {
+			listener = aTLS.NewListener(tcpListener, h.tlsConfig)
+		}
+		sErr := h.httpServer.Serve(listener)
+		if sErr != nil && !errors.Is(sErr, http.ErrServerClosed) {
+			h.logger.Error("http server serve error: ", sErr)
+		}
+	}()
+	return nil
+}
+
+func (h *Inbound) startUDP() error {
+	if h.tlsConfig == nil {
+		udpConn, err := h.listener.ListenUDP()
+		if err != nil {
+			return err
+		}
+		go h.loopUDPIn(udpConn)
+		return nil
+	}
+	var (
+		quicServer io.Closer
+		err        error
+	)
+	if h.path != "" {
+		quicServer, err = ListenHTTP3Func(h.ctx, h.logger, h.listener, h.tlsConfig, h)
+	} else {
+		quicServer, err = ListenQUICFunc(h.ctx, h.logger, h.listener, h.tlsConfig, h.serveQUICStream)
+	}
+	if err == nil {
+		h.quicServer = quicServer
+	} else if h.networkIsDefault {
+		h.logger.Warn(E.Cause(err, "dns over quic disabled"))
+	} else {
+		return err
+	}
+	return nil
+}
+
+func (h *Inbound) Close() error {
+	return common.Close(
+		h.listener,
+		common.PtrOrNil(h.httpServer),
+		h.quicServer,
+		h.tlsConfig,
+	)
+}
+
+func (h *Inbound) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	if h.tlsConfig != nil {
+		tlsConn, err := tls.ServerHandshake(ctx, conn, h.tlsConfig)
+		if err != nil {
+			N.CloseOnHandshakeFailure(conn, onClose, err)
+			h.logger.ErrorContext(ctx, E.Cause(err, "process connection from ", metadata.Source, ": TLS handshake"))
+			return
+		}
+		conn = tlsConn
+	}
+	err := h.serveStream(ctx, conn, metadata.Source)
+	conn.Close()
+	if onClose != nil {
+		onClose(err)
+	}
+	if err != nil && !E.IsClosedOrCanceled(err) && !E.IsTimeout(err) {
+		h.logger.DebugContext(ctx, E.Cause(err, "process connection from ", metadata.Source))
+	}
+}
+
+// serveStream answers length-prefixed queries on TCP and DNS over TLS
+// connections. Queries are answered concurrently, so responses may be out of
+// order as allowed by RFC 7766.
+func (h *Inbound) serveStream(ctx context.Context, conn net.Conn, source M.Socksaddr) error {
+	var (
+		writeAccess     sync.Mutex
+		inFlight        sync.WaitGroup
+		streamSemaphore = semaphore.NewWeighted(maxStreamQueries)
+	)
+	// answer pending queries even if the client has shut down its write side
+	defer inFlight.Wait()
+	for {
+		err := conn.SetReadDeadline(time.Now().Add(streamIdleTimeout))
+		if err != nil {
+			return err
+		}
+		message, err := transport.ReadMessage(conn)
+		if err != nil {
+			return err
+		}
+		err = streamSemaphore.Acquire(ctx, 1)
+		if err != nil {
+			return err
+		}
+		err = h.querySemaphore.Acquire(ctx, 1)
+		if err != nil {
+			streamSemaphore.Release(1)
+			return err
+		}
+		inFlight.Add(1)
+		go func() {
+			defer inFlight.Done()
+			defer streamSemaphore.Release(1)
+			defer h.querySemaphore.Release(1)
+			response := h.exchange(ctx, N.NetworkTCP, source, message)
+			if response == nil {
+				return
+			}
+			writeAccess.Lock()
+			defer writeAccess.Unlock()
+			err := writeStreamMessage(conn, response)
+			if err != nil {
+				conn.Close()
+			}
+		}()
+	}
+}
+
+// serveQUICStream answers a DNS over QUIC stream, which carries exactly one
+// query as defined in RFC 9250.
+func (h *Inbound) serveQUICStream(ctx context.Context, stream io.ReadWriter, source M.Socksaddr) error {
+	message, err := transport.ReadMessage(stream)
+	if err != nil {
+		return err
+	}
+	err = h.querySemaphore.Acquire(ctx, 1)
+	if err != nil {
+		return err
+	}
+	response := h.exchange(ctx, N.NetworkUDP, source, message)
+	h.querySemaphore.Release(1)
+	if response == nil {
+		return nil
+	}
+	return writeStreamMessage(stream, response)
+}
+
+func (h *Inbound) loopUDPIn(conn net.PacketConn) {
+	buffer := make([]byte, mDNS.MaxMsgSize)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if !E.IsClosed(err) {
+				h.logger.Error("udp listener closed: ", err)
+			}
+			return
+		}
+		var message mDNS.Msg
+		err = message.Unpack(buffer[:n])
+		if err != nil {
+			h.logger.Debug(E.Cause(err, "unpack query from ", addr))
+			continue
+		}
+		source := M.SocksaddrFromNet(addr).Unwrap()
+		if !h.querySemaphore.TryAcquire(1) {
+			h.logger.Debug("too many queries in flight, drop query from ", source)
+			continue
+		}
+		go func() {
+			defer h.querySemaphore.Release(1)
+			ctx := log.ContextWithNewID(h.ctx)
+			response := h.exchange(ctx, N.NetworkUDP, source, &message)
+			if response == nil {
+				return
+			}
+			responseBuffer, err := dns.TruncateDNSMessage(&message, response, 0)
+			if err != nil {
+				h.logger.ErrorContext(ctx, E.Cause(err, "pack response to ", source))
+				return
+			}
+			defer responseBuffer.Release()
+			_, err = conn.WriteTo(responseBuffer.Bytes(), source.UDPAddr())
+			if err != nil && !E.IsClosed(err) {
+				h.logger.DebugContext(ctx, E.Cause(err, "write response to ", source))
+			}
+		}()
+	}
+}
+
+// exchange passes a query through the DNS router. A nil response means the
+// query is dropped.
+func (h *Inbound) exchange(ctx context.Context, network string, source M.Socksaddr, message *mDNS.Msg) *mDNS.Msg {
+	if h.rateLimiter != nil && !h.rateLimiter.Allow(source.Addr) {
+		h.logger.DebugContext(ctx, "rate limit exceeded for ", source.Addr)
+		return responseStatus(message, mDNS.RcodeRefused)
+	}
+	var metadata adapter.InboundContext
+	metadata.Inbound = h.Tag()
+	metadata.InboundType = h.Type()
+	metadata.Network = network
+	metadata.Source = source
+	response, err := h.router.Exchange(adapter.WithContext(ctx, &metadata), message, adapter.DNSQueryOptions{})
+	if err != nil {
+		if errors.Is(err, tun.ErrDrop) {
+			return nil
+		}
+		var rcodeError dns.RcodeError
+		if errors.As(err, &rcodeError) {
+			return responseStatus(message, int(rcodeError))
+		}
+		return responseStatus(message, mDNS.RcodeServerFailure)
+	}
+	return response
+}
+
+func responseStatus(message *mDNS.Msg, rcode int) *mDNS.Msg {
+	return &mDNS.Msg{
+		MsgHdr: mDNS.MsgHdr{
+			Id:                 message.Id,
+			Response:           true,
+			Opcode:             message.Opcode,
+			RecursionDesired:   message.RecursionDesired,
+			RecursionAvailable: true,
+			Rcode:              rcode,
+		},
+		Question: message.Question,
+	}
+}
+
+func writeStreamMessage(writer io.Writer, message *mDNS.Msg) error {
+	exMessage := *message
+	// packing requires room for the uncompressed message
+	exMessage.Compress = false
+	buffer := buf.NewSize(3 + exMessage.Len())
+	exMessage.Compress = true
+	defer buffer.Release()
+	buffer.Resize(2, 0)
+	rawMessage, err := exMessage.PackBuffer(buffer.FreeBytes())
+	if err != nil {
+		return err
+	}
+	buffer.Truncate(len(rawMessage))
+	binary.BigEndian.PutUint16(buffer.ExtendHeader(2), uint16(len(rawMessage)))
+	return common.Error(writer.Write(buffer.Bytes()))
+}
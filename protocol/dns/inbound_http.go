@@ -0,0 +1,77 @@
+package dns
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+
+	"github.com/sagernet/sing-box/dns/transport"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+)
+
+// ServeHTTP answers DNS over HTTPS requests as defined in RFC 8484.
+//
+// Forwarded headers are ignored, so that clients can not evade the rate limit
+// by spoofing their address.
+func (h *Inbound) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != h.path {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var (
+		rawMessage []byte
+		err        error
+	)
+	switch request.Method {
+	case http.MethodGet:
+		rawMessage, err = base64.RawURLEncoding.DecodeString(request.URL.Query().Get("dns"))
+	case http.MethodPost:
+		if request.Header.Get("Content-Type") != transport.MimeType {
+			writer.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		rawMessage, err = io.ReadAll(io.LimitReader(request.Body, mDNS.MaxMsgSize))
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil || len(rawMessage) == 0 {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var message mDNS.Msg
+	err = message.Unpack(rawMessage)
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	network := N.NetworkTCP
+	if request.ProtoMajor == 3 {
+		network = N.NetworkUDP
+	}
+	ctx := log.ContextWithNewID(request.Context())
+	err = h.querySemaphore.Acquire(ctx, 1)
+	if err != nil {
+		return
+	}
+	response := h.exchange(ctx, network, M.ParseSocksaddr(request.RemoteAddr).Unwrap(), &message)
+	h.querySemaphore.Release(1)
+	if response == nil {
+		response = responseStatus(&message, mDNS.RcodeRefused)
+	}
+	exResponse := *response
+	exResponse.Compress = true
+	rawResponse, err := exResponse.Pack()
+	if err != nil {
+		h.logger.ErrorContext(ctx, E.Cause(err, "pack response"))
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", transport.MimeType)
+	writer.Write(rawResponse)
+}
@@ -0,0 +1,50 @@
+package dns
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/sagernet/sing-box/common/ratelimit"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/contrab/freelru"
+	"github.com/sagernet/sing/contrab/maphash"
+)
+
+const (
+	rateLimitCacheSize = 4096
+	rateLimitIdle      = time.Minute
+)
+
+// clientRateLimiter limits queries per client address. Buckets of idle
+// clients are evicted and start full again.
+type clientRateLimiter struct {
+	rate    uint64
+	burst   uint64
+	buckets freelru.Cache[netip.Addr, *ratelimit.Bucket]
+}
+
+func newClientRateLimiter(options option.DNSInboundRateLimitOptions) (*clientRateLimiter, error) {
+	if options.QueriesPerSecond == 0 {
+		return nil, E.New("missing rate_limit.queries_per_second")
+	}
+	burst := options.Burst
+	if burst == 0 {
+		burst = options.QueriesPerSecond
+	}
+	buckets := common.Must1(freelru.NewSharded[netip.Addr, *ratelimit.Bucket](rateLimitCacheSize, maphash.NewHasher[netip.Addr]().Hash32))
+	buckets.SetLifetime(rateLimitIdle)
+	return &clientRateLimiter{
+		rate:    uint64(options.QueriesPerSecond),
+		burst:   uint64(burst),
+		buckets: buckets,
+	}, nil
+}
+
+func (l *clientRateLimiter) Allow(addr netip.Addr) bool {
+	bucket, _, _ := l.buckets.GetAndRefreshOrAdd(addr, func() (*ratelimit.Bucket, bool) {
+		return ratelimit.NewBucketWithBurst(l.rate, l.burst), true
+	})
+	return bucket.Allow(1)
+}
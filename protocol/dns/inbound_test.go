@@ -0,0 +1,174 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/dns/transport"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/sing/service"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDNSRouter struct {
+	adapter.DNSRouter
+	access      sync.Mutex
+	sources     []netip.Addr
+	wait        chan struct{}
+	inFlight    int
+	maxInFlight int
+}
+
+func (r *fakeDNSRouter) Exchange(ctx context.Context, message *mDNS.Msg, options adapter.DNSQueryOptions) (*mDNS.Msg, error) {
+	metadata := adapter.ContextFrom(ctx)
+	r.access.Lock()
+	r.sources = append(r.sources, metadata.Source.Addr)
+	r.inFlight++
+	r.maxInFlight = max(r.maxInFlight, r.inFlight)
+	r.access.Unlock()
+	if r.wait != nil {
+		<-r.wait
+	}
+	r.access.Lock()
+	r.inFlight--
+	r.access.Unlock()
+	response := new(mDNS.Msg)
+	response.SetReply(message)
+	response.Answer = []mDNS.RR{&mDNS.A{
+		Hdr: mDNS.RR_Header{Name: message.Question[0].Name, Rrtype: mDNS.TypeA, Class: mDNS.ClassINET, Ttl: 60},
+		A:   net.IPv4(1, 1, 1, 1),
+	}}
+	return response, nil
+}
+
+func newTestInbound(t *testing.T, options option.DNSInboundOptions) (*Inbound, *fakeDNSRouter) {
+	router := &fakeDNSRouter{}
+	ctx := service.ContextWith[adapter.DNSRouter](context.Background(), router)
+	listenAddr := badoption.Addr(netip.AddrFrom4([4]byte{127, 0, 0, 1}))
+	options.Listen = &listenAddr
+	rawInbound, err := NewInbound(ctx, nil, log.NewNOPFactory().Logger(), "dns-in", options)
+	require.NoError(t, err)
+	inbound := rawInbound.(*Inbound)
+	require.NoError(t, inbound.Start(adapter.StartStateStart))
+	t.Cleanup(func() {
+		inbound.Close()
+	})
+	return inbound, router
+}
+
+func testQuery() *mDNS.Msg {
+	message := new(mDNS.Msg)
+	message.SetQuestion("example.com.", mDNS.TypeA)
+	return message
+}
+
+func TestInboundPlain(t *testing.T) {
+	t.Parallel()
+	inbound, router := newTestInbound(t, option.DNSInboundOptions{})
+	for _, network := range []string{"udp", "tcp"} {
+		var serverAddr string
+		if network == "udp" {
+			serverAddr = inbound.listener.UDPConn().LocalAddr().String()
+		} else {
+			serverAddr = inbound.listener.TCPListener().Addr().String()
+		}
+		client := mDNS.Client{Net: network}
+		response, _, err := client.Exchange(testQuery(), serverAddr)
+		require.NoError(t, err, network)
+		require.Equal(t, mDNS.RcodeSuccess, response.Rcode)
+		require.Len(t, response.Answer, 1)
+	}
+	require.Equal(t, []netip.Addr{netip.AddrFrom4([4]byte{127, 0, 0, 1}), netip.AddrFrom4([4]byte{127, 0, 0, 1})}, router.sources)
+}
+
+func TestInboundHTTP(t *testing.T) {
+	t.Parallel()
+	inbound, _ := newTestInbound(t, option.DNSInboundOptions{
+		Network: "tcp",
+		Path:    "/dns-query",
+	})
+	rawQuery, err := testQuery().Pack()
+	require.NoError(t, err)
+
+	request := httptest.NewRequest(http.MethodPost, "/dns-query", bytes.NewReader(rawQuery))
+	request.Header.Set("Content-Type", transport.MimeType)
+	recorder := httptest.NewRecorder()
+	inbound.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, transport.MimeType, recorder.Header().Get("Content-Type"))
+	var response mDNS.Msg
+	require.NoError(t, response.Unpack(recorder.Body.Bytes()))
+	require.Len(t, response.Answer, 1)
+
+	request = httptest.NewRequest(http.MethodGet, "/dns-query?dns="+base64.RawURLEncoding.EncodeToString(rawQuery), nil)
+	recorder = httptest.NewRecorder()
+	inbound.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	request = httptest.NewRequest(http.MethodGet, "/other", nil)
+	recorder = httptest.NewRecorder()
+	inbound.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestInboundStreamConcurrency(t *testing.T) {
+	t.Parallel()
+	inbound, router := newTestInbound(t, option.DNSInboundOptions{Network: "tcp"})
+	router.wait = make(chan struct{})
+	conn, err := mDNS.Dial("tcp", inbound.listener.TCPListener().Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	const queries = maxStreamQueries * 2
+	for range queries {
+		require.NoError(t, conn.WriteMsg(testQuery()))
+	}
+	require.Eventually(t, func() bool {
+		router.access.Lock()
+		defer router.access.Unlock()
+		return router.inFlight == maxStreamQueries
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	close(router.wait)
+	for range queries {
+		response, err := conn.ReadMsg()
+		require.NoError(t, err)
+		require.Len(t, response.Answer, 1)
+	}
+	router.access.Lock()
+	defer router.access.Unlock()
+	require.Equal(t, maxStreamQueries, router.maxInFlight)
+}
+
+func TestInboundRateLimit(t *testing.T) {
+	t.Parallel()
+	inbound, _ := newTestInbound(t, option.DNSInboundOptions{
+		Network: "udp",
+		RateLimit: &option.DNSInboundRateLimitOptions{
+			QueriesPerSecond: 1,
+			Burst:            2,
+		},
+	})
+	client := mDNS.Client{Net: "udp"}
+	serverAddr := inbound.listener.UDPConn().LocalAddr().String()
+	for i := 0; i < 2; i++ {
+		response, _, err := client.Exchange(testQuery(), serverAddr)
+		require.NoError(t, err)
+		require.Equal(t, mDNS.RcodeSuccess, response.Rcode)
+	}
+	response, _, err := client.Exchange(testQuery(), serverAddr)
+	require.NoError(t, err)
+	require.Equal(t, mDNS.RcodeRefused, response.Rcode)
+}
@@ -0,0 +1,107 @@
+package quic
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/protocol/dns"
+	"github.com/sagernet/sing-quic"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+// maxIncomingStreams limits the concurrent queries of a DNS over QUIC or HTTP/3
+// connection, as each stream carries a single query.
+const maxIncomingStreams = 64
+
+func init() {
+	dns.ListenQUICFunc = func(ctx context.Context, logger logger.ContextLogger, listener *listener.Listener, tlsConfig tls.ServerConfig, handler func(ctx context.Context, stream io.ReadWriter, source M.Socksaddr) error) (io.Closer, error) {
+		udpConn, err := listener.ListenUDP()
+		if err != nil {
+			return nil, err
+		}
+		quicListener, err := qtls.ListenEarly(udpConn, tlsConfig, &quic.Config{
+			MaxIncomingStreams: maxIncomingStreams,
+			Allow0RTT:          true,
+		})
+		if err != nil {
+			udpConn.Close()
+			return nil, err
+		}
+		go func() {
+			for {
+				conn, aErr := quicListener.Accept(ctx)
+				if aErr != nil {
+					udpConn.Close()
+					if !E.IsClosedOrCanceled(aErr) {
+						logger.Error("quic listener closed: ", aErr)
+					}
+					return
+				}
+				go serveQUICConn(log.ContextWithNewID(ctx), logger, conn, handler)
+			}
+		}()
+		return quicListener, nil
+	}
+	dns.ListenHTTP3Func = func(ctx context.Context, logger logger.ContextLogger, listener *listener.Listener, tlsConfig tls.ServerConfig, handler http.Handler) (io.Closer, error) {
+		err := qtls.ConfigureHTTP3(tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		udpConn, err := listener.ListenUDP()
+		if err != nil {
+			return nil, err
+		}
+		quicListener, err := qtls.ListenEarly(udpConn, tlsConfig, &quic.Config{
+			MaxIncomingStreams: maxIncomingStreams,
+			Allow0RTT:          true,
+		})
+		if err != nil {
+			udpConn.Close()
+			return nil, err
+		}
+		h3Server := &http3.Server{
+			Handler: handler,
+			ConnContext: func(ctx context.Context, conn *quic.Conn) context.Context {
+				return log.ContextWithNewID(ctx)
+			},
+		}
+		go func() {
+			sErr := h3Server.ServeListener(quicListener)
+			udpConn.Close()
+			if sErr != nil && !E.IsClosedOrCanceled(sErr) {
+				logger.Error("http3 server closed: ", sErr)
+			}
+		}()
+		return quicListener, nil
+	}
+}
+
+func serveQUICConn(ctx context.Context, logger logger.ContextLogger, conn *quic.Conn, handler func(ctx context.Context, stream io.ReadWriter, source M.Socksaddr) error) {
+	source := M.SocksaddrFromNet(conn.RemoteAddr()).Unwrap()
+	logger.InfoContext(ctx, "inbound connection from ", source)
+	for {
+		stream, err := conn.AcceptStream(ctx)
+		if err != nil {
+			conn.CloseWithError(0, "")
+			return
+		}
+		go func() {
+			err := handler(ctx, stream, source)
+			if err != nil {
+				stream.CancelRead(0)
+				stream.CancelWrite(0)
+				logger.DebugContext(ctx, E.Cause(qtls.WrapError(err), "process stream from ", source))
+				return
+			}
+			stream.Close()
+		}()
+	}
+}
@@ -0,0 +1,284 @@
+package group
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	CircuitBreakerClosed   = "closed"
+	CircuitBreakerOpen     = "open"
+	CircuitBreakerHalfOpen = "half-open"
+)
+
+// circuitBreaker tracks passive failures of group members and ejects members
+// that fail too often. A nil breaker is disabled.
+type circuitBreaker struct {
+	logger      log.Logger
+	maxFailures uint32
+	timeout     time.Duration
+	onChange    func()
+	access      sync.Mutex
+	states      map[string]*circuitState
+}
+
+type circuitState struct {
+	failures    uint32
+	lastFailure time.Time
+	open        bool
+	openedAt    time.Time
+	probeAt     time.Time
+	timer       *time.Timer
+}
+
+func newCircuitBreaker(logger log.Logger, options *option.CircuitBreakerOptions, onChange func()) *circuitBreaker {
+	if options == nil {
+		return nil
+	}
+	breaker := &circuitBreaker{
+		logger:      logger,
+		maxFailures: options.MaxFailures,
+		timeout:     time.Duration(options.Timeout),
+		onChange:    onChange,
+		states:      make(map[string]*circuitState),
+	}
+	if breaker.maxFailures == 0 {
+		breaker.maxFailures = 5
+	}
+	if breaker.timeout <= 0 {
+		breaker.timeout = 30 * time.Second
+	}
+	return breaker
+}
+
+func (b *circuitBreaker) stateOf(state *circuitState, now time.Time) string {
+	if state == nil || !state.open {
+		return CircuitBreakerClosed
+	}
+	if now.Sub(state.openedAt) < b.timeout {
+		return CircuitBreakerOpen
+	}
+	return CircuitBreakerHalfOpen
+}
+
+// probing reports whether a half-open circuit has admitted a probe that has
+// not reported back yet. An unanswered probe expires after the timeout.
+func (b *circuitBreaker) probing(state *circuitState, now time.Time) bool {
+	return !state.probeAt.IsZero() && now.Sub(state.probeAt) < b.timeout
+}
+
+// Available reports whether the outbound may be selected, which is the case
+// unless its circuit is open or a half-open probe is in flight.
+func (b *circuitBreaker) Available(tag string) bool {
+	if b == nil {
+		return true
+	}
+	now := time.Now()
+	b.access.Lock()
+	defer b.access.Unlock()
+	state := b.states[tag]
+	switch b.stateOf(state, now) {
+	case CircuitBreakerOpen:
+		return false
+	case CircuitBreakerHalfOpen:
+		return !b.probing(state, now)
+	default:
+		return true
+	}
+}
+
+// Admit reports whether a connection may be made through the outbound. A
+// half-open circuit admits a single probe until it succeeds or fails.
+func (b *circuitBreaker) Admit(tag string) bool {
+	if b == nil {
+		return true
+	}
+	now := time.Now()
+	b.access.Lock()
+	defer b.access.Unlock()
+	state := b.states[tag]
+	switch b.stateOf(state, now) {
+	case CircuitBreakerOpen:
+		return false
+	case CircuitBreakerHalfOpen:
+		if b.probing(state, now) {
+			return false
+		}
+		state.probeAt = now
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *circuitBreaker) ReportSuccess(tag string) {
+	if b == nil {
+		return
+	}
+	b.access.Lock()
+	state := b.states[tag]
+	switch b.stateOf(state, time.Now()) {
+	case CircuitBreakerOpen:
+		b.access.Unlock()
+		return
+	case CircuitBreakerHalfOpen:
+		b.logger.Info("outbound ", tag, " recovered, circuit closed")
+	}
+	if state != nil {
+		if state.timer != nil {
+			state.timer.Stop()
+		}
+		delete(b.states, tag)
+	}
+	b.access.Unlock()
+}
+
+func (b *circuitBreaker) ReportFailure(tag string, err error) {
+	if b == nil {
+		return
+	}
+	now := time.Now()
+	b.access.Lock()
+	state := b.states[tag]
+	if state == nil {
+		state = &circuitState{}
+		b.states[tag] = state
+	}
+	switch b.stateOf(state, now) {
+	case CircuitBreakerOpen:
+		b.access.Unlock()
+		return
+	case CircuitBreakerHalfOpen:
+		b.logger.Warn("outbound ", tag, " still failing, circuit reopened: ", err)
+	default:
+		// failures older than the timeout are forgotten
+		if now.Sub(state.lastFailure) >= b.timeout {
+			state.failures = 0
+		}
+		state.failures++
+		state.lastFailure = now
+		if state.failures < b.maxFailures {
+			b.access.Unlock()
+			return
+		}
+		b.logger.Warn("outbound ", tag, " ejected after ", state.failures, " failures: ", err)
+	}
+	state.open = true
+	state.openedAt = now
+	state.probeAt = time.Time{}
+	if state.timer != nil {
+		state.timer.Stop()
+	}
+	// let the group retry the outbound once the circuit becomes half-open
+	state.timer = time.AfterFunc(b.timeout, b.onChange)
+	b.access.Unlock()
+	b.onChange()
+}
+
+func (b *circuitBreaker) Status() map[string]adapter.CircuitBreakerStatus {
+	if b == nil {
+		return nil
+	}
+	now := time.Now()
+	b.access.Lock()
+	defer b.access.Unlock()
+	status := make(map[string]adapter.CircuitBreakerStatus, len(b.states))
+	for tag, state := range b.states {
+		it := adapter.CircuitBreakerStatus{
+			State:    b.stateOf(state, now),
+			Failures: state.failures,
+		}
+		if state.open {
+			it.RetryAt = state.openedAt.Add(b.timeout)
+		}
+		status[tag] = it
+	}
+	return status
+}
+
+func (b *circuitBreaker) Close() {
+	if b == nil {
+		return
+	}
+	b.access.Lock()
+	defer b.access.Unlock()
+	for _, state := range b.states {
+		if state.timer != nil {
+			state.timer.Stop()
+			state.timer = nil
+		}
+	}
+}
+
+// NewConn reports handshake failures and connections reset before any data
+// is received as failures of the outbound.
+func (b *circuitBreaker) NewConn(conn net.Conn, tag string) net.Conn {
+	if b == nil {
+		return conn
+	}
+	return &circuitConn{Conn: conn, breaker: b, tag: tag}
+}
+
+type circuitConn struct {
+	net.Conn
+	breaker  *circuitBreaker
+	tag      string
+	reported atomic.Bool
+	closed   atomic.Bool
+}
+
+func (c *circuitConn) Read(b []byte) (n int, err error) {
+	n, err = c.Conn.Read(b)
+	if !c.reported.Load() {
+		if n > 0 {
+			c.report(nil)
+		} else if err != nil {
+			c.report(err)
+		}
+	}
+	return
+}
+
+func (c *circuitConn) Write(b []byte) (n int, err error) {
+	n, err = c.Conn.Write(b)
+	if err != nil && !c.reported.Load() {
+		c.report(err)
+	}
+	return
+}
+
+func (c *circuitConn) report(err error) {
+	if c.reported.Swap(true) {
+		return
+	}
+	if err == nil {
+		c.breaker.ReportSuccess(c.tag)
+	} else if !c.closed.Load() && !E.IsCanceled(err) && !E.IsTimeout(err) {
+		// EOF or reset from the remote before any data counts as a failure
+		c.breaker.ReportFailure(c.tag, err)
+	}
+}
+
+func (c *circuitConn) Close() error {
+	c.closed.Store(true)
+	return c.Conn.Close()
+}
+
+func (c *circuitConn) Upstream() any {
+	return c.Conn
+}
+
+func (c *circuitConn) ReaderReplaceable() bool {
+	return c.reported.Load()
+}
+
+func (c *circuitConn) WriterReplaceable() bool {
+	return c.reported.Load()
+}
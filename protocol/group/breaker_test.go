@@ -0,0 +1,137 @@
+package group
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+	var changes int
+	breaker := newCircuitBreaker(log.NewNOPFactory().Logger(), &option.CircuitBreakerOptions{
+		MaxFailures: 3,
+		Timeout:     badoption.Duration(time.Minute),
+	}, func() {
+		changes++
+	})
+	defer breaker.Close()
+	testErr := errors.New("dial failed")
+
+	breaker.ReportFailure("a", testErr)
+	breaker.ReportFailure("a", testErr)
+	breaker.ReportSuccess("a")
+	breaker.ReportFailure("a", testErr)
+	breaker.ReportFailure("a", testErr)
+	require.True(t, breaker.Available("a"))
+	require.Equal(t, CircuitBreakerClosed, breaker.Status()["a"].State)
+
+	breaker.ReportFailure("a", testErr)
+	require.False(t, breaker.Available("a"))
+	require.True(t, breaker.Available("b"))
+	require.Equal(t, CircuitBreakerOpen, breaker.Status()["a"].State)
+	require.Equal(t, 1, changes)
+
+	// successes are ignored until the circuit becomes half-open
+	breaker.ReportSuccess("a")
+	require.False(t, breaker.Available("a"))
+
+	state := breaker.states["a"]
+	state.openedAt = state.openedAt.Add(-time.Minute)
+	require.True(t, breaker.Available("a"))
+	require.Equal(t, CircuitBreakerHalfOpen, breaker.Status()["a"].State)
+	breaker.ReportFailure("a", testErr)
+	require.False(t, breaker.Available("a"))
+	require.Equal(t, 2, changes)
+
+	// a half-open circuit admits a single probe
+	state.openedAt = state.openedAt.Add(-time.Minute)
+	require.True(t, breaker.Admit("a"))
+	require.False(t, breaker.Admit("a"))
+	require.False(t, breaker.Available("a"))
+	require.True(t, breaker.Admit("b"))
+	require.True(t, breaker.Admit("b"))
+
+	breaker.ReportSuccess("a")
+	require.True(t, breaker.Available("a"))
+	require.True(t, breaker.Admit("a"))
+	require.Empty(t, breaker.Status())
+}
+
+func TestCircuitBreakerDecay(t *testing.T) {
+	t.Parallel()
+	breaker := newCircuitBreaker(log.NewNOPFactory().Logger(), &option.CircuitBreakerOptions{
+		MaxFailures: 2,
+		Timeout:     badoption.Duration(time.Minute),
+	}, func() {})
+	defer breaker.Close()
+	testErr := errors.New("dial failed")
+
+	breaker.ReportFailure("a", testErr)
+	state := breaker.states["a"]
+	state.lastFailure = state.lastFailure.Add(-time.Minute)
+	breaker.ReportFailure("a", testErr)
+	require.True(t, breaker.Available("a"))
+	require.Equal(t, uint32(1), breaker.Status()["a"].Failures)
+
+	breaker.ReportFailure("a", testErr)
+	require.False(t, breaker.Available("a"))
+
+	// an unanswered probe expires after the timeout
+	state.openedAt = state.openedAt.Add(-time.Minute)
+	require.True(t, breaker.Admit("a"))
+	require.False(t, breaker.Admit("a"))
+	state.probeAt = state.probeAt.Add(-time.Minute)
+	require.True(t, breaker.Admit("a"))
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	t.Parallel()
+	breaker := newCircuitBreaker(log.NewNOPFactory().Logger(), nil, nil)
+	breaker.ReportFailure("a", io.EOF)
+	require.True(t, breaker.Available("a"))
+	require.Nil(t, breaker.Status())
+}
+
+func TestCircuitConn(t *testing.T) {
+	t.Parallel()
+	breaker := newCircuitBreaker(log.NewNOPFactory().Logger(), &option.CircuitBreakerOptions{MaxFailures: 1}, func() {})
+	defer breaker.Close()
+
+	// reset by the remote before any data
+	client, server := net.Pipe()
+	conn := breaker.NewConn(client, "a")
+	server.Close()
+	_, err := conn.Read(make([]byte, 1))
+	require.Error(t, err)
+	require.False(t, breaker.Available("a"))
+
+	// closed locally
+	client, server = net.Pipe()
+	defer server.Close()
+	conn = breaker.NewConn(client, "b")
+	conn.Close()
+	_, err = conn.Read(make([]byte, 1))
+	require.Error(t, err)
+	require.True(t, breaker.Available("b"))
+
+	// data received
+	client, server = net.Pipe()
+	defer server.Close()
+	conn = breaker.NewConn(client, "c")
+	go server.Write([]byte("ok"))
+	_, err = conn.Read(make([]byte, 2))
+	require.NoError(t, err)
+	server.Close()
+	_, err = conn.Read(make([]byte, 2))
+	require.Error(t, err)
+	require.True(t, breaker.Available("c"))
+}
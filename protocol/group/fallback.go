@@ -31,7 +31,10 @@ func RegisterFallback(registry *outbound.Registry) {
 	outbound.Register[option.FallbackOutboundOptions](registry, C.TypeFallback, NewFallback)
 }
 
-var _ adapter.OutboundGroup = (*Fallback)(nil)
+var (
+	_ adapter.OutboundGroup       = (*Fallback)(nil)
+	_ adapter.CircuitBreakerGroup = (*Fallback)(nil)
+)
 
 type Fallback struct {
 	myGroupAdapter
@@ -47,6 +50,8 @@ type Fallback struct {
 	idleTimeout                  time.Duration
 	group                        *FallbackGroup
 	interruptExternalConnections bool
+	circuitBreaker               *option.CircuitBreakerOptions
+	probe                        *urltest.Probe
 }
 
 func NewFallback(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.FallbackOutboundOptions) (adapter.Outbound, error) {
@@ -71,7 +76,13 @@ func NewFallback(ctx context.Context, router adapter.Router, logger log.ContextL
 		maxDelay:                     uint16(time.Duration(options.MaxDelay).Milliseconds()),
 		idleTimeout:                  time.Duration(options.IdleTimeout),
 		interruptExternalConnections: options.InterruptExistConnections,
+		circuitBreaker:               options.CircuitBreaker,
+	}
+	probe, err := urltest.NewProbe(options.Probe)
+	if err != nil {
+		return nil, E.Cause(err, "parse probe")
 	}
+	outbound.probe = probe
 	if len(outbound.tags) == 0 && len(outbound.uses) == 0 && !outbound.useAllProviders {
 		return nil, E.New("missing tags and uses")
 	}
@@ -121,6 +132,9 @@ func (s *Fallback) pickOutbounds() ([]adapter.Outbound, error) {
 		if _, ok := s.providers[tag]; !ok {
 			s.providers[tag] = provider
 		}
+		if providerExhausted(provider) {
+			continue
+		}
 		for _, outbound := range provider.Outbounds() {
 			if !s.OutboundFilter(outbound) {
 				continue
@@ -146,7 +160,7 @@ func (s *Fallback) Start() error {
 	if err != nil {
 		return err
 	}
-	group, err := NewFallbackGroup(s.ctx, s.outbound, s.provider, s.logger, outbounds, s.link, s.interval, s.maxDelay, s.idleTimeout, s.interruptExternalConnections)
+	group, err := NewFallbackGroup(s.ctx, s.outbound, s.provider, s.logger, outbounds, s.link, s.interval, s.maxDelay, s.idleTimeout, s.interruptExternalConnections, s.circuitBreaker, s.probe)
 	if err != nil {
 		return err
 	}
@@ -266,15 +280,18 @@ func (s *Fallback) DialContext(ctx context.Context, network string, destination
 	if outbound == nil {
 		outbound, _ = s.group.Select(network)
 	}
+	outbound = s.group.admit(outbound, network)
 	if outbound == nil {
 		return nil, E.New("missing supported outbound")
 	}
 	conn, err := outbound.DialContext(ctx, network, destination)
 	if err == nil {
+		conn = s.group.breaker.NewConn(conn, outbound.Tag())
 		return s.group.interruptGroup.NewConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
 	}
 	s.logger.ErrorContext(ctx, err)
 	s.group.history.DeleteURLTestHistory(outbound.Tag())
+	s.group.breaker.ReportFailure(outbound.Tag(), err)
 	return nil, err
 }
 
@@ -284,6 +301,7 @@ func (s *Fallback) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 	if outbound == nil {
 		outbound, _ = s.group.Select(N.NetworkUDP)
 	}
+	outbound = s.group.admit(outbound, N.NetworkUDP)
 	if outbound == nil {
 		return nil, E.New("missing supported outbound")
 	}
@@ -293,6 +311,7 @@ func (s *Fallback) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 	}
 	s.logger.ErrorContext(ctx, err)
 	s.group.history.DeleteURLTestHistory(outbound.Tag())
+	s.group.breaker.ReportFailure(outbound.Tag(), err)
 	return nil, err
 }
 
@@ -321,6 +340,10 @@ func (s *Fallback) NewDirectRouteConnection(metadata adapter.InboundContext, rou
 	return selected.(adapter.DirectRouteOutbound).NewDirectRouteConnection(metadata, routeContext, timeout)
 }
 
+func (s *Fallback) CircuitBreakerStatus() map[string]adapter.CircuitBreakerStatus {
+	return s.group.breaker.Status()
+}
+
 func (s *Fallback) PerformUpdateCheck(tag string, force bool) {
 	if _, exists := s.providers[tag]; !exists && !force {
 		return
@@ -341,6 +364,7 @@ type FallbackGroup struct {
 	maxDelay                     uint16
 	idleTimeout                  time.Duration
 	history                      adapter.URLTestHistoryStorage
+	probe                        *urltest.Probe
 	checking                     atomic.Bool
 	selected                     common.TypedValue[adapter.Outbound]
 	selectedOutboundTCP          common.TypedValue[adapter.Outbound]
@@ -352,9 +376,10 @@ type FallbackGroup struct {
 	close                        chan struct{}
 	started                      bool
 	lastActive                   common.TypedValue[time.Time]
+	breaker                      *circuitBreaker
 }
 
-func NewFallbackGroup(ctx context.Context, outboundManager adapter.OutboundManager, providerManager adapter.OutboundProviderManager, logger log.Logger, outbounds []adapter.Outbound, link string, interval time.Duration, maxDelay uint16, idleTimeout time.Duration, interruptExternalConnections bool) (*FallbackGroup, error) {
+func NewFallbackGroup(ctx context.Context, outboundManager adapter.OutboundManager, providerManager adapter.OutboundProviderManager, logger log.Logger, outbounds []adapter.Outbound, link string, interval time.Duration, maxDelay uint16, idleTimeout time.Duration, interruptExternalConnections bool, circuitBreaker *option.CircuitBreakerOptions, probe *urltest.Probe) (*FallbackGroup, error) {
 	if interval == 0 {
 		interval = C.DefaultURLTestInterval
 	}
@@ -394,11 +419,13 @@ func NewFallbackGroup(ctx context.Context, outboundManager adapter.OutboundManag
 		maxDelay:                     maxDelay,
 		idleTimeout:                  idleTimeout,
 		history:                      history,
+		probe:                        probe,
 		close:                        make(chan struct{}),
 		pause:                        service.FromContext[pause.Manager](ctx),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: interruptExternalConnections,
 	}
+	fallbackGroup.breaker = newCircuitBreaker(logger, circuitBreaker, fallbackGroup.performUpdateCheck)
 	fallbackGroup.selectedOutboundTCP.Store(TCPOut)
 	fallbackGroup.selectedOutboundUDP.Store(UDPOut)
 	fallbackGroup.updateOutbounds()
@@ -430,6 +457,7 @@ func (g *FallbackGroup) Touch() {
 }
 
 func (g *FallbackGroup) Close() error {
+	g.breaker.Close()
 	g.access.Lock()
 	defer g.access.Unlock()
 	if g.ticker == nil {
@@ -443,9 +471,23 @@ func (g *FallbackGroup) Close() error {
 	return nil
 }
 
+// admit passes the outbound through the circuit breaker and selects another
+// one if a half-open outbound is already being probed by another connection.
+func (g *FallbackGroup) admit(outbound adapter.Outbound, network string) adapter.Outbound {
+	if outbound == nil || g.breaker.Admit(outbound.Tag()) {
+		return outbound
+	}
+	selected, _ := g.Select(network)
+	if selected != nil {
+		// claim the probe if the new selection is half-open as well
+		g.breaker.Admit(selected.Tag())
+	}
+	return selected
+}
+
 func (g *FallbackGroup) Select(network string) (adapter.Outbound, bool) {
 	minOutbound := g.selected.Load()
-	if minOutbound != nil && common.Contains(minOutbound.Network(), network) {
+	if minOutbound != nil && common.Contains(minOutbound.Network(), network) && g.breaker.Available(minOutbound.Tag()) {
 		if history := g.history.LoadURLTestHistory(RealTag(minOutbound)); history != nil {
 			if g.maxDelay == 0 || (g.maxDelay > 0 && history.Delay < g.maxDelay) {
 				return minOutbound, true
@@ -462,7 +504,7 @@ func (g *FallbackGroup) Select(network string) (adapter.Outbound, bool) {
 		minOutbound = g.selectedOutboundUDP.Load()
 	}
 	if minOutbound != nil {
-		if history := g.history.LoadURLTestHistory(RealTag(minOutbound)); history != nil {
+		if history := g.history.LoadURLTestHistory(RealTag(minOutbound)); history != nil && g.breaker.Available(minOutbound.Tag()) {
 			minDelay = history.Delay
 		} else {
 			minOutbound = nil
@@ -472,6 +514,9 @@ func (g *FallbackGroup) Select(network string) (adapter.Outbound, bool) {
 		if !common.Contains(detour.Network(), network) {
 			continue
 		}
+		if !g.breaker.Available(detour.Tag()) {
+			continue
+		}
 		history := g.history.LoadURLTestHistory(RealTag(detour))
 		if history == nil {
 			continue
@@ -494,13 +539,7 @@ func (g *FallbackGroup) Select(network string) (adapter.Outbound, bool) {
 		return fallbackIgnoreOutbound, true
 	}
 	if minOutbound == nil {
-		for _, detour := range g.outbounds {
-			if !common.Contains(detour.Network(), network) {
-				continue
-			}
-			return detour, false
-		}
-		return nil, false
+		return untestedOutbound(g.history, g.outbounds, network), false
 	}
 	return minOutbound, true
 }
@@ -566,12 +605,13 @@ func (g *FallbackGroup) urlTest(ctx context.Context, force bool) (map[string]uin
 		b.Go(realTag, func() (any, error) {
 			testCtx, cancel := context.WithTimeout(g.ctx, C.TCPTimeout)
 			defer cancel()
-			t, err := urltest.URLTest(testCtx, g.link, p)
+			t, err := g.probe.Test(testCtx, g.link, p)
 			if err != nil {
 				g.logger.Debug("outbound ", tag, " unavailable: ", err)
 				g.history.DeleteURLTestHistory(realTag)
 			} else {
 				g.logger.Debug("outbound ", tag, " available: ", t, "ms")
+				g.breaker.ReportSuccess(tag)
 				g.history.StoreURLTestHistory(realTag, &adapter.URLTestHistory{
 					Time:  time.Now(),
 					Delay: t,
@@ -32,6 +32,36 @@ func (g *myGroupAdapter) Now() string {
 	return ""
 }
 
+// providerExhausted reports whether the provider's quota policy disables its
+// outbounds for automatic selection.
+func providerExhausted(provider adapter.OutboundProvider) bool {
+	quota, isQuota := provider.(adapter.OutboundProviderQuota)
+	return isQuota && quota.Exhausted()
+}
+
+// untestedOutbound picks an outbound for network before any test succeeded,
+// preferring the first one without failed tests, then the one with the
+// fewest, so that failures restored from the cache file are not retried first.
+func untestedOutbound(history adapter.URLTestHistoryStorage, outbounds []adapter.Outbound, network string) adapter.Outbound {
+	var (
+		selected    adapter.Outbound
+		minFailures uint32
+	)
+	for _, detour := range outbounds {
+		if !common.Contains(detour.Network(), network) {
+			continue
+		}
+		failures := history.LoadURLTestFailures(RealTag(detour))
+		if failures == 0 {
+			return detour
+		}
+		if selected == nil || failures < minFailures {
+			selected, minFailures = detour, failures
+		}
+	}
+	return selected
+}
+
 func CheckType(types []string) bool {
 	return common.All(types, func(it string) bool {
 		switch it {
@@ -0,0 +1,26 @@
+package group
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUntestedOutbound(t *testing.T) {
+	t.Parallel()
+	history := urltest.NewHistoryStorage()
+	outbounds := []adapter.Outbound{&testOutbound{tag: "a"}, &testOutbound{tag: "b"}, &testOutbound{tag: "c"}}
+	require.Equal(t, "a", untestedOutbound(history, outbounds, N.NetworkTCP).Tag())
+	history.DeleteURLTestHistory("a")
+	history.DeleteURLTestHistory("a")
+	require.Equal(t, "b", untestedOutbound(history, outbounds, N.NetworkTCP).Tag())
+	history.DeleteURLTestHistory("b")
+	history.DeleteURLTestHistory("c")
+	history.DeleteURLTestHistory("c")
+	require.Equal(t, "b", untestedOutbound(history, outbounds, N.NetworkTCP).Tag())
+	require.Nil(t, untestedOutbound(history, nil, N.NetworkTCP))
+}
@@ -3,6 +3,7 @@ package group
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"net"
 	"net/netip"
 	"sync"
@@ -42,6 +43,9 @@ const (
 	StrategyRoundRobin        = "round-robin"
 	StrategyConsistentHashing = "consistent-hashing"
 	StrategyStickySessions    = "sticky-sessions"
+	StrategyLeastConnections  = "least-connections"
+	StrategyLeastLatency      = "least-latency"
+	StrategyWeightedRandom    = "weighted-random"
 )
 
 type LoadBalance struct {
@@ -59,6 +63,8 @@ type LoadBalance struct {
 	group                        *LoadBalanceGroup
 	interruptExternalConnections bool
 	strategy                     string
+	weights                      map[string]uint32
+	probe                        *urltest.Probe
 }
 
 func NewLoadBalance(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.LoadBalanceOutboundOptions) (adapter.Outbound, error) {
@@ -67,10 +73,15 @@ func NewLoadBalance(ctx context.Context, router adapter.Router, logger log.Conte
 		strategy = StrategyRoundRobin
 	}
 	switch strategy {
-	case StrategyRoundRobin, StrategyConsistentHashing, StrategyStickySessions:
+	case StrategyRoundRobin, StrategyConsistentHashing, StrategyStickySessions, StrategyLeastConnections, StrategyLeastLatency, StrategyWeightedRandom:
 	default:
 		return nil, E.New("load-balance strategy not found: ", strategy)
 	}
+	for tag, weight := range options.Weights {
+		if weight == 0 {
+			return nil, E.New("weight of ", tag, " must be positive")
+		}
+	}
 	outbound := &LoadBalance{
 		myGroupAdapter: myGroupAdapter{
 			ctx:             ctx,
@@ -93,7 +104,13 @@ func NewLoadBalance(ctx context.Context, router adapter.Router, logger log.Conte
 		idleTimeout:                  time.Duration(options.IdleTimeout),
 		interruptExternalConnections: options.InterruptExistConnections,
 		strategy:                     strategy,
+		weights:                      options.Weights,
+	}
+	probe, err := urltest.NewProbe(options.Probe)
+	if err != nil {
+		return nil, E.Cause(err, "parse probe")
 	}
+	outbound.probe = probe
 	if len(outbound.tags) == 0 && len(outbound.uses) == 0 && !outbound.useAllProviders {
 		return nil, E.New("missing tags and uses")
 	}
@@ -143,6 +160,9 @@ func (s *LoadBalance) pickOutbounds() ([]adapter.Outbound, error) {
 		if _, ok := s.providers[tag]; !ok {
 			s.providers[tag] = provider
 		}
+		if providerExhausted(provider) {
+			continue
+		}
 		for _, outbound := range provider.Outbounds() {
 			if !s.OutboundFilter(outbound) {
 				continue
@@ -168,7 +188,7 @@ func (s *LoadBalance) Start() error {
 	if err != nil {
 		return err
 	}
-	group, err := NewLoadBalanceGroup(s.ctx, s.outbound, s.provider, s.logger, outbounds, s.link, s.interval, s.idleTimeout, s.ttl, s.interruptExternalConnections, s.strategy)
+	group, err := NewLoadBalanceGroup(s.ctx, s.outbound, s.provider, s.logger, outbounds, s.link, s.interval, s.idleTimeout, s.ttl, s.interruptExternalConnections, s.strategy, s.weights, s.probe)
 	if err != nil {
 		return err
 	}
@@ -258,11 +278,14 @@ func (s *LoadBalance) ListenPacket(ctx context.Context, destination M.Socksaddr)
 
 func (s *LoadBalance) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	ctx = interrupt.ContextWithIsExternalConnection(ctx)
+	// record the real outbound so connections are counted against it
+	metadata.InitExtended()
 	s.connection.NewConnection(ctx, s, conn, metadata, onClose)
 }
 
 func (s *LoadBalance) NewPacketConnectionEx(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	ctx = interrupt.ContextWithIsExternalConnection(ctx)
+	metadata.InitExtended()
 	s.connection.NewPacketConnection(ctx, s, conn, metadata, onClose)
 }
 
@@ -283,6 +306,7 @@ type strategyFn = func(metadata *adapter.InboundContext, touch bool) adapter.Out
 type LoadBalanceGroup struct {
 	ctx                          context.Context
 	provider                     adapter.OutboundProviderManager
+	connection                   adapter.ConnectionManager
 	pause                        pause.Manager
 	pauseCallback                *list.Element[pause.Callback]
 	logger                       log.Logger
@@ -292,6 +316,7 @@ type LoadBalanceGroup struct {
 	idleTimeout                  time.Duration
 	ttl                          time.Duration
 	history                      adapter.URLTestHistoryStorage
+	probe                        *urltest.Probe
 	checking                     atomic.Bool
 	interruptGroup               *interrupt.Group
 	interruptExternalConnections bool
@@ -301,9 +326,10 @@ type LoadBalanceGroup struct {
 	started                      bool
 	lastActive                   common.TypedValue[time.Time]
 	strategyFn                   strategyFn
+	weights                      map[string]uint32
 }
 
-func NewLoadBalanceGroup(ctx context.Context, outboundManager adapter.OutboundManager, providerManager adapter.OutboundProviderManager, logger log.Logger, outbounds []adapter.Outbound, link string, interval time.Duration, idleTimeout time.Duration, ttl time.Duration, interruptExternalConnections bool, strategy string) (*LoadBalanceGroup, error) {
+func NewLoadBalanceGroup(ctx context.Context, outboundManager adapter.OutboundManager, providerManager adapter.OutboundProviderManager, logger log.Logger, outbounds []adapter.Outbound, link string, interval time.Duration, idleTimeout time.Duration, ttl time.Duration, interruptExternalConnections bool, strategy string, weights map[string]uint32, probe *urltest.Probe) (*LoadBalanceGroup, error) {
 	if interval == 0 {
 		interval = C.DefaultURLTestInterval
 	}
@@ -330,6 +356,7 @@ func NewLoadBalanceGroup(ctx context.Context, outboundManager adapter.OutboundMa
 	loadBalanceGroup := &LoadBalanceGroup{
 		ctx:                          ctx,
 		provider:                     providerManager,
+		connection:                   service.FromContext[adapter.ConnectionManager](ctx),
 		logger:                       logger,
 		outbounds:                    outbounds,
 		link:                         link,
@@ -337,10 +364,12 @@ func NewLoadBalanceGroup(ctx context.Context, outboundManager adapter.OutboundMa
 		idleTimeout:                  idleTimeout,
 		ttl:                          ttl,
 		history:                      history,
+		probe:                        probe,
 		close:                        make(chan struct{}),
 		pause:                        service.FromContext[pause.Manager](ctx),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: interruptExternalConnections,
+		weights:                      weights,
 	}
 	switch strategy {
 	case StrategyRoundRobin:
@@ -349,6 +378,12 @@ func NewLoadBalanceGroup(ctx context.Context, outboundManager adapter.OutboundMa
 		loadBalanceGroup.strategyFn = strategyConsistentHashing(loadBalanceGroup, link)
 	case StrategyStickySessions:
 		loadBalanceGroup.strategyFn = strategyStickySessions(loadBalanceGroup, link)
+	case StrategyLeastConnections:
+		loadBalanceGroup.strategyFn = strategyLeastConnections(loadBalanceGroup, link)
+	case StrategyLeastLatency:
+		loadBalanceGroup.strategyFn = strategyLeastLatency(loadBalanceGroup, link)
+	case StrategyWeightedRandom:
+		loadBalanceGroup.strategyFn = strategyWeightedRandom(loadBalanceGroup, link)
 	}
 	return loadBalanceGroup, nil
 }
@@ -452,7 +487,7 @@ func (g *LoadBalanceGroup) urlTest(ctx context.Context, force bool) (map[string]
 		b.Go(realTag, func() (any, error) {
 			testCtx, cancel := context.WithTimeout(g.ctx, C.TCPTimeout)
 			defer cancel()
-			t, err := urltest.URLTest(testCtx, g.link, p)
+			t, err := g.probe.Test(testCtx, g.link, p)
 			if err != nil {
 				g.logger.Debug("outbound ", tag, " unavailable: ", err)
 				g.history.DeleteURLTestHistory(realTag)
@@ -484,6 +519,21 @@ func (g *LoadBalanceGroup) AliveForTestUrl(proxy adapter.Outbound) bool {
 	return false
 }
 
+// Weight returns the static weight of proxy, 1 if not configured.
+func (g *LoadBalanceGroup) Weight(proxy adapter.Outbound) uint32 {
+	if weight, loaded := g.weights[proxy.Tag()]; loaded {
+		return weight
+	}
+	return 1
+}
+
+func (g *LoadBalanceGroup) activeConnections(proxy adapter.Outbound) int {
+	if g.connection == nil {
+		return 0
+	}
+	return g.connection.OutboundCount(proxy.Tag())
+}
+
 func getKey(metadata *adapter.InboundContext) string {
 	if metadata == nil {
 		return ""
@@ -547,25 +597,30 @@ func jumpHash(key uint64, buckets int32) int32 {
 
 func strategyRoundRobin(g *LoadBalanceGroup, url string) strategyFn {
 	idx := 0
+	served := uint32(0)
 	idxMutex := sync.Mutex{}
 	return func(metadata *adapter.InboundContext, touch bool) adapter.Outbound {
 		idxMutex.Lock()
 		defer idxMutex.Unlock()
 
-		i := 0
 		length := len(g.outbounds)
-
-		if touch {
-			defer func() {
-				idx = (idx + i) % length
-			}()
-		}
-
-		for ; i < length; i++ {
+		for i := 0; i < length; i++ {
 			id := (idx + i) % length
 			proxy := g.outbounds[id]
 			if g.AliveForTestUrl(proxy) {
-				i++
+				if touch {
+					// stay on the proxy until it has served its weight
+					if id != idx%length {
+						served = 0
+					}
+					served++
+					if served >= g.Weight(proxy) {
+						idx = (id + 1) % length
+						served = 0
+					} else {
+						idx = id
+					}
+				}
 				return proxy
 			}
 		}
@@ -630,3 +685,93 @@ func strategyStickySessions(g *LoadBalanceGroup, url string) strategyFn {
 		return g.outbounds[0]
 	}
 }
+
+func strategyLeastConnections(g *LoadBalanceGroup, url string) strategyFn {
+	idx := 0
+	idxMutex := sync.Mutex{}
+	return func(metadata *adapter.InboundContext, touch bool) adapter.Outbound {
+		idxMutex.Lock()
+		defer idxMutex.Unlock()
+
+		var (
+			selected     adapter.Outbound
+			selectedID   int
+			selectedLoad float64
+		)
+		// start after the last selected proxy so that ties are rotated
+		length := len(g.outbounds)
+		for i := 0; i < length; i++ {
+			id := (idx + i) % length
+			proxy := g.outbounds[id]
+			if !g.AliveForTestUrl(proxy) {
+				continue
+			}
+			load := float64(g.activeConnections(proxy)) / float64(g.Weight(proxy))
+			if selected == nil || load < selectedLoad {
+				selected, selectedID, selectedLoad = proxy, id, load
+			}
+		}
+		if selected == nil {
+			return g.outbounds[0]
+		}
+		if touch {
+			idx = (selectedID + 1) % length
+		}
+		return selected
+	}
+}
+
+func strategyLeastLatency(g *LoadBalanceGroup, url string) strategyFn {
+	return func(metadata *adapter.InboundContext, touch bool) adapter.Outbound {
+		var (
+			selected adapter.Outbound
+			minDelay uint16
+		)
+		for _, proxy := range g.outbounds {
+			history := g.history.LoadURLTestHistory(RealTag(proxy))
+			if history == nil {
+				continue
+			}
+			if selected == nil || history.Delay < minDelay {
+				selected, minDelay = proxy, history.Delay
+			}
+		}
+		if selected == nil {
+			return g.outbounds[0]
+		}
+		return selected
+	}
+}
+
+// strategyWeightedRandom picks alive proxies at random with a probability
+// proportional to their weight divided by their URL test delay.
+func strategyWeightedRandom(g *LoadBalanceGroup, url string) strategyFn {
+	return func(metadata *adapter.InboundContext, touch bool) adapter.Outbound {
+		var (
+			candidates []adapter.Outbound
+			scores     []float64
+			total      float64
+		)
+		for _, proxy := range g.outbounds {
+			history := g.history.LoadURLTestHistory(RealTag(proxy))
+			if history == nil {
+				continue
+			}
+			score := float64(g.Weight(proxy)) / float64(max(history.Delay, 1))
+			candidates = append(candidates, proxy)
+			scores = append(scores, score)
+			total += score
+		}
+		if len(candidates) == 0 {
+			return g.outbounds[0]
+		}
+		point := rand.Float64() * total
+		for i, score := range scores {
+			if point < score {
+				return candidates[i]
+			}
+			point -= score
+		}
+		return candidates[len(candidates)-1]
+	}
+}
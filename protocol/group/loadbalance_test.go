@@ -0,0 +1,113 @@
+package group
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testOutbound struct {
+	adapter.Outbound
+	tag string
+}
+
+func (o *testOutbound) Tag() string {
+	return o.tag
+}
+
+func (o *testOutbound) Network() []string {
+	return []string{N.NetworkTCP, N.NetworkUDP}
+}
+
+type testConnectionManager struct {
+	adapter.ConnectionManager
+	counts map[string]int
+}
+
+func (m *testConnectionManager) OutboundCount(tag string) int {
+	return m.counts[tag]
+}
+
+func newTestLoadBalanceGroup(tags []string, weights map[string]uint32, delays map[string]uint16) *LoadBalanceGroup {
+	history := urltest.NewHistoryStorage()
+	var outbounds []adapter.Outbound
+	for _, tag := range tags {
+		outbounds = append(outbounds, &testOutbound{tag: tag})
+		if delay, loaded := delays[tag]; loaded {
+			history.StoreURLTestHistory(tag, &adapter.URLTestHistory{Time: time.Now(), Delay: delay})
+		}
+	}
+	return &LoadBalanceGroup{
+		outbounds: outbounds,
+		history:   history,
+		weights:   weights,
+	}
+}
+
+func pickCounts(g *LoadBalanceGroup, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[g.Unwrap(nil, true).Tag()]++
+	}
+	return counts
+}
+
+func TestLoadBalanceWeightedRoundRobin(t *testing.T) {
+	t.Parallel()
+	g := newTestLoadBalanceGroup([]string{"a", "b", "c"}, map[string]uint32{"a": 3}, map[string]uint16{"a": 100, "b": 100, "c": 100})
+	g.strategyFn = strategyRoundRobin(g, "")
+	var sequence []string
+	for i := 0; i < 10; i++ {
+		sequence = append(sequence, g.Unwrap(nil, true).Tag())
+	}
+	require.Equal(t, []string{"a", "a", "a", "b", "c", "a", "a", "a", "b", "c"}, sequence)
+	require.Equal(t, "a", g.Unwrap(nil, false).Tag())
+}
+
+func TestLoadBalanceRoundRobinSkipsDead(t *testing.T) {
+	t.Parallel()
+	g := newTestLoadBalanceGroup([]string{"a", "b", "c"}, map[string]uint32{"b": 2}, map[string]uint16{"a": 100, "b": 100})
+	g.strategyFn = strategyRoundRobin(g, "")
+	require.Equal(t, map[string]int{"a": 4, "b": 8}, pickCounts(g, 12))
+}
+
+func TestLoadBalanceLeastConnections(t *testing.T) {
+	t.Parallel()
+	g := newTestLoadBalanceGroup([]string{"a", "b", "c"}, map[string]uint32{"a": 4}, map[string]uint16{"a": 100, "b": 100, "c": 100})
+	connection := &testConnectionManager{counts: map[string]int{"a": 6, "b": 2, "c": 1}}
+	g.connection = connection
+	g.strategyFn = strategyLeastConnections(g, "")
+	require.Equal(t, "c", g.Unwrap(nil, true).Tag())
+	connection.counts["c"] = 3
+	// a: 6/4 = 1.5, b: 2, c: 3
+	require.Equal(t, "a", g.Unwrap(nil, true).Tag())
+	connection.counts = map[string]int{}
+	require.Equal(t, "b", g.Unwrap(nil, true).Tag())
+	require.Equal(t, "c", g.Unwrap(nil, true).Tag())
+	require.Equal(t, "a", g.Unwrap(nil, true).Tag())
+}
+
+func TestLoadBalanceLeastLatency(t *testing.T) {
+	t.Parallel()
+	g := newTestLoadBalanceGroup([]string{"a", "b", "c"}, nil, map[string]uint16{"a": 300, "b": 80, "c": 120})
+	g.strategyFn = strategyLeastLatency(g, "")
+	require.Equal(t, map[string]int{"b": 5}, pickCounts(g, 5))
+	g.history.DeleteURLTestHistory("b")
+	require.Equal(t, "c", g.Unwrap(nil, true).Tag())
+}
+
+func TestLoadBalanceWeightedRandom(t *testing.T) {
+	t.Parallel()
+	g := newTestLoadBalanceGroup([]string{"a", "b", "c"}, map[string]uint32{"a": 2}, map[string]uint16{"a": 100, "b": 50})
+	g.strategyFn = strategyWeightedRandom(g, "")
+	counts := pickCounts(g, 2000)
+	require.Zero(t, counts["c"])
+	// a and b have the same score
+	require.InDelta(t, 1000, counts["a"], 200)
+	require.InDelta(t, 1000, counts["b"], 200)
+}
@@ -31,7 +31,10 @@ func RegisterURLTest(registry *outbound.Registry) {
 	outbound.Register[option.URLTestOutboundOptions](registry, C.TypeURLTest, NewURLTest)
 }
 
-var _ adapter.OutboundGroup = (*URLTest)(nil)
+var (
+	_ adapter.OutboundGroup       = (*URLTest)(nil)
+	_ adapter.CircuitBreakerGroup = (*URLTest)(nil)
+)
 
 type URLTest struct {
 	myGroupAdapter
@@ -47,6 +50,8 @@ type URLTest struct {
 	idleTimeout                  time.Duration
 	group                        *URLTestGroup
 	interruptExternalConnections bool
+	circuitBreaker               *option.CircuitBreakerOptions
+	probe                        *urltest.Probe
 }
 
 func NewURLTest(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.URLTestOutboundOptions) (adapter.Outbound, error) {
@@ -71,7 +76,13 @@ func NewURLTest(ctx context.Context, router adapter.Router, logger log.ContextLo
 		tolerance:                    options.Tolerance,
 		idleTimeout:                  time.Duration(options.IdleTimeout),
 		interruptExternalConnections: options.InterruptExistConnections,
+		circuitBreaker:               options.CircuitBreaker,
+	}
+	probe, err := urltest.NewProbe(options.Probe)
+	if err != nil {
+		return nil, E.Cause(err, "parse probe")
 	}
+	outbound.probe = probe
 	if len(outbound.tags) == 0 && len(outbound.uses) == 0 && !outbound.useAllProviders {
 		return nil, E.New("missing tags and uses")
 	}
@@ -121,6 +132,9 @@ func (s *URLTest) pickOutbounds() ([]adapter.Outbound, error) {
 		if _, ok := s.providers[tag]; !ok {
 			s.providers[tag] = provider
 		}
+		if providerExhausted(provider) {
+			continue
+		}
 		for _, outbound := range provider.Outbounds() {
 			if !s.OutboundFilter(outbound) {
 				continue
@@ -146,7 +160,7 @@ func (s *URLTest) Start() error {
 	if err != nil {
 		return err
 	}
-	group, err := NewURLTestGroup(s.ctx, s.outbound, s.provider, s.logger, outbounds, s.link, s.interval, s.tolerance, s.idleTimeout, s.interruptExternalConnections)
+	group, err := NewURLTestGroup(s.ctx, s.outbound, s.provider, s.logger, outbounds, s.link, s.interval, s.tolerance, s.idleTimeout, s.interruptExternalConnections, s.circuitBreaker, s.probe)
 	if err != nil {
 		return err
 	}
@@ -216,15 +230,18 @@ func (s *URLTest) DialContext(ctx context.Context, network string, destination M
 	if outbound == nil {
 		outbound, _ = s.group.Select(network)
 	}
+	outbound = s.group.admit(outbound, network)
 	if outbound == nil {
 		return nil, E.New("missing supported outbound")
 	}
 	conn, err := outbound.DialContext(ctx, network, destination)
 	if err == nil {
+		conn = s.group.breaker.NewConn(conn, outbound.Tag())
 		return s.group.interruptGroup.NewConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
 	}
 	s.logger.ErrorContext(ctx, err)
 	s.group.history.DeleteURLTestHistory(outbound.Tag())
+	s.group.breaker.ReportFailure(outbound.Tag(), err)
 	return nil, err
 }
 
@@ -234,6 +251,7 @@ func (s *URLTest) ListenPacket(ctx context.Context, destination M.Socksaddr) (ne
 	if outbound == nil {
 		outbound, _ = s.group.Select(N.NetworkUDP)
 	}
+	outbound = s.group.admit(outbound, N.NetworkUDP)
 	if outbound == nil {
 		return nil, E.New("missing supported outbound")
 	}
@@ -243,6 +261,7 @@ func (s *URLTest) ListenPacket(ctx context.Context, destination M.Socksaddr) (ne
 	}
 	s.logger.ErrorContext(ctx, err)
 	s.group.history.DeleteURLTestHistory(outbound.Tag())
+	s.group.breaker.ReportFailure(outbound.Tag(), err)
 	return nil, err
 }
 
@@ -271,6 +290,10 @@ func (s *URLTest) NewDirectRouteConnection(metadata adapter.InboundContext, rout
 	return selected.(adapter.DirectRouteOutbound).NewDirectRouteConnection(metadata, routeContext, timeout)
 }
 
+func (s *URLTest) CircuitBreakerStatus() map[string]adapter.CircuitBreakerStatus {
+	return s.group.breaker.Status()
+}
+
 func (s *URLTest) PerformUpdateCheck(tag string, force bool) {
 	if _, exists := s.providers[tag]; !exists && !force {
 		return
@@ -290,6 +313,7 @@ type URLTestGroup struct {
 	tolerance                    uint16
 	idleTimeout                  time.Duration
 	history                      adapter.URLTestHistoryStorage
+	probe                        *urltest.Probe
 	checking                     atomic.Bool
 	selectedOutboundTCP          adapter.Outbound
 	selectedOutboundUDP          adapter.Outbound
@@ -300,9 +324,10 @@ type URLTestGroup struct {
 	close                        chan struct{}
 	started                      bool
 	lastActive                   common.TypedValue[time.Time]
+	breaker                      *circuitBreaker
 }
 
-func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManager, providerManager adapter.OutboundProviderManager, logger log.Logger, outbounds []adapter.Outbound, link string, interval time.Duration, tolerance uint16, idleTimeout time.Duration, interruptExternalConnections bool) (*URLTestGroup, error) {
+func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManager, providerManager adapter.OutboundProviderManager, logger log.Logger, outbounds []adapter.Outbound, link string, interval time.Duration, tolerance uint16, idleTimeout time.Duration, interruptExternalConnections bool, circuitBreaker *option.CircuitBreakerOptions, probe *urltest.Probe) (*URLTestGroup, error) {
 	if interval == 0 {
 		interval = C.DefaultURLTestInterval
 	}
@@ -335,7 +360,7 @@ func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManage
 			break
 		}
 	}
-	return &URLTestGroup{
+	urlTestGroup := &URLTestGroup{
 		ctx:                          ctx,
 		provider:                     providerManager,
 		logger:                       logger,
@@ -345,13 +370,16 @@ func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManage
 		tolerance:                    tolerance,
 		idleTimeout:                  idleTimeout,
 		history:                      history,
+		probe:                        probe,
 		close:                        make(chan struct{}),
 		pause:                        service.FromContext[pause.Manager](ctx),
 		selectedOutboundTCP:          TCPOut,
 		selectedOutboundUDP:          UDPOut,
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: interruptExternalConnections,
-	}, nil
+	}
+	urlTestGroup.breaker = newCircuitBreaker(logger, circuitBreaker, urlTestGroup.performUpdateCheck)
+	return urlTestGroup, nil
 }
 
 func (g *URLTestGroup) PostStart() {
@@ -379,6 +407,7 @@ func (g *URLTestGroup) Touch() {
 }
 
 func (g *URLTestGroup) Close() error {
+	g.breaker.Close()
 	g.access.Lock()
 	defer g.access.Unlock()
 	if g.ticker == nil {
@@ -392,19 +421,33 @@ func (g *URLTestGroup) Close() error {
 	return nil
 }
 
+// admit passes the outbound through the circuit breaker and selects another
+// one if a half-open outbound is already being probed by another connection.
+func (g *URLTestGroup) admit(outbound adapter.Outbound, network string) adapter.Outbound {
+	if outbound == nil || g.breaker.Admit(outbound.Tag()) {
+		return outbound
+	}
+	selected, _ := g.Select(network)
+	if selected != nil {
+		// claim the probe if the new selection is half-open as well
+		g.breaker.Admit(selected.Tag())
+	}
+	return selected
+}
+
 func (g *URLTestGroup) Select(network string) (adapter.Outbound, bool) {
 	var minDelay uint16
 	var minOutbound adapter.Outbound
 	switch network {
 	case N.NetworkTCP:
-		if g.selectedOutboundTCP != nil {
+		if g.selectedOutboundTCP != nil && g.breaker.Available(g.selectedOutboundTCP.Tag()) {
 			if history := g.history.LoadURLTestHistory(RealTag(g.selectedOutboundTCP)); history != nil {
 				minOutbound = g.selectedOutboundTCP
 				minDelay = history.Delay
 			}
 		}
 	case N.NetworkUDP:
-		if g.selectedOutboundUDP != nil {
+		if g.selectedOutboundUDP != nil && g.breaker.Available(g.selectedOutboundUDP.Tag()) {
 			if history := g.history.LoadURLTestHistory(RealTag(g.selectedOutboundUDP)); history != nil {
 				minOutbound = g.selectedOutboundUDP
 				minDelay = history.Delay
@@ -415,6 +458,9 @@ func (g *URLTestGroup) Select(network string) (adapter.Outbound, bool) {
 		if !common.Contains(detour.Network(), network) {
 			continue
 		}
+		if !g.breaker.Available(detour.Tag()) {
+			continue
+		}
 		history := g.history.LoadURLTestHistory(RealTag(detour))
 		if history == nil {
 			continue
@@ -425,13 +471,7 @@ func (g *URLTestGroup) Select(network string) (adapter.Outbound, bool) {
 		}
 	}
 	if minOutbound == nil {
-		for _, detour := range g.outbounds {
-			if !common.Contains(detour.Network(), network) {
-				continue
-			}
-			return detour, false
-		}
-		return nil, false
+		return untestedOutbound(g.history, g.outbounds, network), false
 	}
 	return minOutbound, true
 }
@@ -497,12 +537,13 @@ func (g *URLTestGroup) urlTest(ctx context.Context, force bool) (map[string]uint
 		b.Go(realTag, func() (any, error) {
 			testCtx, cancel := context.WithTimeout(g.ctx, C.TCPTimeout)
 			defer cancel()
-			t, err := urltest.URLTest(testCtx, g.link, p)
+			t, err := g.probe.Test(testCtx, g.link, p)
 			if err != nil {
 				g.logger.Debug("outbound ", tag, " unavailable: ", err)
 				g.history.DeleteURLTestHistory(realTag)
 			} else {
 				g.logger.Debug("outbound ", tag, " available: ", t, "ms")
+				g.breaker.ReportSuccess(tag)
 				g.history.StoreURLTestHistory(realTag, &adapter.URLTestHistory{
 					Time:  time.Now(),
 					Delay: t,
@@ -0,0 +1,87 @@
+package route
+
+import (
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/geoip"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service/filemanager"
+)
+
+const defaultASNPath = "asn.mmdb"
+
+// loadASNReader opens the ASN database, downloading it first if missing.
+func (r *Router) loadASNReader() error {
+	path := r.asnOptions.Path
+	if path == "" {
+		path = defaultASNPath
+	}
+	path = filemanager.BasePath(r.ctx, path)
+	if _, err := os.Stat(path); os.IsNotExist(err) && r.asnOptions.DownloadURL != "" {
+		r.logger.Info("downloading ASN database")
+		err = r.downloadASNDatabase(path)
+		if err != nil {
+			return E.Cause(err, "download ASN database")
+		}
+	}
+	reader, err := geoip.OpenASN(path)
+	if err != nil {
+		return err
+	}
+	r.asnReader = reader
+	return nil
+}
+
+func (r *Router) downloadASNDatabase(path string) error {
+	var (
+		transport adapter.HTTPTransport
+		err       error
+	)
+	if r.asnOptions.HTTPClient != nil && !r.asnOptions.HTTPClient.IsEmpty() {
+		transport, err = r.httpClientManager.ResolveTransport(r.ctx, r.logger, *r.asnOptions.HTTPClient)
+		if err != nil {
+			return err
+		}
+	} else {
+		transport = r.httpClientManager.DefaultTransport()
+		if transport == nil {
+			return E.New("default http client transport is not initialized")
+		}
+	}
+	httpClient := &http.Client{Transport: transport}
+	defer httpClient.CloseIdleConnections()
+	request, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.asnOptions.DownloadURL, nil)
+	if err != nil {
+		return err
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return E.New("unexpected status: ", response.Status)
+	}
+	tempPath := path + ".tmp"
+	file, err := filemanager.Create(r.ctx, tempPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, response.Body)
+	file.Close()
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	// reject broken downloads before replacing the database
+	reader, err := geoip.OpenASN(tempPath)
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	reader.Close()
+	return os.Rename(tempPath, path)
+}
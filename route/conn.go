@@ -6,6 +6,7 @@ import (
 	"net"
 	"net/netip"
 	"os"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/ratelimit"
 	"github.com/sagernet/sing-box/common/sniff"
 	"github.com/sagernet/sing-box/common/tlsfragment"
 	"github.com/sagernet/sing-box/common/tlsspoof"
@@ -34,11 +36,15 @@ type ConnectionManager struct {
 	logger      logger.ContextLogger
 	access      sync.Mutex
 	connections list.List[io.Closer]
+
+	outboundAccess sync.Mutex
+	outboundCount  map[string]int
 }
 
 func NewConnectionManager(logger logger.ContextLogger) *ConnectionManager {
 	return &ConnectionManager{
-		logger: logger,
+		logger:        logger,
+		outboundCount: make(map[string]int),
 	}
 }
 
@@ -50,6 +56,48 @@ func (m *ConnectionManager) Count() int {
 	return m.connections.Len()
 }
 
+func (m *ConnectionManager) OutboundCount(tag string) int {
+	m.outboundAccess.Lock()
+	defer m.outboundAccess.Unlock()
+	return m.outboundCount[tag]
+}
+
+// trackOutbound counts the connection against the outbounds handling it
+// until onClose is called.
+func (m *ConnectionManager) trackOutbound(this N.Dialer, metadata *adapter.InboundContext, onClose N.CloseHandlerFunc) N.CloseHandlerFunc {
+	var tags []string
+	if outbound, isOutbound := this.(adapter.Outbound); isOutbound {
+		tags = append(tags, outbound.Tag())
+	}
+	if realOutbound := metadata.GetRealOutbound(); realOutbound != "" && !slices.Contains(tags, realOutbound) {
+		tags = append(tags, realOutbound)
+	}
+	if len(tags) == 0 {
+		return onClose
+	}
+	m.outboundAccess.Lock()
+	for _, tag := range tags {
+		m.outboundCount[tag]++
+	}
+	m.outboundAccess.Unlock()
+	var closed atomic.Bool
+	return func(it error) {
+		if !closed.Swap(true) {
+			m.outboundAccess.Lock()
+			for _, tag := range tags {
+				m.outboundCount[tag]--
+				if m.outboundCount[tag] <= 0 {
+					delete(m.outboundCount, tag)
+				}
+			}
+			m.outboundAccess.Unlock()
+		}
+		if onClose != nil {
+			onClose(it)
+		}
+	}
+}
+
 func (m *ConnectionManager) CloseAll() {
 	m.access.Lock()
 	var closers []io.Closer
@@ -134,6 +182,7 @@ func (m *ConnectionManager) NewConnection(ctx context.Context, this N.Dialer, co
 		m.logger.ErrorContext(ctx, err)
 		return
 	}
+	onClose = m.trackOutbound(this, &metadata, onClose)
 	if metadata.TLSFragment || metadata.TLSRecordFragment {
 		remoteConn = tf.NewConn(remoteConn, ctx, metadata.TLSFragment, metadata.TLSRecordFragment, metadata.TLSFragmentFallbackDelay)
 	}
@@ -148,6 +197,9 @@ func (m *ConnectionManager) NewConnection(ctx context.Context, this N.Dialer, co
 		}
 		remoteConn = spoofConn
 	}
+	if len(metadata.UploadLimits) > 0 || len(metadata.DownloadLimits) > 0 {
+		conn = ratelimit.NewConn(conn, metadata.UploadLimits, metadata.DownloadLimits)
+	}
 	serverFirst := sniff.Skip(&metadata)
 	var done atomic.Bool
 	if m.kickWriteHandshake(ctx, conn, remoteConn, serverFirst, false, &done, onClose) {
@@ -246,6 +298,7 @@ func (m *ConnectionManager) NewPacketConnection(ctx context.Context, this N.Dial
 		m.logger.ErrorContext(ctx, "report handshake success: ", err)
 		return
 	}
+	onClose = m.trackOutbound(this, &metadata, onClose)
 	if destinationAddress.IsValid() {
 		var originDestination M.Socksaddr
 		if metadata.RouteOriginalDestination.IsValid() {
@@ -270,6 +323,9 @@ func (m *ConnectionManager) NewPacketConnection(ctx context.Context, this N.Dial
 	} else if metadata.RouteOriginalDestination.IsValid() && metadata.RouteOriginalDestination != metadata.Destination {
 		remotePacketConn = bufio.NewDestinationNATPacketConn(bufio.NewPacketConn(remotePacketConn), metadata.Destination, metadata.RouteOriginalDestination)
 	}
+	if len(metadata.UploadLimits) > 0 || len(metadata.DownloadLimits) > 0 {
+		conn = ratelimit.NewPacketConn(conn, metadata.UploadLimits, metadata.DownloadLimits)
+	}
 	var udpTimeout time.Duration
 	if metadata.UDPTimeout > 0 {
 		udpTimeout = metadata.UDPTimeout
@@ -0,0 +1,47 @@
+package route
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	R "github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// DryRun routes metadata like routeConnection and routePacketConnection
+// without a connection. Sniff actions are served by the RuleTracer in ctx.
+func (r *Router) DryRun(ctx context.Context, metadata *adapter.InboundContext) (adapter.Rule, adapter.Outbound, error) {
+	if metadata.Network == "" {
+		metadata.Network = N.NetworkTCP
+	}
+	selectedRule, _, _, _, err := r.matchRule(ctx, metadata, false, false, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var selectedOutbound adapter.Outbound
+	if selectedRule == nil {
+		selectedOutbound = r.outbound.Default()
+	} else {
+		var outboundTag string
+		switch action := selectedRule.Action().(type) {
+		case *R.RuleActionRoute:
+			outboundTag = action.Outbound
+		case *R.RuleActionBypass:
+			outboundTag = action.Outbound
+		}
+		if outboundTag == "" {
+			return selectedRule, nil, nil
+		}
+		var loaded bool
+		selectedOutbound, loaded = r.outbound.Outbound(outboundTag)
+		if !loaded {
+			return selectedRule, nil, E.New("outbound not found: ", outboundTag)
+		}
+	}
+	if !common.Contains(selectedOutbound.Network(), metadata.Network) {
+		return selectedRule, selectedOutbound, E.New(metadata.Network, " is not supported by outbound: ", selectedOutbound.Tag())
+	}
+	return selectedRule, selectedOutbound, nil
+}
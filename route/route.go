@@ -78,6 +78,10 @@ func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata ad
 		return nil
 	}
 	metadata.Network = N.NetworkTCP
+	err := r.checkUserQuota(&metadata)
+	if err != nil {
+		return err
+	}
 	switch metadata.Destination.Fqdn {
 	case mux.Destination.Fqdn:
 		return E.New("global multiplex is deprecated since sing-box v1.7.0, enable multiplex in Inbound fields instead.")
@@ -95,7 +99,7 @@ func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata ad
 	if deadline.NeedAdditionalReadDeadline(conn) {
 		conn = deadline.NewConn(conn)
 	}
-	selectedRule, _, buffers, _, err := r.matchRule(ctx, &metadata, false, false, conn, nil)
+	selectedRule, selectedRuleIndex, buffers, _, err := r.matchRule(ctx, &metadata, false, false, conn, nil)
 	if err != nil {
 		return err
 	}
@@ -153,6 +157,9 @@ func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata ad
 	for _, buffer := range buffers {
 		conn = bufio.NewCachedConn(conn, buffer)
 	}
+	if selectedRule != nil {
+		conn = r.ruleStats[selectedRuleIndex].trackConn(conn)
+	}
 	if len(r.trackers) > 0 {
 		metadata.InitExtended()
 	}
@@ -221,6 +228,10 @@ func (r *Router) routePacketConnection(ctx context.Context, conn N.PacketConn, m
 	}
 	// TODO: move to UoT
 	metadata.Network = N.NetworkUDP
+	err := r.checkUserQuota(&metadata)
+	if err != nil {
+		return err
+	}
 
 	// Currently we don't have deadline usages for UDP connections
 	/*if deadline.NeedAdditionalReadDeadline(conn) {
@@ -229,7 +240,7 @@ func (r *Router) routePacketConnection(ctx context.Context, conn N.PacketConn, m
 	if metadata.InboundType == C.TypeTun && metadata.Protocol == C.ProtocolDNS {
 		return r.hijackDNSPacket(ctx, conn, nil, metadata, onClose)
 	}
-	selectedRule, _, _, packetBuffers, err := r.matchRule(ctx, &metadata, false, false, nil, conn)
+	selectedRule, selectedRuleIndex, _, packetBuffers, err := r.matchRule(ctx, &metadata, false, false, nil, conn)
 	if err != nil {
 		return err
 	}
@@ -284,6 +295,9 @@ func (r *Router) routePacketConnection(ctx context.Context, conn N.PacketConn, m
 		conn = bufio.NewCachedPacketConn(conn, buffer.Buffer, buffer.Destination)
 		N.PutPacketBuffer(buffer)
 	}
+	if selectedRule != nil {
+		conn = r.ruleStats[selectedRuleIndex].trackPacketConn(conn)
+	}
 	if len(r.trackers) > 0 {
 		metadata.InitExtended()
 	}
@@ -465,12 +479,20 @@ func (r *Router) matchRule(
 		metadata.IPVersion = 6
 	}
 
+	tracer := adapter.RuleTracerFromContext(ctx)
 match:
 	for currentRuleIndex, currentRule := range r.rules {
 		metadata.ResetRuleCache()
-		if !currentRule.Match(metadata) {
+		matched := currentRule.Match(metadata)
+		if tracer != nil {
+			tracer.TraceRule("route", currentRuleIndex, currentRule, matched)
+		}
+		if !matched {
 			continue
 		}
+		if inputConn != nil || inputPacketConn != nil {
+			r.ruleStats[currentRuleIndex].hit()
+		}
 		if !preMatch {
 			ruleDescription := currentRule.String()
 			if ruleDescription != "" {
@@ -560,6 +582,15 @@ match:
 				metadata.TLSSpoof = routeOptions.TLSSpoof
 				metadata.TLSSpoofMethod = routeOptions.TLSSpoofMethod
 			}
+			if routeOptions.BandwidthLimit != nil && !preMatch {
+				uploadLimit, downloadLimit := routeOptions.BandwidthLimit.Buckets(metadata.Inbound, metadata.User)
+				if uploadLimit != nil {
+					metadata.UploadLimits = append(metadata.UploadLimits, uploadLimit)
+				}
+				if downloadLimit != nil {
+					metadata.DownloadLimits = append(metadata.DownloadLimits, downloadLimit)
+				}
+			}
 		}
 		switch action := currentRule.Action().(type) {
 		case *R.RuleActionSniff:
@@ -584,6 +615,35 @@ match:
 			if fatalErr != nil {
 				return
 			}
+		case *R.RuleActionScript:
+			outboundTag, err := r.actionScript(ctx, metadata, action)
+			if err != nil {
+				r.logger.ErrorContext(ctx, E.Cause(err, "run script for rule[", currentRuleIndex, "]"))
+				continue match
+			}
+			if outboundTag == "" {
+				continue match
+			}
+			selectedOutbound, loaded := r.outbound.Outbound(outboundTag)
+			if !loaded {
+				r.logger.ErrorContext(ctx, "script[", currentRuleIndex, "]: outbound not found: ", outboundTag)
+				continue match
+			}
+			if selectedOutbound.Type() == C.TypeSelector {
+				selectedOutbound = selectedOutbound.(adapter.SelectorGroup).Selected()
+			}
+			if selectedOutbound.Type() == C.TypePass {
+				continue match
+			}
+			if !preMatch {
+				r.logger.DebugContext(ctx, "script[", currentRuleIndex, "] => ", outboundTag)
+			}
+			selectedRule = &scriptRule{
+				Rule:   currentRule,
+				action: &R.RuleActionRoute{Outbound: outboundTag},
+			}
+			selectedRuleIndex = currentRuleIndex
+			break match
 		}
 		actionType := currentRule.Action().Type()
 		if actionType == C.RuleActionTypeRoute ||
@@ -617,6 +677,21 @@ func (r *Router) actionSniff(
 		r.logger.DebugContext(ctx, "duplicate sniff skipped")
 		return
 	}
+	if inputConn == nil && inputPacketConn == nil {
+		// dry run, see DryRun
+		if tracer := adapter.RuleTracerFromContext(ctx); tracer != nil {
+			metadata.SnifferNames = action.SnifferNames
+			metadata.SniffError = tracer.Sniff(metadata)
+			//goland:noinspection GoDeprecation
+			if metadata.SniffError == nil && !metadata.Destination.IsFqdn() && action.OverrideDestination && M.IsDomainName(metadata.SniffHost) {
+				metadata.Destination = M.Socksaddr{
+					Fqdn: metadata.SniffHost,
+					Port: metadata.Destination.Port,
+				}
+			}
+		}
+		return
+	}
 	if inputConn != nil {
 		if len(action.StreamSniffers) == 0 && len(action.PacketSniffers) > 0 {
 			return
@@ -4,10 +4,14 @@ import (
 	"context"
 	"os"
 	"runtime"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-box/common/process"
+	"github.com/sagernet/sing-box/common/script"
 	"github.com/sagernet/sing-box/common/taskmonitor"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -36,6 +40,9 @@ type Router struct {
 	network           adapter.NetworkManager
 	httpClientManager adapter.HTTPClientManager
 	rules             []adapter.Rule
+	ruleStats         []*ruleStats
+	ruleStatsDone     chan struct{}
+	cacheFile         adapter.CacheFile
 	needFindProcess   bool
 	needFindNeighbor  bool
 	leaseFiles        []string
@@ -44,11 +51,16 @@ type Router struct {
 	processSearcher   process.Searcher
 	processCache      freelru.Cache[processCacheKey, processCacheEntry]
 	neighborResolver  adapter.NeighborResolver
+	asnOptions        *option.ASNOptions
+	asnReader         *geoip.ASNReader
 	pauseManager      pause.Manager
 	trackers          []adapter.ConnectionTracker
+	userQuotas        []adapter.UserQuotaService
 	platformInterface adapter.PlatformInterface
 	started           bool
 	reloadChan        chan<- struct{}
+	scriptSource      string
+	script            atomic.Pointer[script.Program]
 }
 
 func NewRouter(ctx context.Context, logFactory log.Factory, options option.RouteOptions, dnsOptions option.DNSOptions, reloadChan chan<- struct{}) *Router {
@@ -71,10 +83,18 @@ func NewRouter(ctx context.Context, logFactory log.Factory, options option.Route
 		pauseManager:      service.FromContext[pause.Manager](ctx),
 		platformInterface: service.FromContext[adapter.PlatformInterface](ctx),
 		reloadChan:        reloadChan,
+		scriptSource:      strings.Join(options.Script, "\n"),
+		asnOptions:        options.ASN,
 	}
 }
 
 func (r *Router) Initialize(rules []option.Rule, ruleSets []option.RuleSet) error {
+	if r.scriptSource != "" {
+		err := r.UpdateScript(r.scriptSource)
+		if err != nil {
+			return E.Cause(err, "parse route script")
+		}
+	}
 	for i, options := range rules {
 		err := R.ValidateNoNestedRuleActions(options)
 		if err != nil {
@@ -85,6 +105,7 @@ func (r *Router) Initialize(rules []option.Rule, ruleSets []option.RuleSet) erro
 			return E.Cause(err, "parse rule[", i, "]")
 		}
 		r.rules = append(r.rules, rule)
+		r.ruleStats = append(r.ruleStats, &ruleStats{})
 	}
 	for i, options := range ruleSets {
 		if _, exists := r.ruleSetMap[options.Tag]; exists {
@@ -134,6 +155,14 @@ func (r *Router) Start(stage adapter.StartStage) error {
 			}
 		}
 	case adapter.StartStateStart:
+		if r.asnOptions != nil {
+			monitor.Start("initialize ASN database")
+			err := r.loadASNReader()
+			monitor.Finish()
+			if err != nil {
+				return E.Cause(err, "initialize ASN database")
+			}
+		}
 		var startContext *adapter.HTTPStartContext
 		if len(r.ruleSets) > 0 {
 			monitor.Start("initialize rule-set")
@@ -161,6 +190,13 @@ func (r *Router) Start(stage adapter.StartStage) error {
 			startContext.Close()
 		}
 		r.network.Initialize(r.ruleSets)
+		cacheFile := service.FromContext[adapter.CacheFile](r.ctx)
+		if cacheFile != nil && cacheFile.StoreRuleStats() {
+			r.cacheFile = cacheFile
+			r.loadRuleStats()
+			r.ruleStatsDone = make(chan struct{})
+			go r.loopSaveRuleStats()
+		}
 		needFindProcess := r.needFindProcess
 		for _, ruleSet := range r.ruleSets {
 			metadata := ruleSet.Metadata()
@@ -227,6 +263,14 @@ func (r *Router) Start(stage adapter.StartStage) error {
 func (r *Router) Close() error {
 	monitor := taskmonitor.New(r.logger, C.StopTimeout)
 	var err error
+	if r.ruleStatsDone != nil {
+		close(r.ruleStatsDone)
+		monitor.Start("save rule statistics")
+		err = E.Append(err, r.saveRuleStats(), func(saveErr error) error {
+			return E.Cause(saveErr, "save rule statistics")
+		})
+		monitor.Finish()
+	}
 	if r.neighborResolver != nil {
 		monitor.Start("close neighbor resolver")
 		err = E.Append(err, r.neighborResolver.Close(), func(closeErr error) error {
@@ -234,6 +278,13 @@ func (r *Router) Close() error {
 		})
 		monitor.Finish()
 	}
+	if r.asnReader != nil {
+		monitor.Start("close ASN database")
+		err = E.Append(err, r.asnReader.Close(), func(closeErr error) error {
+			return E.Cause(closeErr, "close ASN database")
+		})
+		monitor.Finish()
+	}
 	for i, rule := range r.rules {
 		monitor.Start("close rule[", i, "]")
 		err = E.Append(err, rule.Close(), func(err error) error {
@@ -285,6 +336,24 @@ func (r *Router) Trackers() []adapter.ConnectionTracker {
 
 func (r *Router) AppendTracker(tracker adapter.ConnectionTracker) {
 	r.trackers = append(r.trackers, tracker)
+	if userQuota, isUserQuota := tracker.(adapter.UserQuotaService); isUserQuota {
+		r.userQuotas = append(r.userQuotas, userQuota)
+	}
+}
+
+// checkUserQuota rejects connections of users out of quota. It runs at routing
+// time, so the inbound has already accepted the handshake.
+func (r *Router) checkUserQuota(metadata *adapter.InboundContext) error {
+	if metadata.User == "" {
+		return nil
+	}
+	for _, userQuota := range r.userQuotas {
+		err := userQuota.CheckUser(metadata)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r *Router) NeedFindProcess() bool {
@@ -299,6 +368,13 @@ func (r *Router) NeighborResolver() adapter.NeighborResolver {
 	return r.neighborResolver
 }
 
+func (r *Router) ASNReader() adapter.ASNReader {
+	if r.asnReader == nil {
+		return nil
+	}
+	return r.asnReader
+}
+
 func (r *Router) ResetNetwork() {
 	r.httpClientManager.ResetNetwork()
 	r.dns.ResetNetwork()
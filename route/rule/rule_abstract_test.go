@@ -3,6 +3,7 @@ package rule
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
@@ -20,6 +21,26 @@ func (f *fakeRuleSet) Name() string {
 	return "fake-rule-set"
 }
 
+func (f *fakeRuleSet) Type() string {
+	return C.RuleSetTypeInline
+}
+
+func (f *fakeRuleSet) Format() string {
+	return C.RuleSetFormatSource
+}
+
+func (f *fakeRuleSet) UpdatedTime() time.Time {
+	return time.Time{}
+}
+
+func (f *fakeRuleSet) Update(context.Context) error {
+	return nil
+}
+
+func (f *fakeRuleSet) RuleCount() uint64 {
+	return 0
+}
+
 func (f *fakeRuleSet) StartContext(context.Context, *adapter.HTTPStartContext) error {
 	return nil
 }
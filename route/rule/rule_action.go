@@ -10,6 +10,8 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/ratelimit"
+	"github.com/sagernet/sing-box/common/script"
 	"github.com/sagernet/sing-box/common/sniff"
 	"github.com/sagernet/sing-box/common/tlsspoof"
 	C "github.com/sagernet/sing-box/constant"
@@ -30,6 +32,13 @@ func newRuleActionRouteOptions(options option.RawRouteOptionsActionOptions) (Rul
 	if err != nil {
 		return RuleActionRouteOptions{}, err
 	}
+	var bandwidthLimit *ratelimit.Limiter
+	if options.BandwidthLimit != nil {
+		bandwidthLimit, err = ratelimit.NewLimiter(options.BandwidthLimit.Upload.Value(), options.BandwidthLimit.Download.Value(), options.BandwidthLimit.Scope)
+		if err != nil {
+			return RuleActionRouteOptions{}, E.Cause(err, "bandwidth_limit")
+		}
+	}
 	return RuleActionRouteOptions{
 		OverrideAddress:           M.ParseSocksaddrHostPort(options.OverrideAddress, 0),
 		OverridePort:              options.OverridePort,
@@ -43,6 +52,7 @@ func newRuleActionRouteOptions(options option.RawRouteOptionsActionOptions) (Rul
 		TLSRecordFragment:         options.TLSRecordFragment,
 		TLSSpoof:                  spoof,
 		TLSSpoofMethod:            spoofMethod,
+		BandwidthLimit:            bandwidthLimit,
 	}, nil
 }
 
@@ -119,6 +129,16 @@ func NewRuleAction(ctx context.Context, logger logger.ContextLogger, action opti
 			RewriteTTL:             action.ResolveOptions.RewriteTTL,
 			ClientSubnet:           action.ResolveOptions.ClientSubnet.Build(netip.Prefix{}),
 		}, nil
+	case C.RuleActionTypeScript:
+		scriptAction := &RuleActionScript{}
+		if len(action.ScriptOptions.Script) > 0 {
+			program, err := script.Compile(strings.Join(action.ScriptOptions.Script, "\n"))
+			if err != nil {
+				return nil, err
+			}
+			scriptAction.Program = program
+		}
+		return scriptAction, nil
 	default:
 		panic(F.ToString("unknown rule action: ", action.Action))
 	}
@@ -136,6 +156,7 @@ func NewDNSRuleAction(logger logger.ContextLogger, action option.DNSRuleAction)
 				Timeout:                time.Duration(action.RouteOptions.Timeout),
 				DisableCache:           action.RouteOptions.DisableCache,
 				DisableOptimisticCache: action.RouteOptions.DisableOptimisticCache,
+				DisableDNSSEC:          action.RouteOptions.DisableDNSSEC,
 				RewriteTTL:             action.RouteOptions.RewriteTTL,
 				ClientSubnet:           netip.Prefix(common.PtrValueOrDefault(action.RouteOptions.ClientSubnet)),
 			},
@@ -148,6 +169,7 @@ func NewDNSRuleAction(logger logger.ContextLogger, action option.DNSRuleAction)
 				Timeout:                time.Duration(action.RouteOptions.Timeout),
 				DisableCache:           action.RouteOptions.DisableCache,
 				DisableOptimisticCache: action.RouteOptions.DisableOptimisticCache,
+				DisableDNSSEC:          action.RouteOptions.DisableDNSSEC,
 				RewriteTTL:             action.RouteOptions.RewriteTTL,
 				ClientSubnet:           netip.Prefix(common.PtrValueOrDefault(action.RouteOptions.ClientSubnet)),
 			},
@@ -160,6 +182,7 @@ func NewDNSRuleAction(logger logger.ContextLogger, action option.DNSRuleAction)
 			Timeout:                time.Duration(action.RouteOptionsOptions.Timeout),
 			DisableCache:           action.RouteOptionsOptions.DisableCache,
 			DisableOptimisticCache: action.RouteOptionsOptions.DisableOptimisticCache,
+			DisableDNSSEC:          action.RouteOptionsOptions.DisableDNSSEC,
 			RewriteTTL:             action.RouteOptionsOptions.RewriteTTL,
 			ClientSubnet:           netip.Prefix(common.PtrValueOrDefault(action.RouteOptionsOptions.ClientSubnet)),
 		}
@@ -232,6 +255,7 @@ type RuleActionRouteOptions struct {
 	TLSRecordFragment         bool
 	TLSSpoof                  string
 	TLSSpoofMethod            tlsspoof.Method
+	BandwidthLimit            *ratelimit.Limiter
 }
 
 func (r *RuleActionRouteOptions) Type() string {
@@ -284,6 +308,9 @@ func (r *RuleActionRouteOptions) Descriptions() []string {
 		descriptions = append(descriptions, F.ToString("tls-spoof=", r.TLSSpoof))
 		descriptions = append(descriptions, F.ToString("tls-spoof-method=", r.TLSSpoofMethod.String()))
 	}
+	if r.BandwidthLimit != nil {
+		descriptions = append(descriptions, F.ToString("bandwidth-limit=", r.BandwidthLimit.Scope()))
+	}
 	return descriptions
 }
 
@@ -332,6 +359,9 @@ func formatDNSRouteAction(action string, server string, options RuleActionDNSRou
 	if options.DisableOptimisticCache {
 		descriptions = append(descriptions, "disable-optimistic-cache")
 	}
+	if options.DisableDNSSEC {
+		descriptions = append(descriptions, "disable-dnssec")
+	}
 	if options.RewriteTTL != nil {
 		descriptions = append(descriptions, F.ToString("rewrite-ttl=", *options.RewriteTTL))
 	}
@@ -349,6 +379,7 @@ type RuleActionDNSRouteOptions struct {
 	Timeout                time.Duration
 	DisableCache           bool
 	DisableOptimisticCache bool
+	DisableDNSSEC          bool
 	RewriteTTL             *uint32
 	ClientSubnet           netip.Prefix
 }
@@ -365,6 +396,9 @@ func (r *RuleActionDNSRouteOptions) String() string {
 	if r.DisableOptimisticCache {
 		descriptions = append(descriptions, "disable-optimistic-cache")
 	}
+	if r.DisableDNSSEC {
+		descriptions = append(descriptions, "disable-dnssec")
+	}
 	if r.RewriteTTL != nil {
 		descriptions = append(descriptions, F.ToString("rewrite-ttl=", *r.RewriteTTL))
 	}
@@ -587,6 +621,22 @@ func (r *RuleActionResolve) String() string {
 	}
 }
 
+type RuleActionScript struct {
+	// Program is nil if the action uses the script configured in route.
+	Program *script.Program
+}
+
+func (r *RuleActionScript) Type() string {
+	return C.RuleActionTypeScript
+}
+
+func (r *RuleActionScript) String() string {
+	if r.Program == nil {
+		return "script"
+	}
+	return "script(inline)"
+}
+
 type RuleActionPredefined struct {
 	Rcode  int
 	Answer []dns.RR
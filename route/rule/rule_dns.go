@@ -172,11 +172,24 @@ func NewDefaultDNSRule(ctx context.Context, logger log.ContextLogger, options op
 		rule.sourceAddressItems = append(rule.sourceAddressItems, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.SourceIPASN) > 0 {
+		item := NewIPASNItem(router, true, options.SourceIPASN)
+		rule.sourceAddressItems = append(rule.sourceAddressItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if options.IPIsPrivate {
 		item := NewIPIsPrivateItem(false)
 		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.IPASN) > 0 {
+		if !options.MatchResponse {
+			return nil, E.New("ip_asn requires match_response to be enabled")
+		}
+		item := NewIPASNItem(router, false, options.IPASN)
+		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if options.IPAcceptAny {
 		item := NewIPAcceptAnyItem()
 		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
@@ -279,6 +292,12 @@ func NewDefaultDNSRule(ctx context.Context, logger log.ContextLogger, options op
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	timeItems, err := newTimeItems(ctx, options.TimeRange, options.Weekday, options.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	rule.items = append(rule.items, timeItems...)
+	rule.allItems = append(rule.allItems, timeItems...)
 	if len(options.NetworkType) > 0 {
 		item := NewNetworkTypeItem(networkManager, common.Map(options.NetworkType, option.InterfaceType.Build))
 		rule.items = append(rule.items, item)
@@ -161,6 +161,11 @@ func NewDefaultHeadlessRule(ctx context.Context, options option.DefaultHeadlessR
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.TLSFingerprint) > 0 {
+		item := NewTLSFingerprintItem(options.TLSFingerprint)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if networkManager != nil {
 		if len(options.NetworkType) > 0 {
 			item := NewNetworkTypeItem(networkManager, common.Map(options.NetworkType, option.InterfaceType.Build))
@@ -223,6 +228,7 @@ func NewDefaultHeadlessRule(ctx context.Context, options option.DefaultHeadlessR
 		len(options.ProcessPath) +
 		len(options.ProcessPathRegex) +
 		len(options.PackageName) +
+		len(options.TLSFingerprint) +
 		len(options.NetworkType) +
 		// len(options.NetworkIsExpensive) +
 		// len(options.NetworkIsConstrained) +
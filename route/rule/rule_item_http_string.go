@@ -0,0 +1,87 @@
+package rule
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*HTTPStringItem)(nil)
+
+// HTTPStringItem matches a string field of the sniffed HTTP request against
+// plain values and regular expressions.
+type HTTPStringItem struct {
+	field       func(metadata *adapter.InboundContext) string
+	matchValue  func(field string, value string) bool
+	values      []string
+	matchers    []*regexp.Regexp
+	description string
+}
+
+// NewHTTPPathItem matches request paths starting with any of prefixes.
+func NewHTTPPathItem(prefixes []string, expressions []string) (*HTTPStringItem, error) {
+	return newHTTPStringItem("http_path", func(metadata *adapter.InboundContext) string {
+		return metadata.HTTPPath
+	}, strings.HasPrefix, prefixes, expressions)
+}
+
+// NewHTTPUserAgentItem matches user agents containing any of keywords.
+func NewHTTPUserAgentItem(keywords []string, expressions []string) (*HTTPStringItem, error) {
+	return newHTTPStringItem("http_user_agent", func(metadata *adapter.InboundContext) string {
+		return metadata.HTTPUserAgent
+	}, strings.Contains, keywords, expressions)
+}
+
+func newHTTPStringItem(name string, field func(metadata *adapter.InboundContext) string, matchValue func(field string, value string) bool, values []string, expressions []string) (*HTTPStringItem, error) {
+	matchers := make([]*regexp.Regexp, 0, len(expressions))
+	for i, regex := range expressions {
+		matcher, err := regexp.Compile(regex)
+		if err != nil {
+			return nil, E.Cause(err, "parse ", name, "_regex expression ", i)
+		}
+		matchers = append(matchers, matcher)
+	}
+	var descriptions []string
+	if len(values) == 1 {
+		descriptions = append(descriptions, name+"="+values[0])
+	} else if len(values) > 1 {
+		descriptions = append(descriptions, F.ToString(name, "=[", strings.Join(values, " "), "]"))
+	}
+	if len(expressions) == 1 {
+		descriptions = append(descriptions, name+"_regex="+expressions[0])
+	} else if len(expressions) > 1 {
+		descriptions = append(descriptions, F.ToString(name, "_regex=[", strings.Join(expressions, " "), "]"))
+	}
+	return &HTTPStringItem{
+		field:       field,
+		matchValue:  matchValue,
+		values:      values,
+		matchers:    matchers,
+		description: strings.Join(descriptions, " "),
+	}, nil
+}
+
+func (r *HTTPStringItem) Match(metadata *adapter.InboundContext) bool {
+	field := r.field(metadata)
+	if field == "" {
+		return false
+	}
+	for _, value := range r.values {
+		if r.matchValue(field, value) {
+			return true
+		}
+	}
+	for _, matcher := range r.matchers {
+		if matcher.MatchString(field) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *HTTPStringItem) String() string {
+	return r.description
+}
@@ -0,0 +1,30 @@
+package rule
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPStringItem(t *testing.T) {
+	t.Parallel()
+	path, err := NewHTTPPathItem([]string{"/api"}, []string{`^/v\d+/`})
+	require.NoError(t, err)
+	require.Equal(t, "http_path=/api http_path_regex=^/v\\d+/", path.String())
+	require.True(t, path.Match(&adapter.InboundContext{HTTPPath: "/api/users"}))
+	require.True(t, path.Match(&adapter.InboundContext{HTTPPath: "/v2/users"}))
+	require.False(t, path.Match(&adapter.InboundContext{HTTPPath: "/static/api"}))
+	require.False(t, path.Match(&adapter.InboundContext{HTTPUserAgent: "/api"}))
+
+	userAgent, err := NewHTTPUserAgentItem([]string{"curl", "Wget"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "http_user_agent=[curl Wget]", userAgent.String())
+	require.True(t, userAgent.Match(&adapter.InboundContext{HTTPUserAgent: "Mozilla/5.0 curl/8.0"}))
+	require.False(t, userAgent.Match(&adapter.InboundContext{HTTPUserAgent: "Mozilla/5.0"}))
+	require.False(t, userAgent.Match(&adapter.InboundContext{HTTPPath: "curl"}))
+
+	_, err = NewHTTPUserAgentItem(nil, []string{"("})
+	require.ErrorContains(t, err, "parse http_user_agent_regex expression 0")
+}
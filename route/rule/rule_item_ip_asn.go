@@ -0,0 +1,73 @@
+package rule
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/geoip"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*IPASNItem)(nil)
+
+type IPASNItem struct {
+	router   adapter.Router
+	reader   adapter.ASNReader
+	isSource bool
+	values   []string
+	matcher  *geoip.ASNMatcher
+}
+
+func NewIPASNItem(router adapter.Router, isSource bool, values []string) *IPASNItem {
+	return &IPASNItem{
+		router:   router,
+		isSource: isSource,
+		values:   values,
+		matcher:  geoip.NewASNMatcher(values),
+	}
+}
+
+func (r *IPASNItem) Start() error {
+	r.reader = r.router.ASNReader()
+	if r.reader == nil {
+		return E.New("missing ASN database, see route.asn")
+	}
+	return nil
+}
+
+func (r *IPASNItem) Match(metadata *adapter.InboundContext) bool {
+	if r.reader == nil {
+		return false
+	}
+	if r.isSource || metadata.IPCIDRMatchSource {
+		return r.match(metadata.Source.Addr)
+	}
+	if metadata.DestinationAddressMatchFromResponse {
+		return slices.ContainsFunc(metadata.DNSResponseAddressesForMatch(), r.match)
+	}
+	if metadata.Destination.IsIP() {
+		return r.match(metadata.Destination.Addr)
+	}
+	return slices.ContainsFunc(metadata.DestinationAddresses, r.match)
+}
+
+func (r *IPASNItem) match(addr netip.Addr) bool {
+	number, organization, loaded := r.reader.LookupASN(addr)
+	return loaded && r.matcher.Match(number, organization)
+}
+
+func (r *IPASNItem) String() string {
+	var description string
+	if r.isSource {
+		description = "source_ip_asn="
+	} else {
+		description = "ip_asn="
+	}
+	if len(r.values) == 1 {
+		return description + r.values[0]
+	}
+	return F.ToString(description, "[", strings.Join(r.values, " "), "]")
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-tun"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/x/list"
@@ -44,12 +45,18 @@ func (r *ruleSetItemTestRouter) RuleSet(tag string) (adapter.RuleSet, bool) {
 	ruleSet, loaded := r.ruleSets[tag]
 	return ruleSet, loaded
 }
-func (r *ruleSetItemTestRouter) Rules() []adapter.Rule                      { return nil }
-func (r *ruleSetItemTestRouter) NeedFindProcess() bool                      { return false }
-func (r *ruleSetItemTestRouter) NeedFindNeighbor() bool                     { return false }
-func (r *ruleSetItemTestRouter) NeighborResolver() adapter.NeighborResolver { return nil }
-func (r *ruleSetItemTestRouter) AppendTracker(adapter.ConnectionTracker)    {}
-func (r *ruleSetItemTestRouter) ResetNetwork()                              {}
+func (r *ruleSetItemTestRouter) Rules() []adapter.Rule                            { return nil }
+func (r *ruleSetItemTestRouter) NeedFindProcess() bool                            { return false }
+func (r *ruleSetItemTestRouter) NeedFindNeighbor() bool                           { return false }
+func (r *ruleSetItemTestRouter) NeighborResolver() adapter.NeighborResolver       { return nil }
+func (r *ruleSetItemTestRouter) ASNReader() adapter.ASNReader                     { return nil }
+func (r *ruleSetItemTestRouter) AppendTracker(adapter.ConnectionTracker)          {}
+func (r *ruleSetItemTestRouter) ResetNetwork()                                    {}
+func (r *ruleSetItemTestRouter) OutboundManager() adapter.OutboundManager         { return nil }
+func (r *ruleSetItemTestRouter) ProviderManager() adapter.OutboundProviderManager { return nil }
+func (r *ruleSetItemTestRouter) RuleSets() []adapter.RuleSet                      { return nil }
+func (r *ruleSetItemTestRouter) Trackers() []adapter.ConnectionTracker            { return nil }
+func (r *ruleSetItemTestRouter) Reload()                                          {}
 
 type countingRuleSet struct {
 	name string
@@ -57,6 +64,11 @@ type countingRuleSet struct {
 }
 
 func (s *countingRuleSet) Name() string                                                  { return s.name }
+func (s *countingRuleSet) Type() string                                                  { return C.RuleSetTypeInline }
+func (s *countingRuleSet) Format() string                                                { return C.RuleSetFormatSource }
+func (s *countingRuleSet) UpdatedTime() time.Time                                        { return time.Time{} }
+func (s *countingRuleSet) Update(context.Context) error                                  { return nil }
+func (s *countingRuleSet) RuleCount() uint64                                             { return 0 }
 func (s *countingRuleSet) StartContext(context.Context, *adapter.HTTPStartContext) error { return nil }
 func (s *countingRuleSet) PostStart() error                                              { return nil }
 func (s *countingRuleSet) Metadata() adapter.RuleSetMetadata                             { return adapter.RuleSetMetadata{} }
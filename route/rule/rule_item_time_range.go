@@ -0,0 +1,155 @@
+package rule
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/ntp"
+)
+
+var _ RuleItem = (*TimeRangeItem)(nil)
+
+type TimeRangeItem struct {
+	timeFunc    func() time.Time
+	description []string
+	ranges      []timeRange
+}
+
+// timeRange is a half-open interval of minutes since midnight, wrapping
+// midnight when start is after end.
+type timeRange struct {
+	start int
+	end   int
+}
+
+func (r timeRange) contains(minute int) bool {
+	if r.start < r.end {
+		return minute >= r.start && minute < r.end
+	}
+	return minute >= r.start || minute < r.end
+}
+
+func NewTimeRangeItem(timeFunc func() time.Time, timeRanges []string) (*TimeRangeItem, error) {
+	ranges := make([]timeRange, 0, len(timeRanges))
+	for _, value := range timeRanges {
+		startString, endString, loaded := strings.Cut(value, "-")
+		if !loaded {
+			return nil, E.New("invalid time range: ", value)
+		}
+		start, err := parseTimeOfDay(startString)
+		if err != nil {
+			return nil, E.Cause(err, "invalid time range: ", value)
+		}
+		end, err := parseTimeOfDay(endString)
+		if err != nil {
+			return nil, E.Cause(err, "invalid time range: ", value)
+		}
+		if start == end {
+			return nil, E.New("invalid time range: ", value, ": empty range")
+		}
+		ranges = append(ranges, timeRange{start, end})
+	}
+	return &TimeRangeItem{
+		timeFunc:    timeFunc,
+		description: timeRanges,
+		ranges:      ranges,
+	}, nil
+}
+
+func parseTimeOfDay(value string) (int, error) {
+	hourString, minuteString, loaded := strings.Cut(strings.TrimSpace(value), ":")
+	if !loaded {
+		return 0, E.New("missing minute in ", value)
+	}
+	hour, err := strconv.ParseUint(hourString, 10, 8)
+	if err != nil || hour > 24 {
+		return 0, E.New("invalid hour in ", value)
+	}
+	minute, err := strconv.ParseUint(minuteString, 10, 8)
+	if err != nil || minute > 59 || hour == 24 && minute != 0 {
+		return 0, E.New("invalid minute in ", value)
+	}
+	return int(hour*60+minute) % (24 * 60), nil
+}
+
+func (r *TimeRangeItem) Match(metadata *adapter.InboundContext) bool {
+	now := r.timeFunc()
+	minute := now.Hour()*60 + now.Minute()
+	for _, timeRange := range r.ranges {
+		if timeRange.contains(minute) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *TimeRangeItem) String() string {
+	if len(r.description) == 1 {
+		return F.ToString("time_range=", r.description[0])
+	}
+	return F.ToString("time_range=[", strings.Join(r.description, " "), "]")
+}
+
+// newTimeItems creates the time_range and weekday items of a rule.
+func newTimeItems(ctx context.Context, timeRanges []string, weekdays []string, timezone string) ([]RuleItem, error) {
+	if len(timeRanges) == 0 && len(weekdays) == 0 {
+		if timezone != "" {
+			return nil, E.New("timezone is only allowed with time_range or weekday")
+		}
+		return nil, nil
+	}
+	timeFunc, err := newRuleTimeFunc(ctx, timezone)
+	if err != nil {
+		return nil, err
+	}
+	var items []RuleItem
+	if len(timeRanges) > 0 {
+		item, err := NewTimeRangeItem(timeFunc, timeRanges)
+		if err != nil {
+			return nil, E.Cause(err, "time_range")
+		}
+		items = append(items, item)
+	}
+	if len(weekdays) > 0 {
+		item, err := NewWeekdayItem(timeFunc, weekdays)
+		if err != nil {
+			return nil, E.Cause(err, "weekday")
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// newRuleTimeFunc returns the clock of time rule items, using the NTP time
+// service if configured and converted to timezone if set.
+func newRuleTimeFunc(ctx context.Context, timezone string) (func() time.Time, error) {
+	var location *time.Location
+	if timezone != "" {
+		var err error
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, E.Cause(err, "load timezone")
+		}
+	}
+	// rules are created before the NTP service is registered
+	loadTimeFunc := sync.OnceValue(func() func() time.Time {
+		timeFunc := ntp.TimeFuncFromContext(ctx)
+		if timeFunc == nil {
+			return time.Now
+		}
+		return timeFunc
+	})
+	return func() time.Time {
+		now := loadTimeFunc()()
+		if location == nil {
+			return now.Local()
+		}
+		return now.In(location)
+	}, nil
+}
@@ -0,0 +1,118 @@
+package rule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing/common/ntp"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTimeService struct {
+	now time.Time
+}
+
+func (s *fakeTimeService) TimeFunc() func() time.Time {
+	return func() time.Time {
+		return s.now
+	}
+}
+
+func TestTimeRangeItem(t *testing.T) {
+	t.Parallel()
+	var now time.Time
+	item, err := NewTimeRangeItem(func() time.Time {
+		return now
+	}, []string{"22:00-06:00", "12:00-12:30"})
+	require.NoError(t, err)
+	for _, testCase := range []struct {
+		clock   string
+		matched bool
+	}{
+		{"21:59", false},
+		{"22:00", true},
+		{"23:59", true},
+		{"00:00", true},
+		{"05:59", true},
+		{"06:00", false},
+		{"12:00", true},
+		{"12:29", true},
+		{"12:30", false},
+	} {
+		now, err = time.Parse("15:04", testCase.clock)
+		require.NoError(t, err)
+		require.Equal(t, testCase.matched, item.Match(nil), testCase.clock)
+	}
+
+	item, err = NewTimeRangeItem(func() time.Time {
+		return now
+	}, []string{"18:00-24:00"})
+	require.NoError(t, err)
+	now = time.Date(2026, 1, 1, 23, 59, 0, 0, time.UTC)
+	require.True(t, item.Match(nil))
+	now = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.False(t, item.Match(nil))
+}
+
+func TestTimeRangeItemInvalid(t *testing.T) {
+	t.Parallel()
+	for _, value := range []string{
+		"",
+		"08:00",
+		"08:00-08:00",
+		"8-9",
+		"25:00-01:00",
+		"24:01-01:00",
+		"08:60-09:00",
+		"-1:00-09:00",
+		"a:00-09:00",
+	} {
+		_, err := NewTimeRangeItem(time.Now, []string{value})
+		require.Error(t, err, value)
+	}
+}
+
+func TestRuleTimeFunc(t *testing.T) {
+	t.Parallel()
+	ctx := service.ContextWithDefaultRegistry(context.Background())
+	timeFunc, err := newRuleTimeFunc(ctx, "Asia/Shanghai")
+	require.NoError(t, err)
+
+	// the time service registered after the rule is created is used
+	timeService := &fakeTimeService{now: time.Date(2026, 1, 1, 20, 0, 0, 0, time.UTC)}
+	service.MustRegister[ntp.TimeService](ctx, timeService)
+	now := timeFunc()
+	require.Equal(t, "Asia/Shanghai", now.Location().String())
+	require.Equal(t, 4, now.Hour())
+	require.Equal(t, time.Friday, now.Weekday())
+
+	items, err := newTimeItems(ctx, []string{"03:00-05:00"}, []string{"fri"}, "Asia/Shanghai")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	for _, item := range items {
+		require.True(t, item.Match(nil), item.String())
+	}
+	items, err = newTimeItems(ctx, []string{"19:00-21:00"}, nil, "Asia/Shanghai")
+	require.NoError(t, err)
+	require.False(t, items[0].Match(nil))
+	items, err = newTimeItems(ctx, []string{"19:00-21:00"}, nil, "UTC")
+	require.NoError(t, err)
+	require.True(t, items[0].Match(nil))
+}
+
+func TestRuleTimeFuncInvalid(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	_, err := newTimeItems(ctx, []string{"08:00-09:00"}, nil, "Invalid/Zone")
+	require.Error(t, err)
+	_, err = newTimeItems(ctx, nil, nil, "UTC")
+	require.Error(t, err)
+	_, err = newTimeItems(ctx, nil, []string{"someday"}, "")
+	require.Error(t, err)
+	items, err := newTimeItems(ctx, nil, nil, "")
+	require.NoError(t, err)
+	require.Empty(t, items)
+}
@@ -0,0 +1,38 @@
+package rule
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*TLSFingerprintItem)(nil)
+
+type TLSFingerprintItem struct {
+	fingerprints   []string
+	fingerprintMap map[string]bool
+}
+
+func NewTLSFingerprintItem(fingerprints []string) *TLSFingerprintItem {
+	fingerprintMap := make(map[string]bool)
+	for _, fingerprint := range fingerprints {
+		fingerprintMap[strings.ToLower(fingerprint)] = true
+	}
+	return &TLSFingerprintItem{
+		fingerprints:   fingerprints,
+		fingerprintMap: fingerprintMap,
+	}
+}
+
+func (r *TLSFingerprintItem) Match(metadata *adapter.InboundContext) bool {
+	return metadata.JA3 != "" && r.fingerprintMap[metadata.JA3] ||
+		metadata.JA4 != "" && r.fingerprintMap[metadata.JA4]
+}
+
+func (r *TLSFingerprintItem) String() string {
+	if len(r.fingerprints) == 1 {
+		return F.ToString("tls_fingerprint=", r.fingerprints[0])
+	}
+	return F.ToString("tls_fingerprint=[", strings.Join(r.fingerprints, " "), "]")
+}
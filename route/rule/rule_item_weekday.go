@@ -0,0 +1,69 @@
+package rule
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*WeekdayItem)(nil)
+
+type WeekdayItem struct {
+	timeFunc    func() time.Time
+	description []string
+	weekdays    [7]bool
+}
+
+func NewWeekdayItem(timeFunc func() time.Time, weekdays []string) (*WeekdayItem, error) {
+	item := &WeekdayItem{
+		timeFunc:    timeFunc,
+		description: weekdays,
+	}
+	for _, value := range weekdays {
+		fromString, toString, isRange := strings.Cut(value, "-")
+		from, err := parseWeekday(fromString)
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if isRange {
+			to, err = parseWeekday(toString)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// ranges such as fri-mon wrap the end of the week
+		for day := from; ; day = (day + 1) % 7 {
+			item.weekdays[day] = true
+			if day == to {
+				break
+			}
+		}
+	}
+	return item, nil
+}
+
+func parseWeekday(value string) (time.Weekday, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if value == name || value == name[:3] {
+			return day, nil
+		}
+	}
+	return 0, E.New("invalid weekday: ", value)
+}
+
+func (r *WeekdayItem) Match(metadata *adapter.InboundContext) bool {
+	return r.weekdays[r.timeFunc().Weekday()]
+}
+
+func (r *WeekdayItem) String() string {
+	if len(r.description) == 1 {
+		return F.ToString("weekday=", r.description[0])
+	}
+	return F.ToString("weekday=[", strings.Join(r.description, " "), "]")
+}
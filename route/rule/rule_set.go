@@ -2,6 +2,7 @@ package rule
 
 import (
 	"context"
+	"io"
 	"reflect"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -26,6 +27,16 @@ func NewRuleSet(ctx context.Context, logger logger.ContextLogger, options option
 	}
 }
 
+// convertRuleSet converts third-party rule lists with the convertor of the
+// box, which keeps the format parsers out of this package.
+func convertRuleSet(ctx context.Context, format string, reader io.Reader, logger logger.Logger) (option.PlainRuleSetCompat, error) {
+	convertor := service.FromContext[adapter.RuleSetConvertor](ctx)
+	if convertor == nil {
+		return option.PlainRuleSetCompat{}, E.New("missing rule-set convertor in context")
+	}
+	return convertor.ConvertRuleSet(format, reader, logger)
+}
+
 func extractIPSetFromRule(rawRule adapter.HeadlessRule) []*netipx.IPSet {
 	switch rule := rawRule.(type) {
 	case *DefaultHeadlessRule:
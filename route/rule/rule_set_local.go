@@ -154,6 +154,16 @@ func (s *LocalRuleSet) reloadFile(path string) error {
 		if err != nil {
 			return err
 		}
+	case C.RuleSetFormatAdGuard, C.RuleSetFormatClash, C.RuleSetFormatSurge, C.RuleSetFormatDnsmasq, C.RuleSetFormatHosts:
+		setFile, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		ruleSet, err = convertRuleSet(s.ctx, s.fileFormat, setFile, s.logger)
+		setFile.Close()
+		if err != nil {
+			return err
+		}
 	default:
 		return E.New("unknown rule-set format: ", s.fileFormat)
 	}
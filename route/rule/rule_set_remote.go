@@ -46,6 +46,8 @@ type RemoteRuleSet struct {
 	metadata       adapter.RuleSetMetadata
 	lastUpdated    time.Time
 	lastEtag       string
+	lastModified   string
+	verifier       *ruleSetVerifier
 	updateTicker   *time.Ticker
 	cacheFile      adapter.CacheFile
 	filePath       string
@@ -64,6 +66,11 @@ func NewRemoteRuleSet(ctx context.Context, logger logger.ContextLogger, options
 	} else {
 		updateInterval = 24 * time.Hour
 	}
+	verifier, err := newRuleSetVerifier(options.RemoteOptions)
+	if err != nil {
+		cancel()
+		return nil, E.Cause(err, "parse verify options")
+	}
 	return &RemoteRuleSet{
 		ctx:            ctx,
 		cancel:         cancel,
@@ -72,6 +79,7 @@ func NewRemoteRuleSet(ctx context.Context, logger logger.ContextLogger, options
 		options:        options,
 		updateInterval: updateInterval,
 		pauseManager:   service.FromContext[pause.Manager](ctx),
+		verifier:       verifier,
 	}, nil
 }
 
@@ -138,6 +146,7 @@ func (s *RemoteRuleSet) StartContext(ctx context.Context, startContext *adapter.
 			} else {
 				s.lastUpdated = savedSet.LastUpdated
 				s.lastEtag = savedSet.LastEtag
+				s.lastModified = savedSet.LastModified
 			}
 		}
 	}
@@ -212,6 +221,11 @@ func (s *RemoteRuleSet) loadBytes(content []byte) error {
 		if err != nil {
 			return err
 		}
+	case C.RuleSetFormatAdGuard, C.RuleSetFormatClash, C.RuleSetFormatSurge, C.RuleSetFormatDnsmasq, C.RuleSetFormatHosts:
+		ruleSet, err = convertRuleSet(s.ctx, s.options.Format, bytes.NewReader(content), s.logger)
+		if err != nil {
+			return err
+		}
 	default:
 		return E.New("unknown rule-set format: ", s.options.Format)
 	}
@@ -278,6 +292,12 @@ func (s *RemoteRuleSet) fetch(ctx context.Context, isStart bool) error {
 	if s.lastEtag != "" {
 		request.Header.Set("If-None-Match", s.lastEtag)
 	}
+	if s.lastModified != "" {
+		request.Header.Set("If-Modified-Since", s.lastModified)
+	} else if s.filePath != "" && !s.lastUpdated.IsZero() {
+		// validators are not saved with the file, use its modification time
+		request.Header.Set("If-Modified-Since", s.lastUpdated.UTC().Format(http.TimeFormat))
+	}
 	if !isStart {
 		defer s.httpClient.CloseIdleConnections()
 	}
@@ -312,6 +332,13 @@ func (s *RemoteRuleSet) fetch(ctx context.Context, isStart bool) error {
 	if err != nil {
 		return err
 	}
+	err = s.verifier.Verify(ctx, s.httpClient, content)
+	if err != nil {
+		if !s.lastUpdated.IsZero() {
+			err = E.Cause(err, "keeping the last verified rule-set")
+		}
+		return err
+	}
 	err = s.loadBytes(content)
 	if err != nil {
 		return err
@@ -320,6 +347,7 @@ func (s *RemoteRuleSet) fetch(ctx context.Context, isStart bool) error {
 	if eTagHeader != "" {
 		s.lastEtag = eTagHeader
 	}
+	s.lastModified = response.Header.Get("Last-Modified")
 	s.lastUpdated = time.Now()
 	if s.filePath != "" {
 		err = os.WriteFile(s.filePath, content, 0o666)
@@ -328,9 +356,10 @@ func (s *RemoteRuleSet) fetch(ctx context.Context, isStart bool) error {
 		}
 	} else if s.cacheFile != nil {
 		err = s.cacheFile.SaveRuleSet(s.options.Tag, &adapter.SavedBinary{
-			LastUpdated: s.lastUpdated,
-			Content:     content,
-			LastEtag:    s.lastEtag,
+			LastUpdated:  s.lastUpdated,
+			Content:      content,
+			LastEtag:     s.lastEtag,
+			LastModified: s.lastModified,
 		})
 		if err != nil {
 			s.logger.Error("save rule-set cache: ", err)
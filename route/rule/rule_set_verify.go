@@ -0,0 +1,118 @@
+package rule
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/minisign"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const maxVerifyFileSize = 64 * 1024
+
+// ruleSetVerifier checks downloaded rule-sets before they replace the
+// active one. A nil verifier accepts everything.
+type ruleSetVerifier struct {
+	sha256       []byte
+	sha256URL    string
+	publicKey    *minisign.PublicKey
+	signatureURL string
+}
+
+func newRuleSetVerifier(options option.RemoteRuleSet) (*ruleSetVerifier, error) {
+	verifyOptions := options.Verify
+	if verifyOptions == nil {
+		return nil, nil
+	}
+	verifier := &ruleSetVerifier{
+		sha256URL: verifyOptions.SHA256URL,
+	}
+	if verifyOptions.SHA256 != "" {
+		if verifyOptions.SHA256URL != "" {
+			return nil, E.New("sha256 is conflict with sha256_url")
+		}
+		checksum, err := hex.DecodeString(verifyOptions.SHA256)
+		if err != nil || len(checksum) != sha256.Size {
+			return nil, E.New("invalid sha256 checksum: ", verifyOptions.SHA256)
+		}
+		verifier.sha256 = checksum
+	}
+	if verifyOptions.MinisignPublicKey != "" {
+		publicKey, err := minisign.ParsePublicKey(verifyOptions.MinisignPublicKey)
+		if err != nil {
+			return nil, E.Cause(err, "parse minisign_public_key")
+		}
+		verifier.publicKey = publicKey
+		verifier.signatureURL = verifyOptions.MinisignSignatureURL
+		if verifier.signatureURL == "" {
+			verifier.signatureURL = options.URL + ".minisig"
+		}
+	} else if verifyOptions.MinisignSignatureURL != "" {
+		return nil, E.New("minisign_signature_url requires minisign_public_key")
+	}
+	if verifier.sha256 == nil && verifier.sha256URL == "" && verifier.publicKey == nil {
+		return nil, E.New("missing sha256, sha256_url or minisign_public_key")
+	}
+	return verifier, nil
+}
+
+func (v *ruleSetVerifier) Verify(ctx context.Context, client *http.Client, content []byte) error {
+	if v == nil {
+		return nil
+	}
+	expectedChecksum := v.sha256
+	if v.sha256URL != "" {
+		checksumFile, err := fetchVerifyFile(ctx, client, v.sha256URL)
+		if err != nil {
+			return E.Cause(err, "fetch sha256 checksum")
+		}
+		// sha256sum output: the checksum followed by the file name
+		fields := strings.Fields(string(checksumFile))
+		if len(fields) == 0 {
+			return E.New("empty sha256 checksum file")
+		}
+		expectedChecksum, err = hex.DecodeString(fields[0])
+		if err != nil || len(expectedChecksum) != sha256.Size {
+			return E.New("invalid sha256 checksum: ", fields[0])
+		}
+	}
+	if expectedChecksum != nil {
+		checksum := sha256.Sum256(content)
+		if !bytes.Equal(checksum[:], expectedChecksum) {
+			return E.New("sha256 checksum mismatch: expected ", hex.EncodeToString(expectedChecksum), ", got ", hex.EncodeToString(checksum[:]))
+		}
+	}
+	if v.publicKey != nil {
+		signature, err := fetchVerifyFile(ctx, client, v.signatureURL)
+		if err != nil {
+			return E.Cause(err, "fetch minisign signature")
+		}
+		err = v.publicKey.Verify(content, signature)
+		if err != nil {
+			return E.Cause(err, "verify minisign signature")
+		}
+	}
+	return nil
+}
+
+func fetchVerifyFile(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	return io.ReadAll(io.LimitReader(response.Body, maxVerifyFileSize))
+}
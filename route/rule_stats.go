@@ -0,0 +1,145 @@
+package route
+
+import (
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const ruleStatsSaveInterval = 5 * time.Minute
+
+var _ adapter.RuleStatsRouter = (*Router)(nil)
+
+type ruleStats struct {
+	matches  atomic.Uint64
+	upload   atomic.Uint64
+	download atomic.Uint64
+	lastHit  atomic.Int64
+}
+
+func (s *ruleStats) hit() {
+	s.matches.Add(1)
+	s.lastHit.Store(time.Now().UnixNano())
+}
+
+func (s *ruleStats) countUpload(n int64) {
+	s.upload.Add(uint64(n))
+}
+
+func (s *ruleStats) countDownload(n int64) {
+	s.download.Add(uint64(n))
+}
+
+func (s *ruleStats) trackConn(conn net.Conn) net.Conn {
+	return bufio.NewCounterConn(conn, []N.CountFunc{s.countUpload}, []N.CountFunc{s.countDownload})
+}
+
+func (s *ruleStats) trackPacketConn(conn N.PacketConn) N.PacketConn {
+	return bufio.NewCounterPacketConn(conn, []N.CountFunc{s.countUpload}, []N.CountFunc{s.countDownload})
+}
+
+func (s *ruleStats) load() adapter.RuleStats {
+	stats := adapter.RuleStats{
+		Matches:  s.matches.Load(),
+		Upload:   s.upload.Load(),
+		Download: s.download.Load(),
+	}
+	if lastHit := s.lastHit.Load(); lastHit > 0 {
+		stats.LastHit = time.Unix(0, lastHit)
+	}
+	return stats
+}
+
+func (s *ruleStats) restore(saved *adapter.SavedRuleStats) {
+	s.matches.Store(saved.Matches)
+	s.upload.Store(saved.Upload)
+	s.download.Store(saved.Download)
+	if !saved.LastHit.IsZero() {
+		s.lastHit.Store(saved.LastHit.UnixNano())
+	}
+}
+
+func (s *ruleStats) reset() {
+	s.matches.Store(0)
+	s.upload.Store(0)
+	s.download.Store(0)
+	s.lastHit.Store(0)
+}
+
+func (r *Router) RuleStats() []adapter.RuleStats {
+	stats := make([]adapter.RuleStats, len(r.ruleStats))
+	for i, it := range r.ruleStats {
+		stats[i] = it.load()
+	}
+	return stats
+}
+
+func (r *Router) ResetRuleStats() {
+	for _, it := range r.ruleStats {
+		it.reset()
+	}
+}
+
+// ruleStatsKeys identifies saved statistics by the rule and its action, so
+// that statistics survive reordering of rules but not changes to them.
+func (r *Router) ruleStatsKeys() []string {
+	keys := make([]string, len(r.rules))
+	keyCount := make(map[string]int)
+	for i, rule := range r.rules {
+		key := rule.String() + " => " + rule.Action().String()
+		keyCount[key]++
+		if count := keyCount[key]; count > 1 {
+			key += " #" + strconv.Itoa(count)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+func (r *Router) loadRuleStats() {
+	savedStats := r.cacheFile.LoadRuleStats()
+	for i, key := range r.ruleStatsKeys() {
+		if saved := savedStats[key]; saved != nil {
+			r.ruleStats[i].restore(saved)
+		}
+	}
+}
+
+func (r *Router) saveRuleStats() error {
+	savedStats := make(map[string]*adapter.SavedRuleStats)
+	for i, key := range r.ruleStatsKeys() {
+		stats := r.ruleStats[i].load()
+		if stats.Matches == 0 {
+			continue
+		}
+		savedStats[key] = &adapter.SavedRuleStats{
+			Matches:  stats.Matches,
+			Upload:   stats.Upload,
+			Download: stats.Download,
+			LastHit:  stats.LastHit,
+		}
+	}
+	return r.cacheFile.SaveRuleStats(savedStats)
+}
+
+func (r *Router) loopSaveRuleStats() {
+	ticker := time.NewTicker(ruleStatsSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.ruleStatsDone:
+			return
+		case <-ticker.C:
+			err := r.saveRuleStats()
+			if err != nil {
+				r.logger.Error(E.Cause(err, "save rule statistics"))
+			}
+		}
+	}
+}
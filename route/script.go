@@ -0,0 +1,229 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"path/filepath"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/script"
+	R "github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var _ adapter.ScriptRouter = (*Router)(nil)
+
+func (r *Router) Script() string {
+	program := r.script.Load()
+	if program == nil {
+		return ""
+	}
+	return program.Source()
+}
+
+func (r *Router) UpdateScript(source string) error {
+	if source == "" {
+		r.script.Store(nil)
+		return nil
+	}
+	program, err := script.Compile(source)
+	if err != nil {
+		return err
+	}
+	r.script.Store(program)
+	return nil
+}
+
+func (r *Router) EvaluateScript(source string, metadata adapter.InboundContext) (string, error) {
+	var program *script.Program
+	if source != "" {
+		var err error
+		program, err = script.Compile(source)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		program = r.script.Load()
+		if program == nil {
+			return "", E.New("missing script")
+		}
+	}
+	return r.runScript(program, &metadata)
+}
+
+func (r *Router) actionScript(ctx context.Context, metadata *adapter.InboundContext, action *R.RuleActionScript) (string, error) {
+	program := action.Program
+	if program == nil {
+		program = r.script.Load()
+		if program == nil {
+			r.logger.DebugContext(ctx, "script skipped: no script configured")
+			return "", nil
+		}
+	}
+	return r.runScript(program, metadata)
+}
+
+func (r *Router) runScript(program *script.Program, metadata *adapter.InboundContext) (string, error) {
+	result, err := program.Run(script.Environment{
+		Variables: map[string]any{
+			"metadata": scriptMetadata(metadata),
+		},
+		Functions: map[string]script.Function{
+			"rule_set": func(arguments []any) (any, error) {
+				return r.scriptMatchRuleSet(metadata, arguments)
+			},
+			"provider": r.scriptProvider,
+			"outbound": r.scriptOutbound,
+			"selected": r.scriptSelected,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	switch result := result.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return result, nil
+	default:
+		return "", E.New("script must return a string, got ", result)
+	}
+}
+
+// scriptMatchRuleSet reports whether metadata matches any of the given rule-sets.
+func (r *Router) scriptMatchRuleSet(metadata *adapter.InboundContext, arguments []any) (any, error) {
+	if len(arguments) == 0 {
+		return nil, E.New("missing rule-set tag")
+	}
+	for index := range arguments {
+		tag, err := script.StringArgument(arguments, index)
+		if err != nil {
+			return nil, err
+		}
+		ruleSet, loaded := r.RuleSet(tag)
+		if !loaded {
+			return nil, E.New("rule-set not found: ", tag)
+		}
+		nestedMetadata := *metadata
+		nestedMetadata.ResetRuleCache()
+		if ruleSet.Match(&nestedMetadata) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// scriptProvider returns outbound tags of a provider.
+func (r *Router) scriptProvider(arguments []any) (any, error) {
+	err := script.Arguments(arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := script.StringArgument(arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+	if r.provider == nil {
+		return nil, E.New("provider not found: ", tag)
+	}
+	provider, loaded := r.provider.OutboundProvider(tag)
+	if !loaded {
+		return nil, E.New("provider not found: ", tag)
+	}
+	return script.StringList(common.Map(provider.Outbounds(), adapter.Outbound.Tag)), nil
+}
+
+// scriptOutbound reports whether an outbound exists.
+func (r *Router) scriptOutbound(arguments []any) (any, error) {
+	err := script.Arguments(arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := script.StringArgument(arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+	_, loaded := r.outbound.Outbound(tag)
+	return loaded, nil
+}
+
+// scriptSelected returns the tag currently selected by a group.
+func (r *Router) scriptSelected(arguments []any) (any, error) {
+	err := script.Arguments(arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := script.StringArgument(arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+	outbound, loaded := r.outbound.Outbound(tag)
+	if !loaded {
+		return nil, E.New("outbound not found: ", tag)
+	}
+	group, isGroup := outbound.(adapter.OutboundGroup)
+	if !isGroup {
+		return nil, E.New("outbound is not a group: ", tag)
+	}
+	return group.Now(), nil
+}
+
+func scriptMetadata(metadata *adapter.InboundContext) map[string]any {
+	host := metadata.Destination.Fqdn
+	if host == "" {
+		host = metadata.SniffHost
+	}
+	if host == "" {
+		host = metadata.Domain
+	}
+	object := map[string]any{
+		"network":          metadata.Network,
+		"inbound":          metadata.Inbound,
+		"inbound_type":     metadata.InboundType,
+		"ip_version":       int64(metadata.IPVersion),
+		"host":             host,
+		"domain":           metadata.Destination.Fqdn,
+		"protocol":         metadata.Protocol,
+		"client":           metadata.Client,
+		"user":             metadata.User,
+		"source_ip":        "",
+		"source_port":      int64(metadata.Source.Port),
+		"destination_ip":   "",
+		"destination_port": int64(metadata.Destination.Port),
+		"destination_ips":  script.StringList(common.Map(metadata.DestinationAddresses, func(it netip.Addr) string { return it.String() })),
+		"process_name":     "",
+		"process_path":     "",
+		"package_name":     "",
+		"process_user":     "",
+		"process_user_id":  int64(-1),
+	}
+	if metadata.Source.IsIP() {
+		object["source_ip"] = metadata.Source.Addr.String()
+	}
+	if metadata.Destination.IsIP() {
+		object["destination_ip"] = metadata.Destination.Addr.String()
+	}
+	if processInfo := metadata.ProcessInfo; processInfo != nil {
+		if processInfo.ProcessPath != "" {
+			object["process_path"] = processInfo.ProcessPath
+			object["process_name"] = filepath.Base(processInfo.ProcessPath)
+		}
+		if len(processInfo.AndroidPackageNames) > 0 {
+			object["package_name"] = processInfo.AndroidPackageNames[0]
+		}
+		object["process_user"] = processInfo.UserName
+		object["process_user_id"] = int64(processInfo.UserId)
+	}
+	return object
+}
+
+// scriptRule reports a rule whose script action selected an outbound.
+type scriptRule struct {
+	adapter.Rule
+	action *R.RuleActionRoute
+}
+
+func (r *scriptRule) Action() adapter.RuleAction {
+	return r.action
+}
@@ -0,0 +1,175 @@
+package quota
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/logger"
+	sHTTP "github.com/sagernet/sing/protocol/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type APIServer struct {
+	logger  logger.Logger
+	service *Service
+	secret  string
+}
+
+func NewAPIServer(logger logger.Logger, service *Service, secret string) *APIServer {
+	return &APIServer{
+		logger:  logger,
+		service: service,
+		secret:  secret,
+	}
+}
+
+func (s *APIServer) Route(r chi.Router) {
+	r.Use(func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			s.logger.Debug(request.Method, " ", request.RequestURI, " ", sHTTP.SourceAddress(request))
+			if s.secret != "" {
+				bearer, token, found := strings.Cut(request.Header.Get("Authorization"), " ")
+				if bearer != "Bearer" || !found || token != s.secret {
+					render.Status(request, http.StatusUnauthorized)
+					render.PlainText(writer, request, "unauthorized")
+					return
+				}
+			}
+			handler.ServeHTTP(writer, request)
+		})
+	})
+	r.Get("/users", s.listUser)
+	r.Get("/users/{name}", s.getUser)
+	r.Patch("/users/{name}", s.updateUser)
+	r.Delete("/users/{name}", s.deleteUser)
+	r.Post("/users/{name}/reset", s.resetUser)
+}
+
+func (s *APIServer) listUser(writer http.ResponseWriter, request *http.Request) {
+	now := time.Now()
+	s.service.access.RLock()
+	users := make([]UserObject, 0, len(s.service.users))
+	for _, user := range s.service.users {
+		users = append(users, user.object(now))
+	}
+	s.service.access.RUnlock()
+	slices.SortFunc(users, func(a, b UserObject) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	render.JSON(writer, request, render.M{
+		"users": users,
+	})
+}
+
+func (s *APIServer) getUser(writer http.ResponseWriter, request *http.Request) {
+	s.service.access.RLock()
+	user := s.service.users[chi.URLParam(request, "name")]
+	s.service.access.RUnlock()
+	if user == nil {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	render.JSON(writer, request, user.object(time.Now()))
+}
+
+// updateUser overrides the limit, expiry or usage of a user, and adds the
+// user if not exists. A zero limit or an empty expiry means unlimited.
+func (s *APIServer) updateUser(writer http.ResponseWriter, request *http.Request) {
+	name := chi.URLParam(request, "name")
+	var updateRequest struct {
+		Limit    *uint64 `json:"limit"`
+		Expire   *string `json:"expire"`
+		Upload   *uint64 `json:"upload"`
+		Download *uint64 `json:"download"`
+	}
+	err := render.DecodeJSON(request.Body, &updateRequest)
+	if err != nil {
+		render.Status(request, http.StatusBadRequest)
+		render.PlainText(writer, request, err.Error())
+		return
+	}
+	var expire time.Time
+	if updateRequest.Expire != nil && *updateRequest.Expire != "" {
+		expire, err = parseExpire(*updateRequest.Expire)
+		if err != nil {
+			render.Status(request, http.StatusBadRequest)
+			render.PlainText(writer, request, err.Error())
+			return
+		}
+	}
+	s.service.access.Lock()
+	user := s.service.users[name]
+	if user == nil {
+		user = &userQuota{name: name}
+		s.service.users[name] = user
+	}
+	s.service.access.Unlock()
+	user.access.Lock()
+	if updateRequest.Limit != nil {
+		user.limitOverride = updateRequest.Limit
+	}
+	if updateRequest.Expire != nil {
+		user.expireOverride = &expire
+	}
+	user.updateEffective()
+	user.access.Unlock()
+	if updateRequest.Upload != nil {
+		user.upload.Store(*updateRequest.Upload)
+	}
+	if updateRequest.Download != nil {
+		user.download.Store(*updateRequest.Download)
+	}
+	user.exceeded.Store(false)
+	user.dirty.Store(true)
+	s.service.saveCache()
+	render.JSON(writer, request, user.object(time.Now()))
+}
+
+func (s *APIServer) resetUser(writer http.ResponseWriter, request *http.Request) {
+	s.service.access.RLock()
+	user := s.service.users[chi.URLParam(request, "name")]
+	s.service.access.RUnlock()
+	if user == nil {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	user.upload.Store(0)
+	user.download.Store(0)
+	user.exceeded.Store(false)
+	user.dirty.Store(true)
+	s.service.saveCache()
+	render.JSON(writer, request, user.object(time.Now()))
+}
+
+// deleteUser drops the usage and runtime overrides of a user, and removes
+// the user if added by the API.
+func (s *APIServer) deleteUser(writer http.ResponseWriter, request *http.Request) {
+	name := chi.URLParam(request, "name")
+	s.service.access.Lock()
+	user := s.service.users[name]
+	if user != nil && !user.configured {
+		delete(s.service.users, name)
+	}
+	s.service.access.Unlock()
+	if user == nil {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	user.load(&adapter.SavedUserQuota{})
+	user.exceeded.Store(false)
+	user.dirty.Store(false)
+	if s.service.cacheFile != nil {
+		err := s.service.cacheFile.DeleteUserQuota(s.service.Tag(), name)
+		if err != nil {
+			render.Status(request, http.StatusInternalServerError)
+			render.PlainText(writer, request, err.Error())
+			return
+		}
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,304 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	boxService "github.com/sagernet/sing-box/adapter/service"
+	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+	aTLS "github.com/sagernet/sing/common/tls"
+	"github.com/sagernet/sing/service"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/http2"
+)
+
+func RegisterService(registry *boxService.Registry) {
+	boxService.Register[option.QuotaServiceOptions](registry, C.TypeQuota, NewService)
+}
+
+var _ adapter.UserQuotaService = (*Service)(nil)
+
+type Service struct {
+	boxService.Adapter
+	ctx          context.Context
+	cancel       context.CancelFunc
+	logger       log.ContextLogger
+	inbounds     map[string]bool
+	saveInterval time.Duration
+	cacheFile    adapter.CacheFile
+	listener     *listener.Listener
+	tlsConfig    tls.ServerConfig
+	httpServer   *http.Server
+	access       sync.RWMutex
+	users        map[string]*userQuota
+}
+
+func NewService(ctx context.Context, logger log.ContextLogger, tag string, options option.QuotaServiceOptions) (adapter.Service, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Service{
+		Adapter:      boxService.NewAdapter(C.TypeQuota, tag),
+		ctx:          ctx,
+		cancel:       cancel,
+		logger:       logger,
+		saveInterval: time.Duration(options.SaveInterval),
+		users:        make(map[string]*userQuota),
+	}
+	if s.saveInterval <= 0 {
+		s.saveInterval = time.Minute
+	}
+	inboundManager := service.FromContext[adapter.InboundManager](ctx)
+	if len(options.Inbounds) > 0 {
+		s.inbounds = make(map[string]bool)
+		for _, inboundTag := range options.Inbounds {
+			_, loaded := inboundManager.Get(inboundTag)
+			if !loaded {
+				return nil, E.New("inbound ", inboundTag, " not found")
+			}
+			s.inbounds[inboundTag] = true
+		}
+	}
+	for i, userOptions := range options.Users {
+		if userOptions.Name == "" {
+			return nil, E.New("parse user[", i, "]: missing name")
+		}
+		if s.users[userOptions.Name] != nil {
+			return nil, E.New("parse user[", i, "]: duplicate name: ", userOptions.Name)
+		}
+		user := &userQuota{
+			name:       userOptions.Name,
+			configured: true,
+			limit:      userOptions.Limit.Value(),
+		}
+		if userOptions.Expire != "" {
+			expire, err := parseExpire(userOptions.Expire)
+			if err != nil {
+				return nil, E.Cause(err, "parse user[", i, "]")
+			}
+			user.expire = expire
+		}
+		user.updateEffective()
+		s.users[user.name] = user
+	}
+	if options.ListenPort != 0 {
+		chiRouter := chi.NewRouter()
+		chiRouter.Route("/", NewAPIServer(logger, s, options.Secret).Route)
+		s.listener = listener.New(listener.Options{
+			Context: ctx,
+			Logger:  logger,
+			Network: []string{N.NetworkTCP},
+			Listen:  options.ListenOptions,
+		})
+		s.httpServer = &http.Server{
+			Handler: chiRouter,
+		}
+		if options.TLS != nil {
+			tlsConfig, err := tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
+			if err != nil {
+				return nil, err
+			}
+			s.tlsConfig = tlsConfig
+		}
+	} else if options.TLS != nil {
+		return nil, E.New("tls requires listen_port to be set")
+	}
+	service.FromContext[adapter.Router](ctx).AppendTracker(s)
+	return s, nil
+}
+
+// parseExpire parses a RFC 3339 time or a date in local time.
+func parseExpire(value string) (time.Time, error) {
+	expire, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return expire, nil
+	}
+	expire, err = time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, E.New("invalid expire: ", value, ", expected RFC 3339 time or YYYY-MM-DD")
+	}
+	return expire, nil
+}
+
+func (s *Service) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	s.cacheFile = service.FromContext[adapter.CacheFile](s.ctx)
+	if s.cacheFile != nil {
+		s.loadCache()
+		go s.loopSaveCache()
+	} else {
+		s.logger.Warn("cache file is not enabled, usage will be lost on restart")
+	}
+	if s.listener == nil {
+		return nil
+	}
+	if s.tlsConfig != nil {
+		err := s.tlsConfig.Start()
+		if err != nil {
+			return E.Cause(err, "create TLS config")
+		}
+	}
+	tcpListener, err := s.listener.ListenTCP()
+	if err != nil {
+		return err
+	}
+	if s.tlsConfig != nil {
+		if !common.Contains(s.tlsConfig.NextProtos(), http2.NextProtoTLS) {
+			s.tlsConfig.SetNextProtos(append([]string{"h2"}, s.tlsConfig.NextProtos()...))
+		}
+		tcpListener = aTLS.NewListener(tcpListener, s.tlsConfig)
+	}
+	go func() {
+		err = s.httpServer.Serve(tcpListener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("serve error: ", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Service) loadCache() {
+	for name, saved := range s.cacheFile.LoadUserQuotas(s.Tag()) {
+		s.access.Lock()
+		user := s.users[name]
+		if user == nil && saved.AddedByAPI {
+			user = &userQuota{name: name}
+			s.users[name] = user
+		}
+		s.access.Unlock()
+		if user == nil {
+			err := s.cacheFile.DeleteUserQuota(s.Tag(), name)
+			if err != nil {
+				s.logger.Error(E.Cause(err, "delete quota of removed user ", name))
+			}
+			continue
+		}
+		user.load(saved)
+	}
+}
+
+func (s *Service) loopSaveCache() {
+	ticker := time.NewTicker(s.saveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.saveCache()
+		}
+	}
+}
+
+func (s *Service) saveCache() {
+	if s.cacheFile == nil {
+		return
+	}
+	s.access.RLock()
+	users := make([]*userQuota, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+	s.access.RUnlock()
+	for _, user := range users {
+		if !user.dirty.Swap(false) {
+			continue
+		}
+		err := s.cacheFile.SaveUserQuota(s.Tag(), user.name, user.save())
+		if err != nil {
+			s.logger.Error(E.Cause(err, "save quota of user ", user.name))
+		}
+	}
+}
+
+func (s *Service) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.saveCache()
+	return common.Close(
+		common.PtrOrNil(s.httpServer),
+		common.PtrOrNil(s.listener),
+		s.tlsConfig,
+	)
+}
+
+func (s *Service) loadUser(metadata *adapter.InboundContext) *userQuota {
+	if metadata.User == "" || s.inbounds != nil && !s.inbounds[metadata.Inbound] {
+		return nil
+	}
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return s.users[metadata.User]
+}
+
+func (s *Service) CheckUser(metadata *adapter.InboundContext) error {
+	user := s.loadUser(metadata)
+	if user == nil {
+		return nil
+	}
+	if user.expired(time.Now()) {
+		return E.New("user ", user.name, " rejected: quota expired")
+	}
+	if user.overLimit() {
+		s.markExceeded(user)
+		return E.New("user ", user.name, " rejected: traffic quota exceeded")
+	}
+	return nil
+}
+
+func (s *Service) markExceeded(user *userQuota) {
+	if !user.exceeded.Swap(true) {
+		s.logger.Warn("user ", user.name, " exceeded traffic quota")
+	}
+}
+
+func (s *Service) RoutedConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) net.Conn {
+	user := s.loadUser(&metadata)
+	if user == nil {
+		return conn
+	}
+	return bufio.NewCounterConn(conn,
+		[]N.CountFunc{s.countFunc(user, user.addUpload, conn)},
+		[]N.CountFunc{s.countFunc(user, user.addDownload, conn)},
+	)
+}
+
+func (s *Service) RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) N.PacketConn {
+	user := s.loadUser(&metadata)
+	if user == nil {
+		return conn
+	}
+	return bufio.NewCounterPacketConn(conn,
+		[]N.CountFunc{s.countFunc(user, user.addUpload, conn)},
+		[]N.CountFunc{s.countFunc(user, user.addDownload, conn)},
+	)
+}
+
+// countFunc closes the connection once the user runs out of quota or expires.
+func (s *Service) countFunc(user *userQuota, add func(n int64), closer io.Closer) N.CountFunc {
+	return func(n int64) {
+		add(n)
+		if user.overLimit() {
+			s.markExceeded(user)
+			closer.Close()
+		} else if user.expired(time.Now()) {
+			closer.Close()
+		}
+	}
+}
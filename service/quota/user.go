@@ -0,0 +1,127 @@
+package quota
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+)
+
+type userQuota struct {
+	name     string
+	upload   atomic.Uint64
+	download atomic.Uint64
+	dirty    atomic.Bool
+	exceeded atomic.Bool
+	// effectiveLimit and effectiveExpire are read on the data path without
+	// the lock, and refreshed by updateEffective.
+	effectiveLimit  atomic.Uint64
+	effectiveExpire atomic.Int64
+	access          sync.Mutex
+	configured      bool
+	limit           uint64
+	expire          time.Time
+	limitOverride   *uint64
+	expireOverride  *time.Time
+}
+
+type UserObject struct {
+	Name     string     `json:"name"`
+	Upload   uint64     `json:"upload"`
+	Download uint64     `json:"download"`
+	Limit    uint64     `json:"limit,omitempty"`
+	Expire   *time.Time `json:"expire,omitempty"`
+	Exceeded bool       `json:"exceeded"`
+	Expired  bool       `json:"expired"`
+}
+
+func (u *userQuota) used() uint64 {
+	return u.upload.Load() + u.download.Load()
+}
+
+// updateEffective applies the runtime overrides to the configured limit and
+// expiry, and must be called with access held.
+func (u *userQuota) updateEffective() {
+	limit, expire := u.limit, u.expire
+	if u.limitOverride != nil {
+		limit = *u.limitOverride
+	}
+	if u.expireOverride != nil {
+		expire = *u.expireOverride
+	}
+	u.effectiveLimit.Store(limit)
+	if expire.IsZero() {
+		u.effectiveExpire.Store(0)
+	} else {
+		u.effectiveExpire.Store(expire.UnixNano())
+	}
+}
+
+// effective returns the limit and expiry, overridden at runtime if set.
+// A zero limit or expiry means unlimited.
+func (u *userQuota) effective() (uint64, time.Time) {
+	var expire time.Time
+	if expireNano := u.effectiveExpire.Load(); expireNano != 0 {
+		expire = time.Unix(0, expireNano)
+	}
+	return u.effectiveLimit.Load(), expire
+}
+
+func (u *userQuota) overLimit() bool {
+	limit := u.effectiveLimit.Load()
+	return limit > 0 && u.used() >= limit
+}
+
+func (u *userQuota) expired(now time.Time) bool {
+	expire := u.effectiveExpire.Load()
+	return expire != 0 && now.UnixNano() >= expire
+}
+
+func (u *userQuota) addUpload(n int64) {
+	u.upload.Add(uint64(n))
+	u.dirty.Store(true)
+}
+
+func (u *userQuota) addDownload(n int64) {
+	u.download.Add(uint64(n))
+	u.dirty.Store(true)
+}
+
+func (u *userQuota) object(now time.Time) UserObject {
+	limit, expire := u.effective()
+	object := UserObject{
+		Name:     u.name,
+		Upload:   u.upload.Load(),
+		Download: u.download.Load(),
+		Limit:    limit,
+	}
+	object.Exceeded = limit > 0 && object.Upload+object.Download >= limit
+	if !expire.IsZero() {
+		object.Expire = &expire
+		object.Expired = !now.Before(expire)
+	}
+	return object
+}
+
+func (u *userQuota) load(saved *adapter.SavedUserQuota) {
+	u.upload.Store(saved.Upload)
+	u.download.Store(saved.Download)
+	u.access.Lock()
+	u.limitOverride = saved.Limit
+	u.expireOverride = saved.Expire
+	u.updateEffective()
+	u.access.Unlock()
+}
+
+func (u *userQuota) save() *adapter.SavedUserQuota {
+	u.access.Lock()
+	defer u.access.Unlock()
+	return &adapter.SavedUserQuota{
+		Upload:     u.upload.Load(),
+		Download:   u.download.Load(),
+		Limit:      u.limitOverride,
+		Expire:     u.expireOverride,
+		AddedByAPI: !u.configured,
+	}
+}
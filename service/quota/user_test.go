@@ -0,0 +1,91 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserQuota(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	user := &userQuota{name: "test", limit: 100, expire: now.Add(time.Hour), configured: true}
+	user.updateEffective()
+	user.addUpload(60)
+	require.False(t, user.overLimit())
+	user.addDownload(40)
+	require.True(t, user.overLimit())
+	require.False(t, user.expired(now))
+
+	limit := uint64(0)
+	expire := now.Add(-time.Minute).Truncate(time.Second)
+	user.load(&adapter.SavedUserQuota{Upload: 1000, Limit: &limit, Expire: &expire})
+	require.False(t, user.overLimit())
+	require.True(t, user.expired(now))
+
+	var saved adapter.SavedUserQuota
+	binary, err := user.save().MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, saved.UnmarshalBinary(binary))
+	require.Equal(t, uint64(1000), saved.Upload)
+	require.Equal(t, uint64(0), saved.Download)
+	require.Equal(t, limit, *saved.Limit)
+	require.True(t, expire.Equal(*saved.Expire))
+	require.False(t, saved.AddedByAPI)
+}
+
+func TestUserQuotaAddedByAPI(t *testing.T) {
+	t.Parallel()
+	user := &userQuota{name: "test"}
+	user.addUpload(10)
+	var saved adapter.SavedUserQuota
+	binary, err := user.save().MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, saved.UnmarshalBinary(binary))
+	require.True(t, saved.AddedByAPI)
+	require.Nil(t, saved.Limit)
+	require.Nil(t, saved.Expire)
+}
+
+type testCloser struct {
+	closed bool
+}
+
+func (c *testCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestCountFuncExpire(t *testing.T) {
+	t.Parallel()
+	service := &Service{}
+	user := &userQuota{name: "test", expire: time.Now().Add(time.Hour), configured: true}
+	user.updateEffective()
+	closer := &testCloser{}
+	countFunc := service.countFunc(user, user.addUpload, closer)
+	countFunc(1)
+	require.False(t, closer.closed)
+
+	expire := time.Now().Add(-time.Second)
+	user.access.Lock()
+	user.expireOverride = &expire
+	user.updateEffective()
+	user.access.Unlock()
+	countFunc(1)
+	require.True(t, closer.closed)
+}
+
+func TestParseExpire(t *testing.T) {
+	t.Parallel()
+	expire, err := parseExpire("2030-01-02T03:04:05Z")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), expire)
+	expire, err = parseExpire("2030-01-02")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2030, 1, 2, 0, 0, 0, 0, time.Local), expire)
+	_, err = parseExpire("tomorrow")
+	require.Error(t, err)
+}
@@ -0,0 +1,230 @@
+package shadowsocksr
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rc4"
+	"io"
+	"net"
+
+	"github.com/sagernet/sing-shadowsocks"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+var CipherList = []string{
+	"none",
+	"aes-128-ctr",
+	"aes-192-ctr",
+	"aes-256-ctr",
+	"aes-128-cfb",
+	"aes-192-cfb",
+	"aes-256-cfb",
+	"rc4-md5",
+	"chacha20",
+	"chacha20-ietf",
+	"xchacha20",
+}
+
+type streamCipher struct {
+	name      string
+	key       []byte
+	ivLength  int
+	encrypter func(key []byte, iv []byte) (cipher.Stream, error)
+	decrypter func(key []byte, iv []byte) (cipher.Stream, error)
+}
+
+func newStreamCipher(method string, password string) (*streamCipher, error) {
+	c := &streamCipher{
+		name: method,
+	}
+	var keyLength int
+	switch method {
+	case "none", "dummy", "plain", "":
+		keyLength = 16
+	case "aes-128-ctr", "aes-192-ctr", "aes-256-ctr":
+		keyLength = aesKeyLength(method)
+		c.ivLength = aes.BlockSize
+		c.encrypter = blockStream(cipher.NewCTR)
+		c.decrypter = blockStream(cipher.NewCTR)
+	case "aes-128-cfb", "aes-192-cfb", "aes-256-cfb":
+		keyLength = aesKeyLength(method)
+		c.ivLength = aes.BlockSize
+		c.encrypter = blockStream(cipher.NewCFBEncrypter)
+		c.decrypter = blockStream(cipher.NewCFBDecrypter)
+	case "rc4-md5":
+		keyLength = 16
+		c.ivLength = 16
+		c.encrypter = rc4MD5Stream
+		c.decrypter = rc4MD5Stream
+	case "chacha20":
+		keyLength = chacha20.KeySize
+		c.ivLength = 8
+		c.encrypter = chacha20Stream
+		c.decrypter = chacha20Stream
+	case "chacha20-ietf":
+		keyLength = chacha20.KeySize
+		c.ivLength = chacha20.NonceSize
+		c.encrypter = chacha20IETFStream
+		c.decrypter = chacha20IETFStream
+	case "xchacha20":
+		keyLength = chacha20.KeySize
+		c.ivLength = chacha20.NonceSizeX
+		c.encrypter = chacha20IETFStream
+		c.decrypter = chacha20IETFStream
+	default:
+		return nil, E.New("unsupported ShadowsocksR method: ", method)
+	}
+	if password == "" {
+		return nil, shadowsocks.ErrMissingPassword
+	}
+	c.key = shadowsocks.Key([]byte(password), keyLength)
+	return c, nil
+}
+
+func aesKeyLength(method string) int {
+	switch method[4:7] {
+	case "128":
+		return 16
+	case "192":
+		return 24
+	default:
+		return 32
+	}
+}
+
+func blockStream(streamCreator func(block cipher.Block, iv []byte) cipher.Stream) func([]byte, []byte) (cipher.Stream, error) {
+	return func(key []byte, iv []byte) (cipher.Stream, error) {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return streamCreator(block, iv), nil
+	}
+}
+
+func rc4MD5Stream(key []byte, iv []byte) (cipher.Stream, error) {
+	h := md5.New()
+	h.Write(key)
+	h.Write(iv)
+	return rc4.NewCipher(h.Sum(nil))
+}
+
+// chacha20Stream implements the original 64-bit nonce ChaCha20 by using the
+// IETF variant with a zero-prefixed nonce, which is identical for the first
+// 256 GiB of keystream.
+func chacha20Stream(key []byte, iv []byte) (cipher.Stream, error) {
+	nonce := make([]byte, chacha20.NonceSize)
+	copy(nonce[4:], iv)
+	return chacha20.NewUnauthenticatedCipher(key, nonce)
+}
+
+func chacha20IETFStream(key []byte, iv []byte) (cipher.Stream, error) {
+	return chacha20.NewUnauthenticatedCipher(key, iv)
+}
+
+func (c *streamCipher) isNone() bool {
+	return c.ivLength == 0
+}
+
+func (c *streamCipher) newIV() []byte {
+	iv := make([]byte, c.ivLength)
+	common.Must1(io.ReadFull(rand.Reader, iv))
+	return iv
+}
+
+// encryptPacket returns iv || E(payload)
+func (c *streamCipher) encryptPacket(payload []byte) ([]byte, error) {
+	if c.isNone() {
+		return payload, nil
+	}
+	iv := c.newIV()
+	stream, err := c.encrypter(c.key, iv)
+	if err != nil {
+		return nil, err
+	}
+	packet := make([]byte, c.ivLength+len(payload))
+	copy(packet, iv)
+	stream.XORKeyStream(packet[c.ivLength:], payload)
+	return packet, nil
+}
+
+// decryptPacket decrypts the packet in place and returns the payload
+func (c *streamCipher) decryptPacket(packet []byte) ([]byte, error) {
+	if c.isNone() {
+		return packet, nil
+	}
+	if len(packet) < c.ivLength {
+		return nil, E.New("packet too short")
+	}
+	stream, err := c.decrypter(c.key, packet[:c.ivLength])
+	if err != nil {
+		return nil, err
+	}
+	payload := packet[c.ivLength:]
+	stream.XORKeyStream(payload, payload)
+	return payload, nil
+}
+
+type streamConn struct {
+	net.Conn
+	cipher      *streamCipher
+	writeIV     []byte
+	readStream  cipher.Stream
+	writeStream cipher.Stream
+}
+
+func (c *streamCipher) streamConn(conn net.Conn) *streamConn {
+	return &streamConn{
+		Conn:    conn,
+		cipher:  c,
+		writeIV: c.newIV(),
+	}
+}
+
+func (c *streamConn) Read(b []byte) (int, error) {
+	if c.readStream == nil {
+		iv := make([]byte, c.cipher.ivLength)
+		_, err := io.ReadFull(c.Conn, iv)
+		if err != nil {
+			return 0, err
+		}
+		c.readStream, err = c.cipher.decrypter(c.cipher.key, iv)
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.readStream.XORKeyStream(b[:n], b[:n])
+	}
+	return n, err
+}
+
+func (c *streamConn) Write(b []byte) (int, error) {
+	var ivLength int
+	if c.writeStream == nil {
+		var err error
+		c.writeStream, err = c.cipher.encrypter(c.cipher.key, c.writeIV)
+		if err != nil {
+			return 0, err
+		}
+		ivLength = len(c.writeIV)
+	}
+	buffer := make([]byte, ivLength+len(b))
+	copy(buffer, c.writeIV[:ivLength])
+	c.writeStream.XORKeyStream(buffer[ivLength:], b)
+	_, err := c.Conn.Write(buffer)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *streamConn) Upstream() any {
+	return c.Conn
+}
@@ -0,0 +1,164 @@
+package shadowsocksr
+
+import (
+	"bytes"
+	"net"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type ClientOptions struct {
+	Server        M.Socksaddr
+	Method        string
+	Password      string
+	Obfs          string
+	ObfsParam     string
+	Protocol      string
+	ProtocolParam string
+}
+
+type Client struct {
+	cipher   *streamCipher
+	obfs     obfs
+	protocol protocol
+}
+
+func NewClient(options ClientOptions) (*Client, error) {
+	streamCipher, err := newStreamCipher(options.Method, options.Password)
+	if err != nil {
+		return nil, err
+	}
+	obfs, err := newObfs(options.Obfs, &obfsBase{
+		host:     options.Server.AddrString(),
+		port:     options.Server.Port,
+		key:      streamCipher.key,
+		ivLength: streamCipher.ivLength,
+		param:    options.ObfsParam,
+	})
+	if err != nil {
+		return nil, err
+	}
+	protocol, err := newProtocol(options.Protocol, &protocolBase{
+		key:      streamCipher.key,
+		overhead: obfs.Overhead(),
+		param:    options.ProtocolParam,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		cipher:   streamCipher,
+		obfs:     obfs,
+		protocol: protocol,
+	}, nil
+}
+
+func (c *Client) DialConn(conn net.Conn, destination M.Socksaddr) (net.Conn, error) {
+	conn = c.obfs.StreamConn(conn)
+	var iv []byte
+	if !c.cipher.isNone() {
+		cipherConn := c.cipher.streamConn(conn)
+		iv = cipherConn.writeIV
+		conn = cipherConn
+	}
+	conn = c.protocol.StreamConn(conn, iv)
+	request := buf.NewSize(M.SocksaddrSerializer.AddrPortLen(destination))
+	defer request.Release()
+	err := M.SocksaddrSerializer.WriteAddrPort(request, destination)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Write(request.Bytes())
+	if err != nil {
+		return nil, E.Cause(err, "write request")
+	}
+	return conn, nil
+}
+
+func (c *Client) DialPacketConn(conn net.Conn) N.NetPacketConn {
+	return &packetConn{Conn: conn, client: c}
+}
+
+var _ N.NetPacketConn = (*packetConn)(nil)
+
+type packetConn struct {
+	net.Conn
+	client *Client
+}
+
+func (c *packetConn) ReadPacket(buffer *buf.Buffer) (M.Socksaddr, error) {
+	_, err := buffer.ReadOnceFrom(c.Conn)
+	if err != nil {
+		return M.Socksaddr{}, err
+	}
+	payload, err := c.client.cipher.decryptPacket(buffer.Bytes())
+	if err != nil {
+		return M.Socksaddr{}, err
+	}
+	payload, err = c.client.protocol.DecodePacket(payload)
+	if err != nil {
+		return M.Socksaddr{}, err
+	}
+	buffer.Advance(c.client.cipher.ivLength)
+	buffer.Truncate(len(payload))
+	destination, err := M.SocksaddrSerializer.ReadAddrPort(buffer)
+	if err != nil {
+		return M.Socksaddr{}, err
+	}
+	return destination.Unwrap(), nil
+}
+
+func (c *packetConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	defer buffer.Release()
+	request := buf.NewSize(M.SocksaddrSerializer.AddrPortLen(destination) + buffer.Len())
+	defer request.Release()
+	err := M.SocksaddrSerializer.WriteAddrPort(request, destination)
+	if err != nil {
+		return err
+	}
+	_, err = request.Write(buffer.Bytes())
+	if err != nil {
+		return err
+	}
+	var encoded bytes.Buffer
+	err = c.client.protocol.EncodePacket(&encoded, request.Bytes())
+	if err != nil {
+		return err
+	}
+	packet, err := c.client.cipher.encryptPacket(encoded.Bytes())
+	if err != nil {
+		return err
+	}
+	return common.Error(c.Conn.Write(packet))
+}
+
+func (c *packetConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	buffer := buf.With(p)
+	destination, err := c.ReadPacket(buffer)
+	if err != nil {
+		return
+	}
+	n = copy(p, buffer.Bytes())
+	if destination.IsFqdn() {
+		addr = destination
+	} else {
+		addr = destination.UDPAddr()
+	}
+	return
+}
+
+func (c *packetConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	err = c.WritePacket(buf.As(p), M.SocksaddrFromNet(addr))
+	if err == nil {
+		n = len(p)
+	}
+	return
+}
+
+func (c *packetConn) Upstream() any {
+	return c.Conn
+}
@@ -0,0 +1,444 @@
+package shadowsocksr
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPassword = "password"
+
+func TestClientLoopback(t *testing.T) {
+	t.Parallel()
+	for _, method := range []string{"none", "aes-128-cfb", "aes-256-ctr", "rc4-md5", "chacha20", "chacha20-ietf"} {
+		for _, obfs := range []string{"plain", "http_simple", "http_post", "tls1.2_ticket_auth"} {
+			for _, protocol := range []string{"origin", "auth_aes128_md5", "auth_aes128_sha1"} {
+				options := ClientOptions{
+					Method:    method,
+					Password:  testPassword,
+					Obfs:      obfs,
+					ObfsParam: "www.example.com",
+					Protocol:  protocol,
+				}
+				t.Run(strings.Join([]string{method, obfs, protocol}, "/"), func(t *testing.T) {
+					t.Parallel()
+					testClientTCP(t, options)
+				})
+			}
+		}
+	}
+}
+
+func TestClientLoopbackPacket(t *testing.T) {
+	t.Parallel()
+	for _, method := range []string{"none", "aes-128-cfb", "chacha20-ietf"} {
+		for _, protocol := range []string{"origin", "auth_aes128_md5", "auth_aes128_sha1"} {
+			options := ClientOptions{
+				Method:   method,
+				Password: testPassword,
+				Protocol: protocol,
+			}
+			t.Run(method+"/"+protocol, func(t *testing.T) {
+				t.Parallel()
+				testClientUDP(t, options)
+			})
+		}
+	}
+}
+
+func testClientTCP(t *testing.T, options ClientOptions) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	options.Server = M.SocksaddrFromNet(listener.Addr())
+	client, err := NewClient(options)
+	require.NoError(t, err)
+
+	destination := M.ParseSocksaddrHostPort("example.com", 443)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- serveTestTCP(listener, client, options, destination)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	clientConn, err := client.DialConn(conn, destination)
+	require.NoError(t, err)
+
+	for _, size := range []int{1, 100, 1400, 9000, 20000} {
+		payload := make([]byte, size)
+		_, err = rand.Read(payload)
+		require.NoError(t, err)
+		_, err = clientConn.Write(payload)
+		require.NoError(t, err)
+		response := make([]byte, size)
+		_, err = io.ReadFull(clientConn, response)
+		require.NoError(t, err)
+		require.Equal(t, payload, response)
+	}
+	clientConn.Close()
+	require.NoError(t, <-serverErr)
+}
+
+func testClientUDP(t *testing.T, options ClientOptions) {
+	serverConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer serverConn.Close()
+	options.Server = M.SocksaddrFromNet(serverConn.LocalAddr())
+	client, err := NewClient(options)
+	require.NoError(t, err)
+
+	go serveTestUDP(serverConn, client, options)
+
+	conn, err := net.Dial("udp", serverConn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	packetConn := client.DialPacketConn(conn)
+	destination := M.ParseSocksaddrHostPort("1.1.1.1", 53)
+	payload := make([]byte, 512)
+	_, err = rand.Read(payload)
+	require.NoError(t, err)
+	_, err = packetConn.WriteTo(payload, destination.UDPAddr())
+	require.NoError(t, err)
+	response := make([]byte, 2048)
+	n, addr, err := packetConn.ReadFrom(response)
+	require.NoError(t, err)
+	require.Equal(t, payload, response[:n])
+	require.Equal(t, destination, M.SocksaddrFromNet(addr))
+}
+
+func serveTestTCP(listener net.Listener, client *Client, options ClientOptions, destination M.Socksaddr) error {
+	conn, err := listener.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	switch options.Obfs {
+	case "http_simple", "http_post":
+		conn, err = newTestHTTPServerConn(conn)
+	case "tls1.2_ticket_auth":
+		conn, err = newTestTLSServerConn(conn, client.cipher.key)
+	}
+	if err != nil {
+		return err
+	}
+	if !client.cipher.isNone() {
+		conn = &testCipherServerConn{Conn: conn, cipher: client.cipher}
+	}
+	switch options.Protocol {
+	case "auth_aes128_md5":
+		conn, err = newTestAuthAES128ServerConn(conn, client.cipher.key, "auth_aes128_md5", md5.New)
+	case "auth_aes128_sha1":
+		conn, err = newTestAuthAES128ServerConn(conn, client.cipher.key, "auth_aes128_sha1", sha1.New)
+	}
+	if err != nil {
+		return err
+	}
+	requestDestination, err := M.SocksaddrSerializer.ReadAddrPort(conn)
+	if err != nil {
+		return err
+	}
+	if requestDestination != destination {
+		return io.ErrUnexpectedEOF
+	}
+	_, err = io.Copy(conn, conn)
+	return err
+}
+
+func serveTestUDP(conn net.PacketConn, client *Client, options ClientOptions) {
+	buffer := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		payload, err := client.cipher.decryptPacket(buffer[:n])
+		if err != nil {
+			return
+		}
+		var h func() hash.Hash
+		switch options.Protocol {
+		case "auth_aes128_md5":
+			h = md5.New
+		case "auth_aes128_sha1":
+			h = sha1.New
+		}
+		if h != nil {
+			// payload + uid(4) + hmac(4)
+			payload = payload[:len(payload)-8]
+		}
+		response := append([]byte(nil), payload...)
+		if h != nil {
+			response = append(response, hmacSum(h, client.cipher.key, response)[:4]...)
+		}
+		response, err = client.cipher.encryptPacket(response)
+		if err != nil {
+			return
+		}
+		conn.WriteTo(response, addr)
+	}
+}
+
+type testHTTPServerConn struct {
+	net.Conn
+	reader    io.Reader
+	responded bool
+}
+
+func newTestHTTPServerConn(conn net.Conn) (net.Conn, error) {
+	reader := bufio.NewReader(conn)
+	requestLine, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(requestLine)
+	if len(fields) != 3 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	headData, err := hex.DecodeString(strings.ReplaceAll(strings.TrimPrefix(fields[1], "/"), "%", ""))
+	if err != nil {
+		return nil, err
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if line == "\r\n" {
+			break
+		}
+	}
+	return &testHTTPServerConn{Conn: conn, reader: io.MultiReader(bytes.NewReader(headData), reader)}, nil
+}
+
+func (c *testHTTPServerConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func (c *testHTTPServerConn) Write(b []byte) (int, error) {
+	if !c.responded {
+		c.responded = true
+		_, err := c.Conn.Write(append([]byte("HTTP/1.1 200 OK\r\nConnection: keep-alive\r\n\r\n"), b...))
+		return len(b), err
+	}
+	return c.Conn.Write(b)
+}
+
+type testTLSServerConn struct {
+	net.Conn
+	reader io.Reader
+	remain int
+}
+
+func newTestTLSServerConn(conn net.Conn, key []byte) (net.Conn, error) {
+	header := make([]byte, 5)
+	_, err := io.ReadFull(conn, header)
+	if err != nil {
+		return nil, err
+	}
+	clientHello := make([]byte, binary.BigEndian.Uint16(header[3:]))
+	_, err = io.ReadFull(conn, clientHello)
+	if err != nil {
+		return nil, err
+	}
+	ticket := &tls12TicketObfs{obfsBase: &obfsBase{key: key}}
+	// handshake header(4) + version(2) + random(32) + session id length(1)
+	copy(ticket.clientID[:], clientHello[39:71])
+
+	var serverHello bytes.Buffer
+	serverHello.Write([]byte{0x16, 3, 3, 0, 0x5b, 2, 0, 0, 0x57, 3, 3})
+	random := make([]byte, 22)
+	rand.Read(random)
+	serverHello.Write(random)
+	serverHello.Write(ticket.hmacSHA1(random))
+	serverHello.WriteByte(0x20)
+	serverHello.Write(ticket.clientID[:])
+	serverHello.Write([]byte{0xc0, 0x2f, 0, 0, 0x0f, 0xff, 0x01, 0, 0x01, 0, 0, 0x17, 0, 0, 0, 0x0b, 0, 0x02, 0x01, 0})
+	serverHello.Write([]byte{0x14, 3, 3, 0, 1, 1, 0x16, 3, 3, 0, 0x20})
+	writeRandom(&serverHello, 22)
+	serverHello.Write(ticket.hmacSHA1(serverHello.Bytes()))
+	_, err = conn.Write(serverHello.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	// ChangeCipherSpec and Finished
+	finish := make([]byte, 43)
+	_, err = io.ReadFull(conn, finish)
+	if err != nil {
+		return nil, err
+	}
+	return &testTLSServerConn{Conn: conn, reader: conn}, nil
+}
+
+func (c *testTLSServerConn) Read(b []byte) (int, error) {
+	if c.remain == 0 {
+		header := make([]byte, 5)
+		_, err := io.ReadFull(c.reader, header)
+		if err != nil {
+			return 0, err
+		}
+		if header[0] != 0x17 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		c.remain = int(binary.BigEndian.Uint16(header[3:]))
+	}
+	if len(b) > c.remain {
+		b = b[:c.remain]
+	}
+	n, err := c.reader.Read(b)
+	c.remain -= n
+	return n, err
+}
+
+func (c *testTLSServerConn) Write(b []byte) (int, error) {
+	var buffer bytes.Buffer
+	writeApplicationData(&buffer, b)
+	_, err := c.Conn.Write(buffer.Bytes())
+	return len(b), err
+}
+
+type testCipherServerConn struct {
+	net.Conn
+	cipher      *streamCipher
+	readStream  cipher.Stream
+	writeStream cipher.Stream
+}
+
+func (c *testCipherServerConn) Read(b []byte) (int, error) {
+	if c.readStream == nil {
+		iv := make([]byte, c.cipher.ivLength)
+		_, err := io.ReadFull(c.Conn, iv)
+		if err != nil {
+			return 0, err
+		}
+		c.readStream, err = c.cipher.decrypter(c.cipher.key, iv)
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := c.Conn.Read(b)
+	c.readStream.XORKeyStream(b[:n], b[:n])
+	return n, err
+}
+
+func (c *testCipherServerConn) Write(b []byte) (int, error) {
+	var iv []byte
+	if c.writeStream == nil {
+		iv = c.cipher.newIV()
+		var err error
+		c.writeStream, err = c.cipher.encrypter(c.cipher.key, iv)
+		if err != nil {
+			return 0, err
+		}
+	}
+	buffer := make([]byte, len(iv)+len(b))
+	copy(buffer, iv)
+	c.writeStream.XORKeyStream(buffer[len(iv):], b)
+	_, err := c.Conn.Write(buffer)
+	return len(b), err
+}
+
+func newTestAuthAES128ServerConn(conn net.Conn, key []byte, salt string, h func() hash.Hash) (net.Conn, error) {
+	header := make([]byte, 31)
+	_, err := io.ReadFull(conn, header)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(kdf(base64.StdEncoding.EncodeToString(key)+salt, 16))
+	if err != nil {
+		return nil, err
+	}
+	encrypted := header[11:27]
+	cipher.NewCBCDecrypter(block, make([]byte, 16)).CryptBlocks(encrypted, encrypted)
+	packedLength := int(binary.LittleEndian.Uint16(encrypted[12:]))
+	randLength := int(binary.LittleEndian.Uint16(encrypted[14:]))
+	remain := make([]byte, packedLength-len(header))
+	_, err = io.ReadFull(conn, remain)
+	if err != nil {
+		return nil, err
+	}
+	protocol := newAuthAES128(&protocolBase{key: key}, salt, h).newConn(nil)
+	serverConn := &protocolConn{Conn: conn, protocol: &testAuthAES128Server{protocol}}
+	serverConn.decoded.Write(remain[randLength : len(remain)-4])
+	return serverConn, nil
+}
+
+type testAuthAES128Server struct {
+	*authAES128
+}
+
+func (s *testAuthAES128Server) Encode(buffer *bytes.Buffer, payload []byte) error {
+	for len(payload) > 8100 {
+		s.writeData(buffer, payload[:8100], len(payload))
+		payload = payload[8100:]
+	}
+	if len(payload) > 0 {
+		s.writeData(buffer, payload, len(payload))
+	}
+	return nil
+}
+
+func TestAuthChainCodec(t *testing.T) {
+	t.Parallel()
+	key := kdf(testPassword, 16)
+	for _, name := range []string{"auth_chain_a", "auth_chain_b"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			protocol, err := newProtocol(name, &protocolBase{key: key})
+			require.NoError(t, err)
+			var base *authChainA
+			switch chain := protocol.(type) {
+			case *authChainA:
+				base = chain
+			case *authChainB:
+				base = chain.authChainA
+			}
+			client := base.newConn(nil)
+			server := base.newConn(nil)
+			initialHash := make([]byte, 16)
+			rand.Read(initialHash)
+			client.lastClientHash = initialHash
+			client.initRC4Cipher()
+			server.lastClientHash = initialHash
+			server.lastServerHash = initialHash
+			server.initRC4Cipher()
+
+			var stream, decoded bytes.Buffer
+			var expected []byte
+			for i, size := range []int{0, 10, 500, 1000, 1400, 2800} {
+				payload := make([]byte, size)
+				rand.Read(payload)
+				expected = append(expected, payload...)
+				if i == 0 {
+					// the first packet starts with the tcp mss
+					payload = append([]byte{0x05, 0xb4}, payload...)
+				}
+				client.writeData(&stream, payload)
+			}
+			require.NoError(t, server.Decode(&decoded, &stream))
+			require.Zero(t, stream.Len())
+			require.Equal(t, expected, decoded.Bytes())
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package shadowsocksr
+
+import (
+	"net"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var ObfsList = []string{
+	"plain",
+	"http_simple",
+	"http_post",
+	"tls1.2_ticket_auth",
+	"tls1.2_ticket_fastauth",
+}
+
+type obfs interface {
+	StreamConn(conn net.Conn) net.Conn
+	Overhead() int
+}
+
+type obfsBase struct {
+	host     string
+	port     uint16
+	key      []byte
+	ivLength int
+	param    string
+}
+
+func newObfs(name string, base *obfsBase) (obfs, error) {
+	switch strings.ToLower(name) {
+	case "", "plain":
+		return &plainObfs{}, nil
+	case "http_simple":
+		return &httpObfs{obfsBase: base}, nil
+	case "http_post":
+		return &httpObfs{obfsBase: base, post: true}, nil
+	case "tls1.2_ticket_auth", "tls1.2_ticket_fastauth":
+		return newTLS12TicketObfs(base), nil
+	default:
+		return nil, E.New("unsupported ShadowsocksR obfs: ", name)
+	}
+}
+
+type plainObfs struct{}
+
+func (o *plainObfs) StreamConn(conn net.Conn) net.Conn {
+	return conn
+}
+
+func (o *plainObfs) Overhead() int {
+	return 0
+}
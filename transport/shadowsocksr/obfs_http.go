@@ -0,0 +1,152 @@
+package shadowsocksr
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const maxHTTPResponseHeaderLength = 8192
+
+var httpUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+}
+
+// httpObfs is the ShadowsocksR http_simple and http_post obfs implementation
+type httpObfs struct {
+	*obfsBase
+	post bool
+}
+
+func (o *httpObfs) StreamConn(conn net.Conn) net.Conn {
+	return &httpObfsConn{Conn: conn, httpObfs: o}
+}
+
+func (o *httpObfs) Overhead() int {
+	return 0
+}
+
+type httpObfsConn struct {
+	net.Conn
+	*httpObfs
+	headerSent     bool
+	headerReceived bool
+	buffer         []byte
+}
+
+func (c *httpObfsConn) Read(b []byte) (int, error) {
+	if len(c.buffer) > 0 {
+		n := copy(b, c.buffer)
+		c.buffer = c.buffer[n:]
+		return n, nil
+	}
+	if c.headerReceived {
+		return c.Conn.Read(b)
+	}
+	var header []byte
+	buffer := make([]byte, 2048)
+	for {
+		n, err := c.Conn.Read(buffer)
+		if n > 0 {
+			header = append(header, buffer[:n]...)
+			if index := bytes.Index(header, []byte("\r\n\r\n")); index != -1 {
+				c.headerReceived = true
+				c.buffer = header[index+4:]
+				n = copy(b, c.buffer)
+				c.buffer = c.buffer[n:]
+				return n, nil
+			}
+			if len(header) > maxHTTPResponseHeaderLength {
+				return 0, io.ErrUnexpectedEOF
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (c *httpObfsConn) Write(b []byte) (int, error) {
+	if c.headerSent {
+		return c.Conn.Write(b)
+	}
+	bLength := len(b)
+	headLength := c.ivLength + 30
+	headDataLength := bLength
+	if bLength-headLength > 64 {
+		headDataLength = headLength + rand.Intn(65)
+	}
+	headData := b[:headDataLength]
+	b = b[headDataLength:]
+
+	var customHead string
+	host := c.host
+	if c.param != "" {
+		if index := strings.Index(c.param, "#"); index != -1 {
+			customHead = strings.ReplaceAll(c.param[index+1:], "\\n", "\r\n")
+			host = c.param[:index]
+		} else {
+			host = c.param
+		}
+	}
+	hosts := strings.Split(host, ",")
+	host = strings.TrimSpace(hosts[rand.Intn(len(hosts))])
+
+	buffer := bytes.NewBuffer(make([]byte, 0, 1024+len(b)))
+	if c.post {
+		buffer.WriteString("POST /")
+	} else {
+		buffer.WriteString("GET /")
+	}
+	for i := range headData {
+		buffer.WriteByte('%')
+		buffer.WriteString(hex.EncodeToString(headData[i : i+1]))
+	}
+	buffer.WriteString(" HTTP/1.1\r\nHost: ")
+	buffer.WriteString(host)
+	if c.port != 80 {
+		buffer.WriteString(":")
+		buffer.WriteString(strconv.Itoa(int(c.port)))
+	}
+	buffer.WriteString("\r\n")
+	if customHead != "" {
+		buffer.WriteString(customHead)
+		buffer.WriteString("\r\n\r\n")
+	} else {
+		buffer.WriteString("User-Agent: ")
+		buffer.WriteString(httpUserAgents[rand.Intn(len(httpUserAgents))])
+		buffer.WriteString("\r\nAccept: text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8\r\nAccept-Language: en-US,en;q=0.8\r\nAccept-Encoding: gzip, deflate\r\n")
+		if c.post {
+			writeBoundary(buffer)
+		}
+		buffer.WriteString("DNT: 1\r\nConnection: keep-alive\r\n\r\n")
+	}
+	buffer.Write(b)
+	_, err := c.Conn.Write(buffer.Bytes())
+	if err != nil {
+		return 0, err
+	}
+	c.headerSent = true
+	return bLength, nil
+}
+
+func (c *httpObfsConn) Upstream() any {
+	return c.Conn
+}
+
+func writeBoundary(buffer *bytes.Buffer) {
+	const charset = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	buffer.WriteString("Content-Type: multipart/form-data; boundary=")
+	for i := 0; i < 32; i++ {
+		buffer.WriteByte(charset[rand.Intn(len(charset))])
+	}
+	buffer.WriteString("\r\n")
+}
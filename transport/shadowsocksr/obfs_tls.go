@@ -0,0 +1,275 @@
+package shadowsocksr
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	mRand "math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	tlsHandshakeStart = iota
+	tlsHandshakeSent
+	tlsHandshakeDone
+)
+
+var errTLSTicketDecode = E.New("tls1.2_ticket_auth: decode error")
+
+// tls12TicketObfs is the ShadowsocksR tls1.2_ticket_auth obfs implementation
+type tls12TicketObfs struct {
+	*obfsBase
+	clientID [32]byte
+}
+
+func newTLS12TicketObfs(base *obfsBase) *tls12TicketObfs {
+	o := &tls12TicketObfs{obfsBase: base}
+	common.Must1(rand.Read(o.clientID[:]))
+	return o
+}
+
+func (o *tls12TicketObfs) StreamConn(conn net.Conn) net.Conn {
+	return &tls12TicketConn{Conn: conn, tls12TicketObfs: o}
+}
+
+func (o *tls12TicketObfs) Overhead() int {
+	return 5
+}
+
+func (o *tls12TicketObfs) hmacSHA1(data []byte) []byte {
+	key := make([]byte, len(o.key)+len(o.clientID))
+	copy(key, o.key)
+	copy(key[len(o.key):], o.clientID[:])
+	mac := hmac.New(sha1.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)[:10]
+}
+
+func (o *tls12TicketObfs) serverName() string {
+	host := o.param
+	if host == "" {
+		host = o.host
+	}
+	if host != "" && host[len(host)-1] >= '0' && host[len(host)-1] <= '9' {
+		host = ""
+	}
+	hosts := strings.Split(host, ",")
+	return strings.TrimSpace(hosts[mRand.Intn(len(hosts))])
+}
+
+type tls12TicketConn struct {
+	net.Conn
+	*tls12TicketObfs
+	access          sync.Mutex
+	handshakeStatus int
+	handshakeRead   bool
+	handshake       bytes.Buffer
+	decoded         bytes.Buffer
+	underDecoded    bytes.Buffer
+	pending         bytes.Buffer
+}
+
+func (c *tls12TicketConn) Read(b []byte) (int, error) {
+	for c.decoded.Len() == 0 {
+		buffer := make([]byte, 8192)
+		n, err := c.Conn.Read(buffer)
+		if n > 0 {
+			if c.handshakeRead {
+				c.underDecoded.Write(buffer[:n])
+			} else {
+				c.handshake.Write(buffer[:n])
+				done, handshakeErr := c.readServerHandshake()
+				if handshakeErr != nil {
+					return 0, handshakeErr
+				}
+				if !done {
+					if err != nil {
+						return 0, err
+					}
+					continue
+				}
+			}
+			decodeErr := c.decodeRecords()
+			if decodeErr != nil {
+				return 0, decodeErr
+			}
+		}
+		if err != nil {
+			if c.decoded.Len() > 0 {
+				break
+			}
+			return 0, err
+		}
+	}
+	return c.decoded.Read(b)
+}
+
+func (c *tls12TicketConn) readServerHandshake() (bool, error) {
+	// ServerHello, ChangeCipherSpec and Finished
+	data := c.handshake.Bytes()
+	var offset int
+	for i := 0; i < 3; i++ {
+		if len(data) < offset+5 {
+			return false, nil
+		}
+		offset += 5 + int(binary.BigEndian.Uint16(data[offset+3:offset+5]))
+		if len(data) < offset {
+			return false, nil
+		}
+	}
+	if offset < 11+32+10 || !hmac.Equal(data[33:43], c.hmacSHA1(data[11:33])) || !hmac.Equal(data[offset-10:offset], c.hmacSHA1(data[:offset-10])) {
+		return false, errTLSTicketDecode
+	}
+	c.underDecoded.Write(data[offset:])
+	c.handshake.Reset()
+	c.handshakeRead = true
+	return true, c.writeFinish()
+}
+
+func (c *tls12TicketConn) decodeRecords() error {
+	for c.underDecoded.Len() > 5 {
+		header := c.underDecoded.Bytes()[:5]
+		if header[0] != 0x17 || header[1] != 3 || header[2] != 3 {
+			c.underDecoded.Reset()
+			return errTLSTicketDecode
+		}
+		size := int(binary.BigEndian.Uint16(header[3:5]))
+		if c.underDecoded.Len() < 5+size {
+			break
+		}
+		c.underDecoded.Next(5)
+		c.decoded.Write(c.underDecoded.Next(size))
+	}
+	return nil
+}
+
+func (c *tls12TicketConn) Write(b []byte) (int, error) {
+	c.access.Lock()
+	defer c.access.Unlock()
+	switch c.handshakeStatus {
+	case tlsHandshakeDone:
+		var buffer bytes.Buffer
+		data := b
+		for len(data) > 2048 {
+			size := mRand.Intn(4096) + 100
+			if len(data) < size {
+				size = len(data)
+			}
+			writeApplicationData(&buffer, data[:size])
+			data = data[size:]
+		}
+		if len(data) > 0 {
+			writeApplicationData(&buffer, data)
+		}
+		_, err := c.Conn.Write(buffer.Bytes())
+		if err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	case tlsHandshakeSent:
+		writeApplicationData(&c.pending, b)
+		return len(b), nil
+	default:
+		writeApplicationData(&c.pending, b)
+		c.handshakeStatus = tlsHandshakeSent
+		_, err := c.Conn.Write(c.clientHello())
+		if err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+}
+
+func (c *tls12TicketConn) writeFinish() error {
+	c.access.Lock()
+	defer c.access.Unlock()
+	var buffer bytes.Buffer
+	buffer.Write([]byte{0x14, 3, 3, 0, 1, 1, 0x16, 3, 3, 0, 0x20})
+	writeRandom(&buffer, 22)
+	buffer.Write(c.hmacSHA1(buffer.Bytes()))
+	buffer.Write(c.pending.Bytes())
+	c.pending.Reset()
+	c.handshakeStatus = tlsHandshakeDone
+	_, err := c.Conn.Write(buffer.Bytes())
+	return err
+}
+
+func (c *tls12TicketConn) clientHello() []byte {
+	var data bytes.Buffer
+	data.Write([]byte{3, 3})
+	// client random: timestamp, random and mac
+	random := make([]byte, 22)
+	binary.BigEndian.PutUint32(random, uint32(time.Now().Unix()))
+	common.Must1(rand.Read(random[4:]))
+	data.Write(random)
+	data.Write(c.hmacSHA1(random))
+	// session id
+	data.WriteByte(0x20)
+	data.Write(c.clientID[:])
+	// cipher suites and compression methods
+	data.Write([]byte{0x00, 0x1c, 0xc0, 0x2b, 0xc0, 0x2f, 0xcc, 0xa9, 0xcc, 0xa8, 0xcc, 0x14, 0xcc, 0x13, 0xc0, 0x0a, 0xc0, 0x14, 0xc0, 0x09, 0xc0, 0x13, 0x00, 0x9c, 0x00, 0x35, 0x00, 0x2f, 0x00, 0x0a})
+	data.Write([]byte{0x01, 0x00})
+
+	var extensions bytes.Buffer
+	extensions.Write([]byte{0xff, 0x01, 0x00, 0x01, 0x00})
+	writeServerName(&extensions, c.serverName())
+	extensions.Write([]byte{0x00, 0x17, 0x00, 0x00})
+	writeSessionTicket(&extensions)
+	extensions.Write([]byte{0x00, 0x0d, 0x00, 0x16, 0x00, 0x14, 0x06, 0x01, 0x06, 0x03, 0x05, 0x01, 0x05, 0x03, 0x04, 0x01, 0x04, 0x03, 0x03, 0x01, 0x03, 0x03, 0x02, 0x01, 0x02, 0x03})
+	extensions.Write([]byte{0x00, 0x05, 0x00, 0x05, 0x01, 0x00, 0x00, 0x00, 0x00})
+	extensions.Write([]byte{0x00, 0x12, 0x00, 0x00})
+	extensions.Write([]byte{0x75, 0x50, 0x00, 0x00})
+	extensions.Write([]byte{0x00, 0x0b, 0x00, 0x02, 0x01, 0x00})
+	extensions.Write([]byte{0x00, 0x0a, 0x00, 0x06, 0x00, 0x04, 0x00, 0x17, 0x00, 0x18})
+	common.Must(binary.Write(&data, binary.BigEndian, uint16(extensions.Len())))
+	data.Write(extensions.Bytes())
+
+	var record bytes.Buffer
+	record.Write([]byte{0x16, 3, 1})
+	common.Must(binary.Write(&record, binary.BigEndian, uint16(data.Len()+4)))
+	record.Write([]byte{1, 0})
+	common.Must(binary.Write(&record, binary.BigEndian, uint16(data.Len())))
+	record.Write(data.Bytes())
+	return record.Bytes()
+}
+
+func (c *tls12TicketConn) Upstream() any {
+	return c.Conn
+}
+
+func writeApplicationData(buffer *bytes.Buffer, data []byte) {
+	buffer.Write([]byte{0x17, 3, 3})
+	common.Must(binary.Write(buffer, binary.BigEndian, uint16(len(data))))
+	buffer.Write(data)
+}
+
+func writeServerName(buffer *bytes.Buffer, serverName string) {
+	length := uint16(len(serverName))
+	buffer.Write([]byte{0, 0})
+	common.Must(binary.Write(buffer, binary.BigEndian, length+5))
+	common.Must(binary.Write(buffer, binary.BigEndian, length+3))
+	buffer.WriteByte(0)
+	common.Must(binary.Write(buffer, binary.BigEndian, length))
+	buffer.WriteString(serverName)
+}
+
+func writeSessionTicket(buffer *bytes.Buffer) {
+	length := 16 * (mRand.Intn(17) + 8)
+	buffer.Write([]byte{0, 0x23})
+	common.Must(binary.Write(buffer, binary.BigEndian, uint16(length)))
+	writeRandom(buffer, length)
+}
+
+func writeRandom(buffer *bytes.Buffer, length int) {
+	random := make([]byte, length)
+	common.Must1(rand.Read(random))
+	buffer.Write(random)
+}
@@ -0,0 +1,229 @@
+package shadowsocksr
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"hash"
+	mRand "math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-shadowsocks"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var ProtocolList = []string{
+	"origin",
+	"auth_aes128_md5",
+	"auth_aes128_sha1",
+	"auth_chain_a",
+	"auth_chain_b",
+}
+
+type protocol interface {
+	StreamConn(conn net.Conn, iv []byte) net.Conn
+	EncodePacket(buffer *bytes.Buffer, payload []byte) error
+	DecodePacket(packet []byte) ([]byte, error)
+}
+
+type streamProtocol interface {
+	Encode(buffer *bytes.Buffer, payload []byte) error
+	Decode(dst *bytes.Buffer, src *bytes.Buffer) error
+}
+
+type protocolBase struct {
+	key      []byte
+	overhead int
+	param    string
+}
+
+func newProtocol(name string, base *protocolBase) (protocol, error) {
+	switch strings.ToLower(name) {
+	case "", "origin":
+		return &originProtocol{}, nil
+	case "auth_aes128_md5":
+		base.overhead += 9
+		return newAuthAES128(base, "auth_aes128_md5", md5.New), nil
+	case "auth_aes128_sha1":
+		base.overhead += 9
+		return newAuthAES128(base, "auth_aes128_sha1", sha1.New), nil
+	case "auth_chain_a":
+		base.overhead += 4
+		return newAuthChainA(base), nil
+	case "auth_chain_b":
+		base.overhead += 4
+		return newAuthChainB(base), nil
+	default:
+		return nil, E.New("unsupported ShadowsocksR protocol: ", name)
+	}
+}
+
+type originProtocol struct{}
+
+func (p *originProtocol) StreamConn(conn net.Conn, iv []byte) net.Conn {
+	return conn
+}
+
+func (p *originProtocol) EncodePacket(buffer *bytes.Buffer, payload []byte) error {
+	buffer.Write(payload)
+	return nil
+}
+
+func (p *originProtocol) DecodePacket(packet []byte) ([]byte, error) {
+	return packet, nil
+}
+
+type authData struct {
+	access       sync.Mutex
+	clientID     [4]byte
+	connectionID uint32
+}
+
+func (a *authData) next() *authData {
+	a.access.Lock()
+	defer a.access.Unlock()
+	if a.connectionID > 0xff000000 || a.connectionID == 0 {
+		common.Must1(rand.Read(a.clientID[:]))
+		a.connectionID = mRand.Uint32() & 0xffffff
+	}
+	a.connectionID++
+	return &authData{
+		clientID:     a.clientID,
+		connectionID: a.connectionID,
+	}
+}
+
+func (a *authData) writeEncrypted(buffer *bytes.Buffer, userKey []byte, paddings [2]int, salt string) error {
+	data := make([]byte, 16)
+	binary.LittleEndian.PutUint32(data, uint32(time.Now().Unix()))
+	copy(data[4:], a.clientID[:])
+	binary.LittleEndian.PutUint32(data[8:], a.connectionID)
+	binary.LittleEndian.PutUint16(data[12:], uint16(paddings[0]))
+	binary.LittleEndian.PutUint16(data[14:], uint16(paddings[1]))
+	block, err := aes.NewCipher(kdf(base64.StdEncoding.EncodeToString(userKey)+salt, 16))
+	if err != nil {
+		return err
+	}
+	cipher.NewCBCEncrypter(block, make([]byte, 16)).CryptBlocks(data, data)
+	buffer.Write(data)
+	return nil
+}
+
+type userData struct {
+	userKey []byte
+	userID  [4]byte
+}
+
+// parseUserData parses the protocol param in the form of uid:password
+func parseUserData(param string, key []byte, userKey func([]byte) []byte) *userData {
+	data := &userData{}
+	params := strings.Split(param, ":")
+	if len(params) > 1 {
+		if userID, err := strconv.ParseUint(params[0], 10, 32); err == nil {
+			binary.LittleEndian.PutUint32(data.userID[:], uint32(userID))
+			data.userKey = userKey([]byte(params[1]))
+		}
+	}
+	if len(data.userKey) == 0 {
+		data.userKey = key
+		common.Must1(rand.Read(data.userID[:]))
+	}
+	return data
+}
+
+type protocolConn struct {
+	net.Conn
+	protocol     streamProtocol
+	decoded      bytes.Buffer
+	underDecoded bytes.Buffer
+}
+
+func (c *protocolConn) Read(b []byte) (int, error) {
+	if c.decoded.Len() > 0 {
+		return c.decoded.Read(b)
+	}
+	buffer := make([]byte, 16384)
+	for {
+		n, err := c.Conn.Read(buffer)
+		if n > 0 {
+			c.underDecoded.Write(buffer[:n])
+			decodeErr := c.protocol.Decode(&c.decoded, &c.underDecoded)
+			if decodeErr != nil {
+				return 0, decodeErr
+			}
+			if c.decoded.Len() > 0 {
+				return c.decoded.Read(b)
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (c *protocolConn) Write(b []byte) (int, error) {
+	var buffer bytes.Buffer
+	err := c.protocol.Encode(&buffer, b)
+	if err != nil {
+		return 0, err
+	}
+	_, err = c.Conn.Write(buffer.Bytes())
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *protocolConn) Upstream() any {
+	return c.Conn
+}
+
+func kdf(password string, keyLength int) []byte {
+	return shadowsocks.Key([]byte(password), keyLength)
+}
+
+func hmacSum(h func() hash.Hash, key []byte, data []byte) []byte {
+	mac := hmac.New(h, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func hashSum(h func() hash.Hash, data []byte) []byte {
+	hasher := h()
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
+
+func headSize(data []byte, defaultValue int) int {
+	if len(data) < 2 {
+		return defaultValue
+	}
+	switch data[0] & 7 {
+	case 1:
+		return 7
+	case 4:
+		return 19
+	case 3:
+		return 4 + int(data[1])
+	}
+	return defaultValue
+}
+
+func firstPacketLength(data []byte) int {
+	length := headSize(data, 30) + mRand.Intn(32)
+	if len(data) < length {
+		return len(data)
+	}
+	return length
+}
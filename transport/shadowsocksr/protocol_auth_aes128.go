@@ -0,0 +1,239 @@
+package shadowsocksr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash"
+	"math"
+	mRand "math/rand"
+	"net"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var (
+	errAuthAES128MAC      = E.New("auth_aes128: incorrect mac")
+	errAuthAES128Length   = E.New("auth_aes128: invalid length")
+	errAuthAES128Checksum = E.New("auth_aes128: incorrect checksum")
+)
+
+// authAES128 is the ShadowsocksR auth_aes128_md5 and auth_aes128_sha1 protocol implementation
+type authAES128 struct {
+	*protocolBase
+	*authData
+	*userData
+	salt     string
+	hash     func() hash.Hash
+	iv       []byte
+	packID   uint32
+	recvID   uint32
+	rawTrans bool
+
+	headerSent bool
+}
+
+func newAuthAES128(base *protocolBase, salt string, h func() hash.Hash) *authAES128 {
+	return &authAES128{
+		protocolBase: base,
+		authData:     &authData{},
+		userData: parseUserData(base.param, base.key, func(password []byte) []byte {
+			return hashSum(h, password)
+		}),
+		salt: salt,
+		hash: h,
+	}
+}
+
+func (a *authAES128) newConn(iv []byte) *authAES128 {
+	return &authAES128{
+		protocolBase: a.protocolBase,
+		authData:     a.next(),
+		userData:     a.userData,
+		salt:         a.salt,
+		hash:         a.hash,
+		iv:           iv,
+		packID:       1,
+		recvID:       1,
+	}
+}
+
+func (a *authAES128) StreamConn(conn net.Conn, iv []byte) net.Conn {
+	return &protocolConn{Conn: conn, protocol: a.newConn(iv)}
+}
+
+func (a *authAES128) hmac(key []byte, data []byte) []byte {
+	return hmacSum(a.hash, key, data)
+}
+
+func (a *authAES128) Decode(dst *bytes.Buffer, src *bytes.Buffer) error {
+	if a.rawTrans {
+		_, err := dst.ReadFrom(src)
+		return err
+	}
+	for src.Len() > 4 {
+		macKey := make([]byte, len(a.userKey)+4)
+		copy(macKey, a.userKey)
+		binary.LittleEndian.PutUint32(macKey[len(a.userKey):], a.recvID)
+		data := src.Bytes()
+		if !bytes.Equal(a.hmac(macKey, data[:2])[:2], data[2:4]) {
+			src.Reset()
+			return errAuthAES128MAC
+		}
+		length := int(binary.LittleEndian.Uint16(data[:2]))
+		if length >= 8192 || length < 7 {
+			a.rawTrans = true
+			src.Reset()
+			return errAuthAES128Length
+		}
+		if length > src.Len() {
+			break
+		}
+		if !bytes.Equal(a.hmac(macKey, data[:length-4])[:4], data[length-4:length]) {
+			a.rawTrans = true
+			src.Reset()
+			return errAuthAES128Checksum
+		}
+		a.recvID++
+		position := int(data[4])
+		if position < 255 {
+			position += 4
+		} else {
+			position = int(binary.LittleEndian.Uint16(data[5:7])) + 4
+		}
+		if position > length-4 {
+			a.rawTrans = true
+			src.Reset()
+			return errAuthAES128Length
+		}
+		dst.Write(data[position : length-4])
+		src.Next(length)
+	}
+	return nil
+}
+
+func (a *authAES128) Encode(buffer *bytes.Buffer, payload []byte) error {
+	fullLength := len(payload)
+	if !a.headerSent {
+		headerLength := firstPacketLength(payload)
+		err := a.writeAuthData(buffer, payload[:headerLength])
+		if err != nil {
+			return err
+		}
+		payload = payload[headerLength:]
+		a.headerSent = true
+	}
+	for len(payload) > 8100 {
+		a.writeData(buffer, payload[:8100], fullLength)
+		payload = payload[8100:]
+	}
+	if len(payload) > 0 {
+		a.writeData(buffer, payload, fullLength)
+	}
+	return nil
+}
+
+func (a *authAES128) EncodePacket(buffer *bytes.Buffer, payload []byte) error {
+	start := buffer.Len()
+	buffer.Write(payload)
+	buffer.Write(a.userID[:])
+	buffer.Write(a.hmac(a.userKey, buffer.Bytes()[start:])[:4])
+	return nil
+}
+
+func (a *authAES128) DecodePacket(packet []byte) ([]byte, error) {
+	if len(packet) < 4 {
+		return nil, errAuthAES128Length
+	}
+	if !bytes.Equal(a.hmac(a.key, packet[:len(packet)-4])[:4], packet[len(packet)-4:]) {
+		return nil, errAuthAES128Checksum
+	}
+	return packet[:len(packet)-4], nil
+}
+
+func (a *authAES128) writeData(buffer *bytes.Buffer, data []byte, fullLength int) {
+	randLength := a.dataRandLength(len(data), fullLength)
+	// length(2) + mac of length(2) + random length prefix(1 or 3) + random + data + mac(4)
+	packedLength := 2 + 2 + 3 + randLength + len(data) + 4
+	if randLength < 128 {
+		packedLength -= 2
+	}
+	macKey := make([]byte, len(a.userKey)+4)
+	copy(macKey, a.userKey)
+	binary.LittleEndian.PutUint32(macKey[len(a.userKey):], a.packID)
+	a.packID++
+
+	start := buffer.Len()
+	common.Must(binary.Write(buffer, binary.LittleEndian, uint16(packedLength)))
+	buffer.Write(a.hmac(macKey, buffer.Bytes()[start:])[:2])
+	if randLength < 128 {
+		buffer.WriteByte(byte(randLength + 1))
+	} else {
+		buffer.WriteByte(255)
+		common.Must(binary.Write(buffer, binary.LittleEndian, uint16(randLength+3)))
+	}
+	writeRandom(buffer, randLength)
+	buffer.Write(data)
+	buffer.Write(a.hmac(macKey, buffer.Bytes()[start:])[:4])
+}
+
+func (a *authAES128) writeAuthData(buffer *bytes.Buffer, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	var randLength int
+	if len(data) > 400 {
+		randLength = mRand.Intn(512)
+	} else {
+		randLength = mRand.Intn(1024)
+	}
+	// check head(1) + mac of check head(6) + uid(4) + encrypted(16) + mac(4) + random + data + mac(4)
+	packedLength := 7 + 4 + 16 + 4 + randLength + len(data) + 4
+	macKey := make([]byte, len(a.iv)+len(a.key))
+	copy(macKey, a.iv)
+	copy(macKey[len(a.iv):], a.key)
+
+	start := buffer.Len()
+	writeRandom(buffer, 1)
+	buffer.Write(a.hmac(macKey, buffer.Bytes()[start:])[:6])
+	buffer.Write(a.userID[:])
+	err := a.writeEncrypted(buffer, a.userKey, [2]int{packedLength, randLength}, a.salt)
+	if err != nil {
+		return err
+	}
+	buffer.Write(a.hmac(a.userKey, buffer.Bytes()[start+7:])[:4])
+	writeRandom(buffer, randLength)
+	buffer.Write(data)
+	buffer.Write(a.hmac(a.userKey, buffer.Bytes()[start:])[:4])
+	return nil
+}
+
+func (a *authAES128) dataRandLength(length int, fullLength int) int {
+	if fullLength >= 32*1024-a.overhead {
+		return 0
+	}
+	// 1460: tcp mss
+	revLength := 1460 - length - 9
+	if revLength == 0 {
+		return 0
+	}
+	if revLength < 0 {
+		if revLength > -1460 {
+			return trapezoidRandom(revLength+1460, -0.3)
+		}
+		return mRand.Intn(32)
+	}
+	if length > 900 {
+		return mRand.Intn(revLength)
+	}
+	return trapezoidRandom(revLength, -0.3)
+}
+
+func trapezoidRandom(max int, d float64) int {
+	base := mRand.Float64()
+	if d-0 > 1e-6 {
+		a := 1 - d
+		base = (math.Sqrt(a*a+4*d*base) - a) / (2 * d)
+	}
+	return int(base * float64(max))
+}
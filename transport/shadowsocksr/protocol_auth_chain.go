@@ -0,0 +1,365 @@
+package shadowsocksr
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rc4"
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+	"sort"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var (
+	errAuthChainLength   = E.New("auth_chain: invalid length")
+	errAuthChainChecksum = E.New("auth_chain: incorrect checksum")
+)
+
+// authChainA is the ShadowsocksR auth_chain_a protocol implementation
+type authChainA struct {
+	*protocolBase
+	*authData
+	*userData
+	salt           string
+	iv             []byte
+	packID         uint32
+	recvID         uint32
+	rawTrans       bool
+	headerSent     bool
+	lastClientHash []byte
+	lastServerHash []byte
+	encrypter      cipher.Stream
+	decrypter      cipher.Stream
+	randomClient   shift128Plus
+	randomServer   shift128Plus
+	randLength     func(length int, lastHash []byte, random *shift128Plus) int
+}
+
+func newAuthChainA(base *protocolBase) *authChainA {
+	a := &authChainA{
+		protocolBase: base,
+		authData:     &authData{},
+		userData: parseUserData(base.param, base.key, func(password []byte) []byte {
+			return password
+		}),
+		salt: "auth_chain_a",
+	}
+	a.randLength = authChainARandLength
+	return a
+}
+
+func (a *authChainA) newConn(iv []byte) *authChainA {
+	return &authChainA{
+		protocolBase: a.protocolBase,
+		authData:     a.next(),
+		userData:     a.userData,
+		salt:         a.salt,
+		iv:           iv,
+		packID:       1,
+		recvID:       1,
+		randLength:   a.randLength,
+	}
+}
+
+func (a *authChainA) StreamConn(conn net.Conn, iv []byte) net.Conn {
+	return &protocolConn{Conn: conn, protocol: a.newConn(iv)}
+}
+
+func (a *authChainA) Decode(dst *bytes.Buffer, src *bytes.Buffer) error {
+	if a.rawTrans {
+		_, err := dst.ReadFrom(src)
+		return err
+	}
+	for src.Len() > 4 {
+		macKey := make([]byte, len(a.userKey)+4)
+		copy(macKey, a.userKey)
+		binary.LittleEndian.PutUint32(macKey[len(a.userKey):], a.recvID)
+
+		data := src.Bytes()
+		dataLength := int(binary.LittleEndian.Uint16(data[:2]) ^ binary.LittleEndian.Uint16(a.lastServerHash[14:16]))
+		randLength := a.randLength(dataLength, a.lastServerHash, &a.randomServer)
+		length := dataLength + randLength
+		if length >= 4096 {
+			a.rawTrans = true
+			src.Reset()
+			return errAuthChainLength
+		}
+		if length+4 > src.Len() {
+			break
+		}
+		serverHash := hmacSum(md5.New, macKey, data[:length+2])
+		if !bytes.Equal(serverHash[:2], data[length+2:length+4]) {
+			a.rawTrans = true
+			src.Reset()
+			return errAuthChainChecksum
+		}
+		a.lastServerHash = serverHash
+
+		position := 2
+		if dataLength > 0 && randLength > 0 {
+			position += randStartPosition(randLength, &a.randomServer)
+		}
+		payload := data[position : position+dataLength]
+		a.decrypter.XORKeyStream(payload, payload)
+		if a.recvID == 1 {
+			// the first server packet starts with the tcp mss
+			if len(payload) >= 2 {
+				payload = payload[2:]
+			}
+		}
+		dst.Write(payload)
+		a.recvID++
+		src.Next(length + 4)
+	}
+	return nil
+}
+
+func (a *authChainA) Encode(buffer *bytes.Buffer, payload []byte) error {
+	// the payload is encrypted in place by rc4
+	payload = append([]byte(nil), payload...)
+	if !a.headerSent {
+		headerLength := firstPacketLength(payload)
+		err := a.writeAuthData(buffer, payload[:headerLength])
+		if err != nil {
+			return err
+		}
+		payload = payload[headerLength:]
+		a.headerSent = true
+	}
+	for len(payload) > 2800 {
+		a.writeData(buffer, payload[:2800])
+		payload = payload[2800:]
+	}
+	if len(payload) > 0 {
+		a.writeData(buffer, payload)
+	}
+	return nil
+}
+
+func (a *authChainA) EncodePacket(buffer *bytes.Buffer, payload []byte) error {
+	authData := make([]byte, 3)
+	common.Must1(rand.Read(authData))
+	md5Data := hmacSum(md5.New, a.key, authData)
+	var random shift128Plus
+	randLength := udpRandLength(md5Data, &random)
+	rc4Cipher, err := rc4.NewCipher(kdf(base64.StdEncoding.EncodeToString(a.userKey)+base64.StdEncoding.EncodeToString(md5Data), 16))
+	if err != nil {
+		return err
+	}
+	start := buffer.Len()
+	encrypted := make([]byte, len(payload))
+	rc4Cipher.XORKeyStream(encrypted, payload)
+	buffer.Write(encrypted)
+	writeRandom(buffer, randLength)
+	buffer.Write(authData)
+	common.Must(binary.Write(buffer, binary.LittleEndian, binary.LittleEndian.Uint32(a.userID[:])^binary.LittleEndian.Uint32(md5Data[:4])))
+	buffer.Write(hmacSum(md5.New, a.userKey, buffer.Bytes()[start:])[:1])
+	return nil
+}
+
+func (a *authChainA) DecodePacket(packet []byte) ([]byte, error) {
+	if len(packet) < 9 {
+		return nil, errAuthChainLength
+	}
+	if !bytes.Equal(hmacSum(md5.New, a.userKey, packet[:len(packet)-1])[:1], packet[len(packet)-1:]) {
+		return nil, errAuthChainChecksum
+	}
+	md5Data := hmacSum(md5.New, a.key, packet[len(packet)-8:len(packet)-1])
+	var random shift128Plus
+	randLength := udpRandLength(md5Data, &random)
+	if len(packet) < 8+randLength {
+		return nil, errAuthChainLength
+	}
+	rc4Cipher, err := rc4.NewCipher(kdf(base64.StdEncoding.EncodeToString(a.userKey)+base64.StdEncoding.EncodeToString(md5Data), 16))
+	if err != nil {
+		return nil, err
+	}
+	payload := packet[:len(packet)-8-randLength]
+	rc4Cipher.XORKeyStream(payload, payload)
+	return payload, nil
+}
+
+func (a *authChainA) writeAuthData(buffer *bytes.Buffer, data []byte) error {
+	// check head(4) + mac of check head(8) + uid(4) + encrypted(16) + last server hash(4) + data
+	macKey := make([]byte, len(a.iv)+len(a.key))
+	copy(macKey, a.iv)
+	copy(macKey[len(a.iv):], a.key)
+
+	start := buffer.Len()
+	writeRandom(buffer, 4)
+	a.lastClientHash = hmacSum(md5.New, macKey, buffer.Bytes()[start:])
+	a.initRC4Cipher()
+	buffer.Write(a.lastClientHash[:8])
+	common.Must(binary.Write(buffer, binary.LittleEndian, binary.LittleEndian.Uint32(a.userID[:])^binary.LittleEndian.Uint32(a.lastClientHash[8:12])))
+	err := a.writeEncrypted(buffer, a.userKey, [2]int{a.overhead, 0}, a.salt)
+	if err != nil {
+		return err
+	}
+	a.lastServerHash = hmacSum(md5.New, a.userKey, buffer.Bytes()[start+12:])
+	buffer.Write(a.lastServerHash[:4])
+	a.writeData(buffer, data)
+	return nil
+}
+
+func (a *authChainA) writeData(buffer *bytes.Buffer, data []byte) {
+	a.encrypter.XORKeyStream(data, data)
+
+	macKey := make([]byte, len(a.userKey)+4)
+	copy(macKey, a.userKey)
+	binary.LittleEndian.PutUint32(macKey[len(a.userKey):], a.packID)
+	a.packID++
+
+	start := buffer.Len()
+	common.Must(binary.Write(buffer, binary.LittleEndian, uint16(len(data))^binary.LittleEndian.Uint16(a.lastClientHash[14:16])))
+	randLength := a.randLength(len(data), a.lastClientHash, &a.randomClient)
+	if len(data) == 0 {
+		writeRandom(buffer, randLength)
+	} else if randLength > 0 {
+		startPosition := randStartPosition(randLength, &a.randomClient)
+		writeRandom(buffer, startPosition)
+		buffer.Write(data)
+		writeRandom(buffer, randLength-startPosition)
+	} else {
+		buffer.Write(data)
+	}
+	a.lastClientHash = hmacSum(md5.New, macKey, buffer.Bytes()[start:])
+	buffer.Write(a.lastClientHash[:2])
+}
+
+func (a *authChainA) initRC4Cipher() {
+	key := kdf(base64.StdEncoding.EncodeToString(a.userKey)+base64.StdEncoding.EncodeToString(a.lastClientHash), 16)
+	a.encrypter, _ = rc4.NewCipher(key)
+	a.decrypter, _ = rc4.NewCipher(key)
+}
+
+func authChainARandLength(length int, lastHash []byte, random *shift128Plus) int {
+	if length > 1440 {
+		return 0
+	}
+	random.initFromBinAndLength(lastHash[:16], length)
+	switch {
+	case length > 1300:
+		return int(random.next() % 31)
+	case length > 900:
+		return int(random.next() % 127)
+	case length > 400:
+		return int(random.next() % 521)
+	default:
+		return int(random.next() % 1021)
+	}
+}
+
+func randStartPosition(length int, random *shift128Plus) int {
+	if length == 0 {
+		return 0
+	}
+	return int(int64(random.next()%8589934609) % int64(length))
+}
+
+func udpRandLength(lastHash []byte, random *shift128Plus) int {
+	random.initFromBin(lastHash)
+	return int(random.next() % 127)
+}
+
+// authChainB is the ShadowsocksR auth_chain_b protocol implementation
+type authChainB struct {
+	*authChainA
+	dataSizeList  []int
+	dataSizeList2 []int
+}
+
+func newAuthChainB(base *protocolBase) *authChainB {
+	b := &authChainB{
+		authChainA: newAuthChainA(base),
+	}
+	b.salt = "auth_chain_b"
+	b.initDataSizeList()
+	b.randLength = b.dataRandLength
+	return b
+}
+
+func (b *authChainB) initDataSizeList() {
+	var random shift128Plus
+	random.initFromBin(b.key)
+	length := random.next()%8 + 4
+	b.dataSizeList = make([]int, length)
+	for i := range b.dataSizeList {
+		b.dataSizeList[i] = int(random.next() % 2340 % 2040 % 1440)
+	}
+	sort.Ints(b.dataSizeList)
+
+	length = random.next()%16 + 8
+	b.dataSizeList2 = make([]int, length)
+	for i := range b.dataSizeList2 {
+		b.dataSizeList2[i] = int(random.next() % 2340 % 2040 % 1440)
+	}
+	sort.Ints(b.dataSizeList2)
+}
+
+func (b *authChainB) dataRandLength(length int, lastHash []byte, random *shift128Plus) int {
+	if length >= 1440 {
+		return 0
+	}
+	random.initFromBinAndLength(lastHash[:16], length)
+	position := sort.SearchInts(b.dataSizeList, length+b.overhead)
+	finalPosition := position + int(random.next()%uint64(len(b.dataSizeList)))
+	if finalPosition < len(b.dataSizeList) {
+		return b.dataSizeList[finalPosition] - length - b.overhead
+	}
+
+	position = sort.SearchInts(b.dataSizeList2, length+b.overhead)
+	finalPosition = position + int(random.next()%uint64(len(b.dataSizeList2)))
+	if finalPosition < len(b.dataSizeList2) {
+		return b.dataSizeList2[finalPosition] - length - b.overhead
+	}
+	if finalPosition < position+len(b.dataSizeList2)-1 {
+		return 0
+	}
+	switch {
+	case length > 1300:
+		return int(random.next() % 31)
+	case length > 900:
+		return int(random.next() % 127)
+	case length > 400:
+		return int(random.next() % 521)
+	default:
+		return int(random.next() % 1021)
+	}
+}
+
+type shift128Plus struct {
+	v [2]uint64
+}
+
+func (s *shift128Plus) initFromBin(bin []byte) {
+	var fillBin [16]byte
+	copy(fillBin[:], bin)
+	s.v[0] = binary.LittleEndian.Uint64(fillBin[:8])
+	s.v[1] = binary.LittleEndian.Uint64(fillBin[8:])
+}
+
+func (s *shift128Plus) initFromBinAndLength(bin []byte, length int) {
+	var fillBin [16]byte
+	copy(fillBin[:], bin)
+	binary.LittleEndian.PutUint16(fillBin[:2], uint16(length))
+	s.v[0] = binary.LittleEndian.Uint64(fillBin[:8])
+	s.v[1] = binary.LittleEndian.Uint64(fillBin[8:])
+	for i := 0; i < 4; i++ {
+		s.next()
+	}
+}
+
+func (s *shift128Plus) next() uint64 {
+	x := s.v[0]
+	y := s.v[1]
+	s.v[0] = y
+	x ^= x << 23
+	x ^= y ^ (x >> 17) ^ (y >> 26)
+	s.v[1] = x
+	return x + y
+}